@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/evrintobing17/ecommerce-system/gateway-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// Route sends the requests whose path below /api/v1 or /api/v2 matches
+// Pattern to the HTTP API of Service. In patterns ":name" matches one path
+// segment and a trailing "/*" matches the rest of the path, including nothing.
+type Route struct {
+	Pattern string
+	Service string
+}
+
+// GatewayHandler routes the /api/v1 requests to the services. Routes in
+// grpcRoutes are served by calling the gRPC API of their service, everything
+// else is proxied to the service's HTTP API. The end user authenticated by
+// the gateway is forwarded in a signed identity token.
+type GatewayHandler struct {
+	routes         []Route
+	grpcRoutes     []GRPCRoute
+	proxies        map[string]*httputil.ReverseProxy
+	identitySecret string
+}
+
+type ginContextKey struct{}
+
+// NewGatewayHandler returns a handler proxying to the base URLs in
+// upstreams, keyed by service name, through transport
+func NewGatewayHandler(routes []Route, grpcRoutes []GRPCRoute, upstreams map[string]*url.URL, transport http.RoundTripper, identitySecret string) *GatewayHandler {
+	proxies := make(map[string]*httputil.ReverseProxy, len(upstreams))
+	for service, target := range upstreams {
+		proxies[service] = newReverseProxy(service, target, transport)
+	}
+	return &GatewayHandler{
+		routes:         routes,
+		grpcRoutes:     grpcRoutes,
+		proxies:        proxies,
+		identitySecret: identitySecret,
+	}
+}
+
+// Handle serves a request below /api/v1, registered as "/api/v1/*path"
+func (h *GatewayHandler) Handle(c *gin.Context) {
+	path := c.Param("path")
+
+	for _, route := range h.grpcRoutes {
+		if route.Method != c.Request.Method {
+			continue
+		}
+		if params, ok := matchPath(route.Pattern, path); ok {
+			h.callGRPC(c, route, params)
+			return
+		}
+	}
+
+	h.proxyRoute(c, path)
+}
+
+// HandleTranscoded serves a request below /api/v2, registered as
+// "/api/v2/*path". The services transcode these to their gRPC APIs
+// themselves, so they are always proxied, using the same routes.
+func (h *GatewayHandler) HandleTranscoded(c *gin.Context) {
+	h.proxyRoute(c, c.Param("path"))
+}
+
+func (h *GatewayHandler) proxyRoute(c *gin.Context, path string) {
+	for _, route := range h.routes {
+		if _, ok := matchPath(route.Pattern, path); ok {
+			h.proxy(c, route.Service)
+			return
+		}
+	}
+
+	jsonhttpresponse.RouteNotFound(c)
+}
+
+func (h *GatewayHandler) proxy(c *gin.Context, service string) {
+	proxy, ok := h.proxies[service]
+	if !ok {
+		jsonhttpresponse.InternalServerError(c, fmt.Errorf("no upstream configured for %s", service))
+		return
+	}
+
+	// Only the gateway may set the identity
+	c.Request.Header.Del(serviceauth.IdentityHeader)
+	if claims, ok := middleware.GetClaims(c); ok {
+		token, err := serviceauth.GenerateIdentityToken(claims, h.identitySecret)
+		if err != nil {
+			jsonhttpresponse.InternalServerError(c, err)
+			return
+		}
+		c.Request.Header.Set(serviceauth.IdentityHeader, token)
+	}
+
+	req := c.Request.WithContext(context.WithValue(c.Request.Context(), ginContextKey{}, c))
+	proxy.ServeHTTP(c.Writer, req)
+}
+
+func newReverseProxy(service string, target *url.URL, transport http.RoundTripper) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			ctx := pr.In.Context()
+			// Forward the client IP resolved with the trusted proxies rather
+			// than the address of a load balancer in front of the gateway
+			if c, ok := ctx.Value(ginContextKey{}).(*gin.Context); ok {
+				pr.Out.Header.Set("X-Forwarded-For", c.ClientIP())
+			}
+			if requestID := shared.RequestIDFromContext(ctx); requestID != "" {
+				pr.Out.Header.Set(shared.RequestIDHeader, requestID)
+			}
+			tracing.InjectHTTP(ctx, pr.Out.Header)
+		},
+		Transport: transport,
+		ModifyResponse: func(resp *http.Response) error {
+			// The gateway already set these for the same request and trace
+			resp.Header.Del(shared.RequestIDHeader)
+			resp.Header.Del(tracing.TraceIDHeader)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			c, ok := r.Context().Value(ginContextKey{}).(*gin.Context)
+			if !ok {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			if errors.Is(err, context.Canceled) {
+				// The client went away, there is no one to answer
+				c.Abort()
+				return
+			}
+			slog.WarnContext(r.Context(), "upstream request failed", "service", service, "path", r.URL.Path, "error", err)
+			jsonhttpresponse.FromError(c, models.ErrServiceUnavailable.With("service", service).Wrap(err))
+		},
+	}
+}
+
+// matchPath matches path against pattern, see Route, and returns the values
+// of its ":name" segments
+func matchPath(pattern, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	params := make(map[string]string)
+	for i, segment := range patternSegments {
+		if segment == "*" && i == len(patternSegments)-1 {
+			return params, true
+		}
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:]] = pathSegments[i]
+		case segment != pathSegments[i]:
+			return nil, false
+		}
+	}
+	return params, len(patternSegments) == len(pathSegments)
+}
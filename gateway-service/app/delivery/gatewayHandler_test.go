@@ -0,0 +1,39 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		path       string
+		wantParams map[string]string
+		wantOK     bool
+	}{
+		{"/orders", "/orders", map[string]string{}, true},
+		{"/orders", "/orders/", map[string]string{}, true},
+		{"/orders/:order_id", "/orders/42", map[string]string{"order_id": "42"}, true},
+		{"/shops/:shop_id/members/:user_id", "/shops/3/members/9", map[string]string{"shop_id": "3", "user_id": "9"}, true},
+		{"/products/*", "/products/7/images/1", map[string]string{}, true},
+		{"/products/:id/*", "/products/7/images", map[string]string{"id": "7"}, true},
+		{"/orders/:order_id", "/orders", nil, false},
+		{"/orders/:order_id/cancel", "/orders//cancel", nil, false},
+		{"/orders/:order_id", "/orders/42/cancel", nil, false},
+		{"/orders/:order_id/cancel", "/orders/42/pay", nil, false},
+		{"/orders", "/products", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			params, ok := matchPath(tt.pattern, tt.path)
+			if ok != tt.wantOK {
+				t.Fatalf("matchPath = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
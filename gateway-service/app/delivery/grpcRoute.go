@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// GRPCRoute serves a REST route by calling a unary method of a gRPC API. The
+// request message is filled from the JSON body, then from the query string
+// and then from the ":name" segments of Pattern, which are matched to fields
+// by their proto names. The response message is returned as data, with the
+// proto field names.
+type GRPCRoute struct {
+	Method  string
+	Pattern string
+
+	Conn        grpc.ClientConnInterface
+	FullMethod  string
+	NewRequest  func() proto.Message
+	NewResponse func() proto.Message
+
+	// Authenticated routes reject anonymous requests before calling the
+	// service, for methods that do not require a user themselves
+	Authenticated bool
+	// Created answers 201 instead of 200
+	Created bool
+}
+
+var (
+	protoUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+	protoMarshal   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+)
+
+func (h *GatewayHandler) callGRPC(c *gin.Context, route GRPCRoute, params map[string]string) {
+	claims, authenticated := middleware.GetClaims(c)
+	if route.Authenticated && !authenticated {
+		jsonhttpresponse.Unauthorized(c, "authorization_required", "Authorization header is required")
+		return
+	}
+
+	req := route.NewRequest()
+	if err := bindProto(c, req, params); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	// The client IP resolved with the trusted proxies, which user-service
+	// keys its login protection on
+	ctx := metadata.AppendToOutgoingContext(c.Request.Context(), "x-forwarded-for", c.ClientIP())
+	if authenticated {
+		token, err := serviceauth.GenerateIdentityToken(claims, h.identitySecret)
+		if err != nil {
+			jsonhttpresponse.InternalServerError(c, err)
+			return
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, serviceauth.IdentityMetadataKey, token)
+	}
+
+	resp := route.NewResponse()
+	if err := route.Conn.Invoke(ctx, route.FullMethod, req, resp); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	data, err := protoMarshal.Marshal(resp)
+	if err != nil {
+		jsonhttpresponse.InternalServerError(c, err)
+		return
+	}
+	if route.Created {
+		jsonhttpresponse.StatusCreated(c, json.RawMessage(data))
+		return
+	}
+	jsonhttpresponse.OK(c, json.RawMessage(data))
+}
+
+// bindProto fills msg from the JSON body of the request, its query string and
+// the path parameters. Query parameters that are not fields of msg are
+// ignored.
+func bindProto(c *gin.Context, msg proto.Message, params map[string]string) error {
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return apperror.NewInvalidArgument("invalid_request", "request body could not be read").Wrap(err)
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := protoUnmarshal.Unmarshal(body, msg); err != nil {
+				return apperror.NewInvalidArgument("malformed_body", "request body is not valid JSON").Wrap(err)
+			}
+		}
+	}
+
+	for name, values := range c.Request.URL.Query() {
+		if err := setField(msg, name, values[0]); err != nil {
+			return err
+		}
+	}
+	for name, value := range params {
+		if err := setField(msg, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField sets the scalar field name of msg from its text value. A name
+// of the form "field[key]" sets the key of a map<string, string> field.
+func setField(msg proto.Message, name, value string) error {
+	m := msg.ProtoReflect()
+	if field, key, ok := mapEntryParam(m, name); ok {
+		m.Mutable(field).Map().Set(protoreflect.ValueOfString(key).MapKey(), protoreflect.ValueOfString(value))
+		return nil
+	}
+	field := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if field == nil || field.IsList() || field.IsMap() {
+		return nil
+	}
+
+	var v protoreflect.Value
+	var err error
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(value)
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 64)
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 64)
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.DoubleKind:
+		var f float64
+		f, err = strconv.ParseFloat(value, 64)
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(value, 32)
+		v = protoreflect.ValueOfFloat32(float32(f))
+	default:
+		return nil
+	}
+	if err != nil {
+		return apperror.NewInvalidArgument("invalid_parameter", "invalid "+name).With("parameter", name)
+	}
+	m.Set(field, v)
+	return nil
+}
+
+// mapEntryParam resolves a "field[key]" parameter name to a
+// map<string, string> field of m and the key
+func mapEntryParam(m protoreflect.Message, name string) (protoreflect.FieldDescriptor, string, bool) {
+	open := strings.IndexByte(name, '[')
+	if open <= 0 || !strings.HasSuffix(name, "]") {
+		return nil, "", false
+	}
+	field := m.Descriptor().Fields().ByName(protoreflect.Name(name[:open]))
+	if field == nil || !field.IsMap() ||
+		field.MapKey().Kind() != protoreflect.StringKind || field.MapValue().Kind() != protoreflect.StringKind {
+		return nil, "", false
+	}
+	return field, name[open+1 : len(name)-1], true
+}
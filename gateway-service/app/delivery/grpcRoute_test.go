@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	productProto "github.com/evrintobing17/ecommerce-system/shared/proto/product"
+	userProto "github.com/evrintobing17/ecommerce-system/shared/proto/user"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// connStub records the outgoing metadata of the last call and answers with
+// an empty response
+type connStub struct {
+	grpc.ClientConnInterface
+	md metadata.MD
+}
+
+func (c *connStub) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	return nil
+}
+
+// newGRPCRouter serves the login route of grpc mode through conn behind a
+// load balancer at 10.0.0.1
+func newGRPCRouter(t *testing.T, conn grpc.ClientConnInterface) *gin.Engine {
+	t.Helper()
+	handler := NewGatewayHandler(nil, []GRPCRoute{{
+		Method:      http.MethodPost,
+		Pattern:     "/login",
+		Conn:        conn,
+		FullMethod:  userProto.UserService_Login_FullMethodName,
+		NewRequest:  func() proto.Message { return &userProto.LoginRequest{} },
+		NewResponse: func() proto.Message { return &userProto.LoginResponse{} },
+	}}, nil, nil, "identity-secret")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	router.Any("/api/v1/*path", handler.Handle)
+	return router
+}
+
+func TestGRPCRouteForwardsClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"behind the load balancer", "10.0.0.1:4000", "198.51.100.9", "198.51.100.9"},
+		{"direct client forging the header", "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &connStub{}
+			router := newGRPCRouter(t, conn)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"email_or_phone":"customer@example.com","password":"secret"}`))
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body)
+			}
+			if got := conn.md.Get("x-forwarded-for"); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("x-forwarded-for = %v, want [%s]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindProto(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		params   map[string]string
+		want     *productProto.SearchProductsRequest
+		wantCode string
+	}{
+		{
+			name:   "body",
+			target: "/api/v1/products/search",
+			body:   `{"q":"lamp","min_price":10,"attributes":{"color":"red"},"colour":"ignored"}`,
+			want:   &productProto.SearchProductsRequest{Q: "lamp", MinPrice: proto.Float64(10), Attributes: map[string]string{"color": "red"}},
+		},
+		{
+			name:   "query and map entries",
+			target: "/api/v1/products/search?q=lamp&in_stock_only=true&page=2&max_price=99.5&attributes[color]=red&unknown=1",
+			want: &productProto.SearchProductsRequest{
+				Q:           "lamp",
+				InStockOnly: true,
+				Page:        2,
+				MaxPrice:    proto.Float64(99.5),
+				Attributes:  map[string]string{"color": "red"},
+			},
+		},
+		{
+			name:   "query over body, path over query",
+			target: "/api/v1/shops/3/products?q=desk&shop_id=4",
+			body:   `{"q":"lamp","page":2}`,
+			params: map[string]string{"shop_id": "3"},
+			want:   &productProto.SearchProductsRequest{Q: "desk", ShopId: 3, Page: 2},
+		},
+		{name: "malformed body", target: "/api/v1/products/search", body: `{"q":`, wantCode: "malformed_body"},
+		{name: "invalid number", target: "/api/v1/products/search?page=two", wantCode: "invalid_parameter"},
+		{name: "int32 overflow", target: "/api/v1/products/search?page=3000000000", wantCode: "invalid_parameter"},
+		{name: "invalid path parameter", target: "/api/v1/shops/x/products", params: map[string]string{"shop_id": "x"}, wantCode: "invalid_parameter"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tt.target, strings.NewReader(tt.body))
+
+			got := &productProto.SearchProductsRequest{}
+			err := bindProto(c, got, tt.params)
+			if tt.wantCode != "" {
+				if appErr, ok := apperror.As(err); !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("bindProto: err = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bindProto: %v", err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("request = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+import "github.com/evrintobing17/ecommerce-system/shared/apperror"
+
+var (
+	// ErrServiceUnavailable is returned when the service behind a route
+	// cannot be reached; the service is in its "service" metadata
+	ErrServiceUnavailable = apperror.NewUnavailable("service_unavailable", "service is unavailable")
+)
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+)
+
+// Modes of the gateway
+const (
+	// ModeProxy forwards every request to the HTTP API of its service
+	ModeProxy = "proxy"
+	// ModeGRPC calls the gRPC API of the service for the routes in
+	// grpcRoutes and proxies the rest
+	ModeGRPC = "grpc"
+)
+
+// Config is the configuration of the API gateway. It has no database, so it
+// does not embed config.Common.
+type Config struct {
+	ServiceAuth serviceauth.Config `yaml:"service_auth"`
+
+	// JWTSecret verifies end-user tokens, once, at the gateway
+	JWTSecret string `env:"JWT_SECRET" yaml:"jwt_secret" required:"true" secret:"true"`
+
+	HTTPAddr config.ListenAddr `env:"GATEWAY_PORT" yaml:"http_addr" default:"8000"`
+	Mode     string            `env:"GATEWAY_MODE" yaml:"mode" default:"proxy"`
+
+	UserServiceURL      string `env:"USER_SERVICE_URL" yaml:"user_service_url" default:"http://user-service:8080"`
+	ProductServiceURL   string `env:"PRODUCT_SERVICE_URL" yaml:"product_service_url" default:"http://product-service:8081"`
+	OrderServiceURL     string `env:"ORDER_SERVICE_URL" yaml:"order_service_url" default:"http://order-service:8082"`
+	ShopServiceURL      string `env:"SHOP_SERVICE_URL" yaml:"shop_service_url" default:"http://shop-service:8083"`
+	WarehouseServiceURL string `env:"WAREHOUSE_SERVICE_URL" yaml:"warehouse_service_url" default:"http://warehouse-service:8084"`
+
+	// gRPC addresses, only dialed in grpc mode
+	UserServiceAddr      string `env:"USER_SERVICE_GRPC_ADDR" yaml:"user_service_addr" default:"user-service:50058"`
+	ProductServiceAddr   string `env:"PRODUCT_SERVICE_GRPC_ADDR" yaml:"product_service_addr" default:"product-service:50052"`
+	WarehouseServiceAddr string `env:"WAREHOUSE_SERVICE_GRPC_ADDR" yaml:"warehouse_service_addr" default:"warehouse-service:50055"`
+
+	CORS      middleware.CORSConfig      `yaml:"cors"`
+	RateLimit middleware.RateLimitConfig `yaml:"rate_limit"`
+
+	// TrustedProxies are the addresses or CIDRs of load balancers in front
+	// of the gateway whose X-Forwarded-For header is believed. With none,
+	// the client IP is the address of the connection.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" yaml:"trusted_proxies"`
+
+	// UpstreamTimeout bounds a proxied request, including reading the
+	// response headers of the service
+	UpstreamTimeout time.Duration `env:"UPSTREAM_TIMEOUT" yaml:"upstream_timeout" default:"30s"`
+
+	LogLevel            string        `env:"LOG_LEVEL" yaml:"log_level" default:"info"`
+	ShutdownTimeout     time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" default:"30s"`
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" yaml:"health_check_interval" default:"10s"`
+	GRPCClientTimeout   time.Duration `env:"GRPC_CLIENT_TIMEOUT" yaml:"grpc_client_timeout" default:"5s"`
+}
+
+// Validate checks the mode, the service URLs and the secrets
+func (c *Config) Validate() error {
+	var errs []error
+	switch c.LogLevel {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL %q is not one of debug, info, warn, error", c.LogLevel))
+	}
+	switch c.Mode {
+	case ModeProxy, ModeGRPC:
+	default:
+		errs = append(errs, fmt.Errorf("GATEWAY_MODE %q is not one of proxy, grpc", c.Mode))
+	}
+
+	// The identity forwarded to the services is signed with the service
+	// token secret
+	if c.ServiceAuth.TokenSecret == "" {
+		errs = append(errs, errors.New("SERVICE_TOKEN_SECRET is required"))
+	}
+
+	for name, raw := range c.serviceURLs() {
+		if u, err := url.Parse(raw); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("URL of %s %q is not an absolute URL", name, raw))
+		}
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR", proxy))
+			}
+		}
+	}
+
+	if c.UpstreamTimeout <= 0 {
+		errs = append(errs, errors.New("UPSTREAM_TIMEOUT must be positive"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.HealthCheckInterval <= 0 {
+		errs = append(errs, errors.New("HEALTH_CHECK_INTERVAL must be positive"))
+	}
+	if c.GRPCClientTimeout <= 0 {
+		errs = append(errs, errors.New("GRPC_CLIENT_TIMEOUT must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// serviceURLs maps the name of each service to the base URL of its HTTP API
+func (c *Config) serviceURLs() map[string]string {
+	return map[string]string{
+		"user-service":      c.UserServiceURL,
+		"product-service":   c.ProductServiceURL,
+		"order-service":     c.OrderServiceURL,
+		"shop-service":      c.ShopServiceURL,
+		"warehouse-service": c.WarehouseServiceURL,
+	}
+}
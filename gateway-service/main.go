@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+
+	gatewayDelivery "github.com/evrintobing17/ecommerce-system/gateway-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+	"github.com/evrintobing17/ecommerce-system/shared/grpc_client"
+	"github.com/evrintobing17/ecommerce-system/shared/health"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/lifecycle"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+)
+
+func main() {
+	// Load configuration
+	var cfg Config
+	if err := config.Load("gateway-service", &cfg); err != nil {
+		log.Fatal(err)
+	}
+	cfg.ServiceAuth.ServiceName = "gateway-service"
+
+	// Initialize logger
+	shared.InitLogger("gateway-service", cfg.LogLevel)
+	config.Log(&cfg)
+	grpc_client.DefaultTimeout = cfg.GRPCClientTimeout
+
+	app := lifecycle.New("gateway-service", cfg.ShutdownTimeout)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "gateway-service")
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	app.AddCloser("tracing", shutdownTracing)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = cfg.UpstreamTimeout
+	healthClient := &http.Client{Transport: transport}
+
+	// /readyz of the gateway aggregates the readiness of every service
+	healthChecks := health.New("gateway-service", cfg.HealthCheckInterval)
+	upstreams := make(map[string]*url.URL)
+	for service, rawURL := range cfg.serviceURLs() {
+		target, err := url.Parse(rawURL)
+		if err != nil {
+			log.Fatalf("Invalid URL of %s: %v", service, err)
+		}
+		upstreams[service] = target
+		healthChecks.AddReadinessCheck(service, health.HTTPChecker(healthClient, target.JoinPath("readyz").String()))
+	}
+
+	var grpcRouteList []gatewayDelivery.GRPCRoute
+	if cfg.Mode == ModeGRPC {
+		conns := make(map[string]*grpc.ClientConn)
+		for service, addr := range map[string]string{
+			"user-service":      cfg.UserServiceAddr,
+			"product-service":   cfg.ProductServiceAddr,
+			"warehouse-service": cfg.WarehouseServiceAddr,
+		} {
+			conn, err := grpc_client.NewConnection(addr, cfg.ServiceAuth)
+			if err != nil {
+				log.Fatalf("Failed to connect to %s: %v", service, err)
+			}
+			app.AddCloser(service+" connection", func(context.Context) error {
+				return conn.Close()
+			})
+			healthChecks.AddReadinessCheck(service+" grpc", health.GRPCChecker(conn, ""))
+			conns[service] = conn
+		}
+		grpcRouteList = grpcRoutes(conns["user-service"], conns["product-service"], conns["warehouse-service"])
+	}
+
+	gatewayHandler := gatewayDelivery.NewGatewayHandler(routes, grpcRouteList, upstreams, transport, cfg.ServiceAuth.TokenSecret)
+
+	// Initialize HTTP server
+	router := gin.New()
+	// gin believes X-Forwarded-For from any peer by default, which would let
+	// clients pick the IP their rate limit is keyed on
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+	router.Use(tracing.GinMiddleware("gateway-service")...)
+	router.Use(middleware.RequestID(), middleware.AccessLog())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.CORS(cfg.CORS))
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
+	router.Use(shared.GinMetricsMiddleware())
+	shared.RegisterMetricsHandler(router)
+	healthChecks.RegisterHTTP(router)
+
+	// Tokens are validated once here; the services trust the forwarded
+	// identity. Anonymous requests pass for the public routes and are
+	// rejected by the services where a user is required.
+	api := router.Group("/api/v1")
+	api.Use(middleware.OptionalAuthMiddleware(cfg.JWTSecret), middleware.RateLimit(cfg.RateLimit))
+	api.Any("/*path", gatewayHandler.Handle)
+
+	apiV2 := router.Group("/api/v2")
+	apiV2.Use(middleware.OptionalAuthMiddleware(cfg.JWTSecret), middleware.RateLimit(cfg.RateLimit))
+	apiV2.Any("/*path", gatewayHandler.HandleTranscoded)
+
+	app.AddHTTPServer("Gateway", string(cfg.HTTPAddr), router)
+
+	app.AddWorker("health", healthChecks.Run)
+	app.OnShutdown(healthChecks.Shutdown)
+
+	if err := app.Run(); err != nil {
+		log.Fatal("Gateway stopped with error:", err)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+
+	gatewayDelivery "github.com/evrintobing17/ecommerce-system/gateway-service/app/delivery"
+	productpb "github.com/evrintobing17/ecommerce-system/shared/proto/product"
+	userpb "github.com/evrintobing17/ecommerce-system/shared/proto/user"
+	warehousepb "github.com/evrintobing17/ecommerce-system/shared/proto/warehouse"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// routes maps the paths below /api/v1 and /api/v2 to the service serving
+// them. The first matching route wins, so more specific patterns come first.
+var routes = []gatewayDelivery.Route{
+	{Pattern: "/register", Service: "user-service"},
+	{Pattern: "/login/*", Service: "user-service"},
+	{Pattern: "/password/*", Service: "user-service"},
+	{Pattern: "/verification/*", Service: "user-service"},
+	{Pattern: "/profile", Service: "user-service"},
+	{Pattern: "/mfa/*", Service: "user-service"},
+	{Pattern: "/users/*", Service: "user-service"},
+	{Pattern: "/roles/*", Service: "user-service"},
+
+	{Pattern: "/products/*", Service: "product-service"},
+	{Pattern: "/categories/*", Service: "product-service"},
+
+	{Pattern: "/checkout", Service: "order-service"},
+	{Pattern: "/orders/*", Service: "order-service"},
+	{Pattern: "/shops/:id/orders", Service: "order-service"},
+
+	{Pattern: "/shops/*", Service: "shop-service"},
+	{Pattern: "/invitations/*", Service: "shop-service"},
+
+	{Pattern: "/warehouses/*", Service: "warehouse-service"},
+}
+
+// grpcRoutes lists the routes served through the gRPC APIs in grpc mode.
+// Only methods that enforce the same access rules as their HTTP
+// counterparts are listed; their responses are the gRPC response messages.
+func grpcRoutes(userConn, productConn, warehouseConn grpc.ClientConnInterface) []gatewayDelivery.GRPCRoute {
+	return []gatewayDelivery.GRPCRoute{
+		{
+			Method: http.MethodPost, Pattern: "/register",
+			Conn: userConn, FullMethod: userpb.UserService_Register_FullMethodName,
+			NewRequest:  func() proto.Message { return &userpb.RegisterRequest{} },
+			NewResponse: func() proto.Message { return &userpb.RegisterResponse{} },
+			Created:     true,
+		},
+		{
+			Method: http.MethodPost, Pattern: "/login",
+			Conn: userConn, FullMethod: userpb.UserService_Login_FullMethodName,
+			NewRequest:  func() proto.Message { return &userpb.LoginRequest{} },
+			NewResponse: func() proto.Message { return &userpb.LoginResponse{} },
+		},
+		{
+			Method: http.MethodPost, Pattern: "/login/mfa",
+			Conn: userConn, FullMethod: userpb.UserService_VerifyMFA_FullMethodName,
+			NewRequest:  func() proto.Message { return &userpb.VerifyMFARequest{} },
+			NewResponse: func() proto.Message { return &userpb.LoginResponse{} },
+		},
+		{
+			Method: http.MethodGet, Pattern: "/products",
+			Conn: productConn, FullMethod: productpb.ProductService_GetProducts_FullMethodName,
+			NewRequest:    func() proto.Message { return &productpb.GetProductsRequest{} },
+			NewResponse:   func() proto.Message { return &productpb.GetProductsResponse{} },
+			Authenticated: true,
+		},
+		{
+			Method: http.MethodGet, Pattern: "/products/search",
+			Conn: productConn, FullMethod: productpb.ProductService_SearchProducts_FullMethodName,
+			NewRequest:    func() proto.Message { return &productpb.SearchProductsRequest{} },
+			NewResponse:   func() proto.Message { return &productpb.SearchProductsResponse{} },
+			Authenticated: true,
+		},
+		{
+			Method: http.MethodGet, Pattern: "/products/:product_id",
+			Conn: productConn, FullMethod: productpb.ProductService_GetProduct_FullMethodName,
+			NewRequest:    func() proto.Message { return &productpb.GetProductRequest{} },
+			NewResponse:   func() proto.Message { return &productpb.GetProductResponse{} },
+			Authenticated: true,
+		},
+		{
+			Method: http.MethodGet, Pattern: "/warehouses/stock",
+			Conn: warehouseConn, FullMethod: warehousepb.WarehouseService_GetStock_FullMethodName,
+			NewRequest:    func() proto.Message { return &warehousepb.GetStockRequest{} },
+			NewResponse:   func() proto.Message { return &warehousepb.GetStockResponse{} },
+			Authenticated: true,
+		},
+		{
+			Method: http.MethodGet, Pattern: "/warehouses/:warehouse_id",
+			Conn: warehouseConn, FullMethod: warehousepb.WarehouseService_GetWarehouse_FullMethodName,
+			NewRequest:    func() proto.Message { return &warehousepb.GetWarehouseRequest{} },
+			NewResponse:   func() proto.Message { return &warehousepb.GetWarehouseResponse{} },
+			Authenticated: true,
+		},
+		{
+			Method: http.MethodGet, Pattern: "/warehouses",
+			Conn: warehouseConn, FullMethod: warehousepb.WarehouseService_GetWarehouses_FullMethodName,
+			NewRequest:    func() proto.Message { return &warehousepb.GetWarehousesRequest{} },
+			NewResponse:   func() proto.Message { return &warehousepb.GetWarehousesResponse{} },
+			Authenticated: true,
+		},
+		{
+			Method: http.MethodPost, Pattern: "/warehouses",
+			Conn: warehouseConn, FullMethod: warehousepb.WarehouseService_CreateWarehouse_FullMethodName,
+			NewRequest:  func() proto.Message { return &warehousepb.CreateWarehouseRequest{} },
+			NewResponse: func() proto.Message { return &warehousepb.CreateWarehouseResponse{} },
+			Created:     true,
+		},
+		{
+			Method: http.MethodPost, Pattern: "/warehouses/transfer",
+			Conn: warehouseConn, FullMethod: warehousepb.WarehouseService_TransferStock_FullMethodName,
+			NewRequest:  func() proto.Message { return &warehousepb.TransferStockRequest{} },
+			NewResponse: func() proto.Message { return &warehousepb.TransferStockResponse{} },
+		},
+	}
+}
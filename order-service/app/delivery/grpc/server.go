@@ -2,14 +2,13 @@ package grpc
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/order"
 
 	"github.com/evrintobing17/ecommerce-system/order-service/app"
 	"github.com/evrintobing17/ecommerce-system/order-service/app/models"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
 )
 
 type orderServer struct {
@@ -21,113 +20,87 @@ func NewOrderServer(orderUsecase app.OrderUsecase) *orderServer {
 	return &orderServer{orderUsecase: orderUsecase}
 }
 
+// CreateOrder places an order for the calling user
 func (s *orderServer) CreateOrder(ctx context.Context, req *proto.CreateOrderRequest) (*proto.CreateOrderResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, middleware.ErrAuthorizationRequired
+	}
+
 	// Convert proto items to domain items
 	var items []models.OrderItem
 	for _, item := range req.Items {
 		items = append(items, models.OrderItem{
 			ProductID: int(item.ProductId),
+			VariantID: int(item.VariantId),
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 		})
 	}
 
-	order, err := s.orderUsecase.CreateOrder(int(req.UserId), items)
+	order, err := s.orderUsecase.CreateOrder(ctx, claims.UserID, items)
 	if err != nil {
-		log.Printf("CreateOrder error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to create order: %v", err)
-	}
-
-	// Convert domain order to proto order
-	var protoItems []*proto.OrderItem
-	for _, item := range order.Items {
-		protoItems = append(protoItems, &proto.OrderItem{
-			ProductId: int32(item.ProductID),
-			Quantity:  item.Quantity,
-			Price:     item.Price,
-		})
+		slog.WarnContext(ctx, "create order failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.CreateOrderResponse{
-		Order: &proto.Order{
-			Id:          int32(order.ID),
-			UserId:      int32(order.UserID),
-			Items:       protoItems,
-			TotalAmount: order.TotalAmount,
-			Status:      string(order.Status),
-			CreatedAt:   order.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   order.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
+		Order: toProtoOrder(order),
 	}, nil
 }
 
 func (s *orderServer) GetOrder(ctx context.Context, req *proto.GetOrderRequest) (*proto.GetOrderResponse, error) {
-	order, err := s.orderUsecase.GetOrder(int(req.OrderId))
+	order, err := s.orderUsecase.GetOrder(ctx, int(req.OrderId))
 	if err != nil {
-		log.Printf("GetOrder error: %v", err)
-		return nil, status.Errorf(codes.NotFound, "order not found: %v", err)
+		slog.WarnContext(ctx, "get order failed", "error", err)
+		return nil, err
 	}
-
-	// Convert domain order to proto order
-	var protoItems []*proto.OrderItem
-	for _, item := range order.Items {
-		protoItems = append(protoItems, &proto.OrderItem{
-			ProductId: int32(item.ProductID),
-			Quantity:  item.Quantity,
-			Price:     item.Price,
-		})
+	if err := authorizeOrder(ctx, order); err != nil {
+		return nil, err
 	}
 
 	return &proto.GetOrderResponse{
-		Order: &proto.Order{
-			Id:          int32(order.ID),
-			UserId:      int32(order.UserID),
-			Items:       protoItems,
-			TotalAmount: order.TotalAmount,
-			Status:      string(order.Status),
-			CreatedAt:   order.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   order.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
+		Order: toProtoOrder(order),
 	}, nil
 }
 
 func (s *orderServer) ProcessPayment(ctx context.Context, req *proto.ProcessPaymentRequest) (*proto.ProcessPaymentResponse, error) {
-	order, err := s.orderUsecase.ProcessPayment(int(req.OrderId), req.PaymentMethod, req.PaymentDetails)
+	order, err := s.orderUsecase.GetOrder(ctx, int(req.OrderId))
 	if err != nil {
-		log.Printf("ProcessPayment error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to process payment: %v", err)
+		slog.WarnContext(ctx, "process payment failed", "error", err)
+		return nil, err
+	}
+	if err := authorizeOrder(ctx, order); err != nil {
+		return nil, err
 	}
 
-	// Convert domain order to proto order
-	var protoItems []*proto.OrderItem
-	for _, item := range order.Items {
-		protoItems = append(protoItems, &proto.OrderItem{
-			ProductId: int32(item.ProductID),
-			Quantity:  item.Quantity,
-			Price:     item.Price,
-		})
+	order, err = s.orderUsecase.ProcessPayment(ctx, int(req.OrderId), req.PaymentMethod, req.PaymentDetails)
+	if err != nil {
+		slog.WarnContext(ctx, "process payment failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.ProcessPaymentResponse{
 		Success: true,
 		Message: "Payment processed successfully",
-		Order: &proto.Order{
-			Id:          int32(order.ID),
-			UserId:      int32(order.UserID),
-			Items:       protoItems,
-			TotalAmount: order.TotalAmount,
-			Status:      string(order.Status),
-			CreatedAt:   order.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   order.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
+		Order:   toProtoOrder(order),
 	}, nil
 }
 
 func (s *orderServer) CancelOrder(ctx context.Context, req *proto.CancelOrderRequest) (*proto.CancelOrderResponse, error) {
-	err := s.orderUsecase.CancelOrder(int(req.OrderId))
+	order, err := s.orderUsecase.GetOrder(ctx, int(req.OrderId))
 	if err != nil {
-		log.Printf("CancelOrder error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to cancel order: %v", err)
+		slog.WarnContext(ctx, "cancel order failed", "error", err)
+		return nil, err
+	}
+	if err := authorizeOrder(ctx, order); err != nil {
+		return nil, err
+	}
+
+	err = s.orderUsecase.CancelOrder(ctx, int(req.OrderId))
+	if err != nil {
+		slog.WarnContext(ctx, "cancel order failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.CancelOrderResponse{
@@ -135,3 +108,72 @@ func (s *orderServer) CancelOrder(ctx context.Context, req *proto.CancelOrderReq
 		Message: "Order cancelled successfully",
 	}, nil
 }
+
+// ListOrders lists the orders of the calling user
+func (s *orderServer) ListOrders(ctx context.Context, req *proto.ListOrdersRequest) (*proto.ListOrdersResponse, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, middleware.ErrAuthorizationRequired
+	}
+
+	page, limit := int(req.Page), int(req.Limit)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	orders, total, err := s.orderUsecase.GetUserOrders(ctx, claims.UserID, page, limit)
+	if err != nil {
+		slog.WarnContext(ctx, "list orders failed", "error", err)
+		return nil, err
+	}
+
+	protoOrders := make([]*proto.Order, 0, len(orders))
+	for _, order := range orders {
+		protoOrders = append(protoOrders, toProtoOrder(order))
+	}
+
+	return &proto.ListOrdersResponse{
+		Orders: protoOrders,
+		Total:  int32(total),
+		Page:   int32(page),
+		Limit:  int32(limit),
+	}, nil
+}
+
+// authorizeOrder rejects callers other than the owner of the order
+func authorizeOrder(ctx context.Context, order *models.Order) error {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return middleware.ErrAuthorizationRequired
+	}
+	if order.UserID != claims.UserID {
+		return models.ErrOrderAccessDenied
+	}
+	return nil
+}
+
+// toProtoOrder converts a domain order to its proto representation
+func toProtoOrder(order *models.Order) *proto.Order {
+	var protoItems []*proto.OrderItem
+	for _, item := range order.Items {
+		protoItems = append(protoItems, &proto.OrderItem{
+			ProductId: int32(item.ProductID),
+			VariantId: int32(item.VariantID),
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+
+	return &proto.Order{
+		Id:          int32(order.ID),
+		UserId:      int32(order.UserID),
+		Items:       protoItems,
+		TotalAmount: order.TotalAmount,
+		Status:      string(order.Status),
+		CreatedAt:   order.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   order.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
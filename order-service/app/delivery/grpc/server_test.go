@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/order-service/app"
+	"github.com/evrintobing17/ecommerce-system/order-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	proto "github.com/evrintobing17/ecommerce-system/shared/proto/order"
+)
+
+// orderUsecaseStub serves pending order 1 of user 1 and records the orders
+// it is asked to pay or cancel
+type orderUsecaseStub struct {
+	app.OrderUsecase
+	paid      []int
+	cancelled []int
+	orderedBy []int
+}
+
+func (u *orderUsecaseStub) CreateOrder(ctx context.Context, userID int, items []models.OrderItem) (*models.Order, error) {
+	u.orderedBy = append(u.orderedBy, userID)
+	return &models.Order{ID: 2, UserID: userID, Status: models.OrderStatusPending, Items: items}, nil
+}
+
+func (u *orderUsecaseStub) GetOrder(ctx context.Context, id int) (*models.Order, error) {
+	if id != 1 {
+		return nil, models.ErrOrderNotFound
+	}
+	return &models.Order{ID: id, UserID: 1, Status: models.OrderStatusPending}, nil
+}
+
+func (u *orderUsecaseStub) ProcessPayment(ctx context.Context, orderID int, paymentMethod, paymentDetails string) (*models.Order, error) {
+	u.paid = append(u.paid, orderID)
+	return &models.Order{ID: orderID, UserID: 1, Status: models.OrderStatusPaid}, nil
+}
+
+func (u *orderUsecaseStub) CancelOrder(ctx context.Context, orderID int) error {
+	u.cancelled = append(u.cancelled, orderID)
+	return nil
+}
+
+func TestOrderAccess(t *testing.T) {
+	owner := middleware.ContextWithClaims(context.Background(), &shared.Claims{UserID: 1})
+	stranger := middleware.ContextWithClaims(context.Background(), &shared.Claims{UserID: 2})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"owner", owner, nil},
+		{"other user", stranger, models.ErrOrderAccessDenied},
+		{"service caller without claims", context.Background(), middleware.ErrAuthorizationRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := &orderUsecaseStub{}
+			server := NewOrderServer(usecase)
+
+			_, err := server.ProcessPayment(tt.ctx, &proto.ProcessPaymentRequest{OrderId: 1, PaymentMethod: "card"})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ProcessPayment: err = %v, want %v", err, tt.want)
+			}
+			_, err = server.CancelOrder(tt.ctx, &proto.CancelOrderRequest{OrderId: 1})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CancelOrder: err = %v, want %v", err, tt.want)
+			}
+			_, err = server.GetOrder(tt.ctx, &proto.GetOrderRequest{OrderId: 1})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("GetOrder: err = %v, want %v", err, tt.want)
+			}
+
+			if tt.want != nil && (len(usecase.paid) > 0 || len(usecase.cancelled) > 0) {
+				t.Errorf("paid %v and cancelled %v, want no changes for a rejected caller", usecase.paid, usecase.cancelled)
+			}
+		})
+	}
+}
+
+func TestCreateOrderIsForTheCaller(t *testing.T) {
+	usecase := &orderUsecaseStub{}
+	server := NewOrderServer(usecase)
+	request := &proto.CreateOrderRequest{UserId: 2, Items: []*proto.OrderItem{{ProductId: 1, Quantity: 1, Price: 10}}}
+
+	if _, err := server.CreateOrder(context.Background(), request); !errors.Is(err, middleware.ErrAuthorizationRequired) {
+		t.Errorf("without claims: err = %v, want %v", err, middleware.ErrAuthorizationRequired)
+	}
+
+	ctx := middleware.ContextWithClaims(context.Background(), &shared.Claims{UserID: 1})
+	if _, err := server.CreateOrder(ctx, request); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if len(usecase.orderedBy) != 1 || usecase.orderedBy[0] != 1 {
+		t.Errorf("ordered for users %v, want [1] regardless of user_id", usecase.orderedBy)
+	}
+}
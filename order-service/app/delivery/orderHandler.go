@@ -2,11 +2,11 @@ package http
 
 import (
 	"fmt"
-	"net/http"
 	"strconv"
 
 	"github.com/evrintobing17/ecommerce-system/order-service/app"
 	"github.com/evrintobing17/ecommerce-system/order-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,7 +21,7 @@ func NewOrderHandler(orderUsecase app.OrderUsecase) *OrderHandler {
 func (h *OrderHandler) Checkout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -30,17 +30,17 @@ func (h *OrderHandler) Checkout(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	order, err := h.orderUsecase.Checkout(userID.(int), request.Items)
+	order, err := h.orderUsecase.Checkout(c.Request.Context(), userID.(int), request.Items)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	jsonhttpresponse.StatusCreated(c, gin.H{
 		"order": order,
 		"message": "Order created successfully. Please complete payment within 5 minutes.",
 	})
@@ -51,7 +51,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	id := fmt.Sprint(userID)
 	convID, _ := strconv.Atoi(id)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -60,17 +60,17 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	order, err := h.orderUsecase.CreateOrder(convID, request.Items)
+	order, err := h.orderUsecase.CreateOrder(c.Request.Context(), convID, request.Items)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	jsonhttpresponse.StatusCreated(c, gin.H{
 		"order": order,
 	})
 }
@@ -79,23 +79,23 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 	orderID, _ := strconv.Atoi(c.Param("id"))
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
-	order, err := h.orderUsecase.GetOrder(orderID)
+	order, err := h.orderUsecase.GetOrder(c.Request.Context(), orderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
 	// Check if the user owns this order
 	if order.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		jsonhttpresponse.FromError(c, models.ErrOrderAccessDenied)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"order": order,
 	})
 }
@@ -103,7 +103,7 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 func (h *OrderHandler) GetUserOrders(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -111,25 +111,40 @@ func (h *OrderHandler) GetUserOrders(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	id := fmt.Sprint(userID)
 	convID, _ := strconv.Atoi(id)
-	orders, total, err := h.orderUsecase.GetUserOrders(convID, page, limit)
+	orders, total, err := h.orderUsecase.GetUserOrders(c.Request.Context(), convID, page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.Paginated(c, gin.H{
 		"orders": orders,
-		"total":  total,
-		"page":   page,
-		"limit":  limit,
-	})
+	}, page, limit, total)
+}
+
+// GetShopOrders lists the orders of a shop. Access is checked by
+// RequireShopPermission with the order:view action.
+func (h *OrderHandler) GetShopOrders(c *gin.Context) {
+	shopID, _ := strconv.Atoi(c.Param("id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	orders, total, err := h.orderUsecase.GetShopOrders(c.Request.Context(), shopID, page, limit)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.Paginated(c, gin.H{
+		"orders": orders,
+	}, page, limit, total)
 }
 
 func (h *OrderHandler) ProcessPayment(c *gin.Context) {
 	orderID, _ := strconv.Atoi(c.Param("id"))
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -139,29 +154,29 @@ func (h *OrderHandler) ProcessPayment(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	order, err := h.orderUsecase.GetOrder(orderID)
+	order, err := h.orderUsecase.GetOrder(c.Request.Context(), orderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
 	// Check if the user owns this order
 	if order.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		jsonhttpresponse.FromError(c, models.ErrOrderAccessDenied)
 		return
 	}
 
-	order, err = h.orderUsecase.ProcessPayment(orderID, request.PaymentMethod, request.PaymentDetails)
+	order, err = h.orderUsecase.ProcessPayment(c.Request.Context(), orderID, request.PaymentMethod, request.PaymentDetails)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"order":   order,
 		"message": "Payment processed successfully",
 	})
@@ -171,29 +186,29 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	orderID, _ := strconv.Atoi(c.Param("id"))
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
-	order, err := h.orderUsecase.GetOrder(orderID)
+	order, err := h.orderUsecase.GetOrder(c.Request.Context(), orderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
 	// Check if the user owns this order
 	if order.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		jsonhttpresponse.FromError(c, models.ErrOrderAccessDenied)
 		return
 	}
 
-	err = h.orderUsecase.CancelOrder(orderID)
+	err = h.orderUsecase.CancelOrder(c.Request.Context(), orderID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"message": "Order cancelled successfully",
 	})
 }
@@ -0,0 +1,13 @@
+package models
+
+import "github.com/evrintobing17/ecommerce-system/shared/apperror"
+
+var (
+	ErrOrderNotFound       = apperror.NewNotFound("order_not_found", "order not found")
+	ErrOrderAccessDenied   = apperror.NewPermissionDenied("order_access_denied", "access denied")
+	ErrOrderNotPending     = apperror.NewFailedPrecondition("order_not_pending", "order is not in pending status")
+	ErrOrderNotCancellable = apperror.NewFailedPrecondition("order_not_cancellable", "only pending orders can be cancelled")
+	ErrInsufficientStock   = apperror.NewFailedPrecondition("insufficient_stock", "could not reserve stock")
+	ErrVariantNotFound     = apperror.NewNotFound("variant_not_found", "the product has no such variant")
+	ErrProductUnavailable  = apperror.NewFailedPrecondition("product_unavailable", "the product is not published")
+)
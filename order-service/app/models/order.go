@@ -26,8 +26,9 @@ type Order struct {
 
 type OrderItem struct {
 	ID        int       `gorm:"primaryKey" json:"id"`
-	OrderID   string    `json:"order_id"`
+	OrderID   int       `json:"order_id"`
 	ProductID int       `json:"product_id"`
+	VariantID int       `json:"variant_id"`
 	ShopID    int       `json:"shop_id"`
 	Quantity  int32     `json:"quantity"`
 	Price     float64   `json:"price"`
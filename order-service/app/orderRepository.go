@@ -1,17 +1,19 @@
 package app
 
 import (
+	"context"
 	"time"
 
 	"github.com/evrintobing17/ecommerce-system/order-service/app/models"
 )
 
 type OrderRepository interface {
-	FindExpiredOrders(expiredTime time.Time) ([]*models.Order, error) // New method
-	Create(order *models.Order) error
-	FindByID(id int) (*models.Order, error)
-	FindByUserID(userID, page, limit int) ([]*models.Order, int64, error)
-	Update(order *models.Order) error
-	UpdateStatus(id int, status models.OrderStatus) error
-	Delete(id int) error
+	FindExpiredOrders(ctx context.Context, expiredTime time.Time) ([]*models.Order, error) // New method
+	Create(ctx context.Context, order *models.Order) error
+	FindByID(ctx context.Context, id int) (*models.Order, error)
+	FindByUserID(ctx context.Context, userID, page, limit int) ([]*models.Order, int64, error)
+	FindByShopID(ctx context.Context, shopID, page, limit int) ([]*models.Order, int64, error)
+	Update(ctx context.Context, order *models.Order) error
+	UpdateStatus(ctx context.Context, id int, status models.OrderStatus) error
+	Delete(ctx context.Context, id int) error
 }
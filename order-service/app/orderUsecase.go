@@ -1,13 +1,18 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/order-service/app/models"
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/order-service/app/models"
+)
 
 type OrderUsecase interface {
-	CreateOrder(userID int, items []models.OrderItem) (*models.Order, error)
-	GetOrder(id int) (*models.Order, error)
-	GetUserOrders(userID, page, limit int) ([]*models.Order, int64, error)
-	ProcessPayment(orderID int, paymentMethod, paymentDetails string) (*models.Order, error)
-	CancelOrder(orderID int) error
-	Checkout(userID int, items []models.OrderItem) (*models.Order, error)
-	ReleaseExpiredOrders() error
+	CreateOrder(ctx context.Context, userID int, items []models.OrderItem) (*models.Order, error)
+	GetOrder(ctx context.Context, id int) (*models.Order, error)
+	GetUserOrders(ctx context.Context, userID, page, limit int) ([]*models.Order, int64, error)
+	GetShopOrders(ctx context.Context, shopID, page, limit int) ([]*models.Order, int64, error)
+	ProcessPayment(ctx context.Context, orderID int, paymentMethod, paymentDetails string) (*models.Order, error)
+	CancelOrder(ctx context.Context, orderID int) error
+	Checkout(ctx context.Context, userID int, items []models.OrderItem) (*models.Order, error)
+	ReleaseExpiredOrders(ctx context.Context) error
 }
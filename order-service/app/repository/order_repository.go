@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/evrintobing17/ecommerce-system/order-service/app"
@@ -19,9 +21,9 @@ func NewOrderRepository(db *gorm.DB) app.OrderRepository {
 }
 
 
-func (r *orderRepository) FindExpiredOrders(expiredTime time.Time) ([]*models.Order, error) {
+func (r *orderRepository) FindExpiredOrders(ctx context.Context, expiredTime time.Time) ([]*models.Order, error) {
 	var orders []*models.Order
-	err := r.db.Preload("Items").
+	err := r.db.WithContext(ctx).Preload("Items").
 		Where("status = ? AND expires_at <= ?", models.OrderStatusPending, expiredTime).
 		Find(&orders).Error
 	if err != nil {
@@ -30,35 +32,35 @@ func (r *orderRepository) FindExpiredOrders(expiredTime time.Time) ([]*models.Or
 	return orders, nil
 }
 
-func (r *orderRepository) Create(order *models.Order) error {
-	return r.db.Create(order).Error
+func (r *orderRepository) Create(ctx context.Context, order *models.Order) error {
+	return r.db.WithContext(ctx).Create(order).Error
 }
 
-func (r *orderRepository) FindByID(id int) (*models.Order, error) {
+func (r *orderRepository) FindByID(ctx context.Context, id int) (*models.Order, error) {
 	var order models.Order
-	err := r.db.Preload("Items").First(&order, "id = ?", id).Error
+	err := r.db.WithContext(ctx).Preload("Items").First(&order, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("order not found")
+			return nil, models.ErrOrderNotFound.With("order_id", strconv.Itoa(id))
 		}
 		return nil, err
 	}
 	return &order, nil
 }
 
-func (r *orderRepository) FindByUserID(userID, page, limit int) ([]*models.Order, int64, error) {
+func (r *orderRepository) FindByUserID(ctx context.Context, userID, page, limit int) ([]*models.Order, int64, error) {
 	var orders []*models.Order
 	var total int64
 
 	// Get total count
-	err := r.db.Model(&models.Order{}).Where("user_id = ?", userID).Count(&total).Error
+	err := r.db.WithContext(ctx).Model(&models.Order{}).Where("user_id = ?", userID).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Apply pagination
 	offset := (page - 1) * limit
-	err = r.db.Preload("Items").Where("user_id = ?", userID).Offset(offset).Limit(limit).Find(&orders).Error
+	err = r.db.WithContext(ctx).Preload("Items").Where("user_id = ?", userID).Offset(offset).Limit(limit).Find(&orders).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -66,14 +68,40 @@ func (r *orderRepository) FindByUserID(userID, page, limit int) ([]*models.Order
 	return orders, total, nil
 }
 
-func (r *orderRepository) Update(order *models.Order) error {
-	return r.db.Save(order).Error
+func (r *orderRepository) FindByShopID(ctx context.Context, shopID, page, limit int) ([]*models.Order, int64, error) {
+	var orders []*models.Order
+	var total int64
+
+	shopOrders := r.db.WithContext(ctx).Model(&models.OrderItem{}).Select("order_id").Where("shop_id = ?", shopID)
+
+	// Get total count
+	err := r.db.WithContext(ctx).Model(&models.Order{}).Where("id IN (?)", shopOrders).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	offset := (page - 1) * limit
+	err = r.db.WithContext(ctx).Preload("Items", "shop_id = ?", shopID).
+		Where("id IN (?)", shopOrders).
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+func (r *orderRepository) Update(ctx context.Context, order *models.Order) error {
+	return r.db.WithContext(ctx).Save(order).Error
 }
 
-func (r *orderRepository) UpdateStatus(id int, status models.OrderStatus) error {
-	return r.db.Model(&models.Order{}).Where("id = ?", id).Update("status", status).Error
+func (r *orderRepository) UpdateStatus(ctx context.Context, id int, status models.OrderStatus) error {
+	return r.db.WithContext(ctx).Model(&models.Order{}).Where("id = ?", id).Update("status", status).Error
 }
 
-func (r *orderRepository) Delete(id int) error {
-	return r.db.Delete(&models.Order{}, "id = ?", id).Error
+func (r *orderRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.Order{}, "id = ?", id).Error
 }
@@ -2,9 +2,9 @@ package usecase
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/evrintobing17/ecommerce-system/order-service/app"
@@ -33,62 +33,74 @@ func NewOrderUsecase(orderRepo app.OrderRepository, productClient productProto.P
 	}
 }
 
-func (u *orderUsecase) Checkout(userID int, items []models.OrderItem) (*models.Order, error) {
+func (u *orderUsecase) Checkout(ctx context.Context, userID int, items []models.OrderItem) (*models.Order, error) {
 
-	// 1. Validate products and check stock availability
-	for _, item := range items {
-		// Check product exists and get current stock
-		product, err := u.productClient.GetProduct(context.Background(), &productProto.GetProductRequest{
+	// 1. Validate the products are published and resolve the ordered
+	// variants, which set the shop and price of each item
+	for i := range items {
+		item := &items[i]
+		resp, err := u.productClient.GetProduct(ctx, &productProto.GetProductRequest{
 			ProductId: int32(item.ProductID),
 		})
-		if err != nil || product == nil {
-			return nil, fmt.Errorf("product %s not found: %w", item.ProductID, err)
+		if err != nil {
+			return nil, fmt.Errorf("getting product %d: %w", item.ProductID, err)
+		}
+		if resp.Product.Status != "published" {
+			return nil, models.ErrProductUnavailable.With("product_id", strconv.Itoa(item.ProductID))
 		}
 
+		variant := orderedVariant(resp.Product, item.VariantID)
+		if variant == nil {
+			return nil, models.ErrVariantNotFound.With("product_id", strconv.Itoa(item.ProductID)).With("variant_id", strconv.Itoa(item.VariantID))
+		}
+		item.VariantID = int(variant.Id)
+		item.ShopID = int(resp.Product.ShopId)
+		item.Price = resp.Product.Price
+		if variant.Price != nil {
+			item.Price = *variant.Price
+		}
 	}
-	var stockTotal int32
+
 	// 2. Reserve stock in warehouse
+	variantIDs := make([]int32, 0, len(items))
 	for _, item := range items {
-		// Find available warehouses with stock
-		warehouses, err := u.warehouseClient.GetWarehouses(context.Background(), &warehouseProto.GetWarehousesRequest{
-			ActiveOnly: true,
-			ShopId:     int32(item.ShopID),
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to get warehouses: %w", err)
-		}
+		variantIDs = append(variantIDs, int32(item.VariantID))
+	}
+	availability, err := u.warehouseClient.GetAvailability(ctx, &warehouseProto.GetAvailabilityRequest{
+		VariantIds: variantIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock availability: %w", err)
+	}
+	warehousesByVariant := make(map[int32][]*warehouseProto.WarehouseAvailability, len(availability.Variants))
+	for _, variant := range availability.Variants {
+		warehousesByVariant[variant.VariantId] = variant.Warehouses
+	}
 
-		// Try to reserve stock in any available warehouse
+	for _, item := range items {
+		// Try to reserve stock in any warehouse with enough available
 		stockReserved := false
-		for _, warehouse := range warehouses.Warehouses {
-			// Check stock in this warehouse
-			stock, err := u.warehouseClient.GetStock(context.Background(), &warehouseProto.GetStockRequest{
-				ProductId:   int32(item.ProductID),
-				WarehouseId: warehouse.Id,
-			})
-			if err != nil {
+		for _, warehouse := range warehousesByVariant[int32(item.VariantID)] {
+			if warehouse.Available < item.Quantity {
 				continue
 			}
 
-			availableStock := stock.Stock.Quantity - stock.Stock.Reserved
-			stockTotal += availableStock
-			if stockTotal >= item.Quantity {
-				// Reserve the stock
-				_, err := u.warehouseClient.UpdateStock(context.Background(), &warehouseProto.UpdateStockRequest{
-					ProductId:   int32(item.ProductID),
-					WarehouseId: warehouse.Id,
-					Reserved:    item.Quantity,
-					Operation:   "add_reserved",
-				})
-				if err == nil {
-					stockReserved = true
-					break
-				}
+			// Reserve the stock
+			_, err := u.warehouseClient.UpdateStock(ctx, &warehouseProto.UpdateStockRequest{
+				VariantId:   int32(item.VariantID),
+				WarehouseId: warehouse.WarehouseId,
+				Reserved:    item.Quantity,
+				Operation:   "add_reserved",
+			})
+			if err == nil {
+				warehouse.Available -= item.Quantity
+				stockReserved = true
+				break
 			}
 		}
 
 		if !stockReserved {
-			return nil, fmt.Errorf("could not reserve stock for product %s", item.ProductID)
+			return nil, models.ErrInsufficientStock.With("variant_id", strconv.Itoa(item.VariantID))
 		}
 	}
 
@@ -106,6 +118,8 @@ func (u *orderUsecase) Checkout(userID int, items []models.OrderItem) (*models.O
 	for _, item := range items {
 		repoItems = append(repoItems, models.OrderItem{
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			ShopID:    item.ShopID,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 			CreatedAt: time.Now(),
@@ -123,10 +137,10 @@ func (u *orderUsecase) Checkout(userID int, items []models.OrderItem) (*models.O
 		ExpiresAt:   expiresAt,
 	}
 
-	err := u.orderRepo.Create(order)
+	err = u.orderRepo.Create(ctx, order)
 	if err != nil {
 		// If order creation fails, release reserved stock
-		u.releaseReservedStock(items)
+		u.releaseReservedStock(ctx, items)
 		return nil, err
 	}
 
@@ -136,6 +150,8 @@ func (u *orderUsecase) Checkout(userID int, items []models.OrderItem) (*models.O
 		resultItems = append(resultItems, models.OrderItem{
 			ID:        item.ID,
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			ShopID:    item.ShopID,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 			CreatedAt: item.CreatedAt,
@@ -155,21 +171,32 @@ func (u *orderUsecase) Checkout(userID int, items []models.OrderItem) (*models.O
 	}, nil
 }
 
-func (u *orderUsecase) releaseReservedStock(items []models.OrderItem) {
+// orderedVariant returns the variant of the product with the ID, or its
+// default variant when variantID is 0
+func orderedVariant(product *productProto.Product, variantID int) *productProto.Variant {
+	for _, variant := range product.Variants {
+		if (variantID == 0 && variant.IsDefault) || int(variant.Id) == variantID {
+			return variant
+		}
+	}
+	return nil
+}
+
+func (u *orderUsecase) releaseReservedStock(ctx context.Context, items []models.OrderItem) {
 	for _, item := range items {
 		// Find where stock was reserved and release it
-		warehouses, err := u.warehouseClient.GetWarehouses(context.Background(), &warehouseProto.GetWarehousesRequest{
+		warehouses, err := u.warehouseClient.GetWarehouses(ctx, &warehouseProto.GetWarehousesRequest{
 			ActiveOnly: true,
 		})
 		if err != nil {
-			log.Printf("Error getting warehouses for stock release: %v", err)
+			slog.WarnContext(ctx, "failed to get warehouses for stock release", "error", err)
 			continue
 		}
 
 		for _, warehouse := range warehouses.Warehouses {
 			// Try to release reserved stock
-			_, err := u.warehouseClient.UpdateStock(context.Background(), &warehouseProto.UpdateStockRequest{
-				ProductId:   int32(item.ProductID),
+			_, err := u.warehouseClient.UpdateStock(ctx, &warehouseProto.UpdateStockRequest{
+				VariantId:   int32(item.VariantID),
 				WarehouseId: warehouse.Id,
 				Reserved:    item.Quantity,
 				Operation:   "subtract_reserved",
@@ -181,14 +208,14 @@ func (u *orderUsecase) releaseReservedStock(items []models.OrderItem) {
 	}
 }
 
-func (u *orderUsecase) ProcessPayment(orderID int, paymentMethod, paymentDetails string) (*models.Order, error) {
-	order, err := u.orderRepo.FindByID(orderID)
+func (u *orderUsecase) ProcessPayment(ctx context.Context, orderID int, paymentMethod, paymentDetails string) (*models.Order, error) {
+	order, err := u.orderRepo.FindByID(ctx, orderID)
 	if err != nil {
 		return nil, err
 	}
 
 	if order.Status != models.OrderStatusPending {
-		return nil, errors.New("order is not in pending status")
+		return nil, models.ErrOrderNotPending
 	}
 
 	// Process payment (simulated)
@@ -196,7 +223,7 @@ func (u *orderUsecase) ProcessPayment(orderID int, paymentMethod, paymentDetails
 	order.Status = models.OrderStatusPaid
 	order.UpdatedAt = time.Now()
 
-	err = u.orderRepo.UpdateStatus(orderID, order.Status)
+	err = u.orderRepo.UpdateStatus(ctx, orderID, order.Status)
 	if err != nil {
 		return nil, err
 	}
@@ -204,18 +231,18 @@ func (u *orderUsecase) ProcessPayment(orderID int, paymentMethod, paymentDetails
 	// Convert reserved stock to actual deduction
 	for _, item := range order.Items {
 		// Find where stock was reserved
-		warehouses, err := u.warehouseClient.GetWarehouses(context.Background(), &warehouseProto.GetWarehousesRequest{
+		warehouses, err := u.warehouseClient.GetWarehouses(ctx, &warehouseProto.GetWarehousesRequest{
 			ActiveOnly: true,
 		})
 		if err != nil {
-			log.Printf("Error getting warehouses for stock deduction: %v", err)
+			slog.WarnContext(ctx, "failed to get warehouses for stock deduction", "error", err)
 			continue
 		}
 
 		for _, warehouse := range warehouses.Warehouses {
 			// Deduct the reserved stock
-			_, err := u.warehouseClient.UpdateStock(context.Background(), &warehouseProto.UpdateStockRequest{
-				ProductId:   int32(item.ProductID),
+			_, err := u.warehouseClient.UpdateStock(ctx, &warehouseProto.UpdateStockRequest{
+				VariantId:   int32(item.VariantID),
 				WarehouseId: warehouse.Id,
 				Quantity:    item.Quantity,
 				Reserved:    item.Quantity,
@@ -233,6 +260,8 @@ func (u *orderUsecase) ProcessPayment(orderID int, paymentMethod, paymentDetails
 		items = append(items, models.OrderItem{
 			ID:        item.ID,
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			ShopID:    item.ShopID,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 			CreatedAt: item.CreatedAt,
@@ -252,10 +281,10 @@ func (u *orderUsecase) ProcessPayment(orderID int, paymentMethod, paymentDetails
 	}, nil
 }
 
-func (u *orderUsecase) ReleaseExpiredOrders() error {
+func (u *orderUsecase) ReleaseExpiredOrders(ctx context.Context) error {
 	// Find orders that have expired (not paid within timeout)
 	expiredTime := time.Now()
-	orders, err := u.orderRepo.FindExpiredOrders(expiredTime)
+	orders, err := u.orderRepo.FindExpiredOrders(ctx, expiredTime)
 	if err != nil {
 		return err
 	}
@@ -265,18 +294,18 @@ func (u *orderUsecase) ReleaseExpiredOrders() error {
 			// Release reserved stock
 			for _, item := range order.Items {
 				// Find where stock was reserved and release it
-				warehouses, err := u.warehouseClient.GetWarehouses(context.Background(), &warehouseProto.GetWarehousesRequest{
+				warehouses, err := u.warehouseClient.GetWarehouses(ctx, &warehouseProto.GetWarehousesRequest{
 					ActiveOnly: true,
 				})
 				if err != nil {
-					log.Printf("Error getting warehouses for stock release: %v", err)
+					slog.WarnContext(ctx, "failed to get warehouses for stock release", "error", err)
 					continue
 				}
 
 				for _, warehouse := range warehouses.Warehouses {
 					// Try to release reserved stock
-					_, err := u.warehouseClient.UpdateStock(context.Background(), &warehouseProto.UpdateStockRequest{
-						ProductId:   int32(item.ProductID),
+					_, err := u.warehouseClient.UpdateStock(ctx, &warehouseProto.UpdateStockRequest{
+						VariantId:   int32(item.VariantID),
 						WarehouseId: warehouse.Id,
 						Reserved:    item.Quantity,
 						Operation:   "subtract_reserved",
@@ -289,9 +318,9 @@ func (u *orderUsecase) ReleaseExpiredOrders() error {
 
 			// Update order status to cancelled
 			order.Status = models.OrderStatusCancelled
-			err := u.orderRepo.UpdateStatus(order.ID, order.Status)
+			err := u.orderRepo.UpdateStatus(ctx, order.ID, order.Status)
 			if err != nil {
-				log.Printf("Error cancelling order %s: %v", order.ID, err)
+				slog.ErrorContext(ctx, "failed to cancel expired order", "order_id", order.ID, "error", err)
 			}
 		}
 	}
@@ -299,7 +328,7 @@ func (u *orderUsecase) ReleaseExpiredOrders() error {
 	return nil
 }
 
-func (u *orderUsecase) CreateOrder(userID int, items []models.OrderItem) (*models.Order, error) {
+func (u *orderUsecase) CreateOrder(ctx context.Context, userID int, items []models.OrderItem) (*models.Order, error) {
 	// Calculate total amount
 	var totalAmount float64
 	for _, item := range items {
@@ -311,6 +340,8 @@ func (u *orderUsecase) CreateOrder(userID int, items []models.OrderItem) (*model
 	for _, item := range items {
 		repoItems = append(repoItems, models.OrderItem{
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			ShopID:    item.ShopID,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 			CreatedAt: time.Now(),
@@ -327,7 +358,7 @@ func (u *orderUsecase) CreateOrder(userID int, items []models.OrderItem) (*model
 		UpdatedAt:   time.Now(),
 	}
 
-	err := u.orderRepo.Create(order)
+	err := u.orderRepo.Create(ctx, order)
 	if err != nil {
 		return nil, err
 	}
@@ -338,6 +369,8 @@ func (u *orderUsecase) CreateOrder(userID int, items []models.OrderItem) (*model
 		resultItems = append(resultItems, models.OrderItem{
 			ID:        item.ID,
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			ShopID:    item.ShopID,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 			CreatedAt: item.CreatedAt,
@@ -356,8 +389,8 @@ func (u *orderUsecase) CreateOrder(userID int, items []models.OrderItem) (*model
 	}, nil
 }
 
-func (u *orderUsecase) GetOrder(id int) (*models.Order, error) {
-	order, err := u.orderRepo.FindByID(id)
+func (u *orderUsecase) GetOrder(ctx context.Context, id int) (*models.Order, error) {
+	order, err := u.orderRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -368,6 +401,8 @@ func (u *orderUsecase) GetOrder(id int) (*models.Order, error) {
 		items = append(items, models.OrderItem{
 			ID:        item.ID,
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			ShopID:    item.ShopID,
 			Quantity:  item.Quantity,
 			Price:     item.Price,
 			CreatedAt: item.CreatedAt,
@@ -386,8 +421,8 @@ func (u *orderUsecase) GetOrder(id int) (*models.Order, error) {
 	}, nil
 }
 
-func (u *orderUsecase) GetUserOrders(userID, page, limit int) ([]*models.Order, int64, error) {
-	orders, total, err := u.orderRepo.FindByUserID(userID, page, limit)
+func (u *orderUsecase) GetUserOrders(ctx context.Context, userID, page, limit int) ([]*models.Order, int64, error) {
+	orders, total, err := u.orderRepo.FindByUserID(ctx, userID, page, limit)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -399,6 +434,8 @@ func (u *orderUsecase) GetUserOrders(userID, page, limit int) ([]*models.Order,
 			items = append(items, models.OrderItem{
 				ID:        item.ID,
 				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				ShopID:    item.ShopID,
 				Quantity:  item.Quantity,
 				Price:     item.Price,
 				CreatedAt: item.CreatedAt,
@@ -420,18 +457,55 @@ func (u *orderUsecase) GetUserOrders(userID, page, limit int) ([]*models.Order,
 	return result, total, nil
 }
 
-func (u *orderUsecase) CancelOrder(orderID int) error {
-	order, err := u.orderRepo.FindByID(orderID)
+func (u *orderUsecase) CancelOrder(ctx context.Context, orderID int) error {
+	order, err := u.orderRepo.FindByID(ctx, orderID)
 	if err != nil {
 		return err
 	}
 
 	if order.Status != models.OrderStatusPending {
-		return errors.New("only pending orders can be cancelled")
+		return models.ErrOrderNotCancellable
 	}
 
 	order.Status = models.OrderStatusCancelled
 	order.UpdatedAt = time.Now()
 
-	return u.orderRepo.UpdateStatus(orderID, order.Status)
+	return u.orderRepo.UpdateStatus(ctx, orderID, order.Status)
+}
+
+// GetShopOrders returns the orders containing at least one item sold by the shop
+func (u *orderUsecase) GetShopOrders(ctx context.Context, shopID, page, limit int) ([]*models.Order, int64, error) {
+	orders, total, err := u.orderRepo.FindByShopID(ctx, shopID, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result []*models.Order
+	for _, order := range orders {
+		var items []models.OrderItem
+		for _, item := range order.Items {
+			items = append(items, models.OrderItem{
+				ID:        item.ID,
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				ShopID:    item.ShopID,
+				Quantity:  item.Quantity,
+				Price:     item.Price,
+				CreatedAt: item.CreatedAt,
+				UpdatedAt: item.UpdatedAt,
+			})
+		}
+
+		result = append(result, &models.Order{
+			ID:          order.ID,
+			UserID:      order.UserID,
+			Items:       items,
+			TotalAmount: order.TotalAmount,
+			Status:      models.OrderStatus(order.Status),
+			CreatedAt:   order.CreatedAt,
+			UpdatedAt:   order.UpdatedAt,
+		})
+	}
+
+	return result, total, nil
 }
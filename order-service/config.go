@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+)
+
+// Config is the configuration of order-service
+type Config struct {
+	config.Common `yaml:",inline"`
+
+	HTTPAddr config.ListenAddr `env:"ORDER_SERVICE_PORT" yaml:"http_addr" default:"8082"`
+	GRPCAddr config.ListenAddr `env:"ORDER_GRPC_PORT" yaml:"grpc_addr" default:"50053"`
+
+	ProductServiceAddr   string `env:"PRODUCT_SERVICE_GRPC_ADDR" yaml:"product_service_addr" default:"product-service:50052"`
+	WarehouseServiceAddr string `env:"WAREHOUSE_SERVICE_GRPC_ADDR" yaml:"warehouse_service_addr" default:"warehouse-service:50055"`
+	ShopServiceAddr      string `env:"SHOP_SERVICE_GRPC_ADDR" yaml:"shop_service_addr" default:"shop-service:50054"`
+
+	// OrderTimeoutMinutes is how long a pending order keeps its stock reserved
+	OrderTimeoutMinutes int `env:"ORDER_TIMEOUT_MINUTES" yaml:"order_timeout_minutes" default:"15"`
+}
+
+// Validate checks the order timeout next to the common settings
+func (c *Config) Validate() error {
+	var errs []error
+	if err := c.Common.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.OrderTimeoutMinutes <= 0 {
+		errs = append(errs, errors.New("ORDER_TIMEOUT_MINUTES must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -1,158 +1,203 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net"
-	"net/http"
+	"log/slog"
 	"os"
-	"strconv"
 	"time"
 
 	grpcHandler "github.com/evrintobing17/ecommerce-system/order-service/app/delivery/grpc"
 	grpcProduct "github.com/evrintobing17/ecommerce-system/shared/proto/product"
+	grpcShop "github.com/evrintobing17/ecommerce-system/shared/proto/shop"
 	grpcWarehouse "github.com/evrintobing17/ecommerce-system/shared/proto/warehouse"
 
 	"github.com/evrintobing17/ecommerce-system/shared/grpc_client"
+	"github.com/evrintobing17/ecommerce-system/shared/health"
+	"github.com/evrintobing17/ecommerce-system/shared/lifecycle"
 	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/migrate"
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/order"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+	"github.com/evrintobing17/ecommerce-system/shared/transcoding"
 
 	delivery "github.com/evrintobing17/ecommerce-system/order-service/app/delivery"
-	"github.com/evrintobing17/ecommerce-system/order-service/app/models"
 	"github.com/evrintobing17/ecommerce-system/order-service/app/repository"
 	"github.com/evrintobing17/ecommerce-system/order-service/app/usecase"
 	"google.golang.org/grpc"
 
 	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	// Load configuration
+	var cfg Config
+	if err := config.Load("order-service", &cfg); err != nil {
+		log.Fatal(err)
 	}
 
 	// Initialize logger
-	shared.InitLogger()
+	shared.InitLogger("order-service", cfg.LogLevel)
+	config.Log(&cfg)
+	grpc_client.DefaultTimeout = cfg.GRPCClientTimeout
 
-	// Initialize database
-	db, err := shared.ConnectDB()
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
-	defer func() {
-		if err := shared.CloseDB(db); err != nil {
-			log.Println("Error closing database:", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.RunCommand(context.Background(), "order-service", cfg.Database, migrationFiles(), "order-service/migrations", os.Args[2:]); err != nil {
+			log.Fatal(err)
 		}
-	}()
+		return
+	}
 
-	// Auto migrate models
-	err = shared.MigrateDB(db, &models.Order{}, &models.OrderItem{})
+	app := lifecycle.New("order-service", cfg.ShutdownTimeout)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "order-service")
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		log.Fatal("Failed to initialize tracing:", err)
 	}
+	app.AddCloser("tracing", shutdownTracing)
 
-	productServiceAddr := os.Getenv("PRODUCT_SERVICE_GRPC_ADDR")
-	if productServiceAddr == "" {
-		productServiceAddr = "product-service:50052"
+	// Initialize database
+	db, err := shared.ConnectDB(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
 	}
+	app.AddCloser("database", func(context.Context) error {
+		return shared.CloseDB(db)
+	})
 
-	warehouseServiceAddr := os.Getenv("WAREHOUSE_SERVICE_GRPC_ADDR")
-	if warehouseServiceAddr == "" {
-		warehouseServiceAddr = "warehouse-service:50055"
+	// Apply pending migrations
+	if cfg.MigrateOnStart {
+		if err := migrate.Up(context.Background(), db, "order-service", migrationFiles()); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
 	}
 
-	productConn, _ := grpc_client.NewConnection(productServiceAddr)
+	serviceAuth := cfg.ServiceAuth
+
+	productConn, err := grpc_client.NewConnection(cfg.ProductServiceAddr, serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to product-service:", err)
+	}
 	defer productConn.Close()
 
 	productClient := grpcProduct.NewProductServiceClient(productConn)
 
-	warehouseConn, _ := grpc_client.NewConnection(warehouseServiceAddr)
+	warehouseConn, err := grpc_client.NewConnection(cfg.WarehouseServiceAddr, serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to warehouse-service:", err)
+	}
 	defer warehouseConn.Close()
 
 	warehouseClient := grpcWarehouse.NewWarehouseServiceClient(warehouseConn)
 
-	orderTimeoutMinutes := 15
-	if timeoutStr := os.Getenv("ORDER_TIMEOUT_MINUTES"); timeoutStr != "" {
-		if timeout, err := strconv.Atoi(timeoutStr); err == nil {
-			orderTimeoutMinutes = timeout
-		}
+	shopConn, err := grpc_client.NewConnection(cfg.ShopServiceAddr, serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to shop-service:", err)
 	}
-	orderTimeout := time.Duration(orderTimeoutMinutes) * time.Minute
+	defer shopConn.Close()
+
+	shopAccessChecker := middleware.NewShopAccessChecker(grpcShop.NewShopServiceClient(shopConn))
+
+	orderTimeout := time.Duration(cfg.OrderTimeoutMinutes) * time.Minute
 
 	// Initialize repositories
 	orderRepo := repository.NewOrderRepository(db)
 
 	// Initialize use cases
 	orderUsecase := usecase.NewOrderUsecase(orderRepo, productClient, warehouseClient, orderTimeout)
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute) // Check every 5 minutes
+	const expiryInterval = 5 * time.Minute
+	expiryHeartbeat := health.NewHeartbeat(3 * expiryInterval)
+	app.AddWorker("order-expiry", func(ctx context.Context) {
+		ticker := time.NewTicker(expiryInterval)
 		defer ticker.Stop()
 
-		for range ticker.C {
-			if err := orderUsecase.ReleaseExpiredOrders(); err != nil {
-				log.Printf("Error releasing expired orders: %v", err)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := orderUsecase.ReleaseExpiredOrders(ctx); err != nil {
+					slog.ErrorContext(ctx, "failed to release expired orders", "error", err)
+				}
+				expiryHeartbeat.Beat()
 			}
 		}
-	}()
+	})
+
 	// Initialize HTTP server
-	router := gin.Default()
+	router := gin.New()
+	router.Use(tracing.GinMiddleware("order-service")...)
+	router.Use(middleware.RequestID(), middleware.AccessLog())
 	orderHandler := delivery.NewOrderHandler(orderUsecase)
-	router.Use(gin.Recovery())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.GatewayIdentity(cfg.ServiceAuth.TokenSecret))
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
 	router.Use(shared.GinMetricsMiddleware())
 	shared.RegisterMetricsHandler(router)
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "OK",
-		})
-	})
+	healthChecks := health.New("order-service", cfg.HealthCheckInterval)
+	healthChecks.AddReadinessCheck("database", health.DatabaseChecker(db))
+	healthChecks.AddReadinessCheck("product-service", health.GRPCChecker(productConn, ""))
+	healthChecks.AddReadinessCheck("warehouse-service", health.GRPCChecker(warehouseConn, ""))
+	healthChecks.AddReadinessCheck("shop-service", health.GRPCChecker(shopConn, ""))
+	healthChecks.AddLivenessCheck("order-expiry", expiryHeartbeat)
+	healthChecks.RegisterHTTP(router)
 
 	// HTTP routes
-	api := router.Group("/api/v1")
-	jwtSecret := os.Getenv("JWT_SECRET")
-	api.Use(middleware.AuthMiddleware(jwtSecret))
-	{
-		api.POST("/checkout", orderHandler.Checkout)
-		api.POST("/orders", orderHandler.CreateOrder)
-		api.GET("/orders/:id", orderHandler.GetOrder)
-		api.GET("/orders", orderHandler.GetUserOrders)
-		api.POST("/orders/:id/payment", orderHandler.ProcessPayment)
-		api.DELETE("/orders/:id", orderHandler.CancelOrder)
-	}
+	jwtSecret := cfg.JWTSecret
+	registerRoutes(router, orderHandler, shopAccessChecker, jwtSecret)
 
 	// Initialize gRPC server
 	orderServer := grpcHandler.NewOrderServer(orderUsecase)
 
-	// Start gRPC server
-	go func() {
-		grpcPort := os.Getenv("ORDER_GRPC_PORT")
-		if grpcPort == "" {
-			grpcPort = "50053"
-		}
-
-		lis, err := net.Listen("tcp", grpcPort)
-		if err != nil {
-			log.Fatal("Failed to listen:", err)
-		}
-
-		grpcServer := grpc.NewServer()
-		proto.RegisterOrderServiceServer(grpcServer, orderServer)
+	serverOptions, err := middleware.GRPCServerOptions(middleware.GRPCServerConfig{
+		ServiceAuth: serviceAuth,
+		JWTSecret:   jwtSecret,
+		// Orders belong to customers, not shops: the handlers limit reads,
+		// payment and cancellation to the owner of the order
+		MethodPermissions: map[string]string{
+			proto.OrderService_CreateOrder_FullMethodName:    shared.PermOrderCreate,
+			proto.OrderService_GetOrder_FullMethodName:       "",
+			proto.OrderService_ListOrders_FullMethodName:     "",
+			proto.OrderService_ProcessPayment_FullMethodName: "",
+			proto.OrderService_CancelOrder_FullMethodName:    "",
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to configure gRPC server:", err)
+	}
+	grpcServer := grpc.NewServer(serverOptions...)
+	proto.RegisterOrderServiceServer(grpcServer, orderServer)
+	healthChecks.RegisterGRPC(grpcServer)
+	app.AddGRPCServer("Order", string(cfg.GRPCAddr), grpcServer)
+
+	// REST/JSON transcoding of the gRPC API below /api/v2. It calls the
+	// service's own gRPC server so the same interceptors apply.
+	selfConn, err := grpc_client.NewConnection(cfg.GRPCAddr.DialAddr(), serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to own gRPC server:", err)
+	}
+	defer selfConn.Close()
+	restMux := transcoding.NewServeMux()
+	if err := proto.RegisterOrderServiceHandler(context.Background(), restMux, selfConn); err != nil {
+		log.Fatal("Failed to register REST transcoding:", err)
+	}
+	transcoding.Mount(router, restMux)
+	transcoding.RegisterSwagger(router, transcoding.Specs{
+		Title:     "Order Service API",
+		OpenAPIV2: proto.OpenAPIV2,
+		OpenAPIV3: proto.OpenAPIV3,
+	})
 
-		log.Printf("Order gRPC server started on port %s", grpcPort)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatal("Failed to serve gRPC:", err)
-		}
-	}()
+	app.AddHTTPServer("Order", string(cfg.HTTPAddr), router)
 
-	// Start HTTP server
-	httpPort := os.Getenv("ORDER_SERVICE_PORT")
-	if httpPort == "" {
-		httpPort = "8082"
-	}
+	app.AddWorker("health", healthChecks.Run)
+	app.OnShutdown(healthChecks.Shutdown)
 
-	log.Printf("Order HTTP server started on port %s", httpPort)
-	if err := router.Run(":" + httpPort); err != nil {
-		log.Fatal("Failed to start HTTP server:", err)
+	if err := app.Run(); err != nil {
+		log.Fatal("Order service stopped with error:", err)
 	}
 }
@@ -0,0 +1 @@
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS fk_orders_items;
@@ -0,0 +1,5 @@
+-- Databases created by GORM AutoMigrate have order_items.order_id as TEXT
+ALTER TABLE order_items ALTER COLUMN order_id TYPE BIGINT USING order_id::BIGINT;
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS fk_orders_items;
+ALTER TABLE order_items
+    ADD CONSTRAINT fk_orders_items FOREIGN KEY (order_id) REFERENCES orders (id);
@@ -0,0 +1,5 @@
+-- Items reference the ordered product variant. Existing items ordered a
+-- product's default variant, which has the product's ID.
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id BIGINT;
+UPDATE order_items SET variant_id = product_id WHERE variant_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_order_items_variant_id ON order_items (variant_id);
@@ -0,0 +1,25 @@
+package main
+
+import (
+	delivery "github.com/evrintobing17/ecommerce-system/order-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// registerRoutes adds the HTTP API below /api/v1 to router
+func registerRoutes(router gin.IRouter, orderHandler *delivery.OrderHandler, shopAccessChecker middleware.ShopAccessChecker, jwtSecret string) {
+	api := router.Group("/api/v1")
+	api.Use(middleware.AuthMiddleware(jwtSecret))
+	{
+		api.POST("/checkout", middleware.RequirePermission(shared.PermOrderCreate), orderHandler.Checkout)
+		api.POST("/orders", middleware.RequirePermission(shared.PermOrderCreate), orderHandler.CreateOrder)
+		api.GET("/orders/:id", orderHandler.GetOrder)
+		api.GET("/orders", orderHandler.GetUserOrders)
+		api.POST("/orders/:id/payment", orderHandler.ProcessPayment)
+		api.DELETE("/orders/:id", orderHandler.CancelOrder)
+		api.GET("/shops/:id/orders",
+			middleware.RequireShopPermission(shared.PermOrderView, shopAccessChecker, middleware.ShopIDFromParam("id")),
+			orderHandler.GetShopOrders)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/order-service/app"
+	delivery "github.com/evrintobing17/ecommerce-system/order-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/order-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse/responsetest"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "test-secret"
+
+// orderUsecaseStub serves the orders of user 1: order 1 is pending, order 2
+// is paid and cancelling order 3 fails unexpectedly. Order 4 belongs to
+// user 2.
+type orderUsecaseStub struct {
+	app.OrderUsecase
+}
+
+func (u *orderUsecaseStub) order(id int) (*models.Order, error) {
+	switch id {
+	case 1, 3:
+		return &models.Order{ID: id, UserID: 1, Status: models.OrderStatusPending}, nil
+	case 2:
+		return &models.Order{ID: id, UserID: 1, Status: models.OrderStatusPaid}, nil
+	case 4:
+		return &models.Order{ID: id, UserID: 2, Status: models.OrderStatusPending}, nil
+	}
+	return nil, models.ErrOrderNotFound
+}
+
+func (u *orderUsecaseStub) CreateOrder(ctx context.Context, userID int, items []models.OrderItem) (*models.Order, error) {
+	for _, item := range items {
+		if item.Quantity > 10 {
+			return nil, models.ErrInsufficientStock
+		}
+	}
+	return &models.Order{ID: 5, UserID: userID, Items: items, Status: models.OrderStatusPending}, nil
+}
+
+func (u *orderUsecaseStub) Checkout(ctx context.Context, userID int, items []models.OrderItem) (*models.Order, error) {
+	return u.CreateOrder(ctx, userID, items)
+}
+
+func (u *orderUsecaseStub) GetOrder(ctx context.Context, id int) (*models.Order, error) {
+	return u.order(id)
+}
+
+func (u *orderUsecaseStub) GetUserOrders(ctx context.Context, userID, page, limit int) ([]*models.Order, int64, error) {
+	order, _ := u.order(1)
+	return []*models.Order{order}, 1, nil
+}
+
+func (u *orderUsecaseStub) GetShopOrders(ctx context.Context, shopID, page, limit int) ([]*models.Order, int64, error) {
+	return u.GetUserOrders(ctx, 1, page, limit)
+}
+
+func (u *orderUsecaseStub) ProcessPayment(ctx context.Context, orderID int, paymentMethod, paymentDetails string) (*models.Order, error) {
+	order, err := u.order(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, models.ErrOrderNotPending
+	}
+	order.Status = models.OrderStatusPaid
+	return order, nil
+}
+
+func (u *orderUsecaseStub) CancelOrder(ctx context.Context, orderID int) error {
+	if orderID == 3 {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+// shopAccessStub grants every action on shop 1
+type shopAccessStub struct{}
+
+func (shopAccessStub) CanAccessShop(ctx context.Context, claims *shared.Claims, shopID int, action string) (bool, error) {
+	return shopID == 1, nil
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
+	registerRoutes(router, delivery.NewOrderHandler(&orderUsecaseStub{}), shopAccessStub{}, testJWTSecret)
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	customer := responsetest.Token(t, shared.TokenSubject{UserID: 1, Role: shared.RoleCustomer, Permissions: []string{shared.PermOrderCreate}}, testJWTSecret)
+	noPermissions := responsetest.Token(t, shared.TokenSubject{UserID: 1, Role: shared.RoleCustomer}, testJWTSecret)
+
+	responsetest.Run(t, newTestRouter(), []responsetest.Case{
+		{Name: "unknown route", Method: http.MethodGet, Path: "/api/v1/nothing", Status: http.StatusNotFound, Code: "route_not_found"},
+		{Name: "missing token", Method: http.MethodGet, Path: "/api/v1/orders", Status: http.StatusUnauthorized, Code: "authorization_required"},
+		{Name: "invalid token", Method: http.MethodGet, Path: "/api/v1/orders", Token: "invalid", Status: http.StatusUnauthorized, Code: "invalid_token"},
+
+		{Name: "checkout", Method: http.MethodPost, Path: "/api/v1/checkout", Token: customer, Body: `{"items":[{"product_id":1,"quantity":1}]}`, Status: http.StatusCreated},
+		{Name: "checkout without items", Method: http.MethodPost, Path: "/api/v1/checkout", Token: customer, Body: `{}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"items"}},
+		{Name: "checkout without permission", Method: http.MethodPost, Path: "/api/v1/checkout", Token: noPermissions, Body: `{}`, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "create order", Method: http.MethodPost, Path: "/api/v1/orders", Token: customer, Body: `{"items":[{"product_id":1,"quantity":1}]}`, Status: http.StatusCreated},
+		{Name: "create order with malformed body", Method: http.MethodPost, Path: "/api/v1/orders", Token: customer, Body: `{"items":`, Status: http.StatusBadRequest, Code: "malformed_body", Fields: []string{""}},
+		{Name: "create order with wrong type", Method: http.MethodPost, Path: "/api/v1/orders", Token: customer, Body: `{"items":[{"quantity":"one"}]}`, Status: http.StatusBadRequest, Code: "invalid_type", Fields: []string{"items[0].quantity"}},
+		{Name: "create order without stock", Method: http.MethodPost, Path: "/api/v1/orders", Token: customer, Body: `{"items":[{"product_id":1,"quantity":11}]}`, Status: http.StatusUnprocessableEntity, Code: "insufficient_stock"},
+
+		{Name: "get order", Method: http.MethodGet, Path: "/api/v1/orders/1", Token: customer, Status: http.StatusOK},
+		{Name: "get missing order", Method: http.MethodGet, Path: "/api/v1/orders/9", Token: customer, Status: http.StatusNotFound, Code: "order_not_found"},
+		{Name: "get order of another user", Method: http.MethodGet, Path: "/api/v1/orders/4", Token: customer, Status: http.StatusForbidden, Code: "order_access_denied"},
+		{Name: "list orders", Method: http.MethodGet, Path: "/api/v1/orders", Token: customer, Status: http.StatusOK, Meta: true},
+
+		{Name: "pay order", Method: http.MethodPost, Path: "/api/v1/orders/1/payment", Token: customer, Body: `{"payment_method":"card","payment_details":"4111"}`, Status: http.StatusOK},
+		{Name: "pay order without details", Method: http.MethodPost, Path: "/api/v1/orders/1/payment", Token: customer, Body: `{}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"payment_method", "payment_details"}},
+		{Name: "pay paid order", Method: http.MethodPost, Path: "/api/v1/orders/2/payment", Token: customer, Body: `{"payment_method":"card","payment_details":"4111"}`, Status: http.StatusUnprocessableEntity, Code: "order_not_pending"},
+		{Name: "pay order of another user", Method: http.MethodPost, Path: "/api/v1/orders/4/payment", Token: customer, Body: `{"payment_method":"card","payment_details":"4111"}`, Status: http.StatusForbidden, Code: "order_access_denied"},
+
+		{Name: "cancel order", Method: http.MethodDelete, Path: "/api/v1/orders/1", Token: customer, Status: http.StatusOK},
+		{Name: "cancel order failing", Method: http.MethodDelete, Path: "/api/v1/orders/3", Token: customer, Status: http.StatusInternalServerError, Code: "internal"},
+
+		{Name: "list shop orders", Method: http.MethodGet, Path: "/api/v1/shops/1/orders", Token: customer, Status: http.StatusOK, Meta: true},
+		{Name: "list orders of another shop", Method: http.MethodGet, Path: "/api/v1/shops/2/orders", Token: customer, Status: http.StatusForbidden, Code: "permission_denied"},
+	})
+}
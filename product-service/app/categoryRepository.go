@@ -0,0 +1,28 @@
+package app
+
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+type CategoryRepository interface {
+	// Create stores the category below its parent and sets its Path
+	Create(ctx context.Context, category *models.Category) error
+	FindByID(ctx context.Context, id int) (*models.Category, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Category, error)
+	FindByIDs(ctx context.Context, ids []int) ([]*models.Category, error)
+	// FindAll returns all categories ordered by name
+	FindAll(ctx context.Context) ([]*models.Category, error)
+	// FindSubtree returns the category and its descendants ordered by name
+	FindSubtree(ctx context.Context, category *models.Category) ([]*models.Category, error)
+	HasChildren(ctx context.Context, id int) (bool, error)
+	// CountProducts returns the number of distinct published products in
+	// each category or its descendants, keyed by category ID. Categories
+	// without products are left out.
+	CountProducts(ctx context.Context) (map[int]int64, error)
+	// Update saves the category and, when its Path differs from oldPath,
+	// moves its descendants along
+	Update(ctx context.Context, category *models.Category, oldPath string) error
+	Delete(ctx context.Context, id int) error
+}
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+type CategoryUsecase interface {
+	GetCategoryTree(ctx context.Context) ([]*models.CategoryNode, error)
+	GetCategory(ctx context.Context, id int) (*models.CategoryDetail, error)
+	// CreateCategory creates a root category when parentID is nil. An empty
+	// slug is derived from the name.
+	CreateCategory(ctx context.Context, name, slug string, parentID *int) (*models.Category, error)
+	// UpdateCategory changes the non-empty fields. A nil parentID keeps the
+	// parent and 0 moves the category to the root.
+	UpdateCategory(ctx context.Context, id int, name, slug string, parentID *int) (*models.Category, error)
+	DeleteCategory(ctx context.Context, id int) error
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"strconv"
+
+	usecase "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryUsecase usecase.CategoryUsecase
+}
+
+func NewCategoryHandler(categoryUsecase usecase.CategoryUsecase) *CategoryHandler {
+	return &CategoryHandler{categoryUsecase: categoryUsecase}
+}
+
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	roots, err := h.categoryUsecase.GetCategoryTree(c.Request.Context())
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"categories": roots,
+	})
+}
+
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	categoryID, _ := strconv.Atoi(c.Param("id"))
+
+	category, err := h.categoryUsecase.GetCategory(c.Request.Context(), categoryID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"category": category,
+	})
+}
+
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var request struct {
+		Name     string `json:"name" binding:"required"`
+		Slug     string `json:"slug"`
+		ParentID *int   `json:"parent_id"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	category, err := h.categoryUsecase.CreateCategory(c.Request.Context(), request.Name, request.Slug, request.ParentID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.StatusCreated(c, gin.H{
+		"category": category,
+	})
+}
+
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	categoryID, _ := strconv.Atoi(c.Param("id"))
+	var request struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+		// Moves the category with its subtree; 0 moves it to the root
+		ParentID *int `json:"parent_id"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	category, err := h.categoryUsecase.UpdateCategory(c.Request.Context(), categoryID, request.Name, request.Slug, request.ParentID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"category": category,
+	})
+}
+
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	categoryID, _ := strconv.Atoi(c.Param("id"))
+
+	err := h.categoryUsecase.DeleteCategory(c.Request.Context(), categoryID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"message": "Category deleted successfully",
+	})
+}
@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+
+	usecase "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	proto "github.com/evrintobing17/ecommerce-system/shared/proto/product"
+)
+
+type categoryServer struct {
+	proto.UnimplementedCategoryServiceServer
+	categoryUsecase usecase.CategoryUsecase
+}
+
+func NewCategoryServer(categoryUsecase usecase.CategoryUsecase) *categoryServer {
+	return &categoryServer{categoryUsecase: categoryUsecase}
+}
+
+func (s *categoryServer) GetCategoryTree(ctx context.Context, req *proto.GetCategoryTreeRequest) (*proto.GetCategoryTreeResponse, error) {
+	roots, err := s.categoryUsecase.GetCategoryTree(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "get category tree failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.GetCategoryTreeResponse{
+		Roots: toProtoCategoryNodes(roots),
+	}, nil
+}
+
+func (s *categoryServer) GetCategory(ctx context.Context, req *proto.GetCategoryRequest) (*proto.GetCategoryResponse, error) {
+	detail, err := s.categoryUsecase.GetCategory(ctx, int(req.CategoryId))
+	if err != nil {
+		slog.WarnContext(ctx, "get category failed", "error", err)
+		return nil, err
+	}
+
+	var breadcrumbs []*proto.Category
+	for _, category := range detail.Breadcrumbs {
+		breadcrumbs = append(breadcrumbs, toProtoCategory(category))
+	}
+
+	return &proto.GetCategoryResponse{
+		Category:     toProtoCategory(detail.Category),
+		ProductCount: int32(detail.ProductCount),
+		Breadcrumbs:  breadcrumbs,
+		Children:     toProtoCategoryNodes(detail.Children),
+	}, nil
+}
+
+func (s *categoryServer) CreateCategory(ctx context.Context, req *proto.CreateCategoryRequest) (*proto.CreateCategoryResponse, error) {
+	var parentID *int
+	if req.ParentId != 0 {
+		id := int(req.ParentId)
+		parentID = &id
+	}
+
+	category, err := s.categoryUsecase.CreateCategory(ctx, req.Name, req.Slug, parentID)
+	if err != nil {
+		slog.WarnContext(ctx, "create category failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.CreateCategoryResponse{
+		Category: toProtoCategory(category),
+	}, nil
+}
+
+func (s *categoryServer) UpdateCategory(ctx context.Context, req *proto.UpdateCategoryRequest) (*proto.UpdateCategoryResponse, error) {
+	var parentID *int
+	if req.ParentId != nil {
+		id := int(*req.ParentId)
+		parentID = &id
+	}
+
+	category, err := s.categoryUsecase.UpdateCategory(ctx, int(req.CategoryId), req.Name, req.Slug, parentID)
+	if err != nil {
+		slog.WarnContext(ctx, "update category failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.UpdateCategoryResponse{
+		Category: toProtoCategory(category),
+	}, nil
+}
+
+func (s *categoryServer) DeleteCategory(ctx context.Context, req *proto.DeleteCategoryRequest) (*proto.DeleteCategoryResponse, error) {
+	if err := s.categoryUsecase.DeleteCategory(ctx, int(req.CategoryId)); err != nil {
+		slog.WarnContext(ctx, "delete category failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.DeleteCategoryResponse{
+		Success: true,
+		Message: "Category deleted successfully",
+	}, nil
+}
+
+// toProtoCategory converts a domain category to its proto representation
+func toProtoCategory(category *models.Category) *proto.Category {
+	result := &proto.Category{
+		Id:        int32(category.ID),
+		Name:      category.Name,
+		Slug:      category.Slug,
+		CreatedAt: category.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt: category.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if category.ParentID != nil {
+		result.ParentId = int32(*category.ParentID)
+	}
+	return result
+}
+
+func toProtoCategoryNodes(nodes []*models.CategoryNode) []*proto.CategoryNode {
+	var result []*proto.CategoryNode
+	for _, node := range nodes {
+		result = append(result, &proto.CategoryNode{
+			Category:     toProtoCategory(node.Category),
+			ProductCount: int32(node.ProductCount),
+			Children:     toProtoCategoryNodes(node.Children),
+		})
+	}
+	return result
+}
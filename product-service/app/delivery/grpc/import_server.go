@@ -0,0 +1,117 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	usecase "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	proto "github.com/evrintobing17/ecommerce-system/shared/proto/product"
+)
+
+type importServer struct {
+	proto.UnimplementedProductImportServiceServer
+	importUsecase usecase.ImportUsecase
+}
+
+func NewImportServer(importUsecase usecase.ImportUsecase) *importServer {
+	return &importServer{
+		importUsecase: importUsecase,
+	}
+}
+
+// ShopIDFromImportJob resolves the shop of the import job in the job_id
+// field of the request, for use in middleware.ShopPermission
+func (s *importServer) ShopIDFromImportJob(ctx context.Context, req interface{}) (int, error) {
+	r, ok := req.(interface{ GetJobId() int32 })
+	if !ok {
+		return 0, fmt.Errorf("%T has no job_id", req)
+	}
+
+	job, err := s.importUsecase.GetImportJob(ctx, int(r.GetJobId()))
+	if err != nil {
+		return 0, err
+	}
+	return job.ShopID, nil
+}
+
+func (s *importServer) ImportProducts(ctx context.Context, req *proto.ImportProductsRequest) (*proto.ImportProductsResponse, error) {
+	job, err := s.importUsecase.ImportProducts(ctx, int(req.ShopId), models.ImportFormat(req.Format), bytes.NewReader(req.Data))
+	if err != nil {
+		slog.WarnContext(ctx, "import products failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.ImportProductsResponse{
+		Job: toProtoImportJob(job),
+	}, nil
+}
+
+func (s *importServer) GetImportJob(ctx context.Context, req *proto.GetImportJobRequest) (*proto.GetImportJobResponse, error) {
+	job, err := s.importUsecase.GetImportJob(ctx, int(req.JobId))
+	if err != nil {
+		slog.WarnContext(ctx, "get import job failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.GetImportJobResponse{
+		Job: toProtoImportJob(job),
+	}, nil
+}
+
+func (s *importServer) ExportProducts(ctx context.Context, req *proto.ExportProductsRequest) (*proto.ExportProductsResponse, error) {
+	format := models.ImportFormat(req.Format)
+	if format == "" {
+		format = models.ImportFormatCSV
+	}
+	var buf bytes.Buffer
+	if err := s.importUsecase.ExportProducts(ctx, int(req.ShopId), format, &buf); err != nil {
+		slog.WarnContext(ctx, "export products failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.ExportProductsResponse{
+		Data:        buf.Bytes(),
+		ContentType: format.ContentType(),
+	}, nil
+}
+
+func toProtoImportJob(job *models.ImportJob) *proto.ImportJob {
+	rowErrors := make([]*proto.ImportRowError, 0, len(job.Errors))
+	for _, rowError := range job.Errors {
+		rowErrors = append(rowErrors, &proto.ImportRowError{
+			Row:     int32(rowError.Row),
+			Sku:     rowError.SKU,
+			Code:    rowError.Code,
+			Message: rowError.Message,
+		})
+	}
+
+	return &proto.ImportJob{
+		Id:            int32(job.ID),
+		ShopId:        int32(job.ShopID),
+		Format:        string(job.Format),
+		Status:        string(job.Status),
+		TotalRows:     int32(job.TotalRows),
+		ProcessedRows: int32(job.ProcessedRows),
+		CreatedRows:   int32(job.CreatedRows),
+		UpdatedRows:   int32(job.UpdatedRows),
+		FailedRows:    int32(job.FailedRows),
+		Errors:        rowErrors,
+		Error:         job.Error,
+		CreatedAt:     job.CreatedAt.Format("2006-01-02 15:04:05"),
+		StartedAt:     formatOptionalTime(job.StartedAt),
+		FinishedAt:    formatOptionalTime(job.FinishedAt),
+	}
+}
+
+// formatOptionalTime formats t, or returns "" when it is not set
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -1,43 +1,89 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
 
 	usecase "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/product"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
 )
 
 type productServer struct {
 	proto.UnimplementedProductServiceServer
-	productUsecase usecase.ProductUsecase
+	productUsecase    usecase.ProductUsecase
+	shopAccessChecker middleware.ShopAccessChecker
+}
+
+func NewProductServer(productUsecase usecase.ProductUsecase, shopAccessChecker middleware.ShopAccessChecker) *productServer {
+	return &productServer{
+		productUsecase:    productUsecase,
+		shopAccessChecker: shopAccessChecker,
+	}
+}
+
+// ShopIDFromProduct resolves the shop that owns the product in the
+// product_id field of the request, for use in middleware.ShopPermission
+func (s *productServer) ShopIDFromProduct(ctx context.Context, req interface{}) (int, error) {
+	r, ok := req.(interface{ GetProductId() int32 })
+	if !ok {
+		return 0, fmt.Errorf("%T has no product_id", req)
+	}
+
+	product, err := s.productUsecase.GetProduct(ctx, int(r.GetProductId()))
+	if err != nil {
+		return 0, err
+	}
+	return product.ShopID, nil
 }
 
-func NewProductServer(productUsecase usecase.ProductUsecase) *productServer {
-	return &productServer{productUsecase: productUsecase}
+// draftReaders are the services that may read draft products without an end
+// user. Other service callers, including product-service itself when it
+// transcodes anonymous /api/v2 requests, only see published products.
+var draftReaders = []string{"order-service"}
+
+// canSeeDraft reports whether the caller may see the product while it is a
+// draft: the draftReaders calling without an end user, and members allowed to
+// write the products of its shop
+func (s *productServer) canSeeDraft(ctx context.Context, product *models.Product) bool {
+	if _, ok := middleware.ClaimsFromContext(ctx); !ok {
+		caller, _ := serviceauth.CallerFromContext(ctx)
+		return slices.Contains(draftReaders, caller)
+	}
+	return middleware.AuthorizeShop(ctx, s.shopAccessChecker, product.ShopID, shared.PermProductWrite) == nil
 }
 
 func (s *productServer) GetProducts(ctx context.Context, req *proto.GetProductsRequest) (*proto.GetProductsResponse, error) {
-	products, total, err := s.productUsecase.GetProducts(int(req.ShopId), int(req.Page), int(req.Limit))
+	filter := models.ProductFilter{
+		ShopID:               int(req.ShopId),
+		CategoryID:           int(req.CategoryId),
+		IncludeSubcategories: req.IncludeSubcategories == nil || *req.IncludeSubcategories,
+		InStockOnly:          req.InStockOnly,
+		Status:               models.ProductStatus(req.Status),
+	}
+	if filter.Status != "" && filter.Status != models.ProductStatusPublished && filter.ShopID != 0 {
+		if err := middleware.AuthorizeShop(ctx, s.shopAccessChecker, filter.ShopID, shared.PermProductWrite); err != nil {
+			return nil, err
+		}
+	}
+	products, total, err := s.productUsecase.GetProducts(ctx, filter, int(req.Page), int(req.Limit))
 	if err != nil {
-		log.Printf("GetProducts error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to get products: %v", err)
+		slog.WarnContext(ctx, "get products failed", "error", err)
+		return nil, err
 	}
 
 	var protoProducts []*proto.Product
 	for _, product := range products {
-		protoProducts = append(protoProducts, &proto.Product{
-			Id:          int32(product.ID),
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			ShopId:      int32(product.ShopID),
-			CreatedAt:   product.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   product.UpdatedAt.Format("2006-01-02 15:04:05"),
-		})
+		protoProducts = append(protoProducts, toProtoProduct(product))
 	}
 
 	return &proto.GetProductsResponse{
@@ -49,21 +95,401 @@ func (s *productServer) GetProducts(ctx context.Context, req *proto.GetProductsR
 }
 
 func (s *productServer) GetProduct(ctx context.Context, req *proto.GetProductRequest) (*proto.GetProductResponse, error) {
-	product, err := s.productUsecase.GetProduct(int(req.ProductId))
+	product, err := s.productUsecase.GetProduct(ctx, int(req.ProductId))
 	if err != nil {
-		log.Printf("GetProduct error: %v", err)
-		return nil, status.Errorf(codes.NotFound, "product not found: %v", err)
+		slog.WarnContext(ctx, "get product failed", "error", err)
+		return nil, err
+	}
+	if product.Status == models.ProductStatusDraft && !s.canSeeDraft(ctx, product) {
+		return nil, models.ErrProductNotFound.With("product_id", strconv.Itoa(product.ID))
 	}
 
 	return &proto.GetProductResponse{
-		Product: &proto.Product{
-			Id:          int32(product.ID),
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			ShopId:      int32(product.ShopID),
-			CreatedAt:   product.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:   product.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
+		Product: toProtoProduct(product),
+	}, nil
+}
+
+func (s *productServer) SearchProducts(ctx context.Context, req *proto.SearchProductsRequest) (*proto.SearchProductsResponse, error) {
+	result, err := s.productUsecase.SearchProducts(ctx, models.SearchQuery{
+		Text:                 req.Q,
+		ShopID:               int(req.ShopId),
+		CategoryID:           int(req.CategoryId),
+		IncludeSubcategories: req.IncludeSubcategories == nil || *req.IncludeSubcategories,
+		MinPrice:             req.MinPrice,
+		MaxPrice:             req.MaxPrice,
+		Attributes:           req.Attributes,
+		InStockOnly:          req.InStockOnly,
+		Sort:                 models.SearchSort(req.Sort),
+		Page:                 int(req.Page),
+		Limit:                int(req.Limit),
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "search products failed", "error", err)
+		return nil, err
+	}
+
+	var protoProducts []*proto.Product
+	for _, product := range result.Products {
+		protoProducts = append(protoProducts, toProtoProduct(product))
+	}
+
+	return &proto.SearchProductsResponse{
+		Products: protoProducts,
+		Total:    int32(result.Total),
+		Page:     int32(result.Page),
+		Limit:    int32(result.Limit),
+		Facets:   toProtoFacets(result.Facets),
+	}, nil
+}
+
+func (s *productServer) CreateProduct(ctx context.Context, req *proto.CreateProductRequest) (*proto.CreateProductResponse, error) {
+	product, err := s.productUsecase.CreateProduct(ctx, req.Name, req.Description, req.Price, 0, int(req.ShopId), req.Attributes, req.Sku)
+	if err != nil {
+		slog.WarnContext(ctx, "create product failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.CreateProductResponse{
+		Product: toProtoProduct(product),
+	}, nil
+}
+
+func (s *productServer) UpdateProduct(ctx context.Context, req *proto.UpdateProductRequest) (*proto.UpdateProductResponse, error) {
+	product, err := s.productUsecase.GetProduct(ctx, int(req.ProductId))
+	if err != nil {
+		slog.WarnContext(ctx, "update product failed", "error", err)
+		return nil, err
+	}
+
+	if req.Name != "" {
+		product.Name = req.Name
+	}
+	if req.Description != "" {
+		product.Description = req.Description
+	}
+	if req.Price > 0 {
+		product.Price = req.Price
+	}
+	if len(req.Attributes) > 0 {
+		product.Attributes = req.Attributes
+	}
+
+	if err := s.productUsecase.UpdateProduct(ctx, product); err != nil {
+		slog.WarnContext(ctx, "update product failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.UpdateProductResponse{
+		Product: toProtoProduct(product),
+	}, nil
+}
+
+func (s *productServer) DeleteProduct(ctx context.Context, req *proto.DeleteProductRequest) (*proto.DeleteProductResponse, error) {
+	if err := s.productUsecase.DeleteProduct(ctx, int(req.ProductId)); err != nil {
+		slog.WarnContext(ctx, "delete product failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.DeleteProductResponse{
+		Success: true,
+		Message: "Product deleted successfully",
+	}, nil
+}
+
+func (s *productServer) SetProductStatus(ctx context.Context, req *proto.SetProductStatusRequest) (*proto.SetProductStatusResponse, error) {
+	var publishAt *time.Time
+	if req.PublishAt != "" {
+		t, err := time.Parse(time.RFC3339, req.PublishAt)
+		if err != nil {
+			return nil, models.ErrInvalidPublishAt
+		}
+		publishAt = &t
+	}
+
+	product, err := s.productUsecase.SetProductStatus(ctx, int(req.ProductId), models.ProductStatus(req.Status), publishAt)
+	if err != nil {
+		slog.WarnContext(ctx, "set product status failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.SetProductStatusResponse{
+		Product: toProtoProduct(product),
+	}, nil
+}
+
+func (s *productServer) SetProductCategories(ctx context.Context, req *proto.SetProductCategoriesRequest) (*proto.SetProductCategoriesResponse, error) {
+	categoryIDs := make([]int, 0, len(req.CategoryIds))
+	for _, id := range req.CategoryIds {
+		categoryIDs = append(categoryIDs, int(id))
+	}
+	if err := s.productUsecase.SetProductCategories(ctx, int(req.ProductId), categoryIDs); err != nil {
+		slog.WarnContext(ctx, "set product categories failed", "error", err)
+		return nil, err
+	}
+
+	product, err := s.productUsecase.GetProduct(ctx, int(req.ProductId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.SetProductCategoriesResponse{
+		Product: toProtoProduct(product),
+	}, nil
+}
+
+func (s *productServer) SetProductOptions(ctx context.Context, req *proto.SetProductOptionsRequest) (*proto.SetProductOptionsResponse, error) {
+	options := make([]*models.ProductOption, 0, len(req.Options))
+	for _, option := range req.Options {
+		options = append(options, &models.ProductOption{
+			Name:   option.Name,
+			Values: option.Values,
+		})
+	}
+	if err := s.productUsecase.SetProductOptions(ctx, int(req.ProductId), options); err != nil {
+		slog.WarnContext(ctx, "set product options failed", "error", err)
+		return nil, err
+	}
+
+	product, err := s.productUsecase.GetProduct(ctx, int(req.ProductId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.SetProductOptionsResponse{
+		Product: toProtoProduct(product),
 	}, nil
 }
+
+func (s *productServer) GetVariant(ctx context.Context, req *proto.GetVariantRequest) (*proto.GetVariantResponse, error) {
+	variant, err := s.productUsecase.GetVariant(ctx, int(req.ProductId), int(req.VariantId))
+	if err != nil {
+		slog.WarnContext(ctx, "get variant failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.GetVariantResponse{
+		Variant: toProtoVariant(variant),
+	}, nil
+}
+
+func (s *productServer) CreateVariant(ctx context.Context, req *proto.CreateVariantRequest) (*proto.CreateVariantResponse, error) {
+	variant, err := s.productUsecase.CreateVariant(ctx, &models.Variant{
+		ProductID:   int(req.ProductId),
+		SKU:         req.Sku,
+		Barcode:     req.Barcode,
+		Price:       req.Price,
+		WeightGrams: req.WeightGrams,
+		Options:     req.Options,
+		IsDefault:   req.IsDefault,
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "create variant failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.CreateVariantResponse{
+		Variant: toProtoVariant(variant),
+	}, nil
+}
+
+func (s *productServer) UpdateVariant(ctx context.Context, req *proto.UpdateVariantRequest) (*proto.UpdateVariantResponse, error) {
+	err := s.productUsecase.UpdateVariant(ctx, &models.Variant{
+		ID:          int(req.VariantId),
+		ProductID:   int(req.ProductId),
+		SKU:         req.Sku,
+		Barcode:     req.Barcode,
+		Price:       req.Price,
+		WeightGrams: req.WeightGrams,
+		Options:     req.Options,
+		IsDefault:   req.IsDefault,
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "update variant failed", "error", err)
+		return nil, err
+	}
+
+	variant, err := s.productUsecase.GetVariant(ctx, int(req.ProductId), int(req.VariantId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.UpdateVariantResponse{
+		Variant: toProtoVariant(variant),
+	}, nil
+}
+
+func (s *productServer) DeleteVariant(ctx context.Context, req *proto.DeleteVariantRequest) (*proto.DeleteVariantResponse, error) {
+	if err := s.productUsecase.DeleteVariant(ctx, int(req.ProductId), int(req.VariantId)); err != nil {
+		slog.WarnContext(ctx, "delete variant failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.DeleteVariantResponse{
+		Success: true,
+		Message: "Variant deleted successfully",
+	}, nil
+}
+
+func (s *productServer) UploadProductImage(ctx context.Context, req *proto.UploadProductImageRequest) (*proto.UploadProductImageResponse, error) {
+	image, err := s.productUsecase.UploadImage(ctx, int(req.ProductId), bytes.NewReader(req.Data))
+	if err != nil {
+		slog.WarnContext(ctx, "upload product image failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.UploadProductImageResponse{
+		Image: toProtoImage(image),
+	}, nil
+}
+
+func (s *productServer) ReorderProductImages(ctx context.Context, req *proto.ReorderProductImagesRequest) (*proto.ReorderProductImagesResponse, error) {
+	imageIDs := make([]int, 0, len(req.ImageIds))
+	for _, id := range req.ImageIds {
+		imageIDs = append(imageIDs, int(id))
+	}
+	if err := s.productUsecase.ReorderImages(ctx, int(req.ProductId), imageIDs); err != nil {
+		slog.WarnContext(ctx, "reorder product images failed", "error", err)
+		return nil, err
+	}
+
+	product, err := s.productUsecase.GetProduct(ctx, int(req.ProductId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.ReorderProductImagesResponse{
+		Product: toProtoProduct(product),
+	}, nil
+}
+
+func (s *productServer) DeleteProductImage(ctx context.Context, req *proto.DeleteProductImageRequest) (*proto.DeleteProductImageResponse, error) {
+	if err := s.productUsecase.DeleteImage(ctx, int(req.ProductId), int(req.ImageId)); err != nil {
+		slog.WarnContext(ctx, "delete product image failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.DeleteProductImageResponse{
+		Success: true,
+		Message: "Image deleted successfully",
+	}, nil
+}
+
+// UpdateStock is kept for existing callers and delegates to warehouse-service
+func (s *productServer) UpdateStock(ctx context.Context, req *proto.UpdateStockRequest) (*proto.UpdateStockResponse, error) {
+	newStock, err := s.productUsecase.UpdateStock(ctx, int(req.ProductId), int(req.VariantId), int(req.WarehouseId), req.Quantity, req.Operation)
+	if err != nil {
+		slog.WarnContext(ctx, "update stock failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.UpdateStockResponse{
+		Success:  true,
+		NewStock: newStock,
+	}, nil
+}
+
+// toProtoProduct converts a domain product to its proto representation
+func toProtoProduct(product *models.Product) *proto.Product {
+	categoryIDs := make([]int32, 0, len(product.CategoryIDs))
+	for _, id := range product.CategoryIDs {
+		categoryIDs = append(categoryIDs, int32(id))
+	}
+	options := make([]*proto.ProductOption, 0, len(product.Options))
+	for _, option := range product.Options {
+		options = append(options, &proto.ProductOption{Name: option.Name, Values: option.Values})
+	}
+	variants := make([]*proto.Variant, 0, len(product.Variants))
+	for _, variant := range product.Variants {
+		variants = append(variants, toProtoVariant(variant))
+	}
+	images := make([]*proto.ProductImage, 0, len(product.Images))
+	for _, image := range product.Images {
+		images = append(images, toProtoImage(image))
+	}
+	var publishAt string
+	if product.PublishAt != nil {
+		publishAt = product.PublishAt.Format("2006-01-02 15:04:05")
+	}
+
+	return &proto.Product{
+		Id:          int32(product.ID),
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Stock:       product.Stock,
+		ShopId:      int32(product.ShopID),
+		CreatedAt:   product.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   product.UpdatedAt.Format("2006-01-02 15:04:05"),
+		Attributes:  product.Attributes,
+		CategoryIds: categoryIDs,
+		Options:     options,
+		Variants:    variants,
+		Status:      string(product.Status),
+		PublishAt:   publishAt,
+		Images:      images,
+	}
+}
+
+func toProtoVariant(variant *models.Variant) *proto.Variant {
+	return &proto.Variant{
+		Id:          int32(variant.ID),
+		ProductId:   int32(variant.ProductID),
+		Sku:         variant.SKU,
+		Barcode:     variant.Barcode,
+		Price:       variant.Price,
+		WeightGrams: variant.WeightGrams,
+		Options:     variant.Options,
+		IsDefault:   variant.IsDefault,
+		Stock:       variant.Stock,
+		CreatedAt:   variant.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   variant.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func toProtoImage(image *models.ProductImage) *proto.ProductImage {
+	return &proto.ProductImage{
+		Id:           int32(image.ID),
+		Url:          image.URL,
+		ThumbnailUrl: image.ThumbnailURL,
+		ContentType:  image.ContentType,
+		Width:        int32(image.Width),
+		Height:       int32(image.Height),
+		Size:         image.Size,
+		Position:     int32(image.Position),
+		CreatedAt:    image.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// toProtoFacets converts search facets to their proto representation, with
+// the attributes sorted by name
+func toProtoFacets(facets models.SearchFacets) *proto.SearchFacets {
+	result := &proto.SearchFacets{}
+	for _, shop := range facets.Shops {
+		result.Shops = append(result.Shops, toProtoFacetCount(shop))
+	}
+	for _, priceRange := range facets.PriceRanges {
+		result.PriceRanges = append(result.PriceRanges, &proto.PriceRangeFacet{
+			Min:   priceRange.Min,
+			Max:   priceRange.Max,
+			Count: int32(priceRange.Count),
+		})
+	}
+
+	names := make([]string, 0, len(facets.Attributes))
+	for name := range facets.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attribute := &proto.AttributeFacet{Name: name}
+		for _, value := range facets.Attributes[name] {
+			attribute.Values = append(attribute.Values, toProtoFacetCount(value))
+		}
+		result.Attributes = append(result.Attributes, attribute)
+	}
+	return result
+}
+
+func toProtoFacetCount(facet models.FacetCount) *proto.FacetCount {
+	return &proto.FacetCount{Value: facet.Value, Count: int32(facet.Count)}
+}
@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	usecase "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	proto "github.com/evrintobing17/ecommerce-system/shared/proto/product"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const testServiceSecret = "service-secret"
+
+// productUsecaseStub serves draft product 1 and published product 2, both
+// of shop 1
+type productUsecaseStub struct {
+	usecase.ProductUsecase
+}
+
+func (u *productUsecaseStub) GetProduct(ctx context.Context, id int) (*models.Product, error) {
+	switch id {
+	case 1:
+		return &models.Product{ID: id, Name: "Draft", ShopID: 1, Status: models.ProductStatusDraft}, nil
+	case 2:
+		return &models.Product{ID: id, Name: "Published", ShopID: 1, Status: models.ProductStatusPublished}, nil
+	}
+	return nil, models.ErrProductNotFound
+}
+
+// shopAccessStub grants every action on shop 1 to user 1
+type shopAccessStub struct{}
+
+func (shopAccessStub) CanAccessShop(ctx context.Context, claims *shared.Claims, shopID int, action string) (bool, error) {
+	return claims.UserID == 1 && shopID == 1, nil
+}
+
+// serviceContext returns the context the service auth interceptor hands to
+// the server for a call made with the service token of service
+func serviceContext(t *testing.T, service string) context.Context {
+	t.Helper()
+	token, err := serviceauth.GenerateToken(service, testServiceSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(serviceauth.TokenMetadataKey, token))
+
+	interceptor := serviceauth.UnaryServerInterceptor(serviceauth.Config{TokenSecret: testServiceSecret}, nil)
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: proto.ProductService_GetProduct_FullMethodName},
+		func(handlerCtx context.Context, req interface{}) (interface{}, error) {
+			ctx = handlerCtx
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatalf("authenticating %s: %v", service, err)
+	}
+	return ctx
+}
+
+func TestGetProductHidesDrafts(t *testing.T) {
+	server := NewProductServer(&productUsecaseStub{}, shopAccessStub{})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		// seesDraft is whether the draft product is returned
+		seesDraft bool
+	}{
+		{"anonymous", context.Background(), false},
+		{"order-service", serviceContext(t, "order-service"), true},
+		// Transcoded /api/v2 requests come in with product-service's own token
+		{"anonymous transcoded request", serviceContext(t, "product-service"), false},
+		{"other service", serviceContext(t, "warehouse-service"), false},
+		{"shop member", middleware.ContextWithClaims(context.Background(), &shared.Claims{UserID: 1}), true},
+		{"other user", middleware.ContextWithClaims(context.Background(), &shared.Claims{UserID: 2}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := server.GetProduct(tt.ctx, &proto.GetProductRequest{ProductId: 2}); err != nil {
+				t.Fatalf("GetProduct of published product: %v", err)
+			}
+
+			_, err := server.GetProduct(tt.ctx, &proto.GetProductRequest{ProductId: 1})
+			if tt.seesDraft && err != nil {
+				t.Errorf("GetProduct of draft: %v", err)
+			}
+			if !tt.seesDraft && !errors.Is(err, models.ErrProductNotFound) {
+				t.Errorf("GetProduct of draft: err = %v, want %v", err, models.ErrProductNotFound)
+			}
+		})
+	}
+}
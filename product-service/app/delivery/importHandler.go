@@ -0,0 +1,118 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	usecase "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ImportHandler struct {
+	importUsecase usecase.ImportUsecase
+}
+
+func NewImportHandler(importUsecase usecase.ImportUsecase) *ImportHandler {
+	return &ImportHandler{importUsecase: importUsecase}
+}
+
+// ImportProducts queues the import of the "file" of a multipart form into
+// the shop in shop_id. The format is taken from the format parameter or the
+// file extension.
+func (h *ImportHandler) ImportProducts(c *gin.Context) {
+	shopID, _ := strconv.Atoi(c.Query("shop_id"))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		jsonhttpresponse.FromError(c, models.ErrInvalidImportFile.Wrap(err))
+		return
+	}
+	format := models.ImportFormat(c.Query("format"))
+	if format == "" {
+		format = formatFromFilename(fileHeader.Filename)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+	defer file.Close()
+
+	job, err := h.importUsecase.ImportProducts(c.Request.Context(), shopID, format, file)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.StatusCreated(c, gin.H{
+		"job": job,
+	})
+}
+
+// formatFromFilename returns the import format of the file extension
+func formatFromFilename(filename string) models.ImportFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return models.ImportFormatCSV
+	case ".jsonl", ".ndjson":
+		return models.ImportFormatJSONL
+	}
+	return ""
+}
+
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	jobID, _ := strconv.Atoi(c.Param("job_id"))
+
+	job, err := h.importUsecase.GetImportJob(c.Request.Context(), jobID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"job": job,
+	})
+}
+
+// ExportProducts sends the products of the shop in shop_id as a file in the
+// format parameter, csv by default
+func (h *ImportHandler) ExportProducts(c *gin.Context) {
+	shopID, _ := strconv.Atoi(c.Query("shop_id"))
+	format := models.ImportFormat(c.DefaultQuery("format", string(models.ImportFormatCSV)))
+
+	var buf bytes.Buffer
+	if err := h.importUsecase.ExportProducts(c.Request.Context(), shopID, format, &buf); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%d.%s"`, shopID, format))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// ShopIDFromImportJob resolves the shop of the import job in the :job_id
+// path parameter, for use with middleware.RequireShopPermission
+func (h *ImportHandler) ShopIDFromImportJob(c *gin.Context) (int, error) {
+	jobID, err := middleware.IntFromParam(c, "job_id")
+	if err != nil {
+		return 0, err
+	}
+
+	job, err := h.importUsecase.GetImportJob(c.Request.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, models.ErrImportJobNotFound) {
+			return 0, fmt.Errorf("%w: import job %d", middleware.ErrResourceNotFound, jobID)
+		}
+		return 0, err
+	}
+	return job.ShopID, nil
+}
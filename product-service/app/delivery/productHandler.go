@@ -1,76 +1,158 @@
 package http
 
 import (
-	"net/http"
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	usecase "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared"
 	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 type ProductHandler struct {
-	productUsecase usecase.ProductUsecase
+	productUsecase    usecase.ProductUsecase
+	shopAccessChecker middleware.ShopAccessChecker
 }
 
-func NewProductHandler(productUsecase usecase.ProductUsecase) *ProductHandler {
-	return &ProductHandler{productUsecase: productUsecase}
+func NewProductHandler(productUsecase usecase.ProductUsecase, shopAccessChecker middleware.ShopAccessChecker) *ProductHandler {
+	return &ProductHandler{
+		productUsecase:    productUsecase,
+		shopAccessChecker: shopAccessChecker,
+	}
 }
 
+// GetProducts lists published products. Members allowed to write the
+// products of the shop in shop_id may list its drafts and archived products
+// with the status parameter.
 func (h *ProductHandler) GetProducts(c *gin.Context) {
-	shopID, _ := strconv.Atoi(c.Query("shop_id"))
+	filter := models.ProductFilter{
+		Status:               models.ProductStatus(c.Query("status")),
+		InStockOnly:          c.Query("in_stock_only") == "true",
+		IncludeSubcategories: c.Query("include_subcategories") != "false",
+	}
+	filter.ShopID, _ = strconv.Atoi(c.Query("shop_id"))
+	filter.CategoryID, _ = strconv.Atoi(c.Query("category_id"))
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	products, total, err := h.productUsecase.GetProducts(shopID, page, limit)
+	if filter.Status != "" && filter.Status != models.ProductStatusPublished && filter.ShopID != 0 {
+		if err := h.authorizeShop(c, filter.ShopID); err != nil {
+			jsonhttpresponse.FromError(c, err)
+			return
+		}
+	}
+
+	products, total, err := h.productUsecase.GetProducts(c.Request.Context(), filter, page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	jsonhttpresponse.OK(c, gin.H{
+	jsonhttpresponse.Paginated(c, gin.H{
 		"products": products,
-		"total":    total,
-		"page":     page,
-		"limit":    limit,
-	})
+	}, page, limit, total)
+}
+
+// SearchProducts answers GET /products/search. Attribute filters are given
+// as attributes[name]=value parameters.
+func (h *ProductHandler) SearchProducts(c *gin.Context) {
+	query := models.SearchQuery{
+		Text:                 c.Query("q"),
+		IncludeSubcategories: c.Query("include_subcategories") != "false",
+		Attributes:           c.QueryMap("attributes"),
+		InStockOnly:          c.Query("in_stock_only") == "true",
+		Sort:                 models.SearchSort(c.Query("sort")),
+	}
+	query.ShopID, _ = strconv.Atoi(c.Query("shop_id"))
+	query.CategoryID, _ = strconv.Atoi(c.Query("category_id"))
+	query.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	query.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	var ok bool
+	if query.MinPrice, ok = priceQuery(c, "min_price"); !ok {
+		jsonhttpresponse.BadRequest(c, "invalid_parameter", "min_price must be a non-negative number")
+		return
+	}
+	if query.MaxPrice, ok = priceQuery(c, "max_price"); !ok {
+		jsonhttpresponse.BadRequest(c, "invalid_parameter", "max_price must be a non-negative number")
+		return
+	}
+
+	result, err := h.productUsecase.SearchProducts(c.Request.Context(), query)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.Paginated(c, gin.H{
+		"products": result.Products,
+		"facets":   result.Facets,
+	}, result.Page, result.Limit, result.Total)
 }
 
+// priceQuery returns the price in the query parameter, or nil if it is not
+// set. It reports false when the value is not a non-negative number.
+func priceQuery(c *gin.Context, param string) (*float64, bool) {
+	value, ok := c.GetQuery(param)
+	if !ok {
+		return nil, true
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price < 0 {
+		return nil, false
+	}
+	return &price, true
+}
+
+// GetProduct returns the product. Drafts are only shown to members allowed to
+// write the products of its shop.
 func (h *ProductHandler) GetProduct(c *gin.Context) {
 	productID, _ := strconv.Atoi(c.Param("id"))
 
-	product, err := h.productUsecase.GetProduct(productID)
+	product, err := h.productUsecase.GetProduct(c.Request.Context(), productID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+	if product.Status == models.ProductStatusDraft && h.authorizeShop(c, product.ShopID) != nil {
+		jsonhttpresponse.FromError(c, models.ErrProductNotFound.With("product_id", strconv.Itoa(productID)))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"product": product,
 	})
 }
 
 func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	var request struct {
-		Name        string  `json:"name" binding:"required"`
-		Description string  `json:"description" binding:"required"`
-		Price       float64 `json:"price" binding:"required,min=0"`
-		Stock       int32   `json:"stock" binding:"required,min=0"`
-		ShopID      int     `json:"shop_id" binding:"required"`
+		Name        string            `json:"name" binding:"required"`
+		Description string            `json:"description" binding:"required"`
+		Price       float64           `json:"price" binding:"required,min=0"`
+		Stock       int32             `json:"stock" binding:"required,min=0"`
+		ShopID      int               `json:"shop_id" binding:"required"`
+		Attributes  models.Attributes `json:"attributes"`
+		// SKU of the default variant; generated when empty
+		SKU string `json:"sku"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	product, err := h.productUsecase.CreateProduct(request.Name, request.Description, request.Price, request.Stock, request.ShopID)
+	product, err := h.productUsecase.CreateProduct(c.Request.Context(), request.Name, request.Description, request.Price, request.Stock, request.ShopID, request.Attributes, request.SKU)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	jsonhttpresponse.StatusCreated(c, gin.H{
 		"product": product,
 	})
 }
@@ -81,16 +163,18 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		Name        string  `json:"name"`
 		Description string  `json:"description"`
 		Price       float64 `json:"price" min:"0"`
+		// Replaces all attributes when not empty
+		Attributes models.Attributes `json:"attributes"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	product, err := h.productUsecase.GetProduct(productID)
+	product, err := h.productUsecase.GetProduct(c.Request.Context(), productID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
@@ -103,14 +187,17 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	if request.Price > 0 {
 		product.Price = request.Price
 	}
+	if len(request.Attributes) > 0 {
+		product.Attributes = request.Attributes
+	}
 
-	err = h.productUsecase.UpdateProduct(product)
+	err = h.productUsecase.UpdateProduct(c.Request.Context(), product)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"product": product,
 	})
 }
@@ -118,13 +205,309 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	productID, _ := strconv.Atoi(c.Param("id"))
 
-	err := h.productUsecase.DeleteProduct(productID)
+	err := h.productUsecase.DeleteProduct(c.Request.Context(), productID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"message": "Product deleted successfully",
 	})
 }
+
+func (h *ProductHandler) SetProductStatus(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	var request struct {
+		Status models.ProductStatus `json:"status" binding:"required"`
+		// Schedules the publishing of a draft
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	product, err := h.productUsecase.SetProductStatus(c.Request.Context(), productID, request.Status, request.PublishAt)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"product": product,
+	})
+}
+
+func (h *ProductHandler) SetProductCategories(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	var request struct {
+		CategoryIDs []int `json:"category_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.productUsecase.SetProductCategories(ctx, productID, request.CategoryIDs); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	product, err := h.productUsecase.GetProduct(ctx, productID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"product": product,
+	})
+}
+
+func (h *ProductHandler) SetProductOptions(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	var request struct {
+		Options []*models.ProductOption `json:"options" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.productUsecase.SetProductOptions(ctx, productID, request.Options); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	product, err := h.productUsecase.GetProduct(ctx, productID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"product": product,
+	})
+}
+
+func (h *ProductHandler) GetVariant(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	variantID, _ := strconv.Atoi(c.Param("variant_id"))
+
+	variant, err := h.productUsecase.GetVariant(c.Request.Context(), productID, variantID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"variant": variant,
+	})
+}
+
+func (h *ProductHandler) CreateVariant(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	var request struct {
+		SKU         string                `json:"sku" binding:"required"`
+		Barcode     string                `json:"barcode"`
+		Price       *float64              `json:"price" binding:"omitempty,min=0"`
+		WeightGrams *int32                `json:"weight_grams" binding:"omitempty,min=0"`
+		Options     models.VariantOptions `json:"options"`
+		IsDefault   bool                  `json:"is_default"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	variant, err := h.productUsecase.CreateVariant(c.Request.Context(), &models.Variant{
+		ProductID:   productID,
+		SKU:         request.SKU,
+		Barcode:     request.Barcode,
+		Price:       request.Price,
+		WeightGrams: request.WeightGrams,
+		Options:     request.Options,
+		IsDefault:   request.IsDefault,
+	})
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.StatusCreated(c, gin.H{
+		"variant": variant,
+	})
+}
+
+func (h *ProductHandler) UpdateVariant(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	variantID, _ := strconv.Atoi(c.Param("variant_id"))
+	var request struct {
+		SKU     string  `json:"sku"`
+		Barcode *string `json:"barcode"`
+		// An absent price or weight removes it
+		Price       *float64              `json:"price" binding:"omitempty,min=0"`
+		WeightGrams *int32                `json:"weight_grams" binding:"omitempty,min=0"`
+		Options     models.VariantOptions `json:"options"`
+		// Makes the variant the product's default; false keeps the current one
+		IsDefault bool `json:"is_default"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	variant, err := h.productUsecase.GetVariant(ctx, productID, variantID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	if request.SKU != "" {
+		variant.SKU = request.SKU
+	}
+	if request.Barcode != nil {
+		variant.Barcode = *request.Barcode
+	}
+	if request.Options != nil {
+		variant.Options = request.Options
+	}
+	variant.Price = request.Price
+	variant.WeightGrams = request.WeightGrams
+	variant.IsDefault = request.IsDefault
+
+	if err := h.productUsecase.UpdateVariant(ctx, variant); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	variant, err = h.productUsecase.GetVariant(ctx, productID, variantID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"variant": variant,
+	})
+}
+
+func (h *ProductHandler) DeleteVariant(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	variantID, _ := strconv.Atoi(c.Param("variant_id"))
+
+	if err := h.productUsecase.DeleteVariant(c.Request.Context(), productID, variantID); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"message": "Variant deleted successfully",
+	})
+}
+
+// UploadImage adds the image sent as the "image" file of a multipart form
+func (h *ProductHandler) UploadImage(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		jsonhttpresponse.FromError(c, models.ErrImageRequired.Wrap(err))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+	defer file.Close()
+
+	image, err := h.productUsecase.UploadImage(c.Request.Context(), productID, file)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.StatusCreated(c, gin.H{
+		"image": image,
+	})
+}
+
+func (h *ProductHandler) ReorderImages(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	var request struct {
+		ImageIDs []int `json:"image_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.productUsecase.ReorderImages(ctx, productID, request.ImageIDs); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	product, err := h.productUsecase.GetProduct(ctx, productID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"product": product,
+	})
+}
+
+func (h *ProductHandler) DeleteImage(c *gin.Context) {
+	productID, _ := strconv.Atoi(c.Param("id"))
+	imageID, _ := strconv.Atoi(c.Param("image_id"))
+
+	if err := h.productUsecase.DeleteImage(c.Request.Context(), productID, imageID); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"message": "Image deleted successfully",
+	})
+}
+
+// authorizeShop checks the caller may write the products of the shop
+func (h *ProductHandler) authorizeShop(c *gin.Context, shopID int) error {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return middleware.ErrAuthorizationRequired
+	}
+	ctx := middleware.ContextWithClaims(c.Request.Context(), claims)
+	return middleware.AuthorizeShop(ctx, h.shopAccessChecker, shopID, shared.PermProductWrite)
+}
+
+// ShopIDFromProduct resolves the shop that owns the product in the :id path
+// parameter, for use with middleware.RequireShopPermission
+func (h *ProductHandler) ShopIDFromProduct(c *gin.Context) (int, error) {
+	productID, err := middleware.IntFromParam(c, "id")
+	if err != nil {
+		return 0, err
+	}
+
+	product, err := h.productUsecase.GetProduct(c.Request.Context(), productID)
+	if err != nil {
+		if errors.Is(err, models.ErrProductNotFound) {
+			return 0, fmt.Errorf("%w: product %d", middleware.ErrResourceNotFound, productID)
+		}
+		return 0, err
+	}
+	return product.ShopID, nil
+}
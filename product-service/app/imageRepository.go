@@ -0,0 +1,20 @@
+package app
+
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+type ImageRepository interface {
+	// Create stores the image after the product's other images
+	Create(ctx context.Context, image *models.ProductImage) error
+	FindByID(ctx context.Context, id int) (*models.ProductImage, error)
+	// FindByProductIDs returns the images of each product in display order,
+	// keyed by product ID
+	FindByProductIDs(ctx context.Context, productIDs []int) (map[int][]*models.ProductImage, error)
+	Delete(ctx context.Context, id int) error
+	// Reorder sets the position of the product's images to their index in
+	// imageIDs
+	Reorder(ctx context.Context, productID int, imageIDs []int) error
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+type ImportJobRepository interface {
+	Create(ctx context.Context, job *models.ImportJob) error
+	FindByID(ctx context.Context, id int) (*models.ImportJob, error)
+	// Claim marks the oldest pending job, or a running job not updated
+	// since staleBefore, as running with cleared results and a new attempt
+	// and returns it. It returns nil when there is no such job. Stale jobs
+	// already claimed maxAttempts times are marked failed instead, so that a
+	// file that keeps stopping its worker is not retried forever.
+	Claim(ctx context.Context, staleBefore time.Time, maxAttempts int) (*models.ImportJob, error)
+	// Heartbeat touches the updated_at of the job, so that it does not look
+	// stale while the worker is busy between saves. It returns
+	// ErrImportJobLost when the job has been claimed again since attempt.
+	Heartbeat(ctx context.Context, id, attempt int) error
+	// UpdateProgress saves the status and results of the job, leaving its
+	// file untouched. Like Finish, it returns ErrImportJobLost when the job
+	// has been claimed again since its Attempt.
+	UpdateProgress(ctx context.Context, job *models.ImportJob) error
+	// Finish saves the job and drops its file
+	Finish(ctx context.Context, job *models.ImportJob) error
+}
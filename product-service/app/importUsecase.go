@@ -0,0 +1,21 @@
+package app
+
+import (
+	"context"
+	"io"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+type ImportUsecase interface {
+	// ImportProducts checks the file read from r and queues a job importing
+	// its rows into the products of the shop
+	ImportProducts(ctx context.Context, shopID int, format models.ImportFormat, r io.Reader) (*models.ImportJob, error)
+	GetImportJob(ctx context.Context, id int) (*models.ImportJob, error)
+	// RunNextImportJob runs the oldest queued import job and reports whether
+	// there was one
+	RunNextImportJob(ctx context.Context) (bool, error)
+	// ExportProducts writes the products of the shop in any status except
+	// deleted, in the import format
+	ExportProducts(ctx context.Context, shopID int, format models.ImportFormat, w io.Writer) error
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Category struct {
+	ID       int    `gorm:"primaryKey" json:"id"`
+	ParentID *int   `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	// Path is the materialized path of IDs from the root, e.g. "/1/4/"
+	Path      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AncestorIDs returns the IDs of the category's ancestors, root first
+func (c *Category) AncestorIDs() []int {
+	var ids []int
+	for _, segment := range strings.Split(strings.Trim(c.Path, "/"), "/") {
+		id, err := strconv.Atoi(segment)
+		if err != nil || id == c.ID {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// IsInSubtreeOf reports whether c is other or one of its descendants
+func (c *Category) IsInSubtreeOf(other *Category) bool {
+	return strings.HasPrefix(c.Path, other.Path)
+}
+
+// CategoryNode is a category in the category tree. ProductCount counts the
+// distinct products assigned to the category or any of its descendants.
+type CategoryNode struct {
+	*Category
+	ProductCount int64           `json:"product_count"`
+	Children     []*CategoryNode `json:"children"`
+}
+
+// CategoryDetail is a category with its place in the tree
+type CategoryDetail struct {
+	*Category
+	ProductCount int64 `json:"product_count"`
+	// Breadcrumbs are the ancestors of the category, root first, followed
+	// by the category itself
+	Breadcrumbs []*Category     `json:"breadcrumbs"`
+	Children    []*CategoryNode `json:"children"`
+}
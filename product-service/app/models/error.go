@@ -0,0 +1,48 @@
+package models
+
+import "github.com/evrintobing17/ecommerce-system/shared/apperror"
+
+var (
+	ErrProductNotFound         = apperror.NewNotFound("product_not_found", "product not found")
+	ErrInvalidProductStatus    = apperror.NewInvalidArgument("invalid_product_status", "status must be one of draft, published or archived")
+	ErrInvalidPublishAt        = apperror.NewInvalidArgument("invalid_publish_at", "publish_at must be an RFC 3339 time")
+	ErrPublishAtNotDraft       = apperror.NewInvalidArgument("publish_at_not_draft", "publish_at can only be set on drafts")
+	ErrStatusFilterNeedsShop   = apperror.NewInvalidArgument("shop_id_required", "shop_id is required to list draft or archived products")
+	ErrAvailabilityUnavailable = apperror.NewUnavailable("availability_unavailable", "stock availability is temporarily unavailable")
+	ErrInvalidSearchSort       = apperror.NewInvalidArgument("invalid_search_sort", "sort must be one of relevance, price_asc, price_desc or newest")
+	ErrInvalidPriceRange       = apperror.NewInvalidArgument("invalid_price_range", "min_price must not be greater than max_price")
+	ErrCategoryNotFound        = apperror.NewNotFound("category_not_found", "category not found")
+	ErrCategorySlugTaken       = apperror.NewConflict("category_slug_taken", "category with this slug already exists")
+	ErrInvalidCategoryName     = apperror.NewInvalidArgument("invalid_category_name", "category name must contain letters or digits")
+	ErrInvalidCategoryParent   = apperror.NewInvalidArgument("invalid_category_parent", "a category cannot be moved below itself or its descendants")
+	ErrCategoryHasChildren     = apperror.NewFailedPrecondition("category_has_children", "category has subcategories")
+	ErrVariantNotFound         = apperror.NewNotFound("variant_not_found", "variant not found")
+	ErrSKURequired             = apperror.NewInvalidArgument("sku_required", "variants need a SKU")
+	ErrSKUTaken                = apperror.NewConflict("sku_taken", "a variant with this SKU already exists in the shop")
+	ErrInvalidVariantOptions   = apperror.NewInvalidArgument("invalid_variant_options", "variant options must use the product's options and their values")
+	ErrDuplicateVariant        = apperror.NewConflict("duplicate_variant", "a variant with these options already exists")
+	ErrInvalidProductOptions   = apperror.NewInvalidArgument("invalid_product_options", "options need a unique name and at least one value")
+	ErrProductOptionInUse      = apperror.NewFailedPrecondition("product_option_in_use", "an option value removed from the product is used by a variant")
+	ErrDefaultVariant          = apperror.NewFailedPrecondition("default_variant", "the default variant cannot be deleted; make another variant the default first")
+	ErrImageNotFound           = apperror.NewNotFound("image_not_found", "image not found")
+	ErrImageRequired           = apperror.NewInvalidArgument("image_required", "an image file is required")
+	ErrImageTooLarge           = apperror.NewInvalidArgument("image_too_large", "the image file is too large")
+	ErrUnsupportedImageType    = apperror.NewInvalidArgument("unsupported_image_type", "images must be JPEG, PNG, GIF or WebP")
+	ErrImageDimensionsTooLarge = apperror.NewInvalidArgument("image_dimensions_too_large", "the image has too many pixels")
+	ErrTooManyImages           = apperror.NewFailedPrecondition("too_many_images", "the product has the maximum number of images")
+	ErrInvalidImageOrder       = apperror.NewInvalidArgument("invalid_image_order", "image_ids must list each image of the product once")
+	ErrImportJobNotFound       = apperror.NewNotFound("import_job_not_found", "import job not found")
+	ErrImportJobLost           = apperror.NewConflict("import_job_lost", "import job was claimed by another worker")
+	ErrInvalidImportFormat     = apperror.NewInvalidArgument("invalid_import_format", "format must be csv or jsonl")
+	ErrInvalidImportFile       = apperror.NewInvalidArgument("invalid_import_file", "the import file cannot be read")
+	ErrImportFileTooLarge      = apperror.NewInvalidArgument("import_file_too_large", "the import file is too large")
+	ErrTooManyImportRows       = apperror.NewInvalidArgument("too_many_import_rows", "the import file has too many rows")
+	ErrInvalidImportRow        = apperror.NewInvalidArgument("invalid_import_row", "the row is malformed")
+	ErrNameRequired            = apperror.NewInvalidArgument("name_required", "name is required")
+	ErrInvalidPrice            = apperror.NewInvalidArgument("invalid_price", "price must be a non-negative number")
+	ErrInvalidImportAttributes = apperror.NewInvalidArgument("invalid_attributes", "attributes must be a JSON object of strings")
+	ErrInvalidImportStock      = apperror.NewInvalidArgument("invalid_stock", "stock must map warehouse IDs to non-negative quantities")
+	ErrDuplicateImportSKU      = apperror.NewInvalidArgument("duplicate_sku", "the SKU appears on an earlier row")
+	ErrSKUNotDefaultVariant    = apperror.NewFailedPrecondition("sku_not_default_variant", "the SKU belongs to a variant other than the product's default")
+	ErrWarehouseNotInShop      = apperror.NewInvalidArgument("warehouse_not_in_shop", "the warehouse does not belong to the shop")
+)
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ProductImage is an image of a product. The original file and its
+// thumbnail are kept by the media storage under Key and ThumbnailKey.
+type ProductImage struct {
+	ID           int       `gorm:"primaryKey" json:"id"`
+	ProductID    int       `json:"product_id"`
+	Key          string    `gorm:"column:storage_key" json:"-"`
+	ThumbnailKey string    `json:"-"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `gorm:"column:size_bytes" json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// URL and ThumbnailURL are where clients fetch the files from
+	URL          string `gorm:"-" json:"url"`
+	ThumbnailURL string `gorm:"-" json:"thumbnail_url"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// ImportFormat is the file format of product imports and exports
+type ImportFormat string
+
+const (
+	ImportFormatCSV   ImportFormat = "csv"
+	ImportFormatJSONL ImportFormat = "jsonl"
+)
+
+// Valid reports whether f is one of the import formats
+func (f ImportFormat) Valid() bool {
+	return f == ImportFormatCSV || f == ImportFormatJSONL
+}
+
+// ContentType returns the media type of files in the format
+func (f ImportFormat) ContentType() string {
+	if f == ImportFormatJSONL {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ImportJobStatus is the state of an import job
+type ImportJobStatus string
+
+const (
+	// ImportJobPending jobs wait for the import worker
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	// ImportJobFailed jobs could not run at all; Error says why. Rows that
+	// fail in a completed job are listed in Errors instead.
+	ImportJobFailed ImportJobStatus = "failed"
+)
+
+// ImportJob imports the rows of an uploaded file into the products of a
+// shop. The file is kept in Data until the job finishes.
+type ImportJob struct {
+	ID            int             `gorm:"primaryKey" json:"id"`
+	ShopID        int             `json:"shop_id"`
+	Format        ImportFormat    `json:"format"`
+	Status        ImportJobStatus `json:"status"`
+	TotalRows     int             `json:"total_rows"`
+	ProcessedRows int             `json:"processed_rows"`
+	CreatedRows   int             `json:"created_rows"`
+	UpdatedRows   int             `json:"updated_rows"`
+	FailedRows    int             `json:"failed_rows"`
+	Errors        ImportRowErrors `gorm:"type:jsonb" json:"errors"`
+	Error         string          `json:"error,omitempty"`
+	Data          []byte          `json:"-"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	StartedAt     *time.Time      `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at"`
+	// Attempt is raised by every claim of the job. Only the worker holding
+	// the current attempt may save the job.
+	Attempt int `json:"-"`
+}
+
+func (ImportJob) TableName() string {
+	return "product_import_jobs"
+}
+
+// ImportRowError is why a row of an import was not applied. Row is the line
+// of the file, counting the CSV header.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ImportRowErrors are the row errors of a job, stored as a JSON array
+type ImportRowErrors []ImportRowError
+
+// Value stores the errors as a JSON array
+func (e ImportRowErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	return jsonValue([]ImportRowError(e))
+}
+
+// Scan reads the errors from a JSON array
+func (e *ImportRowErrors) Scan(value interface{}) error {
+	return scanJSON(value, e)
+}
@@ -1,13 +1,88 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProductStatus is the lifecycle state of a product. Only published
+// products are listed in the catalog.
+type ProductStatus string
+
+const (
+	ProductStatusDraft     ProductStatus = "draft"
+	ProductStatusPublished ProductStatus = "published"
+	ProductStatusArchived  ProductStatus = "archived"
+)
+
+// Valid reports whether s is one of the product statuses
+func (s ProductStatus) Valid() bool {
+	switch s {
+	case ProductStatusDraft, ProductStatusPublished, ProductStatusArchived:
+		return true
+	}
+	return false
+}
 
 type Product struct {
-	ID          int       `gorm:"primaryKey" json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	ShopID      int       `json:"shop_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int        `gorm:"primaryKey" json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Price       float64    `json:"price"`
+	ShopID      int        `json:"shop_id"`
+	Attributes  Attributes `gorm:"type:jsonb" json:"attributes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Status ProductStatus `json:"status"`
+	// PublishAt is when the product was published or, for a draft, is
+	// scheduled to be
+	PublishAt *time.Time `json:"publish_at"`
+	// DeletedAt is set when the product is deleted. Deleted products are
+	// archived and only resolved by ID, for the orders referencing them.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Stock is the available quantity across the shop's active warehouses.
+	// It is kept by warehouse-service and not stored here.
+	Stock int32 `gorm:"-" json:"stock"`
+	// CategoryIDs are the categories the product is assigned to, stored in
+	// product_categories
+	CategoryIDs []int `gorm:"-" json:"category_ids"`
+	// Options and Variants are stored in their own tables. Every product has
+	// at least its default variant.
+	Options  []*ProductOption `gorm:"-" json:"options"`
+	Variants []*Variant       `gorm:"-" json:"variants"`
+	// Images are stored in product_images, in display order
+	Images []*ProductImage `gorm:"-" json:"images"`
+}
+
+// ProductFilter selects the products listed by GetProducts. Zero values do
+// not filter, except Status, which defaults to published.
+type ProductFilter struct {
+	ShopID     int
+	CategoryID int
+	Status     ProductStatus
+	// IncludeSubcategories also matches the products of the descendants of
+	// CategoryID
+	IncludeSubcategories bool
+	InStockOnly          bool
+}
+
+// Attributes are free-form product properties such as "color" or
+// "material", stored as a JSONB object and used by search filters and facets
+type Attributes map[string]string
+
+// Value stores the attributes as a JSON object
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	return jsonValue(a)
+}
+
+// Scan reads the attributes from a JSON object
+func (a *Attributes) Scan(value interface{}) error {
+	return scanJSON(value, a)
 }
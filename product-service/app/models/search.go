@@ -0,0 +1,80 @@
+package models
+
+// SearchSort orders search results
+type SearchSort string
+
+const (
+	// SortRelevance ranks by text match and falls back to SortNewest when
+	// there is no text
+	SortRelevance SearchSort = "relevance"
+	SortPriceAsc  SearchSort = "price_asc"
+	SortPriceDesc SearchSort = "price_desc"
+	SortNewest    SearchSort = "newest"
+)
+
+// Valid reports whether s is a known sort order
+func (s SearchSort) Valid() bool {
+	switch s {
+	case SortRelevance, SortPriceAsc, SortPriceDesc, SortNewest:
+		return true
+	}
+	return false
+}
+
+// SearchQuery describes a catalog search. Zero values do not filter.
+type SearchQuery struct {
+	// Text is matched against name and description
+	Text   string
+	ShopID int
+	// CategoryID restricts the search to the category, and its descendants
+	// when IncludeSubcategories is set
+	CategoryID           int
+	IncludeSubcategories bool
+	MinPrice             *float64
+	MaxPrice             *float64
+	// Attributes must all be present with the given values
+	Attributes  Attributes
+	InStockOnly bool
+	// ProductIDs restricts the search to these products when not nil. The
+	// usecase sets it to apply filters the backend cannot evaluate, such as
+	// availability.
+	ProductIDs []int
+	Sort       SearchSort
+	Page       int
+	Limit      int
+}
+
+// SearchResult is a page of matching products with facet counts over all
+// matches. Page and Limit are the paging applied after defaults.
+type SearchResult struct {
+	Products []*Product   `json:"products"`
+	Total    int64        `json:"total"`
+	Page     int          `json:"-"`
+	Limit    int          `json:"-"`
+	Facets   SearchFacets `json:"facets"`
+}
+
+// SearchFacets count the matching products per shop, price range and
+// attribute value
+type SearchFacets struct {
+	Shops       []FacetCount            `json:"shops"`
+	PriceRanges []PriceRangeCount       `json:"price_ranges"`
+	Attributes  map[string][]FacetCount `json:"attributes"`
+}
+
+// FacetCount is the number of matching products with a value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PriceRangeCount is the number of matching products priced from Min up to,
+// but excluding, Max. The last range has no Max.
+type PriceRangeCount struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
+
+// PriceRangeBounds are the lower bounds of the price range facets
+var PriceRangeBounds = []float64{0, 10, 50, 100, 500}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"time"
+)
+
+// ProductOption is a way a product varies, such as "size", with the values
+// its variants may take
+type ProductOption struct {
+	ID        int          `gorm:"primaryKey" json:"-"`
+	ProductID int          `json:"-"`
+	Name      string       `json:"name"`
+	Position  int          `json:"-"`
+	Values    OptionValues `gorm:"type:jsonb" json:"values"`
+}
+
+// OptionValues are the values of a product option, stored as a JSON array
+type OptionValues []string
+
+// Value stores the values as a JSON array
+func (v OptionValues) Value() (driver.Value, error) {
+	if v == nil {
+		return "[]", nil
+	}
+	return jsonValue([]string(v))
+}
+
+// Scan reads the values from a JSON array
+func (v *OptionValues) Scan(value interface{}) error {
+	return scanJSON(value, v)
+}
+
+// Variant is a purchasable version of a product with its own SKU, and the
+// unit stock is kept and reserved in
+type Variant struct {
+	ID        int    `gorm:"primaryKey" json:"id"`
+	ProductID int    `json:"product_id"`
+	ShopID    int    `json:"-"`
+	SKU       string `gorm:"column:sku" json:"sku"`
+	Barcode   string `json:"barcode"`
+	// Price overrides the product price when set
+	Price       *float64 `json:"price"`
+	WeightGrams *int32   `json:"weight_grams"`
+	// Options maps option names to the variant's values
+	Options VariantOptions `gorm:"type:jsonb" json:"options"`
+	// IsDefault marks the variant ordered when only the product is given
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Stock is the available quantity across the active warehouses, kept by
+	// warehouse-service
+	Stock int32 `gorm:"-" json:"stock"`
+}
+
+func (Variant) TableName() string {
+	return "product_variants"
+}
+
+// EffectivePrice returns the price the variant sells for
+func (v *Variant) EffectivePrice(product *Product) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
+
+// VariantOptions are the option values of a variant, stored as a JSON object
+type VariantOptions map[string]string
+
+// Value stores the options as a JSON object
+func (o VariantOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "{}", nil
+	}
+	return jsonValue(map[string]string(o))
+}
+
+// Scan reads the options from a JSON object
+func (o *VariantOptions) Scan(value interface{}) error {
+	return scanJSON(value, o)
+}
+
+// DefaultSKU is the SKU given to a default variant created without one
+func DefaultSKU(productID int) string {
+	return "P" + strconv.Itoa(productID)
+}
+
+// DefaultVariant returns the product's default variant, or nil if its
+// variants are not loaded
+func (p *Product) DefaultVariant() *Variant {
+	for _, variant := range p.Variants {
+		if variant.IsDefault {
+			return variant
+		}
+	}
+	return nil
+}
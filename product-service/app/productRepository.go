@@ -1,12 +1,32 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/product-service/app/models"
+import (
+	"context"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
 
 type ProductRepository interface {
-	Create(product *models.Product) error
-	FindByID(id int) (*models.Product, error)
-	FindAll(shopID int, page, limit int) ([]*models.Product, int64, error)
-	Update(product *models.Product) error
-	UpdateStock(id int, stock int32) error
-	Delete(id int) error
+	Create(ctx context.Context, product *models.Product) error
+	FindByID(ctx context.Context, id int) (*models.Product, error)
+	// FindByIDWithDeleted is FindByID that also returns deleted products
+	FindByIDWithDeleted(ctx context.Context, id int) (*models.Product, error)
+	FindAll(ctx context.Context, filter models.ProductFilter, page, limit int) ([]*models.Product, int64, error)
+	FindIDs(ctx context.Context, filter models.ProductFilter) ([]int, error)
+	// FindIDsByShop returns the IDs of all products of the shop in any
+	// status, except deleted ones
+	FindIDsByShop(ctx context.Context, shopID int) ([]int, error)
+	FindByIDs(ctx context.Context, ids []int) ([]*models.Product, error)
+	// FindCategoryIDs returns the category IDs of each product, keyed by
+	// product ID
+	FindCategoryIDs(ctx context.Context, productIDs []int) (map[int][]int, error)
+	// SetCategories replaces the categories of the product
+	SetCategories(ctx context.Context, productID int, categoryIDs []int) error
+	Update(ctx context.Context, product *models.Product) error
+	// Delete archives and soft deletes the product
+	Delete(ctx context.Context, id int) error
+	// PublishDue publishes the drafts whose PublishAt is not after now and
+	// returns how many there were
+	PublishDue(ctx context.Context, now time.Time) (int64, error)
 }
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+// ProductSearcher is the catalog search backend. The default implementation
+// queries Postgres; an external search engine can be plugged in by
+// implementing it.
+type ProductSearcher interface {
+	// Search returns a page of the published products matching query, in
+	// query.Sort order, with the facet counts over all matches
+	Search(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error)
+	// MatchingIDs returns the IDs of all published products matching query,
+	// ignoring its paging and sort
+	MatchingIDs(ctx context.Context, query models.SearchQuery) ([]int, error)
+}
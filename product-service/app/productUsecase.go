@@ -1,11 +1,44 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/product-service/app/models"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
 
 type ProductUsecase interface {
-	GetProducts(shopID int, page, limit int) ([]*models.Product, int64, error)
-	GetProduct(id int) (*models.Product, error)
-	CreateProduct(name, description string, price float64, stock int32, shopID int) (*models.Product, error)
-	UpdateProduct(product *models.Product) error
-	DeleteProduct(id int) error
+	GetProducts(ctx context.Context, filter models.ProductFilter, page, limit int) ([]*models.Product, int64, error)
+	// GetProduct returns the product in any status, including deleted ones
+	GetProduct(ctx context.Context, id int) (*models.Product, error)
+	SearchProducts(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error)
+	// CreateProduct creates the product as a draft with its default variant,
+	// which gets a generated SKU when sku is empty
+	CreateProduct(ctx context.Context, name, description string, price float64, stock int32, shopID int, attributes models.Attributes, sku string) (*models.Product, error)
+	UpdateProduct(ctx context.Context, product *models.Product) error
+	// DeleteProduct archives and soft deletes the product
+	DeleteProduct(ctx context.Context, id int) error
+	// SetProductStatus moves the product to status; publishAt schedules the
+	// publishing of a draft
+	SetProductStatus(ctx context.Context, productID int, status models.ProductStatus, publishAt *time.Time) (*models.Product, error)
+	// PublishScheduledProducts publishes the drafts whose publish time has come
+	PublishScheduledProducts(ctx context.Context) error
+	// SetProductCategories replaces the categories of the product
+	SetProductCategories(ctx context.Context, productID int, categoryIDs []int) error
+	// SetProductOptions replaces the options of the product
+	SetProductOptions(ctx context.Context, productID int, options []*models.ProductOption) error
+	CreateVariant(ctx context.Context, variant *models.Variant) (*models.Variant, error)
+	GetVariant(ctx context.Context, productID, variantID int) (*models.Variant, error)
+	UpdateVariant(ctx context.Context, variant *models.Variant) error
+	DeleteVariant(ctx context.Context, productID, variantID int) error
+	// UploadImage validates the image read from r and adds it, with a
+	// generated thumbnail, after the product's other images
+	UploadImage(ctx context.Context, productID int, r io.Reader) (*models.ProductImage, error)
+	// ReorderImages sets the display order of all images of the product
+	ReorderImages(ctx context.Context, productID int, imageIDs []int) error
+	DeleteImage(ctx context.Context, productID, imageID int) error
+	// UpdateStock changes the stock of the variant, or of the product's
+	// default variant when variantID is 0
+	UpdateStock(ctx context.Context, productID, variantID, warehouseID int, quantity int32, operation string) (int32, error)
 }
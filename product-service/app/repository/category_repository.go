@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"gorm.io/gorm"
+)
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) product.CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		parentPath := "/"
+		if category.ParentID != nil {
+			var parent models.Category
+			err := tx.First(&parent, "id = ?", *category.ParentID).Error
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return models.ErrCategoryNotFound.With("category_id", strconv.Itoa(*category.ParentID))
+				}
+				return err
+			}
+			parentPath = parent.Path
+		}
+
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		category.Path = parentPath + strconv.Itoa(category.ID) + "/"
+		return tx.Model(category).Update("path", category.Path).Error
+	})
+}
+
+func (r *categoryRepository) FindByID(ctx context.Context, id int) (*models.Category, error) {
+	var category models.Category
+	err := r.db.WithContext(ctx).First(&category, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrCategoryNotFound.With("category_id", strconv.Itoa(id))
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) FindBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	var category models.Category
+	err := r.db.WithContext(ctx).First(&category, "slug = ?", slug).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrCategoryNotFound.With("slug", slug)
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) FindByIDs(ctx context.Context, ids []int) ([]*models.Category, error) {
+	var categories []*models.Category
+	if len(ids) == 0 {
+		return categories, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) FindAll(ctx context.Context) ([]*models.Category, error) {
+	var categories []*models.Category
+	err := r.db.WithContext(ctx).Order("name, id").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) FindSubtree(ctx context.Context, category *models.Category) ([]*models.Category, error) {
+	var categories []*models.Category
+	err := r.db.WithContext(ctx).Where("path LIKE ?", category.Path+"%").Order("name, id").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *categoryRepository) HasChildren(ctx context.Context, id int) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *categoryRepository) CountProducts(ctx context.Context) (map[int]int64, error) {
+	var rows []struct {
+		CategoryID int
+		Count      int64
+	}
+	err := r.db.WithContext(ctx).Table("categories").
+		Select("categories.id AS category_id, count(DISTINCT product_categories.product_id) AS count").
+		Joins("JOIN categories AS descendants ON descendants.path LIKE categories.path || '%'").
+		Joins("JOIN product_categories ON product_categories.category_id = descendants.id").
+		Joins("JOIN products ON products.id = product_categories.product_id AND products.status = ? AND products.deleted_at IS NULL", models.ProductStatusPublished).
+		Group("categories.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category, oldPath string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		category.UpdatedAt = time.Now()
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		if category.Path == oldPath {
+			return nil
+		}
+
+		// Replace the old path prefix of every descendant
+		return tx.Exec("UPDATE categories SET path = ? || substr(path, ?) WHERE path LIKE ? AND id <> ?",
+			category.Path, len(oldPath)+1, oldPath+"%", category.ID).Error
+	})
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.Category{}, "id = ?", id).Error
+}
+
+// whereInCategory restricts db to the products assigned to the category, or
+// with includeSubcategories to it or any of its descendants
+func whereInCategory(db *gorm.DB, categoryID int, includeSubcategories bool) *gorm.DB {
+	if includeSubcategories {
+		return db.Where("products.id IN (SELECT pc.product_id FROM product_categories pc JOIN categories c ON c.id = pc.category_id "+
+			"WHERE c.path LIKE (SELECT path FROM categories WHERE id = ?) || '%')", categoryID)
+	}
+	return db.Where("products.id IN (SELECT product_id FROM product_categories WHERE category_id = ?)", categoryID)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"gorm.io/gorm"
+)
+
+type imageRepository struct {
+	db *gorm.DB
+}
+
+func NewImageRepository(db *gorm.DB) product.ImageRepository {
+	return &imageRepository{db: db}
+}
+
+func (r *imageRepository) Create(ctx context.Context, image *models.ProductImage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var position int
+		err := tx.Model(&models.ProductImage{}).
+			Where("product_id = ?", image.ProductID).
+			Select("COALESCE(MAX(position) + 1, 0)").
+			Scan(&position).Error
+		if err != nil {
+			return err
+		}
+		image.Position = position
+		return tx.Create(image).Error
+	})
+}
+
+func (r *imageRepository) FindByID(ctx context.Context, id int) (*models.ProductImage, error) {
+	var image models.ProductImage
+	err := r.db.WithContext(ctx).First(&image, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrImageNotFound.With("image_id", strconv.Itoa(id))
+		}
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *imageRepository) FindByProductIDs(ctx context.Context, productIDs []int) (map[int][]*models.ProductImage, error) {
+	images := make(map[int][]*models.ProductImage)
+	if len(productIDs) == 0 {
+		return images, nil
+	}
+
+	var rows []*models.ProductImage
+	err := r.db.WithContext(ctx).Where("product_id IN ?", productIDs).Order("product_id, position, id").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range rows {
+		images[image.ProductID] = append(images[image.ProductID], image)
+	}
+	return images, nil
+}
+
+func (r *imageRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.ProductImage{}, "id = ?", id).Error
+}
+
+func (r *imageRepository) Reorder(ctx context.Context, productID int, imageIDs []int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for position, id := range imageIDs {
+			err := tx.Model(&models.ProductImage{}).
+				Where("id = ? AND product_id = ?", id, productID).
+				Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type importJobRepository struct {
+	db *gorm.DB
+}
+
+func NewImportJobRepository(db *gorm.DB) product.ImportJobRepository {
+	return &importJobRepository{db: db}
+}
+
+func (r *importJobRepository) Create(ctx context.Context, job *models.ImportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *importJobRepository) FindByID(ctx context.Context, id int) (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := r.db.WithContext(ctx).Omit("data").First(&job, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrImportJobNotFound.With("job_id", strconv.Itoa(id))
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Claim locks the job with SKIP LOCKED so that several product-service
+// instances never claim the same job
+func (r *importJobRepository) Claim(ctx context.Context, staleBefore time.Time, maxAttempts int) (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Stale jobs out of attempts are given up on. Raising their attempt
+		// also stops a worker that still holds one.
+		now := time.Now()
+		err := tx.Model(&models.ImportJob{}).
+			Where("status = ? AND updated_at < ? AND attempt >= ?", models.ImportJobRunning, staleBefore, maxAttempts).
+			Updates(map[string]any{
+				"status":      models.ImportJobFailed,
+				"error":       fmt.Sprintf("import stopped without finishing %d times", maxAttempts),
+				"data":        nil,
+				"attempt":     gorm.Expr("attempt + 1"),
+				"finished_at": now,
+				"updated_at":  now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND updated_at < ? AND attempt < ?)",
+				models.ImportJobPending, models.ImportJobRunning, staleBefore, maxAttempts).
+			Order("id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		job.Status = models.ImportJobRunning
+		job.ProcessedRows, job.CreatedRows, job.UpdatedRows, job.FailedRows = 0, 0, 0, 0
+		job.Errors = models.ImportRowErrors{}
+		job.StartedAt = &now
+		job.UpdatedAt = now
+		job.Attempt++
+		return tx.Omit("data").Save(&job).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *importJobRepository) Heartbeat(ctx context.Context, id, attempt int) error {
+	result := r.db.WithContext(ctx).Model(&models.ImportJob{}).
+		Where("id = ? AND attempt = ?", id, attempt).
+		Update("updated_at", time.Now())
+	return lostUnlessUpdated(result, id)
+}
+
+func (r *importJobRepository) UpdateProgress(ctx context.Context, job *models.ImportJob) error {
+	return r.save(ctx, job, "id", "data")
+}
+
+func (r *importJobRepository) Finish(ctx context.Context, job *models.ImportJob) error {
+	job.Data = nil
+	return r.save(ctx, job, "id")
+}
+
+// save writes every column of the job except omitted ones, as long as the
+// job has not been claimed again since its attempt
+func (r *importJobRepository) save(ctx context.Context, job *models.ImportJob, omit ...string) error {
+	result := r.db.WithContext(ctx).Model(job).
+		Where("attempt = ?", job.Attempt).
+		Select("*").Omit(omit...).
+		Updates(job)
+	return lostUnlessUpdated(result, job.ID)
+}
+
+func lostUnlessUpdated(result *gorm.DB, id int) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrImportJobLost.With("job_id", strconv.Itoa(id))
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strconv"
+	"time"
 
 	product "github.com/evrintobing17/ecommerce-system/product-service/app"
 	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
@@ -16,30 +19,52 @@ func NewProductRepository(db *gorm.DB) product.ProductRepository {
 	return &productRepository{db: db}
 }
 
-func (r *productRepository) Create(product *models.Product) error {
-	return r.db.Create(product).Error
+// Create stores the product and its variants, which get the product's ID and
+// shop. A variant without SKU gets models.DefaultSKU.
+func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		for _, variant := range product.Variants {
+			variant.ProductID = product.ID
+			variant.ShopID = product.ShopID
+			if variant.SKU == "" {
+				variant.SKU = models.DefaultSKU(product.ID)
+			}
+			if err := tx.Create(variant).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *productRepository) FindByID(ctx context.Context, id int) (*models.Product, error) {
+	return findProduct(r.db.WithContext(ctx), id)
+}
+
+func (r *productRepository) FindByIDWithDeleted(ctx context.Context, id int) (*models.Product, error) {
+	return findProduct(r.db.WithContext(ctx).Unscoped(), id)
 }
 
-func (r *productRepository) FindByID(id int) (*models.Product, error) {
+func findProduct(db *gorm.DB, id int) (*models.Product, error) {
 	var product models.Product
-	err := r.db.First(&product, "id = ?", id).Error
+	err := db.First(&product, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("product not found")
+			return nil, models.ErrProductNotFound.With("product_id", strconv.Itoa(id))
 		}
 		return nil, err
 	}
 	return &product, nil
 }
 
-func (r *productRepository) FindAll(shopID, page, limit int) ([]*models.Product, int64, error) {
+func (r *productRepository) FindAll(ctx context.Context, filter models.ProductFilter, page, limit int) ([]*models.Product, int64, error) {
 	var products []*models.Product
 	var total int64
 
-	query := r.db.Model(&models.Product{})
-	if shopID != 0 {
-		query = query.Where("shop_id = ?", shopID)
-	}
+	query := r.filtered(ctx, filter)
 
 	// Get total count
 	err := query.Count(&total).Error
@@ -57,14 +82,115 @@ func (r *productRepository) FindAll(shopID, page, limit int) ([]*models.Product,
 	return products, total, nil
 }
 
-func (r *productRepository) Update(product *models.Product) error {
-	return r.db.Save(product).Error
+// FindIDs returns the IDs of the products matching filter in ascending
+// order. Its InStockOnly is ignored.
+func (r *productRepository) FindIDs(ctx context.Context, filter models.ProductFilter) ([]int, error) {
+	var ids []int
+	err := r.filtered(ctx, filter).Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *productRepository) FindIDsByShop(ctx context.Context, shopID int) ([]int, error) {
+	var ids []int
+	err := r.db.WithContext(ctx).Model(&models.Product{}).Where("shop_id = ?", shopID).Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// FindByIDs returns the products with the given IDs in ascending ID order
+func (r *productRepository) FindByIDs(ctx context.Context, ids []int) ([]*models.Product, error) {
+	var products []*models.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// filtered returns a query over the products matching the shop, category
+// and status of filter
+func (r *productRepository) filtered(ctx context.Context, filter models.ProductFilter) *gorm.DB {
+	status := filter.Status
+	if status == "" {
+		status = models.ProductStatusPublished
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.Product{}).Where("products.status = ?", status)
+	if filter.ShopID != 0 {
+		query = query.Where("shop_id = ?", filter.ShopID)
+	}
+	if filter.CategoryID != 0 {
+		query = whereInCategory(query, filter.CategoryID, filter.IncludeSubcategories)
+	}
+	return query
 }
 
-func (r *productRepository) UpdateStock(id int, stock int32) error {
-	return r.db.Model(&models.Product{}).Where("id = ?", id).Update("stock", stock).Error
+func (r *productRepository) FindCategoryIDs(ctx context.Context, productIDs []int) (map[int][]int, error) {
+	var rows []struct {
+		ProductID  int
+		CategoryID int
+	}
+	categoryIDs := make(map[int][]int)
+	if len(productIDs) == 0 {
+		return categoryIDs, nil
+	}
+
+	err := r.db.WithContext(ctx).Table("product_categories").
+		Where("product_id IN ?", productIDs).
+		Order("product_id, category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		categoryIDs[row.ProductID] = append(categoryIDs[row.ProductID], row.CategoryID)
+	}
+	return categoryIDs, nil
+}
+
+func (r *productRepository) SetCategories(ctx context.Context, productID int, categoryIDs []int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", productID).Error; err != nil {
+			return err
+		}
+		for _, categoryID := range categoryIDs {
+			err := tx.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (?, ?) ON CONFLICT DO NOTHING", productID, categoryID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Save(product).Error
+}
+
+// Delete archives the product and soft deletes it
+func (r *productRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Product{}).Where("id = ?", id).Update("status", models.ProductStatusArchived).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.Product{}, "id = ?", id).Error
+	})
 }
 
-func (r *productRepository) Delete(id int) error {
-	return r.db.Delete(&models.Product{}, "id = ?", id).Error
+func (r *productRepository) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Product{}).
+		Where("status = ? AND publish_at <= ?", models.ProductStatusDraft, now).
+		Updates(map[string]interface{}{"status": models.ProductStatusPublished, "updated_at": now})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxAttributeFacetValues is the most values counted per attribute facet
+const maxAttributeFacetValues = 20
+
+// postgresProductSearcher searches the products table using the
+// search_vector full-text index, trigram similarity on the name and the
+// JSONB attributes, see migration 0002_product_search
+type postgresProductSearcher struct {
+	db *gorm.DB
+}
+
+func NewPostgresProductSearcher(db *gorm.DB) product.ProductSearcher {
+	return &postgresProductSearcher{db: db}
+}
+
+func (s *postgresProductSearcher) Search(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error) {
+	result := &models.SearchResult{Products: []*models.Product{}}
+
+	err := s.matching(ctx, query).Count(&result.Total).Error
+	if err != nil {
+		return nil, err
+	}
+
+	offset := (query.Page - 1) * query.Limit
+	err = s.matching(ctx, query).Order(searchOrder(query)).Offset(offset).Limit(query.Limit).Find(&result.Products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Facets.Shops, err = s.shopFacet(ctx, query); err != nil {
+		return nil, err
+	}
+	if result.Facets.PriceRanges, err = s.priceRangeFacet(ctx, query); err != nil {
+		return nil, err
+	}
+	if result.Facets.Attributes, err = s.attributeFacets(ctx, query); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *postgresProductSearcher) MatchingIDs(ctx context.Context, query models.SearchQuery) ([]int, error) {
+	var ids []int
+	err := s.matching(ctx, query).Order("products.id").Pluck("products.id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// matching returns a query over the published products matching all filters
+// of query
+func (s *postgresProductSearcher) matching(ctx context.Context, query models.SearchQuery) *gorm.DB {
+	db := s.db.WithContext(ctx).Model(&models.Product{}).Where("products.status = ?", models.ProductStatusPublished)
+
+	if query.Text != "" {
+		db = db.Where("(products.search_vector @@ websearch_to_tsquery('english', ?) OR products.name % ?)", query.Text, query.Text)
+	}
+	if query.ShopID != 0 {
+		db = db.Where("products.shop_id = ?", query.ShopID)
+	}
+	if query.CategoryID != 0 {
+		db = whereInCategory(db, query.CategoryID, query.IncludeSubcategories)
+	}
+	if query.MinPrice != nil {
+		db = db.Where("products.price >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		db = db.Where("products.price <= ?", *query.MaxPrice)
+	}
+	if len(query.Attributes) > 0 {
+		db = db.Where("products.attributes @> ?::jsonb", query.Attributes)
+	}
+	if query.ProductIDs != nil {
+		db = db.Where("products.id IN ?", query.ProductIDs)
+	}
+
+	return db
+}
+
+// searchOrder returns the ORDER BY of query. Relevance is the full-text rank
+// plus the trigram similarity of the name; without text it is newest first.
+func searchOrder(query models.SearchQuery) interface{} {
+	switch query.Sort {
+	case models.SortPriceAsc:
+		return "products.price, products.id"
+	case models.SortPriceDesc:
+		return "products.price DESC, products.id"
+	case models.SortRelevance:
+		if query.Text != "" {
+			return clause.OrderBy{Expression: clause.Expr{
+				SQL:  "ts_rank(products.search_vector, websearch_to_tsquery('english', ?)) + similarity(products.name, ?) DESC, products.id",
+				Vars: []interface{}{query.Text, query.Text},
+			}}
+		}
+	}
+	return "products.created_at DESC, products.id DESC"
+}
+
+func (s *postgresProductSearcher) shopFacet(ctx context.Context, query models.SearchQuery) ([]models.FacetCount, error) {
+	var rows []struct {
+		ShopID int
+		Count  int64
+	}
+	err := s.matching(ctx, query).
+		Select("products.shop_id, count(*) AS count").
+		Group("products.shop_id").
+		Order("count DESC, products.shop_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	facet := make([]models.FacetCount, 0, len(rows))
+	for _, row := range rows {
+		facet = append(facet, models.FacetCount{Value: strconv.Itoa(row.ShopID), Count: row.Count})
+	}
+	return facet, nil
+}
+
+// priceRangeFacet counts the matches per range of models.PriceRangeBounds.
+// Ranges without matches are returned with a zero count.
+func (s *postgresProductSearcher) priceRangeFacet(ctx context.Context, query models.SearchQuery) ([]models.PriceRangeCount, error) {
+	bounds := models.PriceRangeBounds
+	sql, vars := priceBucketExpr(bounds)
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	err := s.matching(ctx, query).
+		Select(sql+" AS bucket, count(*) AS count", vars...).
+		Group("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Bucket] = row.Count
+	}
+
+	facet := make([]models.PriceRangeCount, 0, len(bounds))
+	for i, lower := range bounds {
+		priceRange := models.PriceRangeCount{Min: lower, Count: counts[i]}
+		if i+1 < len(bounds) {
+			upper := bounds[i+1]
+			priceRange.Max = &upper
+		}
+		facet = append(facet, priceRange)
+	}
+	return facet, nil
+}
+
+// priceBucketExpr returns a CASE expression giving the index in bounds of the
+// price range each product falls in
+func priceBucketExpr(bounds []float64) (string, []interface{}) {
+	var sql strings.Builder
+	var vars []interface{}
+	sql.WriteString("CASE")
+	for i := len(bounds) - 1; i > 0; i-- {
+		fmt.Fprintf(&sql, " WHEN products.price >= ? THEN %d", i)
+		vars = append(vars, bounds[i])
+	}
+	sql.WriteString(" ELSE 0 END")
+	return sql.String(), vars
+}
+
+// attributeFacets counts the matches per attribute value, keeping the
+// maxAttributeFacetValues most common values of each attribute
+func (s *postgresProductSearcher) attributeFacets(ctx context.Context, query models.SearchQuery) (map[string][]models.FacetCount, error) {
+	var rows []struct {
+		Key   string
+		Value string
+		Count int64
+	}
+	err := s.matching(ctx, query).
+		Joins("CROSS JOIN LATERAL jsonb_each_text(products.attributes) AS kv").
+		Select("kv.key, kv.value, count(*) AS count").
+		Group("kv.key, kv.value").
+		Order("kv.key, count DESC, kv.value").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	facets := make(map[string][]models.FacetCount)
+	for _, row := range rows {
+		if len(facets[row.Key]) == maxAttributeFacetValues {
+			continue
+		}
+		facets[row.Key] = append(facets[row.Key], models.FacetCount{Value: row.Value, Count: row.Count})
+	}
+	return facets, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"gorm.io/gorm"
+)
+
+type variantRepository struct {
+	db *gorm.DB
+}
+
+func NewVariantRepository(db *gorm.DB) product.VariantRepository {
+	return &variantRepository{db: db}
+}
+
+func (r *variantRepository) Create(ctx context.Context, variant *models.Variant) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultVariant(tx, variant); err != nil {
+			return err
+		}
+		return tx.Create(variant).Error
+	})
+}
+
+func (r *variantRepository) FindByID(ctx context.Context, id int) (*models.Variant, error) {
+	var variant models.Variant
+	err := r.db.WithContext(ctx).First(&variant, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrVariantNotFound.With("variant_id", strconv.Itoa(id))
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *variantRepository) FindBySKU(ctx context.Context, shopID int, sku string) (*models.Variant, error) {
+	var variant models.Variant
+	err := r.db.WithContext(ctx).First(&variant, "shop_id = ? AND sku = ?", shopID, sku).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrVariantNotFound.With("sku", sku)
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *variantRepository) FindByProductIDs(ctx context.Context, productIDs []int) (map[int][]*models.Variant, error) {
+	variants := make(map[int][]*models.Variant)
+	if len(productIDs) == 0 {
+		return variants, nil
+	}
+
+	var rows []*models.Variant
+	err := r.db.WithContext(ctx).Where("product_id IN ?", productIDs).Order("product_id, is_default DESC, id").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range rows {
+		variants[variant.ProductID] = append(variants[variant.ProductID], variant)
+	}
+	return variants, nil
+}
+
+func (r *variantRepository) Update(ctx context.Context, variant *models.Variant) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultVariant(tx, variant); err != nil {
+			return err
+		}
+		return tx.Save(variant).Error
+	})
+}
+
+func (r *variantRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.Variant{}, "id = ?", id).Error
+}
+
+func (r *variantRepository) FindOptions(ctx context.Context, productIDs []int) (map[int][]*models.ProductOption, error) {
+	options := make(map[int][]*models.ProductOption)
+	if len(productIDs) == 0 {
+		return options, nil
+	}
+
+	var rows []*models.ProductOption
+	err := r.db.WithContext(ctx).Where("product_id IN ?", productIDs).Order("product_id, position").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range rows {
+		options[option.ProductID] = append(options[option.ProductID], option)
+	}
+	return options, nil
+}
+
+func (r *variantRepository) SetOptions(ctx context.Context, productID int, options []*models.ProductOption) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.ProductOption{}, "product_id = ?", productID).Error; err != nil {
+			return err
+		}
+		for i, option := range options {
+			option.ProductID = productID
+			option.Position = i
+			if err := tx.Create(option).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// clearDefaultVariant unsets the default of the product's other variants when
+// variant becomes its default
+func clearDefaultVariant(tx *gorm.DB, variant *models.Variant) error {
+	if !variant.IsDefault {
+		return nil
+	}
+	return tx.Model(&models.Variant{}).
+		Where("product_id = ? AND is_default AND id <> ?", variant.ProductID, variant.ID).
+		Update("is_default", false).Error
+}
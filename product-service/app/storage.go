@@ -0,0 +1,13 @@
+package app
+
+import "context"
+
+// Storage keeps the uploaded product media. Keys are slash separated paths
+// chosen by the usecase.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the file; deleting a missing file is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients fetch the file from
+	URL(key string) string
+}
@@ -0,0 +1,73 @@
+// Package storage implements the product media storage on the local file
+// system and on S3-compatible object stores.
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Config selects and configures the media storage backend. BaseURL is the
+// public address the stored files are served from; it defaults to /media for
+// the local backend, served by product-service itself, and to the bucket's
+// address on the S3 endpoint.
+type Config struct {
+	Backend string `env:"MEDIA_STORAGE" yaml:"backend" default:"local"`
+	BaseURL string `env:"MEDIA_BASE_URL" yaml:"base_url"`
+
+	// Dir is where the local backend writes the files
+	Dir string `env:"MEDIA_DIR" yaml:"dir" default:"media"`
+
+	S3Endpoint  string `env:"S3_ENDPOINT" yaml:"s3_endpoint"`
+	S3Region    string `env:"S3_REGION" yaml:"s3_region" default:"us-east-1"`
+	S3Bucket    string `env:"S3_BUCKET" yaml:"s3_bucket"`
+	S3AccessKey string `env:"S3_ACCESS_KEY" yaml:"s3_access_key"`
+	S3SecretKey string `env:"S3_SECRET_KEY" yaml:"s3_secret_key" secret:"true"`
+}
+
+// Validate checks the settings of the selected backend
+func (c Config) Validate() error {
+	switch c.Backend {
+	case BackendLocal:
+		if c.Dir == "" {
+			return fmt.Errorf("MEDIA_DIR is required for the local media storage")
+		}
+	case BackendS3:
+		if c.S3Endpoint == "" || c.S3Bucket == "" || c.S3AccessKey == "" || c.S3SecretKey == "" {
+			return fmt.Errorf("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required for the s3 media storage")
+		}
+	default:
+		return fmt.Errorf("MEDIA_STORAGE %q is not one of local, s3", c.Backend)
+	}
+	return nil
+}
+
+// New returns the storage backend selected by cfg, which has been validated
+func New(cfg Config) (product.Storage, error) {
+	switch cfg.Backend {
+	case BackendS3:
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = strings.TrimSuffix(cfg.S3Endpoint, "/") + "/" + cfg.S3Bucket
+		}
+		return NewS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, baseURL)
+	default:
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = LocalURLPath
+		}
+		return NewLocalStorage(cfg.Dir, baseURL), nil
+	}
+}
+
+// joinURL appends the key to the base URL
+func joinURL(baseURL, key string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + key
+}
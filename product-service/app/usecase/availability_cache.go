@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+)
+
+// availabilityCache keeps the available stock of variants, keyed by variant
+// ID, for a short time, so listing pages does not ask warehouse-service on
+// every request. A zero TTL disables it.
+type availabilityCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[int]cachedAvailability
+	nextSweep time.Time
+}
+
+type cachedAvailability struct {
+	available int32
+	expiresAt time.Time
+}
+
+func newAvailabilityCache(ttl time.Duration) *availabilityCache {
+	return &availabilityCache{
+		ttl:     ttl,
+		entries: make(map[int]cachedAvailability),
+	}
+}
+
+// get returns the cached available stock of the variant, if it has not
+// expired
+func (c *availabilityCache) get(variantID int, now time.Time) (int32, bool) {
+	if c.ttl <= 0 {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[variantID]
+	if !ok || now.After(entry.expiresAt) {
+		return 0, false
+	}
+	return entry.available, true
+}
+
+// set caches the available stock of the variant and drops expired entries
+// about once per TTL
+func (c *availabilityCache) set(variantID int, available int32, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.After(c.nextSweep) {
+		for id, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+	c.entries[variantID] = cachedAvailability{available: available, expiresAt: now.Add(c.ttl)}
+}
+
+// invalidate forgets the variant, after its stock was changed through this
+// service
+func (c *availabilityCache) invalidate(variantID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, variantID)
+}
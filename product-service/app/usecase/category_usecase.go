@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+)
+
+type categoryUsecase struct {
+	categoryRepo product.CategoryRepository
+}
+
+func NewCategoryUsecase(categoryRepo product.CategoryRepository) product.CategoryUsecase {
+	return &categoryUsecase{categoryRepo: categoryRepo}
+}
+
+func (u *categoryUsecase) GetCategoryTree(ctx context.Context) ([]*models.CategoryNode, error) {
+	categories, err := u.categoryRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := u.categoryRepo.CountProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCategoryTree(categories, counts), nil
+}
+
+func (u *categoryUsecase) GetCategory(ctx context.Context, id int) (*models.CategoryDetail, error) {
+	category, err := u.categoryRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := u.categoryRepo.FindByIDs(ctx, category.AncestorIDs())
+	if err != nil {
+		return nil, err
+	}
+	depth := func(c *models.Category) int { return strings.Count(c.Path, "/") }
+	sort.Slice(ancestors, func(i, j int) bool { return depth(ancestors[i]) < depth(ancestors[j]) })
+
+	subtree, err := u.categoryRepo.FindSubtree(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := u.categoryRepo.CountProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &models.CategoryDetail{
+		Category:     category,
+		ProductCount: counts[category.ID],
+		Breadcrumbs:  append(ancestors, category),
+		Children:     []*models.CategoryNode{},
+	}
+	if roots := buildCategoryTree(subtree, counts); len(roots) == 1 {
+		detail.Children = roots[0].Children
+	}
+	return detail, nil
+}
+
+func (u *categoryUsecase) CreateCategory(ctx context.Context, name, slug string, parentID *int) (*models.Category, error) {
+	name = strings.TrimSpace(name)
+	slug, err := u.availableSlug(ctx, name, slug, 0)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != nil && *parentID == 0 {
+		parentID = nil
+	}
+
+	category := &models.Category{
+		ParentID:  parentID,
+		Name:      name,
+		Slug:      slug,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := u.categoryRepo.Create(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (u *categoryUsecase) UpdateCategory(ctx context.Context, id int, name, slug string, parentID *int) (*models.Category, error) {
+	category, err := u.categoryRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name = strings.TrimSpace(name); name != "" {
+		category.Name = name
+	}
+	if slug != "" {
+		if category.Slug, err = u.availableSlug(ctx, category.Name, slug, category.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	oldPath := category.Path
+	if parentID != nil {
+		parentPath := "/"
+		category.ParentID = nil
+		if *parentID != 0 {
+			parent, err := u.categoryRepo.FindByID(ctx, *parentID)
+			if err != nil {
+				return nil, err
+			}
+			if parent.IsInSubtreeOf(category) {
+				return nil, models.ErrInvalidCategoryParent
+			}
+			parentPath = parent.Path
+			category.ParentID = &parent.ID
+		}
+		category.Path = parentPath + strconv.Itoa(category.ID) + "/"
+	}
+
+	if err := u.categoryRepo.Update(ctx, category, oldPath); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory deletes a category without subcategories. Its products
+// lose the assignment.
+func (u *categoryUsecase) DeleteCategory(ctx context.Context, id int) error {
+	if _, err := u.categoryRepo.FindByID(ctx, id); err != nil {
+		return err
+	}
+
+	hasChildren, err := u.categoryRepo.HasChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return models.ErrCategoryHasChildren.With("category_id", strconv.Itoa(id))
+	}
+
+	return u.categoryRepo.Delete(ctx, id)
+}
+
+// availableSlug normalizes slug, or derives it from name when empty, and
+// checks no category other than exceptID uses it
+func (u *categoryUsecase) availableSlug(ctx context.Context, name, slug string, exceptID int) (string, error) {
+	if slug == "" {
+		slug = name
+	}
+	slug = slugify(slug)
+	if slug == "" {
+		return "", models.ErrInvalidCategoryName
+	}
+
+	existing, err := u.categoryRepo.FindBySlug(ctx, slug)
+	if err == nil && existing.ID != exceptID {
+		return "", models.ErrCategorySlugTaken.With("slug", slug)
+	}
+	if err != nil && !apperror.IsKind(err, apperror.NotFound) {
+		return "", err
+	}
+	return slug, nil
+}
+
+// slugify lowercases s and joins its runs of letters and digits with "-"
+func slugify(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// buildCategoryTree arranges categories, ordered by name, into trees. The
+// categories whose parent is not among them are the roots.
+func buildCategoryTree(categories []*models.Category, counts map[int]int64) []*models.CategoryNode {
+	nodes := make(map[int]*models.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &models.CategoryNode{
+			Category:     category,
+			ProductCount: counts[category.ID],
+			Children:     []*models.CategoryNode{},
+		}
+	}
+
+	roots := []*models.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	// Register the decoders of the accepted image types
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/draw"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+const (
+	// maxImagesPerProduct is the most images a product can have
+	maxImagesPerProduct = 20
+	// maxImagePixels bounds the decoded size of an upload, as a small file
+	// can decode to a huge image
+	maxImagePixels = 40_000_000
+	// thumbnailSize is the largest width and height of a thumbnail
+	thumbnailSize = 320
+)
+
+// imageExtensions are the accepted image types, detected from the file
+// content, with the extension their files are stored with
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// UploadImage validates the image read from r, stores it with its thumbnail
+// and adds it after the product's other images
+func (u *productUsecase) UploadImage(ctx context.Context, productID int, r io.Reader) (*models.ProductImage, error) {
+	if _, err := u.productRepo.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(u.maxImageSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, models.ErrImageRequired
+	}
+	if len(data) > u.maxImageSize {
+		return nil, models.ErrImageTooLarge.With("max_bytes", strconv.Itoa(u.maxImageSize))
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, models.ErrUnsupportedImageType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedImageType.Wrap(err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, models.ErrImageDimensionsTooLarge.With("max_pixels", strconv.Itoa(maxImagePixels))
+	}
+
+	images, err := u.imageRepo.FindByProductIDs(ctx, []int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if len(images[productID]) >= maxImagesPerProduct {
+		return nil, models.ErrTooManyImages.With("max_images", strconv.Itoa(maxImagesPerProduct))
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedImageType.Wrap(err)
+	}
+	thumbnail, thumbnailType, thumbnailExtension, err := encodeThumbnail(decoded, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	productImage := &models.ProductImage{
+		ProductID:    productID,
+		Key:          fmt.Sprintf("products/%d/%s.%s", productID, name, extension),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb.%s", productID, name, thumbnailExtension),
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        config.Width,
+		Height:       config.Height,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := u.storage.Put(ctx, productImage.Key, data, contentType); err != nil {
+		return nil, err
+	}
+	if err := u.storage.Put(ctx, productImage.ThumbnailKey, thumbnail, thumbnailType); err != nil {
+		u.deleteImageFiles(ctx, productImage)
+		return nil, err
+	}
+	if err := u.imageRepo.Create(ctx, productImage); err != nil {
+		u.deleteImageFiles(ctx, productImage)
+		return nil, err
+	}
+
+	u.fillImageURLs([]*models.ProductImage{productImage})
+	return productImage, nil
+}
+
+// ReorderImages sets the display order of the product's images. imageIDs
+// must list every image of the product exactly once.
+func (u *productUsecase) ReorderImages(ctx context.Context, productID int, imageIDs []int) error {
+	if _, err := u.productRepo.FindByID(ctx, productID); err != nil {
+		return err
+	}
+
+	images, err := u.imageRepo.FindByProductIDs(ctx, []int{productID})
+	if err != nil {
+		return err
+	}
+	if len(imageIDs) != len(images[productID]) {
+		return models.ErrInvalidImageOrder
+	}
+	listed := make(map[int]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		listed[id] = true
+	}
+	for _, image := range images[productID] {
+		if !listed[image.ID] {
+			return models.ErrInvalidImageOrder.With("missing_image_id", strconv.Itoa(image.ID))
+		}
+	}
+
+	return u.imageRepo.Reorder(ctx, productID, imageIDs)
+}
+
+// DeleteImage removes the image of the product and its files
+func (u *productUsecase) DeleteImage(ctx context.Context, productID, imageID int) error {
+	image, err := u.imageRepo.FindByID(ctx, imageID)
+	if err != nil {
+		return err
+	}
+	if image.ProductID != productID {
+		return models.ErrImageNotFound.With("image_id", strconv.Itoa(imageID))
+	}
+
+	if err := u.imageRepo.Delete(ctx, imageID); err != nil {
+		return err
+	}
+	u.deleteImageFiles(ctx, image)
+	return nil
+}
+
+// deleteImageFiles removes the files of the image from the storage. A file
+// left behind only takes space, so failures are logged.
+func (u *productUsecase) deleteImageFiles(ctx context.Context, image *models.ProductImage) {
+	for _, key := range []string{image.Key, image.ThumbnailKey} {
+		if err := u.storage.Delete(ctx, key); err != nil {
+			slog.WarnContext(ctx, "failed to delete image file", "key", key, "error", err)
+		}
+	}
+}
+
+// fillImageURLs sets the URLs the images are served from
+func (u *productUsecase) fillImageURLs(images []*models.ProductImage) {
+	for _, image := range images {
+		image.URL = u.storage.URL(image.Key)
+		image.ThumbnailURL = u.storage.URL(image.ThumbnailKey)
+	}
+}
+
+// encodeThumbnail scales img down to fit thumbnailSize. Images that may be
+// transparent get a PNG thumbnail, others a JPEG one.
+func encodeThumbnail(img image.Image, contentType string) ([]byte, string, string, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailSize || height > thumbnailSize {
+		if width >= height {
+			width, height = thumbnailSize, max(1, height*thumbnailSize/width)
+		} else {
+			width, height = max(1, width*thumbnailSize/height), thumbnailSize
+		}
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/png", "image/gif":
+		if err := png.Encode(&buf, thumbnail); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", "png", nil
+	default:
+		if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/jpeg", "jpg", nil
+	}
+}
+
+// randomName returns a random file name, so the URLs of images cannot be
+// guessed and never serve a replaced file
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
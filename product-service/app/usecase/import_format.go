@@ -0,0 +1,324 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+// importColumns are the CSV columns of imports and exports. sku, name and
+// price are required; the others may be left out of the header.
+var importColumns = []string{"sku", "name", "description", "price", "status", "attributes", "stock"}
+
+var requiredImportColumns = []string{"sku", "name", "price"}
+
+// importRow is a row of an import file. Err is set when the row is invalid,
+// in which case the other fields may be incomplete.
+type importRow struct {
+	Line        int
+	SKU         string
+	Name        string
+	Description string
+	Price       float64
+	// Status is empty to keep the status of existing products and create
+	// new ones as drafts
+	Status     models.ProductStatus
+	Attributes models.Attributes
+	// Stock is the quantity to set per warehouse ID
+	Stock map[int]int32
+	Err   error
+}
+
+// importRecord is a line of a JSON Lines file
+type importRecord struct {
+	SKU         string               `json:"sku"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Price       *float64             `json:"price"`
+	Status      models.ProductStatus `json:"status,omitempty"`
+	Attributes  models.Attributes    `json:"attributes,omitempty"`
+	Stock       map[int]int32        `json:"stock,omitempty"`
+}
+
+// parseImport reads the rows of an import file. Rows that cannot be parsed
+// or are invalid are returned with Err set; an error is only returned when
+// the file as a whole cannot be read.
+func parseImport(format models.ImportFormat, data []byte) ([]*importRow, error) {
+	var rows []*importRow
+	var err error
+	switch format {
+	case models.ImportFormatCSV:
+		rows, err = parseCSVImport(data)
+	case models.ImportFormatJSONL:
+		rows = parseJSONLImport(data)
+	default:
+		return nil, models.ErrInvalidImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, models.ErrInvalidImportFile.With("reason", "the file has no rows")
+	}
+
+	skus := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		if row.Err == nil {
+			row.Err = validateImportRow(row)
+		}
+		if row.Err == nil && skus[row.SKU] {
+			row.Err = models.ErrDuplicateImportSKU
+		}
+		if row.Err == nil {
+			skus[row.SKU] = true
+		}
+	}
+	return rows, nil
+}
+
+func parseCSVImport(data []byte) ([]*importRow, error) {
+	// Spreadsheet programs may start the file with a UTF-8 byte order mark
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	header, err := reader.Read()
+	if err != nil {
+		return nil, models.ErrInvalidImportFile.Wrap(err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok || !isImportColumn(name) {
+			return nil, models.ErrInvalidImportFile.With("column", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, models.ErrInvalidImportFile.With("missing_column", name)
+		}
+	}
+
+	var rows []*importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				rows = append(rows, &importRow{Line: parseErr.StartLine, Err: models.ErrInvalidImportRow.Wrap(parseErr.Err)})
+				continue
+			}
+			return nil, models.ErrInvalidImportFile.Wrap(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, csvImportRow(line, record, columns))
+	}
+}
+
+func isImportColumn(name string) bool {
+	for _, column := range importColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func csvImportRow(line int, record []string, columns map[string]int) *importRow {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := &importRow{
+		Line:        line,
+		SKU:         field("sku"),
+		Name:        field("name"),
+		Description: field("description"),
+		Status:      models.ProductStatus(field("status")),
+	}
+
+	price, err := strconv.ParseFloat(field("price"), 64)
+	if err != nil {
+		row.Err = models.ErrInvalidPrice
+		return row
+	}
+	row.Price = price
+
+	if attributes := field("attributes"); attributes != "" {
+		if err := json.Unmarshal([]byte(attributes), &row.Attributes); err != nil {
+			row.Err = models.ErrInvalidImportAttributes
+			return row
+		}
+	}
+
+	if stock := field("stock"); stock != "" {
+		row.Stock, row.Err = parseCSVStock(stock)
+	}
+	return row
+}
+
+// parseCSVStock reads the stock column, "warehouse_id:quantity" pairs
+// separated by semicolons
+func parseCSVStock(value string) (map[int]int32, error) {
+	stock := make(map[int]int32)
+	for _, pair := range strings.Split(value, ";") {
+		warehouse, quantity, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, models.ErrInvalidImportStock
+		}
+		warehouseID, err := strconv.Atoi(strings.TrimSpace(warehouse))
+		if err != nil {
+			return nil, models.ErrInvalidImportStock
+		}
+		qty, err := strconv.ParseInt(strings.TrimSpace(quantity), 10, 32)
+		if err != nil {
+			return nil, models.ErrInvalidImportStock
+		}
+		stock[warehouseID] = int32(qty)
+	}
+	return stock, nil
+}
+
+func parseJSONLImport(data []byte) []*importRow {
+	var rows []*importRow
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		row := &importRow{Line: i + 1}
+		rows = append(rows, row)
+
+		var record importRecord
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			row.Err = models.ErrInvalidImportRow.Wrap(err)
+			continue
+		}
+		if decoder.More() {
+			row.Err = models.ErrInvalidImportRow.With("reason", "more than one JSON value on the line")
+			continue
+		}
+
+		row.SKU = strings.TrimSpace(record.SKU)
+		row.Name = strings.TrimSpace(record.Name)
+		row.Description = record.Description
+		row.Status = record.Status
+		row.Attributes = record.Attributes
+		row.Stock = record.Stock
+		if record.Price == nil {
+			row.Err = models.ErrInvalidPrice
+			continue
+		}
+		row.Price = *record.Price
+	}
+	return rows
+}
+
+// validateImportRow checks the values of a parsed row
+func validateImportRow(row *importRow) error {
+	if row.SKU == "" {
+		return models.ErrSKURequired
+	}
+	if row.Name == "" {
+		return models.ErrNameRequired
+	}
+	if row.Price < 0 || math.IsNaN(row.Price) || math.IsInf(row.Price, 0) {
+		return models.ErrInvalidPrice
+	}
+	if row.Status != "" && !row.Status.Valid() {
+		return models.ErrInvalidProductStatus
+	}
+	for warehouseID, quantity := range row.Stock {
+		if warehouseID <= 0 || quantity < 0 {
+			return models.ErrInvalidImportStock
+		}
+	}
+	return nil
+}
+
+// exportWriter writes products in an import format
+type exportWriter interface {
+	Write(sku string, product *models.Product) error
+	Flush() error
+}
+
+func newExportWriter(format models.ImportFormat, w io.Writer) (exportWriter, error) {
+	switch format {
+	case models.ImportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(importColumns); err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{writer: writer}, nil
+	case models.ImportFormatJSONL:
+		return &jsonlExportWriter{encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, models.ErrInvalidImportFormat
+	}
+}
+
+// csvExportWriter writes the CSV columns with an empty stock, so that
+// importing an export does not change the stock
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvExportWriter) Write(sku string, product *models.Product) error {
+	var attributes string
+	if len(product.Attributes) > 0 {
+		b, err := json.Marshal(product.Attributes)
+		if err != nil {
+			return err
+		}
+		attributes = string(b)
+	}
+	return w.writer.Write([]string{
+		sku,
+		product.Name,
+		product.Description,
+		strconv.FormatFloat(product.Price, 'f', -1, 64),
+		string(product.Status),
+		attributes,
+		"",
+	})
+}
+
+func (w *csvExportWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type jsonlExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlExportWriter) Write(sku string, product *models.Product) error {
+	price := product.Price
+	return w.encoder.Encode(importRecord{
+		SKU:         sku,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       &price,
+		Status:      product.Status,
+		Attributes:  product.Attributes,
+	})
+}
+
+func (w *jsonlExportWriter) Flush() error {
+	return nil
+}
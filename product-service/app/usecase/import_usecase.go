@@ -0,0 +1,346 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	warehouseProto "github.com/evrintobing17/ecommerce-system/shared/proto/warehouse"
+)
+
+const (
+	// maxImportRows is the most rows an import file may have
+	maxImportRows = 10000
+	// maxImportRowErrors is the most row errors kept on a job; FailedRows
+	// still counts all of them
+	maxImportRowErrors = 1000
+	// importProgressInterval is how many rows are processed between saves
+	// of the job's progress
+	importProgressInterval = 100
+	// staleImportAfter is how long a running job may go without progress
+	// before another worker takes it over, e.g. after a restart
+	staleImportAfter = 10 * time.Minute
+	// maxImportAttempts is how many times a job is claimed before a stale
+	// job is marked failed instead of being taken over again
+	maxImportAttempts = 3
+	// importHeartbeatInterval is how often a running job is touched, so that
+	// slow rows do not make it look stale
+	importHeartbeatInterval = time.Minute
+	// exportBatchSize is how many products are loaded at once by exports
+	exportBatchSize = 500
+)
+
+type importUsecase struct {
+	importJobRepo   product.ImportJobRepository
+	productRepo     product.ProductRepository
+	variantRepo     product.VariantRepository
+	productUsecase  product.ProductUsecase
+	warehouseClient warehouseProto.WarehouseServiceClient
+	maxImportSize   int
+}
+
+// NewImportUsecase returns the bulk import and export usecase. Rows are
+// written through productUsecase, so they follow the same rules as single
+// product changes. Import files may be up to maxImportSize bytes.
+func NewImportUsecase(importJobRepo product.ImportJobRepository, productRepo product.ProductRepository, variantRepo product.VariantRepository, productUsecase product.ProductUsecase, warehouseClient warehouseProto.WarehouseServiceClient, maxImportSize int) product.ImportUsecase {
+	return &importUsecase{
+		importJobRepo:   importJobRepo,
+		productRepo:     productRepo,
+		variantRepo:     variantRepo,
+		productUsecase:  productUsecase,
+		warehouseClient: warehouseClient,
+		maxImportSize:   maxImportSize,
+	}
+}
+
+func (u *importUsecase) ImportProducts(ctx context.Context, shopID int, format models.ImportFormat, r io.Reader) (*models.ImportJob, error) {
+	if !format.Valid() {
+		return nil, models.ErrInvalidImportFormat
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(u.maxImportSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > u.maxImportSize {
+		return nil, models.ErrImportFileTooLarge.With("max_bytes", strconv.Itoa(u.maxImportSize))
+	}
+
+	// The rows are parsed again by the worker; parsing them here rejects
+	// unreadable files right away
+	rows, err := parseImport(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > maxImportRows {
+		return nil, models.ErrTooManyImportRows.With("max_rows", strconv.Itoa(maxImportRows))
+	}
+
+	job := &models.ImportJob{
+		ShopID:    shopID,
+		Format:    format,
+		Status:    models.ImportJobPending,
+		TotalRows: len(rows),
+		Errors:    models.ImportRowErrors{},
+		Data:      data,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := u.importJobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (u *importUsecase) GetImportJob(ctx context.Context, id int) (*models.ImportJob, error) {
+	return u.importJobRepo.FindByID(ctx, id)
+}
+
+func (u *importUsecase) RunNextImportJob(ctx context.Context) (bool, error) {
+	job, err := u.importJobRepo.Claim(ctx, time.Now().Add(-staleImportAfter), maxImportAttempts)
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	slog.InfoContext(ctx, "running product import", "job_id", job.ID, "shop_id", job.ShopID, "rows", job.TotalRows)
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		u.heartbeat(jobCtx, cancel, job.ID, job.Attempt)
+	}()
+	err = u.runImportJob(jobCtx, job)
+	cancel(nil)
+	<-heartbeatDone
+
+	if errors.Is(err, models.ErrImportJobLost) {
+		slog.WarnContext(ctx, "product import was taken over by another worker", "job_id", job.ID)
+		return true, nil
+	}
+	// On shutdown the job is left running for a worker to take over once it
+	// is stale
+	if ctx.Err() != nil {
+		return true, nil
+	}
+	if err != nil {
+		job.Status = models.ImportJobFailed
+		job.Error = err.Error()
+		slog.WarnContext(ctx, "product import failed", "job_id", job.ID, "error", err)
+	} else {
+		job.Status = models.ImportJobCompleted
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.UpdatedAt = now
+	if err := u.importJobRepo.Finish(ctx, job); err != nil {
+		if errors.Is(err, models.ErrImportJobLost) {
+			slog.WarnContext(ctx, "product import was taken over by another worker", "job_id", job.ID)
+			return true, nil
+		}
+		return true, err
+	}
+	return true, nil
+}
+
+// heartbeat touches the job every importHeartbeatInterval until ctx is done.
+// It cancels the job with ErrImportJobLost once another worker has claimed
+// it, so that the rows are not applied twice at the same time.
+func (u *importUsecase) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, id, attempt int) {
+	ticker := time.NewTicker(importHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := u.importJobRepo.Heartbeat(ctx, id, attempt)
+		switch {
+		case errors.Is(err, models.ErrImportJobLost):
+			cancel(err)
+			return
+		case err != nil && ctx.Err() == nil:
+			slog.WarnContext(ctx, "failed to record import heartbeat", "job_id", id, "error", err)
+		}
+	}
+}
+
+// runImportJob applies the rows of the job, recording the rows that fail.
+// It returns an error when the job cannot run at all.
+func (u *importUsecase) runImportJob(ctx context.Context, job *models.ImportJob) error {
+	rows, err := parseImport(job.Format, job.Data)
+	if err != nil {
+		return err
+	}
+
+	var warehouses map[int]bool
+	for _, row := range rows {
+		if row.Err == nil && len(row.Stock) > 0 {
+			warehouses, err = u.shopWarehouses(ctx, job.ShopID)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	for i, row := range rows {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+
+		created, err := u.importRow(ctx, job.ShopID, row, warehouses)
+		switch {
+		case err != nil:
+			job.FailedRows++
+			if len(job.Errors) < maxImportRowErrors {
+				job.Errors = append(job.Errors, importRowError(ctx, row, err))
+			}
+		case created:
+			job.CreatedRows++
+		default:
+			job.UpdatedRows++
+		}
+		job.ProcessedRows++
+
+		if (i+1)%importProgressInterval == 0 {
+			job.UpdatedAt = time.Now()
+			if err := u.importJobRepo.UpdateProgress(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// shopWarehouses returns the IDs of the warehouses of the shop
+func (u *importUsecase) shopWarehouses(ctx context.Context, shopID int) (map[int]bool, error) {
+	resp, err := u.warehouseClient.GetWarehouses(ctx, &warehouseProto.GetWarehousesRequest{ShopId: int32(shopID)})
+	if err != nil {
+		return nil, err
+	}
+
+	warehouses := make(map[int]bool, len(resp.Warehouses))
+	for _, warehouse := range resp.Warehouses {
+		warehouses[int(warehouse.Id)] = true
+	}
+	return warehouses, nil
+}
+
+// importRow creates or updates the product whose default variant has the
+// row's SKU in the shop, and sets its stock. It reports whether the product
+// was created.
+func (u *importUsecase) importRow(ctx context.Context, shopID int, row *importRow, warehouses map[int]bool) (bool, error) {
+	if row.Err != nil {
+		return false, row.Err
+	}
+	for warehouseID := range row.Stock {
+		if !warehouses[warehouseID] {
+			return false, models.ErrWarehouseNotInShop.With("warehouse_id", strconv.Itoa(warehouseID))
+		}
+	}
+
+	var productID, variantID int
+	created := false
+	variant, err := u.variantRepo.FindBySKU(ctx, shopID, row.SKU)
+	switch {
+	case errors.Is(err, models.ErrVariantNotFound):
+		newProduct, err := u.productUsecase.CreateProduct(ctx, row.Name, row.Description, row.Price, 0, shopID, row.Attributes, row.SKU)
+		if err != nil {
+			return false, err
+		}
+		productID, variantID = newProduct.ID, newProduct.Variants[0].ID
+		created = true
+	case err != nil:
+		return false, err
+	case !variant.IsDefault:
+		return false, models.ErrSKUNotDefaultVariant.With("variant_id", strconv.Itoa(variant.ID))
+	default:
+		err := u.productUsecase.UpdateProduct(ctx, &models.Product{
+			ID:          variant.ProductID,
+			Name:        row.Name,
+			Description: row.Description,
+			Price:       row.Price,
+			Attributes:  row.Attributes,
+		})
+		if err != nil {
+			return false, err
+		}
+		productID, variantID = variant.ProductID, variant.ID
+	}
+
+	if row.Status != "" {
+		if _, err := u.productUsecase.SetProductStatus(ctx, productID, row.Status, nil); err != nil {
+			return created, err
+		}
+	}
+	for warehouseID, quantity := range row.Stock {
+		if _, err := u.productUsecase.UpdateStock(ctx, productID, variantID, warehouseID, quantity, "set"); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// importRowError describes why the row failed. Internal errors are logged
+// and reported without their details.
+func importRowError(ctx context.Context, row *importRow, err error) models.ImportRowError {
+	rowError := models.ImportRowError{Row: row.Line, SKU: row.SKU}
+	if appErr, ok := apperror.As(err); ok && appErr.Kind != apperror.Internal {
+		rowError.Code = appErr.Code
+		rowError.Message = appErr.Error()
+		return rowError
+	}
+
+	slog.WarnContext(ctx, "failed to import product row", "row", row.Line, "sku", row.SKU, "error", err)
+	rowError.Code = "internal"
+	rowError.Message = "internal error"
+	return rowError
+}
+
+func (u *importUsecase) ExportProducts(ctx context.Context, shopID int, format models.ImportFormat, w io.Writer) error {
+	writer, err := newExportWriter(format, w)
+	if err != nil {
+		return err
+	}
+
+	ids, err := u.productRepo.FindIDsByShop(ctx, shopID)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(ids); start += exportBatchSize {
+		batch := ids[start:min(start+exportBatchSize, len(ids))]
+		products, err := u.productRepo.FindByIDs(ctx, batch)
+		if err != nil {
+			return err
+		}
+		variants, err := u.variantRepo.FindByProductIDs(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		for _, product := range products {
+			var sku string
+			for _, variant := range variants[product.ID] {
+				if variant.IsDefault {
+					sku = variant.SKU
+				}
+			}
+			if err := writer.Write(sku, product); err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	product "github.com/evrintobing17/ecommerce-system/product-service/app"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+// importJobRepoStub hands out one job whose rows all fail validation. Saves
+// fail with ErrImportJobLost once the job has been claimed again, which
+// happens after claimedAgainAfter saves when that is not zero.
+type importJobRepoStub struct {
+	product.ImportJobRepository
+	job               *models.ImportJob
+	claimedAgainAfter int
+	saves             []models.ImportJob
+	finished          bool
+}
+
+func (r *importJobRepoStub) Claim(ctx context.Context, staleBefore time.Time, maxAttempts int) (*models.ImportJob, error) {
+	job := r.job
+	r.job = nil
+	if job != nil {
+		job.Status = models.ImportJobRunning
+		job.Attempt++
+	}
+	return job, nil
+}
+
+func (r *importJobRepoStub) Heartbeat(ctx context.Context, id, attempt int) error {
+	return nil
+}
+
+func (r *importJobRepoStub) save(job *models.ImportJob) error {
+	if r.claimedAgainAfter > 0 && len(r.saves) >= r.claimedAgainAfter {
+		return models.ErrImportJobLost.With("job_id", strconv.Itoa(job.ID))
+	}
+	r.saves = append(r.saves, *job)
+	return nil
+}
+
+func (r *importJobRepoStub) UpdateProgress(ctx context.Context, job *models.ImportJob) error {
+	return r.save(job)
+}
+
+func (r *importJobRepoStub) Finish(ctx context.Context, job *models.ImportJob) error {
+	if err := r.save(job); err != nil {
+		return err
+	}
+	r.finished = true
+	return nil
+}
+
+// invalidImportJob returns a JSON Lines job of rows without SKU
+func invalidImportJob(rows int) *models.ImportJob {
+	return &models.ImportJob{
+		ID:        1,
+		ShopID:    1,
+		Format:    models.ImportFormatJSONL,
+		Status:    models.ImportJobPending,
+		TotalRows: rows,
+		Data:      bytes.Repeat([]byte("{}\n"), rows),
+	}
+}
+
+func TestRunNextImportJob(t *testing.T) {
+	repo := &importJobRepoStub{job: invalidImportJob(250)}
+	u := NewImportUsecase(repo, nil, nil, nil, nil, 1<<20)
+
+	ran, err := u.RunNextImportJob(context.Background())
+	if !ran || err != nil {
+		t.Fatalf("RunNextImportJob = %v, %v, want a job run", ran, err)
+	}
+	if !repo.finished {
+		t.Fatalf("job was not finished")
+	}
+
+	// Progress is saved every importProgressInterval rows, then on finishing
+	if len(repo.saves) != 3 {
+		t.Fatalf("saves = %d, want 3", len(repo.saves))
+	}
+	last := repo.saves[len(repo.saves)-1]
+	if last.Status != models.ImportJobCompleted || last.ProcessedRows != 250 || last.FailedRows != 250 {
+		t.Errorf("finished job = %s with %d processed and %d failed rows, want completed with 250 and 250",
+			last.Status, last.ProcessedRows, last.FailedRows)
+	}
+}
+
+func TestRunNextImportJobStopsWhenClaimedAgain(t *testing.T) {
+	// Another worker claims the job after the first progress save
+	repo := &importJobRepoStub{job: invalidImportJob(250), claimedAgainAfter: 1}
+	u := NewImportUsecase(repo, nil, nil, nil, nil, 1<<20)
+
+	ran, err := u.RunNextImportJob(context.Background())
+	if !ran || err != nil {
+		t.Fatalf("RunNextImportJob = %v, %v, want the lost job to be given up quietly", ran, err)
+	}
+	if repo.finished {
+		t.Errorf("lost job was finished, overwriting the results of the other worker")
+	}
+	if len(repo.saves) != 1 || repo.saves[0].ProcessedRows != importProgressInterval {
+		t.Errorf("saves = %+v, want only the first progress save", repo.saves)
+	}
+}
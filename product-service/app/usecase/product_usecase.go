@@ -1,25 +1,69 @@
 package usecase
 
 import (
-	"errors"
+	"context"
+	"log/slog"
+	"strconv"
 	"time"
 
 	product "github.com/evrintobing17/ecommerce-system/product-service/app"
 	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	warehouseProto "github.com/evrintobing17/ecommerce-system/shared/proto/warehouse"
 )
 
-var ErrInsufficientStock = errors.New("insufficient stock")
+// availabilityBatchSize is the most variants WarehouseService.GetAvailability
+// accepts in one call
+const availabilityBatchSize = 100
+
+// maxSearchLimit is the largest search page
+const maxSearchLimit = 100
 
 type productUsecase struct {
-	productRepo product.ProductRepository
+	productRepo     product.ProductRepository
+	productSearcher product.ProductSearcher
+	categoryRepo    product.CategoryRepository
+	variantRepo     product.VariantRepository
+	imageRepo       product.ImageRepository
+	storage         product.Storage
+	warehouseClient warehouseProto.WarehouseServiceClient
+	availability    *availabilityCache
+	maxImageSize    int
 }
 
-func NewProductUsecase(productRepo product.ProductRepository) product.ProductUsecase {
-	return &productUsecase{productRepo: productRepo}
+// NewProductUsecase returns the product usecase. The available stock read
+// from warehouse-service is cached for availabilityTTL. Uploaded images are
+// kept in storage and may be up to maxImageSize bytes.
+func NewProductUsecase(productRepo product.ProductRepository, productSearcher product.ProductSearcher, categoryRepo product.CategoryRepository, variantRepo product.VariantRepository, imageRepo product.ImageRepository, storage product.Storage, warehouseClient warehouseProto.WarehouseServiceClient, availabilityTTL time.Duration, maxImageSize int) product.ProductUsecase {
+	return &productUsecase{
+		productRepo:     productRepo,
+		productSearcher: productSearcher,
+		categoryRepo:    categoryRepo,
+		variantRepo:     variantRepo,
+		imageRepo:       imageRepo,
+		storage:         storage,
+		warehouseClient: warehouseClient,
+		availability:    newAvailabilityCache(availabilityTTL),
+		maxImageSize:    maxImageSize,
+	}
 }
 
-func (u *productUsecase) GetProducts(shopID int, page, limit int) ([]*models.Product, int64, error) {
-	products, total, err := u.productRepo.FindAll(shopID, page, limit)
+func (u *productUsecase) GetProducts(ctx context.Context, filter models.ProductFilter, page, limit int) ([]*models.Product, int64, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, 0, models.ErrInvalidProductStatus
+	}
+	if filter.Status != "" && filter.Status != models.ProductStatusPublished && filter.ShopID == 0 {
+		return nil, 0, models.ErrStatusFilterNeedsShop
+	}
+	if filter.CategoryID != 0 {
+		if _, err := u.categoryRepo.FindByID(ctx, filter.CategoryID); err != nil {
+			return nil, 0, err
+		}
+	}
+	if filter.InStockOnly {
+		return u.getProductsInStock(ctx, filter, page, limit)
+	}
+
+	products, total, err := u.productRepo.FindAll(ctx, filter, page, limit)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -32,42 +76,193 @@ func (u *productUsecase) GetProducts(shopID int, page, limit int) ([]*models.Pro
 			Description: product.Description,
 			Price:       product.Price,
 			ShopID:      product.ShopID,
+			Attributes:  product.Attributes,
+			Status:      product.Status,
+			PublishAt:   product.PublishAt,
 			CreatedAt:   product.CreatedAt,
 			UpdatedAt:   product.UpdatedAt,
 		})
 	}
+	if err := u.fillDetails(ctx, result); err != nil {
+		return nil, 0, err
+	}
+	u.fillStock(ctx, result)
 
 	return result, total, nil
 }
 
-func (u *productUsecase) GetProduct(id int) (*models.Product, error) {
-	product, err := u.productRepo.FindByID(id)
+// getProductsInStock pages through the products with available stock. As
+// the stock is kept by warehouse-service, the availability of all candidate
+// products is read before paging.
+func (u *productUsecase) getProductsInStock(ctx context.Context, filter models.ProductFilter, page, limit int) ([]*models.Product, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	ids, err := u.productRepo.FindIDs(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	inStock, available, err := u.inStock(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := min((page-1)*limit, len(inStock))
+	end := min(start+limit, len(inStock))
+	products, err := u.productRepo.FindByIDs(ctx, inStock[start:end])
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := u.fillDetails(ctx, products); err != nil {
+		return nil, 0, err
+	}
+	applyStock(products, available)
+
+	return products, int64(len(inStock)), nil
+}
+
+// inStock returns the products of productIDs with available stock, in the
+// same order, and the available stock of their variants keyed by variant ID
+func (u *productUsecase) inStock(ctx context.Context, productIDs []int) ([]int, map[int]int32, error) {
+	variants, err := u.variantRepo.FindByProductIDs(ctx, productIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var variantIDs []int
+	for _, productID := range productIDs {
+		for _, variant := range variants[productID] {
+			variantIDs = append(variantIDs, variant.ID)
+		}
+	}
+
+	available, err := u.availableStock(ctx, variantIDs)
+	if err != nil {
+		return nil, nil, models.ErrAvailabilityUnavailable.Wrap(err)
+	}
+
+	inStock := make([]int, 0, len(productIDs))
+	for _, productID := range productIDs {
+		for _, variant := range variants[productID] {
+			if available[variant.ID] > 0 {
+				inStock = append(inStock, productID)
+				break
+			}
+		}
+	}
+	return inStock, available, nil
+}
+
+// GetProduct returns the product in any status, including deleted products,
+// so orders keep resolving the products they reference
+func (u *productUsecase) GetProduct(ctx context.Context, id int) (*models.Product, error) {
+	product, err := u.productRepo.FindByIDWithDeleted(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.Product{
+	result := &models.Product{
 		ID:          product.ID,
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
 		ShopID:      product.ShopID,
+		Attributes:  product.Attributes,
+		Status:      product.Status,
+		PublishAt:   product.PublishAt,
 		CreatedAt:   product.CreatedAt,
 		UpdatedAt:   product.UpdatedAt,
-	}, nil
+	}
+	if err := u.fillDetails(ctx, []*models.Product{result}); err != nil {
+		return nil, err
+	}
+	u.fillStock(ctx, []*models.Product{result})
+
+	return result, nil
 }
 
-func (u *productUsecase) CreateProduct(name, description string, price float64, stock int32, shopID int) (*models.Product, error) {
+// SearchProducts runs query against the search backend. Availability is
+// kept by warehouse-service, so for InStockOnly the stock of all matches is
+// read first and the search restricted to those in stock.
+func (u *productUsecase) SearchProducts(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error) {
+	if query.Sort == "" {
+		query.Sort = models.SortRelevance
+	}
+	if !query.Sort.Valid() {
+		return nil, models.ErrInvalidSearchSort
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return nil, models.ErrInvalidPriceRange
+	}
+	if query.CategoryID != 0 {
+		if _, err := u.categoryRepo.FindByID(ctx, query.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+	query.Limit = min(query.Limit, maxSearchLimit)
+
+	if query.InStockOnly {
+		ids, err := u.productSearcher.MatchingIDs(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		if query.ProductIDs, _, err = u.inStock(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := u.productSearcher.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result.Page, result.Limit = query.Page, query.Limit
+	if err := u.fillDetails(ctx, result.Products); err != nil {
+		return nil, err
+	}
+	u.fillStock(ctx, result.Products)
+
+	return result, nil
+}
+
+// CreateProduct creates the product as a draft with its default variant,
+// whose SKU is sku or, when empty, models.DefaultSKU
+func (u *productUsecase) CreateProduct(ctx context.Context, name, description string, price float64, stock int32, shopID int, attributes models.Attributes, sku string) (*models.Product, error) {
+	if sku != "" {
+		if err := u.checkSKUAvailable(ctx, shopID, sku, 0); err != nil {
+			return nil, err
+		}
+	}
+
 	product := &models.Product{
 		Name:        name,
 		Description: description,
 		Price:       price,
 		ShopID:      shopID,
+		Attributes:  attributes,
+		Status:      models.ProductStatusDraft,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		Variants: []*models.Variant{{
+			SKU:       sku,
+			IsDefault: true,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}},
 	}
 
-	err := u.productRepo.Create(product)
+	err := u.productRepo.Create(ctx, product)
 	if err != nil {
 		return nil, err
 	}
@@ -78,13 +273,19 @@ func (u *productUsecase) CreateProduct(name, description string, price float64,
 		Description: product.Description,
 		Price:       product.Price,
 		ShopID:      product.ShopID,
+		Attributes:  product.Attributes,
+		Status:      product.Status,
+		PublishAt:   product.PublishAt,
 		CreatedAt:   product.CreatedAt,
 		UpdatedAt:   product.UpdatedAt,
+		CategoryIDs: []int{},
+		Options:     []*models.ProductOption{},
+		Variants:    product.Variants,
 	}, nil
 }
 
-func (u *productUsecase) UpdateProduct(product *models.Product) error {
-	existingProduct, err := u.productRepo.FindByID(product.ID)
+func (u *productUsecase) UpdateProduct(ctx context.Context, product *models.Product) error {
+	existingProduct, err := u.productRepo.FindByID(ctx, product.ID)
 	if err != nil {
 		return err
 	}
@@ -92,11 +293,242 @@ func (u *productUsecase) UpdateProduct(product *models.Product) error {
 	existingProduct.Name = product.Name
 	existingProduct.Description = product.Description
 	existingProduct.Price = product.Price
+	existingProduct.Attributes = product.Attributes
 	existingProduct.UpdatedAt = time.Now()
 
-	return u.productRepo.Update(existingProduct)
+	return u.productRepo.Update(ctx, existingProduct)
 }
 
-func (u *productUsecase) DeleteProduct(id int) error {
-	return u.productRepo.Delete(id)
+// DeleteProduct archives and soft deletes the product. It stays resolvable
+// by ID for the orders referencing it.
+func (u *productUsecase) DeleteProduct(ctx context.Context, id int) error {
+	if _, err := u.productRepo.FindByID(ctx, id); err != nil {
+		return err
+	}
+	return u.productRepo.Delete(ctx, id)
+}
+
+// SetProductStatus moves the product to status. publishAt schedules a draft
+// for publishing and may only be given with the draft status; publishing
+// records the time.
+func (u *productUsecase) SetProductStatus(ctx context.Context, productID int, status models.ProductStatus, publishAt *time.Time) (*models.Product, error) {
+	if !status.Valid() {
+		return nil, models.ErrInvalidProductStatus
+	}
+	if publishAt != nil && status != models.ProductStatusDraft {
+		return nil, models.ErrPublishAtNotDraft
+	}
+
+	product, err := u.productRepo.FindByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch status {
+	case models.ProductStatusDraft:
+		product.PublishAt = publishAt
+	case models.ProductStatusPublished:
+		if product.Status != models.ProductStatusPublished {
+			product.PublishAt = &now
+		}
+	}
+	product.Status = status
+	product.UpdatedAt = now
+
+	if err := u.productRepo.Update(ctx, product); err != nil {
+		return nil, err
+	}
+	return u.GetProduct(ctx, productID)
+}
+
+// PublishScheduledProducts publishes the drafts whose publish time has come
+func (u *productUsecase) PublishScheduledProducts(ctx context.Context) error {
+	published, err := u.productRepo.PublishDue(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if published > 0 {
+		slog.InfoContext(ctx, "published scheduled products", "count", published)
+	}
+	return nil
+}
+
+func (u *productUsecase) SetProductCategories(ctx context.Context, productID int, categoryIDs []int) error {
+	if _, err := u.productRepo.FindByID(ctx, productID); err != nil {
+		return err
+	}
+
+	unique := make([]int, 0, len(categoryIDs))
+	seen := make(map[int]bool, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	categories, err := u.categoryRepo.FindByIDs(ctx, unique)
+	if err != nil {
+		return err
+	}
+	found := make(map[int]bool, len(categories))
+	for _, category := range categories {
+		found[category.ID] = true
+	}
+	for _, id := range unique {
+		if !found[id] {
+			return models.ErrCategoryNotFound.With("category_id", strconv.Itoa(id))
+		}
+	}
+
+	return u.productRepo.SetCategories(ctx, productID, unique)
+}
+
+// UpdateStock changes the stock of a variant of the product, or of its
+// default variant when variantID is 0, in one warehouse through
+// warehouse-service, which keeps it, and returns the new quantity there
+func (u *productUsecase) UpdateStock(ctx context.Context, productID, variantID, warehouseID int, quantity int32, operation string) (int32, error) {
+	if _, err := u.productRepo.FindByID(ctx, productID); err != nil {
+		return 0, err
+	}
+
+	if variantID == 0 {
+		variants, err := u.variantRepo.FindByProductIDs(ctx, []int{productID})
+		if err != nil {
+			return 0, err
+		}
+		for _, variant := range variants[productID] {
+			if variant.IsDefault {
+				variantID = variant.ID
+			}
+		}
+	}
+	if _, err := u.findVariant(ctx, productID, variantID); err != nil {
+		return 0, err
+	}
+
+	resp, err := u.warehouseClient.UpdateStock(ctx, &warehouseProto.UpdateStockRequest{
+		VariantId:   int32(variantID),
+		WarehouseId: int32(warehouseID),
+		Quantity:    quantity,
+		Operation:   operation,
+	})
+	if err != nil {
+		return 0, err
+	}
+	u.availability.invalidate(variantID)
+
+	return resp.Stock.Quantity, nil
+}
+
+// fillDetails sets the CategoryIDs, Options, Variants and Images of each product
+func (u *productUsecase) fillDetails(ctx context.Context, products []*models.Product) error {
+	ids := make([]int, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+
+	categoryIDs, err := u.productRepo.FindCategoryIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	options, err := u.variantRepo.FindOptions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	variants, err := u.variantRepo.FindByProductIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	images, err := u.imageRepo.FindByProductIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		product.CategoryIDs = categoryIDs[product.ID]
+		if product.CategoryIDs == nil {
+			product.CategoryIDs = []int{}
+		}
+		product.Options = options[product.ID]
+		if product.Options == nil {
+			product.Options = []*models.ProductOption{}
+		}
+		product.Variants = variants[product.ID]
+		if product.Variants == nil {
+			product.Variants = []*models.Variant{}
+		}
+		product.Images = images[product.ID]
+		if product.Images == nil {
+			product.Images = []*models.ProductImage{}
+		}
+		u.fillImageURLs(product.Images)
+	}
+	return nil
+}
+
+// fillStock sets the Stock of each variant to its available quantity and
+// the Stock of each product to the sum over its variants. Stock that cannot
+// be read is logged and left at 0, so the catalog stays available when
+// warehouse-service is not.
+func (u *productUsecase) fillStock(ctx context.Context, products []*models.Product) {
+	var ids []int
+	for _, product := range products {
+		for _, variant := range product.Variants {
+			ids = append(ids, variant.ID)
+		}
+	}
+
+	available, err := u.availableStock(ctx, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get stock availability", "error", err)
+		return
+	}
+	applyStock(products, available)
+}
+
+// applyStock sets the Stock of the products and their variants from the
+// available stock keyed by variant ID
+func applyStock(products []*models.Product, available map[int]int32) {
+	for _, product := range products {
+		product.Stock = 0
+		for _, variant := range product.Variants {
+			variant.Stock = available[variant.ID]
+			product.Stock += variant.Stock
+		}
+	}
+}
+
+// availableStock returns the available quantity, that is quantity minus
+// reserved, of the variants across the active warehouses, keyed by variant
+// ID. Cached values are reused; the others are read from warehouse-service
+// in batches.
+func (u *productUsecase) availableStock(ctx context.Context, variantIDs []int) (map[int]int32, error) {
+	now := time.Now()
+	available := make(map[int]int32, len(variantIDs))
+	var missing []int32
+	for _, variantID := range variantIDs {
+		if quantity, ok := u.availability.get(variantID, now); ok {
+			available[variantID] = quantity
+			continue
+		}
+		missing = append(missing, int32(variantID))
+	}
+
+	for start := 0; start < len(missing); start += availabilityBatchSize {
+		end := min(start+availabilityBatchSize, len(missing))
+		resp, err := u.warehouseClient.GetAvailability(ctx, &warehouseProto.GetAvailabilityRequest{
+			VariantIds: missing[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, variant := range resp.Variants {
+			available[int(variant.VariantId)] = variant.Available
+			u.availability.set(int(variant.VariantId), variant.Available, now)
+		}
+	}
+
+	return available, nil
 }
@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+// SetProductOptions replaces the options of the product. Option values used
+// by one of its variants cannot be removed.
+func (u *productUsecase) SetProductOptions(ctx context.Context, productID int, options []*models.ProductOption) error {
+	if _, err := u.productRepo.FindByID(ctx, productID); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(options))
+	for _, option := range options {
+		option.Name = strings.TrimSpace(option.Name)
+		if option.Name == "" || names[option.Name] || len(option.Values) == 0 {
+			return models.ErrInvalidProductOptions
+		}
+		names[option.Name] = true
+
+		values := make(map[string]bool, len(option.Values))
+		for _, value := range option.Values {
+			if value == "" || values[value] {
+				return models.ErrInvalidProductOptions.With("option", option.Name)
+			}
+			values[value] = true
+		}
+	}
+
+	variants, err := u.variantRepo.FindByProductIDs(ctx, []int{productID})
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants[productID] {
+		if !validVariantOptions(variant.Options, options) {
+			return models.ErrProductOptionInUse.With("variant_id", strconv.Itoa(variant.ID))
+		}
+	}
+
+	return u.variantRepo.SetOptions(ctx, productID, options)
+}
+
+// CreateVariant adds a variant to the product in variant.ProductID
+func (u *productUsecase) CreateVariant(ctx context.Context, variant *models.Variant) (*models.Variant, error) {
+	product, err := u.productRepo.FindByID(ctx, variant.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	variant.ShopID = product.ShopID
+	if err := u.validateVariant(ctx, variant); err != nil {
+		return nil, err
+	}
+
+	variant.CreatedAt = time.Now()
+	variant.UpdatedAt = time.Now()
+	if err := u.variantRepo.Create(ctx, variant); err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// GetVariant returns the variant of the product with its available stock
+func (u *productUsecase) GetVariant(ctx context.Context, productID, variantID int) (*models.Variant, error) {
+	variant, err := u.findVariant(ctx, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := u.availableStock(ctx, []int{variant.ID})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get stock availability", "error", err)
+	}
+	variant.Stock = available[variant.ID]
+
+	return variant, nil
+}
+
+// findVariant returns the variant if it belongs to the product
+func (u *productUsecase) findVariant(ctx context.Context, productID, variantID int) (*models.Variant, error) {
+	variant, err := u.variantRepo.FindByID(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if variant.ProductID != productID {
+		return nil, models.ErrVariantNotFound.With("variant_id", strconv.Itoa(variantID))
+	}
+	return variant, nil
+}
+
+// UpdateVariant saves the SKU, barcode, price, weight and options of the
+// variant. The default can only be moved by making another variant the
+// default, so a default variant stays one.
+func (u *productUsecase) UpdateVariant(ctx context.Context, variant *models.Variant) error {
+	existingVariant, err := u.findVariant(ctx, variant.ProductID, variant.ID)
+	if err != nil {
+		return err
+	}
+
+	existingVariant.SKU = variant.SKU
+	existingVariant.Barcode = variant.Barcode
+	existingVariant.Price = variant.Price
+	existingVariant.WeightGrams = variant.WeightGrams
+	existingVariant.Options = variant.Options
+	existingVariant.IsDefault = existingVariant.IsDefault || variant.IsDefault
+	if err := u.validateVariant(ctx, existingVariant); err != nil {
+		return err
+	}
+
+	existingVariant.UpdatedAt = time.Now()
+	return u.variantRepo.Update(ctx, existingVariant)
+}
+
+// DeleteVariant removes a variant of the product other than its default
+func (u *productUsecase) DeleteVariant(ctx context.Context, productID, variantID int) error {
+	variant, err := u.findVariant(ctx, productID, variantID)
+	if err != nil {
+		return err
+	}
+	if variant.IsDefault {
+		return models.ErrDefaultVariant
+	}
+
+	if err := u.variantRepo.Delete(ctx, variantID); err != nil {
+		return err
+	}
+	u.availability.invalidate(variantID)
+	return nil
+}
+
+// validateVariant checks the SKU is set and unused in the shop, and that the
+// options are values of the product's options not used by another variant
+func (u *productUsecase) validateVariant(ctx context.Context, variant *models.Variant) error {
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	if variant.SKU == "" {
+		return models.ErrSKURequired
+	}
+	if err := u.checkSKUAvailable(ctx, variant.ShopID, variant.SKU, variant.ID); err != nil {
+		return err
+	}
+
+	options, err := u.variantRepo.FindOptions(ctx, []int{variant.ProductID})
+	if err != nil {
+		return err
+	}
+	if !validVariantOptions(variant.Options, options[variant.ProductID]) {
+		return models.ErrInvalidVariantOptions
+	}
+
+	variants, err := u.variantRepo.FindByProductIDs(ctx, []int{variant.ProductID})
+	if err != nil {
+		return err
+	}
+	for _, other := range variants[variant.ProductID] {
+		if other.ID != variant.ID && maps.Equal(other.Options, variant.Options) {
+			return models.ErrDuplicateVariant.With("variant_id", strconv.Itoa(other.ID))
+		}
+	}
+	return nil
+}
+
+// checkSKUAvailable returns ErrSKUTaken if a variant other than variantID
+// already uses the SKU in the shop
+func (u *productUsecase) checkSKUAvailable(ctx context.Context, shopID int, sku string, variantID int) error {
+	existing, err := u.variantRepo.FindBySKU(ctx, shopID, sku)
+	if err != nil {
+		if errors.Is(err, models.ErrVariantNotFound) {
+			return nil
+		}
+		return err
+	}
+	if existing.ID != variantID {
+		return models.ErrSKUTaken.With("sku", sku)
+	}
+	return nil
+}
+
+// validVariantOptions reports whether every option of a variant names one of
+// options and takes one of its values
+func validVariantOptions(variantOptions models.VariantOptions, options []*models.ProductOption) bool {
+	for name, value := range variantOptions {
+		valid := false
+		for _, option := range options {
+			if option.Name == name {
+				for _, optionValue := range option.Values {
+					valid = valid || optionValue == value
+				}
+			}
+		}
+		if !valid {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+)
+
+type VariantRepository interface {
+	// Create stores the variant. A default variant replaces the product's
+	// previous default.
+	Create(ctx context.Context, variant *models.Variant) error
+	FindByID(ctx context.Context, id int) (*models.Variant, error)
+	FindBySKU(ctx context.Context, shopID int, sku string) (*models.Variant, error)
+	// FindByProductIDs returns the variants of each product, default first,
+	// keyed by product ID
+	FindByProductIDs(ctx context.Context, productIDs []int) (map[int][]*models.Variant, error)
+	// Update saves the variant. A default variant replaces the product's
+	// previous default.
+	Update(ctx context.Context, variant *models.Variant) error
+	Delete(ctx context.Context, id int) error
+	// FindOptions returns the options of each product in position order,
+	// keyed by product ID
+	FindOptions(ctx context.Context, productIDs []int) (map[int][]*models.ProductOption, error)
+	// SetOptions replaces the options of the product
+	SetOptions(ctx context.Context, productID int, options []*models.ProductOption) error
+}
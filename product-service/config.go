@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app/storage"
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+)
+
+// Config is the configuration of product-service
+type Config struct {
+	config.Common `yaml:",inline"`
+
+	HTTPAddr config.ListenAddr `env:"PRODUCT_SERVICE_PORT" yaml:"http_addr" default:"8081"`
+	GRPCAddr config.ListenAddr `env:"PRODUCT_GRPC_PORT" yaml:"grpc_addr" default:"50052"`
+
+	ShopServiceAddr      string `env:"SHOP_SERVICE_GRPC_ADDR" yaml:"shop_service_addr" default:"shop-service:50054"`
+	WarehouseServiceAddr string `env:"WAREHOUSE_SERVICE_GRPC_ADDR" yaml:"warehouse_service_addr" default:"warehouse-service:50055"`
+
+	// AvailabilityCacheTTL is how long the stock read from warehouse-service
+	// is reused; 0 disables the cache
+	AvailabilityCacheTTL time.Duration `env:"AVAILABILITY_CACHE_TTL" yaml:"availability_cache_ttl" default:"5s"`
+
+	// Media is where product images are stored
+	Media storage.Config `yaml:"media"`
+	// MaxImageSize is the largest accepted image upload in bytes
+	MaxImageSize int `env:"MAX_IMAGE_SIZE" yaml:"max_image_size" default:"5242880"`
+	// MaxImportSize is the largest accepted product import file in bytes
+	MaxImportSize int `env:"MAX_IMPORT_SIZE" yaml:"max_import_size" default:"10485760"`
+}
+
+// Validate checks the media and import settings next to the common settings
+func (c *Config) Validate() error {
+	var errs []error
+	if err := c.Common.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Media.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.MaxImageSize <= 0 {
+		errs = append(errs, errors.New("MAX_IMAGE_SIZE must be positive"))
+	}
+	if c.MaxImportSize <= 0 {
+		errs = append(errs, errors.New("MAX_IMPORT_SIZE must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -1,112 +1,266 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net"
-	"net/http"
+	"log/slog"
 	"os"
+	"time"
 
 	delivery "github.com/evrintobing17/ecommerce-system/product-service/app/delivery"
 	grpcServer "github.com/evrintobing17/ecommerce-system/product-service/app/delivery/grpc"
-	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
 	"github.com/evrintobing17/ecommerce-system/product-service/app/repository"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/storage"
 	"github.com/evrintobing17/ecommerce-system/product-service/app/usecase"
 
 	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+	"github.com/evrintobing17/ecommerce-system/shared/grpc_client"
+	"github.com/evrintobing17/ecommerce-system/shared/health"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/lifecycle"
 	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/migrate"
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/product"
+	grpcShop "github.com/evrintobing17/ecommerce-system/shared/proto/shop"
+	grpcWarehouse "github.com/evrintobing17/ecommerce-system/shared/proto/warehouse"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+	"github.com/evrintobing17/ecommerce-system/shared/transcoding"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	// Load configuration
+	var cfg Config
+	if err := config.Load("product-service", &cfg); err != nil {
+		log.Fatal(err)
 	}
 
 	// Initialize logger
-	shared.InitLogger()
+	shared.InitLogger("product-service", cfg.LogLevel)
+	config.Log(&cfg)
+	grpc_client.DefaultTimeout = cfg.GRPCClientTimeout
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.RunCommand(context.Background(), "product-service", cfg.Database, migrationFiles(), "product-service/migrations", os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	app := lifecycle.New("product-service", cfg.ShutdownTimeout)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "product-service")
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	app.AddCloser("tracing", shutdownTracing)
 
 	// Initialize database
-	db, err := shared.ConnectDB()
+	db, err := shared.ConnectDB(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
-	defer func() {
-		if err := shared.CloseDB(db); err != nil {
-			log.Println("Error closing database:", err)
-		}
-	}()
+	app.AddCloser("database", func(context.Context) error {
+		return shared.CloseDB(db)
+	})
 
-	// Auto migrate models
-	err = shared.MigrateDB(db, &models.Product{})
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	// Apply pending migrations
+	if cfg.MigrateOnStart {
+		if err := migrate.Up(context.Background(), db, "product-service", migrationFiles()); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
 	}
 
 	// Initialize repositories
 	productRepo := repository.NewProductRepository(db)
+	productSearcher := repository.NewPostgresProductSearcher(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	variantRepo := repository.NewVariantRepository(db)
+	imageRepo := repository.NewImageRepository(db)
+	importJobRepo := repository.NewImportJobRepository(db)
+
+	// Initialize media storage
+	mediaStorage, err := storage.New(cfg.Media)
+	if err != nil {
+		log.Fatal("Failed to initialize media storage:", err)
+	}
+
+	serviceAuth := cfg.ServiceAuth
+
+	shopConn, err := grpc_client.NewConnection(cfg.ShopServiceAddr, serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to shop-service:", err)
+	}
+	defer shopConn.Close()
+
+	shopAccessChecker := middleware.NewShopAccessChecker(grpcShop.NewShopServiceClient(shopConn))
+
+	warehouseConn, err := grpc_client.NewConnection(cfg.WarehouseServiceAddr, serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to warehouse-service:", err)
+	}
+	defer warehouseConn.Close()
+
+	warehouseClient := grpcWarehouse.NewWarehouseServiceClient(warehouseConn)
 
 	// Initialize use cases
-	productUsecase := usecase.NewProductUsecase(productRepo)
+	productUsecase := usecase.NewProductUsecase(productRepo, productSearcher, categoryRepo, variantRepo, imageRepo, mediaStorage, warehouseClient, cfg.AvailabilityCacheTTL, cfg.MaxImageSize)
+	categoryUsecase := usecase.NewCategoryUsecase(categoryRepo)
+	importUsecase := usecase.NewImportUsecase(importJobRepo, productRepo, variantRepo, productUsecase, warehouseClient, cfg.MaxImportSize)
+	const publishInterval = time.Minute
+	publishHeartbeat := health.NewHeartbeat(3 * publishInterval)
+	app.AddWorker("scheduled-publishing", func(ctx context.Context) {
+		ticker := time.NewTicker(publishInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := productUsecase.PublishScheduledProducts(ctx); err != nil {
+					slog.ErrorContext(ctx, "failed to publish scheduled products", "error", err)
+				}
+				publishHeartbeat.Beat()
+			}
+		}
+	})
+	// Import jobs run one after another; the heartbeat allows for a large
+	// import between beats
+	const importPollInterval = 5 * time.Second
+	importHeartbeat := health.NewHeartbeat(15 * time.Minute)
+	app.AddWorker("product-imports", func(ctx context.Context) {
+		ticker := time.NewTicker(importPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for ctx.Err() == nil {
+					ran, err := importUsecase.RunNextImportJob(ctx)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to run product import", "error", err)
+					}
+					importHeartbeat.Beat()
+					if !ran || err != nil {
+						break
+					}
+				}
+			}
+		}
+	})
 
 	// Initialize HTTP server
-	router := gin.Default()
-	productHandler := delivery.NewProductHandler(productUsecase)
-	router.Use(gin.Recovery())
+	router := gin.New()
+	router.Use(tracing.GinMiddleware("product-service")...)
+	router.Use(middleware.RequestID(), middleware.AccessLog())
+	productHandler := delivery.NewProductHandler(productUsecase, shopAccessChecker)
+	categoryHandler := delivery.NewCategoryHandler(categoryUsecase)
+	importHandler := delivery.NewImportHandler(importUsecase)
+	router.Use(middleware.Recovery())
+	router.Use(middleware.GatewayIdentity(cfg.ServiceAuth.TokenSecret))
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
 	router.Use(shared.GinMetricsMiddleware())
 	shared.RegisterMetricsHandler(router)
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "OK",
-		})
-	})
-	// HTTP routes
-	api := router.Group("/api/v1")
-	jwtSecret := os.Getenv("JWT_SECRET")
-	api.Use(middleware.AuthMiddleware(jwtSecret))
-	{
-		api.GET("/products", productHandler.GetProducts)
-		api.GET("/products/:id", productHandler.GetProduct)
-		api.POST("/products", productHandler.CreateProduct)
-		api.PUT("/products/:id", productHandler.UpdateProduct)
-		api.DELETE("/products/:id", productHandler.DeleteProduct)
+	healthChecks := health.New("product-service", cfg.HealthCheckInterval)
+	healthChecks.AddReadinessCheck("database", health.DatabaseChecker(db))
+	healthChecks.AddReadinessCheck("warehouse-service", health.GRPCChecker(warehouseConn, ""))
+	healthChecks.AddLivenessCheck("scheduled-publishing", publishHeartbeat)
+	healthChecks.AddLivenessCheck("product-imports", importHeartbeat)
+	healthChecks.RegisterHTTP(router)
+	if cfg.Media.Backend == storage.BackendLocal {
+		router.Static(storage.LocalURLPath, cfg.Media.Dir)
 	}
+	// HTTP routes
+	jwtSecret := cfg.JWTSecret
+	registerRoutes(router, productHandler, categoryHandler, importHandler, shopAccessChecker, jwtSecret)
 
 	// Initialize gRPC server
-	productServer := grpcServer.NewProductServer(productUsecase)
+	productServer := grpcServer.NewProductServer(productUsecase, shopAccessChecker)
+	categoryServer := grpcServer.NewCategoryServer(categoryUsecase)
+	importServer := grpcServer.NewImportServer(importUsecase)
 
-	// Start gRPC server
-	go func() {
-		grpcPort := os.Getenv("PRODUCT_GRPC_PORT")
-		if grpcPort == "" {
-			grpcPort = "50052"
-		}
-
-		lis, err := net.Listen("tcp", grpcPort)
-		if err != nil {
-			log.Fatal("Failed to listen:", err)
-		}
+	serverOptions, err := middleware.GRPCServerOptions(middleware.GRPCServerConfig{
+		ServiceAuth: serviceAuth,
+		Allowlist: serviceauth.Allowlist{
+			proto.ProductService_UpdateStock_FullMethodName: {"order-service"},
+		},
+		JWTSecret: jwtSecret,
+		// Categories are shared by all shops
+		MethodPermissions: map[string]string{
+			proto.CategoryService_CreateCategory_FullMethodName: shared.PermCategoryManage,
+			proto.CategoryService_UpdateCategory_FullMethodName: shared.PermCategoryManage,
+			proto.CategoryService_DeleteCategory_FullMethodName: shared.PermCategoryManage,
+		},
+		// Product writes require product:write in the shop that owns the
+		// product. CreateProduct and the import RPCs name the shop directly.
+		ShopPermissions: map[string]middleware.ShopPermission{
+			proto.ProductService_CreateProduct_FullMethodName:        {Action: shared.PermProductWrite, Resolve: middleware.ShopIDFromRequest},
+			proto.ProductService_UpdateProduct_FullMethodName:        {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_DeleteProduct_FullMethodName:        {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_SetProductStatus_FullMethodName:     {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_SetProductCategories_FullMethodName: {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_SetProductOptions_FullMethodName:    {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_CreateVariant_FullMethodName:        {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_UpdateVariant_FullMethodName:        {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_DeleteVariant_FullMethodName:        {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_UploadProductImage_FullMethodName:   {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_ReorderProductImages_FullMethodName: {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductService_DeleteProductImage_FullMethodName:   {Action: shared.PermProductWrite, Resolve: productServer.ShopIDFromProduct},
+			proto.ProductImportService_ImportProducts_FullMethodName: {Action: shared.PermProductWrite, Resolve: middleware.ShopIDFromRequest},
+			proto.ProductImportService_GetImportJob_FullMethodName:   {Action: shared.PermProductWrite, Resolve: importServer.ShopIDFromImportJob},
+			proto.ProductImportService_ExportProducts_FullMethodName: {Action: shared.PermProductWrite, Resolve: middleware.ShopIDFromRequest},
+		},
+		ShopAccessChecker: shopAccessChecker,
+	})
+	if err != nil {
+		log.Fatal("Failed to configure gRPC server:", err)
+	}
+	// Image uploads and imports carry the file in the message
+	serverOptions = append(serverOptions, grpc.MaxRecvMsgSize(max(cfg.MaxImageSize, cfg.MaxImportSize)+1<<20))
+	grpcServer := grpc.NewServer(serverOptions...)
+	proto.RegisterProductServiceServer(grpcServer, productServer)
+	proto.RegisterCategoryServiceServer(grpcServer, categoryServer)
+	proto.RegisterProductImportServiceServer(grpcServer, importServer)
+	healthChecks.RegisterGRPC(grpcServer)
+	app.AddGRPCServer("Product", string(cfg.GRPCAddr), grpcServer)
 
-		grpcServer := grpc.NewServer()
-		proto.RegisterProductServiceServer(grpcServer, productServer)
+	// REST/JSON transcoding of the gRPC API below /api/v2. It calls the
+	// service's own gRPC server so the same interceptors apply.
+	selfConn, err := grpc_client.NewConnection(cfg.GRPCAddr.DialAddr(), serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to own gRPC server:", err)
+	}
+	defer selfConn.Close()
+	restMux := transcoding.NewServeMux()
+	if err := proto.RegisterProductServiceHandler(context.Background(), restMux, selfConn); err != nil {
+		log.Fatal("Failed to register REST transcoding:", err)
+	}
+	if err := proto.RegisterCategoryServiceHandler(context.Background(), restMux, selfConn); err != nil {
+		log.Fatal("Failed to register REST transcoding:", err)
+	}
+	if err := proto.RegisterProductImportServiceHandler(context.Background(), restMux, selfConn); err != nil {
+		log.Fatal("Failed to register REST transcoding:", err)
+	}
+	transcoding.Mount(router, restMux)
+	transcoding.RegisterSwagger(router, transcoding.Specs{
+		Title:     "Product Service API",
+		OpenAPIV2: proto.OpenAPIV2,
+		OpenAPIV3: proto.OpenAPIV3,
+	})
 
-		log.Printf("Product gRPC server started on port %s", grpcPort)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatal("Failed to serve gRPC:", err)
-		}
-	}()
+	app.AddHTTPServer("Product", string(cfg.HTTPAddr), router)
 
-	// Start HTTP server
-	httpPort := os.Getenv("PRODUCT_SERVICE_PORT")
-	if httpPort == "" {
-		httpPort = "8081"
-	}
+	app.AddWorker("health", healthChecks.Run)
+	app.OnShutdown(healthChecks.Shutdown)
 
-	log.Printf("Product HTTP server started on port %s", httpPort)
-	if err := router.Run(":" + httpPort); err != nil {
-		log.Fatal("Failed to start HTTP server:", err)
+	if err := app.Run(); err != nil {
+		log.Fatal("Product service stopped with error:", err)
 	}
 }
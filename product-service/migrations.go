@@ -0,0 +1,16 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var embeddedMigrations embed.FS
+
+// migrationFiles returns the SQL migrations of product-service, see shared/migrate
+func migrationFiles() fs.FS {
+	// Sub only fails for invalid paths
+	files, _ := fs.Sub(embeddedMigrations, "migrations")
+	return files
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS products (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    description TEXT,
+    price DECIMAL,
+    shop_id BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_products_shop_id ON products (shop_id);
@@ -0,0 +1,38 @@
+-- Options name the ways a product varies (size, colour) and list their values
+CREATE TABLE IF NOT EXISTS product_options (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    "values" JSONB NOT NULL DEFAULT '[]'
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_options_product_id_name ON product_options (product_id, name);
+
+-- Variants are the unit of stock. shop_id is copied from the product so SKUs
+-- can be unique per shop.
+CREATE TABLE IF NOT EXISTS product_variants (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    shop_id BIGINT NOT NULL,
+    sku TEXT NOT NULL,
+    barcode TEXT NOT NULL DEFAULT '',
+    price DECIMAL,
+    weight_grams INTEGER,
+    options JSONB NOT NULL DEFAULT '{}',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants (product_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_shop_id_sku ON product_variants (shop_id, sku);
+CREATE INDEX IF NOT EXISTS idx_product_variants_barcode ON product_variants (barcode) WHERE barcode <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_default ON product_variants (product_id) WHERE is_default;
+
+-- Every existing product gets a default variant with the product's ID, so
+-- the stock and order items recorded by product ID keep pointing at it
+INSERT INTO product_variants (id, product_id, shop_id, sku, is_default, created_at, updated_at)
+SELECT id, id, COALESCE(shop_id, 0), 'P' || id, TRUE, NOW(), NOW()
+FROM products
+WHERE NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id);
+
+SELECT setval(pg_get_serial_sequence('product_variants', 'id'), COALESCE((SELECT MAX(id) FROM product_variants), 0) + 1, false);
@@ -0,0 +1,9 @@
+-- Products already in the catalog stay visible; new ones start as drafts
+ALTER TABLE products ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published';
+ALTER TABLE products ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_products_status ON products (status);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
+-- Drafts waiting for scheduled publishing
+CREATE INDEX IF NOT EXISTS idx_products_publish_at ON products (publish_at) WHERE status = 'draft';
@@ -0,0 +1 @@
+ALTER TABLE product_import_jobs DROP COLUMN IF EXISTS attempt;
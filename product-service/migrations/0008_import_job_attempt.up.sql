@@ -0,0 +1,3 @@
+-- Raised by every claim of an import job, so that a worker that lost its job
+-- to another one stops saving it
+ALTER TABLE product_import_jobs ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 0;
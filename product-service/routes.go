@@ -0,0 +1,71 @@
+package main
+
+import (
+	delivery "github.com/evrintobing17/ecommerce-system/product-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// registerRoutes adds the HTTP API below /api/v1 to router
+func registerRoutes(router gin.IRouter, productHandler *delivery.ProductHandler, categoryHandler *delivery.CategoryHandler, importHandler *delivery.ImportHandler, shopAccessChecker middleware.ShopAccessChecker, jwtSecret string) {
+	api := router.Group("/api/v1")
+	api.Use(middleware.AuthMiddleware(jwtSecret))
+	{
+		api.GET("/products", productHandler.GetProducts)
+		api.GET("/products/search", productHandler.SearchProducts)
+		api.POST("/products/imports",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, middleware.ShopIDFromQuery("shop_id")),
+			importHandler.ImportProducts)
+		api.GET("/products/imports/:job_id",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, importHandler.ShopIDFromImportJob),
+			importHandler.GetImportJob)
+		api.GET("/products/export",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, middleware.ShopIDFromQuery("shop_id")),
+			importHandler.ExportProducts)
+		api.GET("/products/:id", productHandler.GetProduct)
+		api.POST("/products",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, middleware.ShopIDFromJSON("shop_id")),
+			productHandler.CreateProduct)
+		api.PUT("/products/:id",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.UpdateProduct)
+		api.DELETE("/products/:id",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.DeleteProduct)
+		api.PUT("/products/:id/status",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.SetProductStatus)
+		api.PUT("/products/:id/categories",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.SetProductCategories)
+		api.PUT("/products/:id/options",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.SetProductOptions)
+		api.GET("/products/:id/variants/:variant_id", productHandler.GetVariant)
+		api.POST("/products/:id/variants",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.CreateVariant)
+		api.PUT("/products/:id/variants/:variant_id",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.UpdateVariant)
+		api.DELETE("/products/:id/variants/:variant_id",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.DeleteVariant)
+		api.POST("/products/:id/images",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.UploadImage)
+		api.PUT("/products/:id/images/order",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.ReorderImages)
+		api.DELETE("/products/:id/images/:image_id",
+			middleware.RequireShopPermission(shared.PermProductWrite, shopAccessChecker, productHandler.ShopIDFromProduct),
+			productHandler.DeleteImage)
+
+		api.GET("/categories", categoryHandler.GetCategoryTree)
+		api.GET("/categories/:id", categoryHandler.GetCategory)
+		api.POST("/categories", middleware.RequirePermission(shared.PermCategoryManage), categoryHandler.CreateCategory)
+		api.PUT("/categories/:id", middleware.RequirePermission(shared.PermCategoryManage), categoryHandler.UpdateCategory)
+		api.DELETE("/categories/:id", middleware.RequirePermission(shared.PermCategoryManage), categoryHandler.DeleteCategory)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/product-service/app"
+	delivery "github.com/evrintobing17/ecommerce-system/product-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/product-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse/responsetest"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "test-secret"
+
+// productUsecaseStub serves product 1, published, and product 2, a draft,
+// both of shop 1, and product 3 of shop 2. Product 1 has variant 1 and
+// image 1. Deleting product 1 fails unexpectedly.
+type productUsecaseStub struct {
+	app.ProductUsecase
+}
+
+func (u *productUsecaseStub) GetProducts(ctx context.Context, filter models.ProductFilter, page, limit int) ([]*models.Product, int64, error) {
+	product, _ := u.GetProduct(ctx, 1)
+	return []*models.Product{product}, 1, nil
+}
+
+func (u *productUsecaseStub) GetProduct(ctx context.Context, id int) (*models.Product, error) {
+	switch id {
+	case 1:
+		return &models.Product{ID: id, Name: "Shirt", ShopID: 1, Status: models.ProductStatusPublished}, nil
+	case 2:
+		return &models.Product{ID: id, Name: "Hat", ShopID: 1, Status: models.ProductStatusDraft}, nil
+	case 3:
+		return &models.Product{ID: id, Name: "Shoe", ShopID: 2, Status: models.ProductStatusPublished}, nil
+	}
+	return nil, models.ErrProductNotFound
+}
+
+func (u *productUsecaseStub) SearchProducts(ctx context.Context, query models.SearchQuery) (*models.SearchResult, error) {
+	if query.Sort != "" && query.Sort != models.SortRelevance {
+		return nil, models.ErrInvalidSearchSort
+	}
+	products, total, _ := u.GetProducts(ctx, models.ProductFilter{}, query.Page, query.Limit)
+	return &models.SearchResult{Products: products, Total: total, Page: query.Page, Limit: query.Limit}, nil
+}
+
+func (u *productUsecaseStub) CreateProduct(ctx context.Context, name, description string, price float64, stock int32, shopID int, attributes models.Attributes, sku string) (*models.Product, error) {
+	return &models.Product{ID: 4, Name: name, Description: description, Price: price, ShopID: shopID, Status: models.ProductStatusDraft}, nil
+}
+
+func (u *productUsecaseStub) UpdateProduct(ctx context.Context, product *models.Product) error {
+	return nil
+}
+
+func (u *productUsecaseStub) DeleteProduct(ctx context.Context, id int) error {
+	return errors.New("connection reset")
+}
+
+func (u *productUsecaseStub) SetProductStatus(ctx context.Context, productID int, status models.ProductStatus, publishAt *time.Time) (*models.Product, error) {
+	if !status.Valid() {
+		return nil, models.ErrInvalidProductStatus
+	}
+	product, err := u.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	product.Status = status
+	return product, nil
+}
+
+func (u *productUsecaseStub) SetProductCategories(ctx context.Context, productID int, categoryIDs []int) error {
+	for _, id := range categoryIDs {
+		if id != 1 {
+			return models.ErrCategoryNotFound
+		}
+	}
+	return nil
+}
+
+func (u *productUsecaseStub) SetProductOptions(ctx context.Context, productID int, options []*models.ProductOption) error {
+	return nil
+}
+
+func (u *productUsecaseStub) GetVariant(ctx context.Context, productID, variantID int) (*models.Variant, error) {
+	if productID != 1 || variantID != 1 {
+		return nil, models.ErrVariantNotFound
+	}
+	return &models.Variant{ID: 1, ProductID: 1, SKU: "SHIRT-1", IsDefault: true}, nil
+}
+
+func (u *productUsecaseStub) CreateVariant(ctx context.Context, variant *models.Variant) (*models.Variant, error) {
+	if variant.SKU == "SHIRT-1" {
+		return nil, models.ErrSKUTaken
+	}
+	variant.ID = 2
+	return variant, nil
+}
+
+func (u *productUsecaseStub) UpdateVariant(ctx context.Context, variant *models.Variant) error {
+	return nil
+}
+
+func (u *productUsecaseStub) DeleteVariant(ctx context.Context, productID, variantID int) error {
+	return models.ErrDefaultVariant
+}
+
+func (u *productUsecaseStub) UploadImage(ctx context.Context, productID int, r io.Reader) (*models.ProductImage, error) {
+	return &models.ProductImage{ID: 2, ProductID: productID, ContentType: "image/png"}, nil
+}
+
+func (u *productUsecaseStub) ReorderImages(ctx context.Context, productID int, imageIDs []int) error {
+	if len(imageIDs) != 1 || imageIDs[0] != 1 {
+		return models.ErrInvalidImageOrder
+	}
+	return nil
+}
+
+func (u *productUsecaseStub) DeleteImage(ctx context.Context, productID, imageID int) error {
+	if imageID != 1 {
+		return models.ErrImageNotFound
+	}
+	return nil
+}
+
+// categoryUsecaseStub serves category 1, a root without children
+type categoryUsecaseStub struct {
+	app.CategoryUsecase
+}
+
+func (u *categoryUsecaseStub) category() *models.Category {
+	return &models.Category{ID: 1, Name: "Clothes", Slug: "clothes"}
+}
+
+func (u *categoryUsecaseStub) GetCategoryTree(ctx context.Context) ([]*models.CategoryNode, error) {
+	return []*models.CategoryNode{{Category: u.category(), Children: []*models.CategoryNode{}}}, nil
+}
+
+func (u *categoryUsecaseStub) GetCategory(ctx context.Context, id int) (*models.CategoryDetail, error) {
+	if id != 1 {
+		return nil, models.ErrCategoryNotFound
+	}
+	return &models.CategoryDetail{Category: u.category(), Breadcrumbs: []*models.Category{u.category()}, Children: []*models.CategoryNode{}}, nil
+}
+
+func (u *categoryUsecaseStub) CreateCategory(ctx context.Context, name, slug string, parentID *int) (*models.Category, error) {
+	if slug == "clothes" {
+		return nil, models.ErrCategorySlugTaken.With("slug", slug)
+	}
+	return &models.Category{ID: 2, Name: name, Slug: slug, ParentID: parentID}, nil
+}
+
+func (u *categoryUsecaseStub) UpdateCategory(ctx context.Context, id int, name, slug string, parentID *int) (*models.Category, error) {
+	if parentID != nil && *parentID == id {
+		return nil, models.ErrInvalidCategoryParent
+	}
+	return u.category(), nil
+}
+
+func (u *categoryUsecaseStub) DeleteCategory(ctx context.Context, id int) error {
+	return nil
+}
+
+// importUsecaseStub serves import job 1 of shop 1 and job 2 of shop 2
+type importUsecaseStub struct {
+	app.ImportUsecase
+}
+
+func (u *importUsecaseStub) ImportProducts(ctx context.Context, shopID int, format models.ImportFormat, r io.Reader) (*models.ImportJob, error) {
+	if !format.Valid() {
+		return nil, models.ErrInvalidImportFormat
+	}
+	return &models.ImportJob{ID: 3, ShopID: shopID, Format: format, Status: models.ImportJobPending}, nil
+}
+
+func (u *importUsecaseStub) GetImportJob(ctx context.Context, id int) (*models.ImportJob, error) {
+	switch id {
+	case 1:
+		return &models.ImportJob{ID: id, ShopID: 1, Format: models.ImportFormatCSV, Status: models.ImportJobPending}, nil
+	case 2:
+		return &models.ImportJob{ID: id, ShopID: 2, Format: models.ImportFormatCSV, Status: models.ImportJobPending}, nil
+	}
+	return nil, models.ErrImportJobNotFound
+}
+
+func (u *importUsecaseStub) ExportProducts(ctx context.Context, shopID int, format models.ImportFormat, w io.Writer) error {
+	if !format.Valid() {
+		return models.ErrInvalidImportFormat
+	}
+	_, err := io.WriteString(w, "sku,name\n")
+	return err
+}
+
+// shopAccessStub grants every action on shop 1
+type shopAccessStub struct{}
+
+func (shopAccessStub) CanAccessShop(ctx context.Context, claims *shared.Claims, shopID int, action string) (bool, error) {
+	return shopID == 1, nil
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
+	registerRoutes(router,
+		delivery.NewProductHandler(&productUsecaseStub{}, shopAccessStub{}),
+		delivery.NewCategoryHandler(&categoryUsecaseStub{}),
+		delivery.NewImportHandler(&importUsecaseStub{}),
+		shopAccessStub{}, testJWTSecret)
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	seller := responsetest.Token(t, shared.TokenSubject{UserID: 1, Role: shared.RoleShopOwner}, testJWTSecret)
+	admin := responsetest.Token(t, shared.TokenSubject{UserID: 2, Role: shared.RoleAdmin}, testJWTSecret)
+	imageBody, imageType := responsetest.MultipartFile(t, "image", "shirt.png", []byte("\x89PNG\r\n\x1a\n"))
+	importBody, importType := responsetest.MultipartFile(t, "file", "products.csv", []byte("sku,name\nSHIRT-1,Shirt\n"))
+	unknownImportBody, unknownImportType := responsetest.MultipartFile(t, "file", "products.xml", []byte("<products/>"))
+
+	responsetest.Run(t, newTestRouter(), []responsetest.Case{
+		{Name: "unknown route", Method: http.MethodGet, Path: "/api/v1/nothing", Status: http.StatusNotFound, Code: "route_not_found"},
+		{Name: "missing token", Method: http.MethodGet, Path: "/api/v1/products", Status: http.StatusUnauthorized, Code: "authorization_required"},
+		{Name: "invalid token", Method: http.MethodGet, Path: "/api/v1/products", Token: "invalid", Status: http.StatusUnauthorized, Code: "invalid_token"},
+
+		{Name: "list products", Method: http.MethodGet, Path: "/api/v1/products", Token: seller, Status: http.StatusOK, Meta: true},
+		{Name: "list drafts of own shop", Method: http.MethodGet, Path: "/api/v1/products?status=draft&shop_id=1", Token: seller, Status: http.StatusOK, Meta: true},
+		{Name: "list drafts of another shop", Method: http.MethodGet, Path: "/api/v1/products?status=draft&shop_id=2", Token: seller, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "search products", Method: http.MethodGet, Path: "/api/v1/products/search?q=shirt", Token: seller, Status: http.StatusOK, Meta: true},
+		{Name: "search with invalid price", Method: http.MethodGet, Path: "/api/v1/products/search?min_price=-1", Token: seller, Status: http.StatusBadRequest, Code: "invalid_parameter"},
+		{Name: "search with invalid sort", Method: http.MethodGet, Path: "/api/v1/products/search?sort=name", Token: seller, Status: http.StatusBadRequest, Code: "invalid_search_sort"},
+
+		{Name: "get product", Method: http.MethodGet, Path: "/api/v1/products/1", Token: seller, Status: http.StatusOK},
+		{Name: "get draft of own shop", Method: http.MethodGet, Path: "/api/v1/products/2", Token: seller, Status: http.StatusOK},
+		{Name: "get missing product", Method: http.MethodGet, Path: "/api/v1/products/9", Token: seller, Status: http.StatusNotFound, Code: "product_not_found"},
+
+		{Name: "create product", Method: http.MethodPost, Path: "/api/v1/products", Token: seller, Body: `{"name":"Shirt","description":"Cotton","price":10,"stock":5,"shop_id":1}`, Status: http.StatusCreated},
+		{Name: "create product without fields", Method: http.MethodPost, Path: "/api/v1/products", Token: seller, Body: `{"shop_id":1,"price":-1}`, Status: http.StatusBadRequest, Fields: []string{"name", "description", "price", "stock"}},
+		{Name: "create product in another shop", Method: http.MethodPost, Path: "/api/v1/products", Token: seller, Body: `{"name":"Shirt","description":"Cotton","price":10,"stock":5,"shop_id":2}`, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "update product", Method: http.MethodPut, Path: "/api/v1/products/1", Token: seller, Body: `{"name":"T-shirt"}`, Status: http.StatusOK},
+		{Name: "update product with wrong type", Method: http.MethodPut, Path: "/api/v1/products/1", Token: seller, Body: `{"price":"ten"}`, Status: http.StatusBadRequest, Code: "invalid_type", Fields: []string{"price"}},
+		{Name: "update missing product", Method: http.MethodPut, Path: "/api/v1/products/9", Token: seller, Body: `{"name":"T-shirt"}`, Status: http.StatusNotFound, Code: "resource_not_found"},
+		{Name: "update product of another shop", Method: http.MethodPut, Path: "/api/v1/products/3", Token: seller, Body: `{"name":"T-shirt"}`, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "delete product failing", Method: http.MethodDelete, Path: "/api/v1/products/1", Token: seller, Status: http.StatusInternalServerError, Code: "internal"},
+
+		{Name: "publish product", Method: http.MethodPut, Path: "/api/v1/products/2/status", Token: seller, Body: `{"status":"published"}`, Status: http.StatusOK},
+		{Name: "set unknown status", Method: http.MethodPut, Path: "/api/v1/products/2/status", Token: seller, Body: `{"status":"sold"}`, Status: http.StatusBadRequest, Code: "invalid_product_status"},
+		{Name: "set status without status", Method: http.MethodPut, Path: "/api/v1/products/2/status", Token: seller, Body: `{}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"status"}},
+		{Name: "set categories", Method: http.MethodPut, Path: "/api/v1/products/1/categories", Token: seller, Body: `{"category_ids":[1]}`, Status: http.StatusOK},
+		{Name: "set missing category", Method: http.MethodPut, Path: "/api/v1/products/1/categories", Token: seller, Body: `{"category_ids":[9]}`, Status: http.StatusNotFound, Code: "category_not_found"},
+		{Name: "set options", Method: http.MethodPut, Path: "/api/v1/products/1/options", Token: seller, Body: `{"options":[{"name":"size","values":["S","M"]}]}`, Status: http.StatusOK},
+		{Name: "set options without options", Method: http.MethodPut, Path: "/api/v1/products/1/options", Token: seller, Body: `{}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"options"}},
+
+		{Name: "get variant", Method: http.MethodGet, Path: "/api/v1/products/1/variants/1", Token: seller, Status: http.StatusOK},
+		{Name: "get missing variant", Method: http.MethodGet, Path: "/api/v1/products/1/variants/9", Token: seller, Status: http.StatusNotFound, Code: "variant_not_found"},
+		{Name: "create variant", Method: http.MethodPost, Path: "/api/v1/products/1/variants", Token: seller, Body: `{"sku":"SHIRT-2"}`, Status: http.StatusCreated},
+		{Name: "create variant with taken SKU", Method: http.MethodPost, Path: "/api/v1/products/1/variants", Token: seller, Body: `{"sku":"SHIRT-1"}`, Status: http.StatusConflict, Code: "sku_taken"},
+		{Name: "create variant with negative price", Method: http.MethodPost, Path: "/api/v1/products/1/variants", Token: seller, Body: `{"sku":"SHIRT-2","price":-1}`, Status: http.StatusBadRequest, Code: "min", Fields: []string{"price"}},
+		{Name: "update variant", Method: http.MethodPut, Path: "/api/v1/products/1/variants/1", Token: seller, Body: `{"barcode":"123"}`, Status: http.StatusOK},
+		{Name: "delete default variant", Method: http.MethodDelete, Path: "/api/v1/products/1/variants/1", Token: seller, Status: http.StatusUnprocessableEntity, Code: "default_variant"},
+
+		{Name: "upload image", Method: http.MethodPost, Path: "/api/v1/products/1/images", Token: seller, Body: imageBody, ContentType: imageType, Status: http.StatusCreated},
+		{Name: "upload without image", Method: http.MethodPost, Path: "/api/v1/products/1/images", Token: seller, Status: http.StatusBadRequest, Code: "image_required"},
+		{Name: "reorder images", Method: http.MethodPut, Path: "/api/v1/products/1/images/order", Token: seller, Body: `{"image_ids":[1]}`, Status: http.StatusOK},
+		{Name: "reorder unknown images", Method: http.MethodPut, Path: "/api/v1/products/1/images/order", Token: seller, Body: `{"image_ids":[1,2]}`, Status: http.StatusBadRequest, Code: "invalid_image_order"},
+		{Name: "delete image", Method: http.MethodDelete, Path: "/api/v1/products/1/images/1", Token: seller, Status: http.StatusOK},
+		{Name: "delete missing image", Method: http.MethodDelete, Path: "/api/v1/products/1/images/9", Token: seller, Status: http.StatusNotFound, Code: "image_not_found"},
+
+		{Name: "import products", Method: http.MethodPost, Path: "/api/v1/products/imports?shop_id=1", Token: seller, Body: importBody, ContentType: importType, Status: http.StatusCreated},
+		{Name: "import unknown format", Method: http.MethodPost, Path: "/api/v1/products/imports?shop_id=1", Token: seller, Body: unknownImportBody, ContentType: unknownImportType, Status: http.StatusBadRequest, Code: "invalid_import_format"},
+		{Name: "import without file", Method: http.MethodPost, Path: "/api/v1/products/imports?shop_id=1", Token: seller, Status: http.StatusBadRequest, Code: "invalid_import_file"},
+		{Name: "import into another shop", Method: http.MethodPost, Path: "/api/v1/products/imports?shop_id=2", Token: seller, Body: importBody, ContentType: importType, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "get import job", Method: http.MethodGet, Path: "/api/v1/products/imports/1", Token: seller, Status: http.StatusOK},
+		{Name: "get import job of another shop", Method: http.MethodGet, Path: "/api/v1/products/imports/2", Token: seller, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "get missing import job", Method: http.MethodGet, Path: "/api/v1/products/imports/9", Token: seller, Status: http.StatusNotFound, Code: "resource_not_found"},
+		{Name: "export unknown format", Method: http.MethodGet, Path: "/api/v1/products/export?shop_id=1&format=xml", Token: seller, Status: http.StatusBadRequest, Code: "invalid_import_format"},
+
+		{Name: "get category tree", Method: http.MethodGet, Path: "/api/v1/categories", Token: seller, Status: http.StatusOK},
+		{Name: "get category", Method: http.MethodGet, Path: "/api/v1/categories/1", Token: seller, Status: http.StatusOK},
+		{Name: "get missing category", Method: http.MethodGet, Path: "/api/v1/categories/9", Token: seller, Status: http.StatusNotFound, Code: "category_not_found"},
+		{Name: "create category", Method: http.MethodPost, Path: "/api/v1/categories", Token: admin, Body: `{"name":"Shoes"}`, Status: http.StatusCreated},
+		{Name: "create category without name", Method: http.MethodPost, Path: "/api/v1/categories", Token: admin, Body: `{"slug":"shoes"}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"name"}},
+		{Name: "create category with taken slug", Method: http.MethodPost, Path: "/api/v1/categories", Token: admin, Body: `{"name":"Clothes","slug":"clothes"}`, Status: http.StatusConflict, Code: "category_slug_taken"},
+		{Name: "create category without permission", Method: http.MethodPost, Path: "/api/v1/categories", Token: seller, Body: `{"name":"Shoes"}`, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "move category below itself", Method: http.MethodPut, Path: "/api/v1/categories/1", Token: admin, Body: `{"parent_id":1}`, Status: http.StatusBadRequest, Code: "invalid_category_parent"},
+		{Name: "delete category", Method: http.MethodDelete, Path: "/api/v1/categories/1", Token: admin, Status: http.StatusOK},
+	})
+}
+
+// TestExportProducts checks the export, which answers with the file instead
+// of the envelope
+func TestExportProducts(t *testing.T) {
+	seller := responsetest.Token(t, shared.TokenSubject{UserID: 1, Role: shared.RoleShopOwner}, testJWTSecret)
+
+	rec := responsetest.Serve(newTestRouter(), responsetest.Case{Method: http.MethodGet, Path: "/api/v1/products/export?shop_id=1", Token: seller})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="products-1.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := rec.Body.String(); got != "sku,name\n" {
+		t.Errorf("body = %q", got)
+	}
+}
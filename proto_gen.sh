@@ -1,18 +1,33 @@
 #!/bin/bash
 
-# Directory where .proto files are located
-PROTO_DIR="./shared/proto"
+# Proto files live in ./shared/proto/<service>/<service>.proto and are
+# compiled relative to ./shared, so the generated code is placed next to them
+PROTO_ROOT="./shared"
 
-# Directory where generated files will be placed
-OUT_DIR="./shared/gen"
+# google/api/annotations.proto and http.proto, needed for the REST mappings,
+# relative to PROTO_ROOT
+GOOGLEAPIS="third_party/googleapis"
 
-# Create output directory if it doesn't exist
-mkdir -p $OUT_DIR
+# Required plugins:
+#   go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.36.9
+#   go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.5.1
+#   go install github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-grpc-gateway@v2.27.2
+#   go install github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2@v2.27.2
+#   go install github.com/google/gnostic/cmd/protoc-gen-openapi@v0.7.1
 
-# Generate Go code for all services
-echo "Generating Go code from proto files..."
-protoc --go_out=$OUT_DIR --go_opt=paths=source_relative \
-    --go-grpc_out=$OUT_DIR --go-grpc_opt=paths=source_relative \
-    -I=$PROTO_DIR $PROTO_DIR/*.proto
+cd "$PROTO_ROOT" || exit 1
 
-echo "Proto files compiled successfully!"
\ No newline at end of file
+# Generate Go code, grpc-gateway handlers and OpenAPI v2/v3 specs for all services
+echo "Generating code from proto files..."
+for proto in proto/*/*.proto; do
+    dir=$(dirname "$proto")
+    protoc -I . -I "$GOOGLEAPIS" \
+        --go_out=. --go_opt=paths=source_relative \
+        --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+        --grpc-gateway_out=. --grpc-gateway_opt=paths=source_relative \
+        --openapiv2_out=. --openapiv2_opt=json_names_for_fields=false \
+        --openapi_out="$dir" --openapi_opt=naming=proto \
+        "$proto" || exit 1
+done
+
+echo "Proto files compiled successfully!"
@@ -0,0 +1,305 @@
+// Package apperror is the error model shared by all services. Usecases and
+// repositories return *Error values carrying a Kind, which decides the HTTP
+// status and gRPC code, and a machine-readable Code such as
+// "order_not_found" that clients can rely on instead of parsing messages.
+//
+// Errors cross service boundaries as gRPC statuses with an ErrorInfo detail
+// holding the code; FromGRPC turns them back into *Error on the client side,
+// so an error raised in the warehouse service reaches the HTTP caller of the
+// order service with its kind and code intact.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain is the ErrorInfo domain of errors raised by the services
+const Domain = "ecommerce-system"
+
+// Kind classifies an error by what the caller can do about it
+type Kind int
+
+const (
+	// Internal is an unexpected failure. Its message is not shown to clients.
+	Internal Kind = iota
+	// NotFound means the requested resource does not exist
+	NotFound
+	// InvalidArgument means the request is malformed regardless of state
+	InvalidArgument
+	// FailedPrecondition means the request is valid but the resource is not
+	// in a state that allows it, e.g. cancelling a paid order
+	FailedPrecondition
+	// Conflict means the resource already exists or was changed concurrently
+	Conflict
+	// Unauthenticated means the caller's credentials are missing or invalid
+	Unauthenticated
+	// PermissionDenied means the caller may not perform the operation
+	PermissionDenied
+	// ResourceExhausted means the caller is being throttled
+	ResourceExhausted
+	// Unavailable means a dependency is down and the call may be retried
+	Unavailable
+)
+
+var kindNames = map[Kind]string{
+	Internal:           "internal",
+	NotFound:           "not_found",
+	InvalidArgument:    "invalid_argument",
+	FailedPrecondition: "failed_precondition",
+	Conflict:           "conflict",
+	Unauthenticated:    "unauthenticated",
+	PermissionDenied:   "permission_denied",
+	ResourceExhausted:  "resource_exhausted",
+	Unavailable:        "unavailable",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// HTTPStatus returns the HTTP status code for errors of kind k
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case InvalidArgument:
+		return http.StatusBadRequest
+	case FailedPrecondition:
+		return http.StatusUnprocessableEntity
+	case Conflict:
+		return http.StatusConflict
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case PermissionDenied:
+		return http.StatusForbidden
+	case ResourceExhausted:
+		return http.StatusTooManyRequests
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode returns the gRPC status code for errors of kind k
+func (k Kind) GRPCCode() codes.Code {
+	switch k {
+	case NotFound:
+		return codes.NotFound
+	case InvalidArgument:
+		return codes.InvalidArgument
+	case FailedPrecondition:
+		return codes.FailedPrecondition
+	case Conflict:
+		return codes.AlreadyExists
+	case Unauthenticated:
+		return codes.Unauthenticated
+	case PermissionDenied:
+		return codes.PermissionDenied
+	case ResourceExhausted:
+		return codes.ResourceExhausted
+	case Unavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// kindFromGRPC is the inverse of Kind.GRPCCode
+func kindFromGRPC(code codes.Code) Kind {
+	switch code {
+	case codes.NotFound:
+		return NotFound
+	case codes.InvalidArgument, codes.OutOfRange:
+		return InvalidArgument
+	case codes.FailedPrecondition:
+		return FailedPrecondition
+	case codes.AlreadyExists, codes.Aborted:
+		return Conflict
+	case codes.Unauthenticated:
+		return Unauthenticated
+	case codes.PermissionDenied:
+		return PermissionDenied
+	case codes.ResourceExhausted:
+		return ResourceExhausted
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return Unavailable
+	default:
+		return Internal
+	}
+}
+
+// Error is an error with a kind and a machine-readable code
+type Error struct {
+	Kind Kind
+	// Code identifies the error, in snake_case, e.g. "insufficient_stock"
+	Code string
+	// Message is a human-readable description safe to show to clients
+	Message string
+	// Metadata adds details such as the ID of the missing resource
+	Metadata map[string]string
+	// Err is the underlying cause, if any. It is not shown to clients.
+	Err error
+}
+
+// New returns an error of the given kind
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap returns an error of the given kind caused by err
+func Wrap(err error, kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+// NewNotFound returns a NotFound error
+func NewNotFound(code, message string) *Error { return New(NotFound, code, message) }
+
+// NewInvalidArgument returns an InvalidArgument error
+func NewInvalidArgument(code, message string) *Error { return New(InvalidArgument, code, message) }
+
+// NewFailedPrecondition returns a FailedPrecondition error
+func NewFailedPrecondition(code, message string) *Error {
+	return New(FailedPrecondition, code, message)
+}
+
+// NewConflict returns a Conflict error
+func NewConflict(code, message string) *Error { return New(Conflict, code, message) }
+
+// NewUnauthenticated returns an Unauthenticated error
+func NewUnauthenticated(code, message string) *Error { return New(Unauthenticated, code, message) }
+
+// NewPermissionDenied returns a PermissionDenied error
+func NewPermissionDenied(code, message string) *Error { return New(PermissionDenied, code, message) }
+
+// NewResourceExhausted returns a ResourceExhausted error
+func NewResourceExhausted(code, message string) *Error { return New(ResourceExhausted, code, message) }
+
+// NewUnavailable returns an Unavailable error
+func NewUnavailable(code, message string) *Error { return New(Unavailable, code, message) }
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same code, so that
+// errors.Is matches a sentinel even after With or Wrap added details
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// With returns a copy of e with a metadata entry added
+func (e *Error) With(key, value string) *Error {
+	copied := *e
+	copied.Metadata = make(map[string]string, len(e.Metadata)+1)
+	for k, v := range e.Metadata {
+		copied.Metadata[k] = v
+	}
+	copied.Metadata[key] = value
+	return &copied
+}
+
+// Wrap returns a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+// GRPCStatus converts e to a gRPC status whose ErrorInfo detail carries the
+// code and metadata. gRPC servers call it for returned errors.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Kind.GRPCCode(), e.Message)
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   e.Code,
+		Domain:   Domain,
+		Metadata: e.Metadata,
+	})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// As returns the *Error in err's chain, if any
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of err, Internal when err is not an *Error
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return Internal
+}
+
+// IsKind reports whether err is an *Error of the given kind
+func IsKind(err error, kind Kind) bool {
+	appErr, ok := As(err)
+	return ok && appErr.Kind == kind
+}
+
+// ToGRPC converts err to a gRPC status error. Errors that are neither an
+// *Error nor a gRPC status become Internal with a generic message so that
+// internal details do not leak to callers.
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := As(err); ok {
+		return appErr.GRPCStatus().Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// FromGRPC converts a gRPC status error returned by another service into an
+// *Error, keeping the code from its ErrorInfo detail. Statuses without one
+// get a code derived from their kind.
+func FromGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := As(err); ok {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	kind := kindFromGRPC(st.Code())
+	appErr := &Error{Kind: kind, Code: kind.String(), Message: st.Message()}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+			appErr.Code = info.Reason
+			appErr.Metadata = info.Metadata
+			break
+		}
+	}
+	return appErr
+}
@@ -0,0 +1,127 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKindMapping(t *testing.T) {
+	tests := []struct {
+		kind       Kind
+		wantStatus int
+		wantCode   codes.Code
+	}{
+		{Internal, http.StatusInternalServerError, codes.Internal},
+		{NotFound, http.StatusNotFound, codes.NotFound},
+		{InvalidArgument, http.StatusBadRequest, codes.InvalidArgument},
+		{FailedPrecondition, http.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{Conflict, http.StatusConflict, codes.AlreadyExists},
+		{Unauthenticated, http.StatusUnauthorized, codes.Unauthenticated},
+		{PermissionDenied, http.StatusForbidden, codes.PermissionDenied},
+		{ResourceExhausted, http.StatusTooManyRequests, codes.ResourceExhausted},
+		{Unavailable, http.StatusServiceUnavailable, codes.Unavailable},
+		{Kind(99), http.StatusInternalServerError, codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			if got := tt.kind.HTTPStatus(); got != tt.wantStatus {
+				t.Errorf("HTTPStatus = %d, want %d", got, tt.wantStatus)
+			}
+			if got := tt.kind.GRPCCode(); got != tt.wantCode {
+				t.Errorf("GRPCCode = %s, want %s", got, tt.wantCode)
+			}
+			// Every known kind survives the trip through a gRPC code
+			if _, known := kindNames[tt.kind]; known {
+				if got := kindFromGRPC(tt.wantCode); got != tt.kind {
+					t.Errorf("kindFromGRPC(%s) = %s, want %s", tt.wantCode, got, tt.kind)
+				}
+			}
+		})
+	}
+}
+
+func TestKindFromGRPCAliases(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want Kind
+	}{
+		{codes.OutOfRange, InvalidArgument},
+		{codes.Aborted, Conflict},
+		{codes.DeadlineExceeded, Unavailable},
+		{codes.Unknown, Internal},
+		{codes.Unimplemented, Internal},
+	}
+
+	for _, tt := range tests {
+		if got := kindFromGRPC(tt.code); got != tt.want {
+			t.Errorf("kindFromGRPC(%s) = %s, want %s", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	sent := NewFailedPrecondition("insufficient_stock", "not enough stock").
+		With("product_id", "42").
+		Wrap(errors.New("available 1"))
+
+	wire := ToGRPC(fmt.Errorf("reserving: %w", sent))
+	st, _ := status.FromError(wire)
+	if st.Code() != codes.FailedPrecondition || st.Message() != "not enough stock" {
+		t.Fatalf("status = %s %q, want FailedPrecondition without the cause", st.Code(), st.Message())
+	}
+
+	received, ok := As(FromGRPC(wire))
+	if !ok {
+		t.Fatalf("FromGRPC returned %T, want *Error", FromGRPC(wire))
+	}
+	if received.Kind != FailedPrecondition || received.Code != "insufficient_stock" || received.Metadata["product_id"] != "42" {
+		t.Errorf("received %+v, want the sent kind, code and metadata", received)
+	}
+	if !errors.Is(received, New(Internal, "insufficient_stock", "")) {
+		t.Error("errors.Is does not match the sentinel by code")
+	}
+}
+
+func TestToGRPC(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{"app error", NewNotFound("order_not_found", "order not found"), codes.NotFound, "order not found"},
+		{"status", status.Error(codes.Unavailable, "warehouse down"), codes.Unavailable, "warehouse down"},
+		{"plain error", errors.New("pq: connection refused"), codes.Internal, "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, _ := status.FromError(ToGRPC(tt.err))
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMessage {
+				t.Errorf("status = %s %q, want %s %q", st.Code(), st.Message(), tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+	if ToGRPC(nil) != nil {
+		t.Error("ToGRPC(nil) != nil")
+	}
+}
+
+func TestFromGRPCWithoutErrorInfo(t *testing.T) {
+	err := FromGRPC(status.Error(codes.DeadlineExceeded, "context deadline exceeded"))
+	appErr, ok := As(err)
+	if !ok || appErr.Kind != Unavailable || appErr.Code != "unavailable" {
+		t.Errorf("FromGRPC = %#v, want an Unavailable error coded by its kind", err)
+	}
+
+	plain := errors.New("not a status")
+	if got := FromGRPC(plain); got != plain {
+		t.Errorf("FromGRPC(plain) = %v, want it unchanged", got)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+)
+
+// Common holds the settings every service has. Service configurations embed
+// it with `yaml:",inline"`.
+type Common struct {
+	Database    shared.DBConfig    `yaml:"database"`
+	ServiceAuth serviceauth.Config `yaml:"service_auth"`
+
+	// JWTSecret verifies end-user tokens. An empty key would accept tokens
+	// signed with an empty key, so it is required.
+	JWTSecret string `env:"JWT_SECRET" yaml:"jwt_secret" required:"true" secret:"true"`
+
+	// MigrateOnStart applies pending SQL migrations when the service starts
+	MigrateOnStart bool `env:"MIGRATE_ON_START" yaml:"migrate_on_start" default:"true"`
+
+	LogLevel            string        `env:"LOG_LEVEL" yaml:"log_level" default:"info"`
+	ShutdownTimeout     time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" default:"30s"`
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" yaml:"health_check_interval" default:"10s"`
+	GRPCClientTimeout   time.Duration `env:"GRPC_CLIENT_TIMEOUT" yaml:"grpc_client_timeout" default:"5s"`
+}
+
+func (c *Common) setServiceName(name string) {
+	c.ServiceAuth.ServiceName = name
+}
+
+// Validate checks the values Load cannot check on its own
+func (c *Common) Validate() error {
+	var errs []error
+	switch c.LogLevel {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL %q is not one of debug, info, warn, error", c.LogLevel))
+	}
+	switch c.Database.LogLevel {
+	case "silent", "error", "warn", "info":
+	default:
+		errs = append(errs, fmt.Errorf("DB_LOG_LEVEL %q is not one of silent, error, warn, info", c.Database.LogLevel))
+	}
+
+	tlsFiles := 0
+	for _, file := range []string{c.ServiceAuth.CertFile, c.ServiceAuth.KeyFile, c.ServiceAuth.CAFile} {
+		if file != "" {
+			tlsFiles++
+		}
+	}
+	if tlsFiles != 0 && tlsFiles != 3 {
+		errs = append(errs, errors.New("GRPC_TLS_CERT, GRPC_TLS_KEY and GRPC_TLS_CA must be set together"))
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.HealthCheckInterval <= 0 {
+		errs = append(errs, errors.New("HEALTH_CHECK_INTERVAL must be positive"))
+	}
+	if c.GRPCClientTimeout <= 0 {
+		errs = append(errs, errors.New("GRPC_CLIENT_TIMEOUT must be positive"))
+	}
+	return errors.Join(errs...)
+}
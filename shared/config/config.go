@@ -0,0 +1,206 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable pointing to an optional YAML
+// configuration file
+const FileEnv = "CONFIG_FILE"
+
+const redacted = "[REDACTED]"
+
+// ListenAddr is a TCP address to listen on. A bare port such as "8080" is
+// accepted and turned into ":8080".
+type ListenAddr string
+
+// DialAddr returns the address a client on the same host dials to reach
+// the listener, filling in "localhost" when no host or a wildcard is given
+func (a ListenAddr) DialAddr() string {
+	host, port, err := net.SplitHostPort(string(normalizeAddr(a)))
+	if err != nil {
+		return string(a)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Validator is implemented by configuration structs that need checks beyond
+// required fields
+type Validator interface {
+	Validate() error
+}
+
+// serviceNamer is implemented by structs embedding Common
+type serviceNamer interface {
+	setServiceName(name string)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load fills cfg, a pointer to a struct, for the named service. Values are
+// taken, from lowest to highest precedence, from the `default` struct tags,
+// the YAML file named by CONFIG_FILE, a .env file in the working directory
+// and the environment variables named by the `env` tags; variables that are
+// already set are not overridden by .env.
+//
+// Fields tagged `required:"true"` must end up non-zero. All problems are
+// reported together in the returned error.
+func Load(service string, cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config: Load needs a pointer to a struct")
+	}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("config: loading .env: %w", err)
+	}
+
+	var problems []string
+	walk(v.Elem(), func(field reflect.StructField, value reflect.Value) {
+		if def, ok := field.Tag.Lookup("default"); ok {
+			if err := setValue(value, def); err != nil {
+				problems = append(problems, fmt.Sprintf("default of %s: %v", fieldName(field), err))
+			}
+		}
+	})
+
+	if path := os.Getenv(FileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("config: reading %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("config: parsing %s: %w", path, err)
+		}
+	}
+
+	walk(v.Elem(), func(field reflect.StructField, value reflect.Value) {
+		if name := field.Tag.Get("env"); name != "" {
+			if raw, ok := os.LookupEnv(name); ok {
+				if err := setValue(value, raw); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+				}
+			}
+		}
+		if value.Type() == reflect.TypeOf(ListenAddr("")) {
+			value.SetString(string(normalizeAddr(ListenAddr(value.String()))))
+		}
+		if field.Tag.Get("required") == "true" && value.IsZero() {
+			problems = append(problems, fmt.Sprintf("%s is required", fieldName(field)))
+		}
+	})
+
+	if namer, ok := cfg.(serviceNamer); ok {
+		namer.setServiceName(service)
+	}
+	if validator, ok := cfg.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			problems = append(problems, strings.ReplaceAll(err.Error(), "\n", "; "))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid %s configuration: %s", service, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Log writes the effective configuration at info level, one attribute per
+// field named after its environment variable. Fields tagged
+// `secret:"true"` are redacted.
+func Log(cfg interface{}) {
+	var attrs []any
+	walk(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) {
+		var shown interface{} = value.Interface()
+		if field.Tag.Get("secret") == "true" && !value.IsZero() {
+			shown = redacted
+		} else if value.Type() == durationType {
+			shown = value.Interface().(time.Duration).String()
+		}
+		attrs = append(attrs, slog.Any(fieldName(field), shown))
+	})
+	slog.Info("effective configuration", attrs...)
+}
+
+// walk calls fn for every leaf field of v, descending into nested structs
+func walk(v reflect.Value, fn func(reflect.StructField, reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			walk(value, fn)
+			continue
+		}
+		if field.Tag.Get("env") == "" && field.Tag.Get("yaml") == "-" {
+			continue
+		}
+		fn(field, value)
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	if name := field.Tag.Get("env"); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func setValue(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(n))
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+func normalizeAddr(addr ListenAddr) ListenAddr {
+	if addr != "" && !strings.Contains(string(addr), ":") {
+		return ":" + addr
+	}
+	return addr
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Common `yaml:",inline"`
+
+	Addr    ListenAddr    `env:"TEST_ADDR" yaml:"addr" default:"8080"`
+	Name    string        `env:"TEST_NAME" yaml:"name" default:"from-default"`
+	Tags    []string      `env:"TEST_TAGS" yaml:"tags" default:"a,b"`
+	Timeout time.Duration `env:"TEST_TIMEOUT" yaml:"timeout" default:"1s"`
+	Token   string        `env:"TEST_TOKEN" yaml:"token" required:"true"`
+}
+
+// clearEnv unsets the variables Load reads for testConfig for the duration
+// of the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		FileEnv, "TEST_ADDR", "TEST_NAME", "TEST_TAGS", "TEST_TIMEOUT", "TEST_TOKEN",
+		"JWT_SECRET", "LOG_LEVEL", "MIGRATE_ON_START", "SHUTDOWN_TIMEOUT", "HEALTH_CHECK_INTERVAL", "GRPC_CLIENT_TIMEOUT",
+		"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE", "DB_LOG_LEVEL",
+		"SERVICE_TOKEN_SECRET", "GRPC_TLS_CERT", "GRPC_TLS_KEY", "GRPC_TLS_CA",
+	} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+// writeFile points CONFIG_FILE at a YAML file holding content
+func writeFile(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(FileEnv, path)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+		want testConfig
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{"JWT_SECRET": "secret", "TEST_TOKEN": "token"},
+			want: testConfig{Addr: ":8080", Name: "from-default", Tags: []string{"a", "b"}, Timeout: time.Second},
+		},
+		{
+			name: "file over defaults",
+			yaml: "jwt_secret: secret\ntoken: token\naddr: 127.0.0.1:9090\nname: from-file\ntags: [c]\ntimeout: 2s\n",
+			want: testConfig{Addr: "127.0.0.1:9090", Name: "from-file", Tags: []string{"c"}, Timeout: 2 * time.Second},
+		},
+		{
+			name: "environment over file",
+			yaml: "jwt_secret: secret\ntoken: token\naddr: 127.0.0.1:9090\nname: from-file\ntags: [c]\ntimeout: 2s\n",
+			env:  map[string]string{"TEST_ADDR": "7070", "TEST_NAME": "from-env", "TEST_TAGS": " d , e ,", "TEST_TIMEOUT": "3m"},
+			want: testConfig{Addr: ":7070", Name: "from-env", Tags: []string{"d", "e"}, Timeout: 3 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			if tt.yaml != "" {
+				writeFile(t, tt.yaml)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			var cfg testConfig
+			if err := Load("test-service", &cfg); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+
+			got := testConfig{Addr: cfg.Addr, Name: cfg.Name, Tags: cfg.Tags, Timeout: cfg.Timeout}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+			if cfg.Token != "token" || cfg.JWTSecret != "secret" {
+				t.Errorf("Token = %q, JWTSecret = %q, want both set", cfg.Token, cfg.JWTSecret)
+			}
+			if cfg.Database.Host != "localhost" || cfg.LogLevel != "info" || !cfg.MigrateOnStart {
+				t.Errorf("common defaults not applied: %+v", cfg.Common)
+			}
+			if cfg.ServiceAuth.ServiceName != "test-service" {
+				t.Errorf("ServiceName = %q, want test-service", cfg.ServiceAuth.ServiceName)
+			}
+		})
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TEST_TIMEOUT", "soon")
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("GRPC_TLS_CERT", "cert.pem")
+	t.Setenv("DB_NAME", "")
+
+	var cfg testConfig
+	err := Load("test-service", &cfg)
+	if err == nil {
+		t.Fatal("Load succeeded, want an error")
+	}
+
+	for _, want := range []string{
+		"invalid test-service configuration",
+		"TEST_TIMEOUT: time: invalid duration",
+		"JWT_SECRET is required",
+		"TEST_TOKEN is required",
+		"DB_NAME is required",
+		`LOG_LEVEL "verbose"`,
+		"GRPC_TLS_CERT, GRPC_TLS_KEY and GRPC_TLS_CA must be set together",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "\n") {
+		t.Errorf("error %q spans several lines, want one", err)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+		want string
+	}{
+		{"missing file", func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing.yaml") }, "config: reading"},
+		{"invalid YAML", func(t *testing.T) string {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			os.WriteFile(path, []byte("timeout: [\n"), 0o600)
+			return path
+		}, "config: parsing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv(FileEnv, tt.path(t))
+
+			var cfg testConfig
+			if err := Load("test-service", &cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load: err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestListenAddrDialAddr(t *testing.T) {
+	tests := []struct {
+		addr ListenAddr
+		want string
+	}{
+		{"8080", "localhost:8080"},
+		{":8080", "localhost:8080"},
+		{"0.0.0.0:8080", "localhost:8080"},
+		{"[::]:8080", "localhost:8080"},
+		{"10.0.0.5:8080", "10.0.0.5:8080"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.addr.DialAddr(); got != tt.want {
+			t.Errorf("ListenAddr(%q).DialAddr() = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
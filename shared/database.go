@@ -2,10 +2,11 @@ package shared
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"time"
 
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,37 +14,31 @@ import (
 
 // DBConfig holds database configuration parameters
 type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `env:"DB_HOST" yaml:"host" default:"localhost"`
+	Port     string `env:"DB_PORT" yaml:"port" default:"5432"`
+	User     string `env:"DB_USER" yaml:"user" default:"postgres" required:"true"`
+	Password string `env:"DB_PASSWORD" yaml:"password" default:"postgres" secret:"true"`
+	DBName   string `env:"DB_NAME" yaml:"name" default:"ecommerce" required:"true"`
+	SSLMode  string `env:"DB_SSLMODE" yaml:"sslmode" default:"disable"`
+	// LogLevel is the GORM log level: silent, error, warn or info
+	LogLevel string `env:"DB_LOG_LEVEL" yaml:"log_level" default:"warn"`
 }
 
 // ConnectDB establishes a connection to the database and returns a GORM DB instance
-func ConnectDB() (*gorm.DB, error) {
-	// Load database configuration from environment variables
-	config := DBConfig{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", "postgres"),
-		DBName:   getEnv("DB_NAME", "ecommerce"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
-	}
-
+func ConnectDB(config DBConfig) (*gorm.DB, error) {
 	// Create DSN (Data Source Name)
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode)
 
-	// Configure GORM logger
+	// Configure GORM logger. Only slow queries and errors are logged unless
+	// the configured level says otherwise.
 	newLogger := logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
+		slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
 		logger.Config{
-			SlowThreshold: time.Second, // Slow SQL threshold
-			LogLevel:      logger.Info, // Log level
-			Colorful:      true,        // Enable color
+			SlowThreshold:        time.Second, // Slow SQL threshold
+			LogLevel:             gormLogLevel(config.LogLevel),
+			Colorful:             false,
+			ParameterizedQueries: true, // Keep values such as password hashes out of the logs
 		},
 	)
 
@@ -55,6 +50,11 @@ func ConnectDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Trace queries with OpenTelemetry
+	if err := tracing.InstrumentDB(db); err != nil {
+		return nil, fmt.Errorf("failed to instrument database: %w", err)
+	}
+
 	// Get underlying SQL DB instance for connection pool settings
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -66,27 +66,22 @@ func ConnectDB() (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	log.Println("Successfully connected to database")
+	slog.Info("connected to database")
 	return db, nil
 }
 
-// MigrateDB performs database migrations for the given models
-func MigrateDB(db *gorm.DB, models ...interface{}) error {
-	for _, model := range models {
-		if err := db.AutoMigrate(model); err != nil {
-			return fmt.Errorf("failed to migrate model: %w", err)
-		}
-	}
-	log.Println("Database migration completed successfully")
-	return nil
-}
-
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// gormLogLevel maps silent, error, warn and info to the GORM log levels
+func gormLogLevel(value string) logger.LogLevel {
+	switch value {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	default:
+		return logger.Warn
 	}
-	return defaultValue
 }
 
 // CloseDB closes the database connection
@@ -1,15 +1,93 @@
 package grpc_client
 
 import (
+	"context"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
-// NewConnection creates a gRPC connection to the specified address
-func NewConnection(address string) (*grpc.ClientConn, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// DefaultTimeout is the deadline applied to outgoing calls whose context has
+// none. Services set it from their configuration before dialing.
+var DefaultTimeout = 5 * time.Second
+
+// NewConnection creates a gRPC connection to the specified address. It uses
+// mutual TLS and attaches a service token when auth configures them, and
+// falls back to an insecure connection otherwise. Calls are traced and carry
+// the trace context in their metadata. Every call gets
+// DefaultTimeout unless it already has a deadline, and the request ID and the
+// end-user "authorization" and gateway identity metadata of an incoming call
+// are forwarded.
+// Errors of unary calls are converted with apperror.FromGRPC, so callers
+// can check them with errors.Is against the sentinels of the called service.
+func NewConnection(address string, auth serviceauth.Config) (*grpc.ClientConn, error) {
+	options, err := serviceauth.DialOptions(auth)
+	if err != nil {
+		return nil, err
+	}
+	if !auth.TLSEnabled() {
+		options = append(options, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	options = append(options,
+		tracing.ClientDialOption(),
+		grpc.WithChainUnaryInterceptor(unaryErrorInterceptor, unaryDeadlineInterceptor, unaryAuthorizationInterceptor),
+		grpc.WithChainStreamInterceptor(streamAuthorizationInterceptor),
+	)
+
+	conn, err := grpc.NewClient(address, options...)
 	if err != nil {
 		return nil, err
 	}
 	return conn, nil
 }
+
+func unaryErrorInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return apperror.FromGRPC(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+func unaryDeadlineInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func unaryAuthorizationInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(forwardAuthorization(ctx), method, req, reply, cc, opts...)
+}
+
+func streamAuthorizationInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(forwardAuthorization(ctx), desc, cc, method, opts...)
+}
+
+// forwardAuthorization copies the "authorization" and "x-identity-token"
+// metadata of the incoming call to the outgoing one, unless the caller set
+// them explicitly, and forwards the request ID
+func forwardAuthorization(ctx context.Context) context.Context {
+	if requestID := shared.RequestIDFromContext(ctx); requestID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, shared.RequestIDHeader, requestID)
+	}
+
+	incoming, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	outgoing, _ := metadata.FromOutgoingContext(ctx)
+	for _, key := range []string{"authorization", serviceauth.IdentityMetadataKey} {
+		if len(outgoing.Get(key)) > 0 {
+			continue
+		}
+		if values := incoming.Get(key); len(values) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, values[0])
+		}
+	}
+	return ctx
+}
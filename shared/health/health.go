@@ -0,0 +1,276 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
+)
+
+// DefaultCheckTimeout bounds a single run of all checks
+const DefaultCheckTimeout = 3 * time.Second
+
+// DefaultInterval is how often the gRPC serving status is refreshed when no
+// interval is given
+const DefaultInterval = 10 * time.Second
+
+// Checker reports whether a dependency or component is healthy
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Health collects the liveness and readiness checks of a service and serves
+// them on /livez and /readyz and through the standard grpc.health.v1 service.
+// Liveness checks should only fail when restarting the process helps, such as
+// a stuck worker; readiness checks cover the database and downstream
+// services.
+type Health struct {
+	service   string
+	liveness  []namedChecker
+	readiness []namedChecker
+	grpc      *grpchealth.Server
+	grpcNames []string
+	interval  time.Duration
+	stopping  atomic.Bool
+}
+
+// New returns the health registry of the named service, refreshing the gRPC
+// serving status every interval (DefaultInterval if zero)
+func New(service string, interval time.Duration) *Health {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Health{
+		service:  service,
+		grpc:     grpchealth.NewServer(),
+		interval: interval,
+	}
+}
+
+// AddLivenessCheck registers a check that /livez depends on
+func (h *Health) AddLivenessCheck(name string, checker Checker) {
+	h.liveness = append(h.liveness, namedChecker{name: name, checker: checker})
+}
+
+// AddReadinessCheck registers a check that /readyz and the gRPC serving
+// status depend on
+func (h *Health) AddReadinessCheck(name string, checker Checker) {
+	h.readiness = append(h.readiness, namedChecker{name: name, checker: checker})
+}
+
+// RegisterHTTP adds /livez and /readyz to router. /health is kept as an
+// alias of /readyz for existing probes.
+func (h *Health) RegisterHTTP(router gin.IRoutes) {
+	router.GET("/livez", h.handler(func() []namedChecker { return h.liveness }))
+	router.GET("/readyz", h.handler(h.readinessChecks))
+	router.GET("/health", h.handler(h.readinessChecks))
+}
+
+// Shutdown marks the service as not ready, on HTTP and gRPC, so load
+// balancers stop routing to it while in-flight requests drain
+func (h *Health) Shutdown() {
+	h.stopping.Store(true)
+	h.grpc.Shutdown()
+}
+
+func (h *Health) readinessChecks() []namedChecker {
+	if h.stopping.Load() {
+		return append([]namedChecker{{name: "shutdown", checker: errShuttingDown}}, h.readiness...)
+	}
+	return h.readiness
+}
+
+var errShuttingDown = CheckerFunc(func(context.Context) error {
+	return errors.New("shutting down")
+})
+
+// RegisterGRPC adds the grpc.health.v1 service to server. Every service
+// registered on server so far gets its own status, next to the overall ""
+// status, and starts out NOT_SERVING until Run has checked readiness.
+func (h *Health) RegisterGRPC(server *grpc.Server) {
+	h.grpcNames = []string{""}
+	for name := range server.GetServiceInfo() {
+		h.grpcNames = append(h.grpcNames, name)
+	}
+	h.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, h.grpc)
+}
+
+// Run refreshes the gRPC serving status until ctx is cancelled. It is meant
+// to be run as a lifecycle worker, with Shutdown as its shutdown hook.
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	ready := false
+	for {
+		results, ok := h.run(ctx, h.readiness)
+		if ok != ready {
+			slog.InfoContext(ctx, "readiness changed", "ready", ok, "checks", results)
+			ready = ok
+		}
+		if ok {
+			h.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		} else {
+			h.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Health) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, name := range h.grpcNames {
+		h.grpc.SetServingStatus(name, status)
+	}
+}
+
+func (h *Health) handler(checks func() []namedChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		results, ok := h.run(c.Request.Context(), checks())
+		status, code := "OK", http.StatusOK
+		if !ok {
+			status, code = "UNAVAILABLE", http.StatusServiceUnavailable
+		}
+		c.JSON(code, gin.H{
+			"status":  status,
+			"service": h.service,
+			"checks":  results,
+		})
+	}
+}
+
+// run executes checks concurrently and returns the result of each one, "OK"
+// or the error message, and whether all of them passed
+func (h *Health) run(ctx context.Context, checks []namedChecker) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCheckTimeout)
+	defer cancel()
+
+	results := make(map[string]string, len(checks))
+	ok := true
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check namedChecker) {
+			defer wg.Done()
+			err := check.checker.Check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				results[check.name] = err.Error()
+				ok = false
+				return
+			}
+			results[check.name] = "OK"
+		}(check)
+	}
+	wg.Wait()
+	return results, ok
+}
+
+// DatabaseChecker pings the database behind db
+func DatabaseChecker(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// GRPCChecker asks the grpc.health.v1 service behind conn for the status of
+// service, "" meaning the server as a whole
+func GRPCChecker(conn grpc.ClientConnInterface, service string) Checker {
+	client := healthpb.NewHealthClient(conn)
+	return CheckerFunc(func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("status %s", resp.GetStatus())
+		}
+		return nil
+	})
+}
+
+// HTTPChecker requests url, typically the /readyz endpoint of another
+// service, and fails unless it answers with a 2xx status
+func HTTPChecker(client *http.Client, url string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+// Heartbeat is a liveness check for a background worker, which calls Beat
+// every time it completes a cycle. It fails once no beat arrived for maxAge.
+type Heartbeat struct {
+	maxAge time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewHeartbeat returns a heartbeat that counts as fresh for maxAge from now
+func NewHeartbeat(maxAge time.Duration) *Heartbeat {
+	return &Heartbeat{maxAge: maxAge, last: time.Now()}
+}
+
+// Beat records that the worker is making progress
+func (hb *Heartbeat) Beat() {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	hb.last = time.Now()
+}
+
+// Check fails when the last beat is older than maxAge
+func (hb *Heartbeat) Check(ctx context.Context) error {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if age := time.Since(hb.last); age > hb.maxAge {
+		return fmt.Errorf("no heartbeat for %s", age.Round(time.Second))
+	}
+	return nil
+}
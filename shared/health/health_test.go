@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var (
+	passing = CheckerFunc(func(context.Context) error { return nil })
+	failing = CheckerFunc(func(context.Context) error { return errors.New("connection refused") })
+)
+
+type response struct {
+	Status  string            `json:"status"`
+	Service string            `json:"service"`
+	Checks  map[string]string `json:"checks"`
+}
+
+func get(t *testing.T, router http.Handler, path string) (int, response) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var body response
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: %v in %s", path, err, w.Body)
+	}
+	return w.Code, body
+}
+
+func TestHTTPEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		liveness   Checker
+		readiness  Checker
+		shutdown   bool
+		path       string
+		wantCode   int
+		wantChecks map[string]string
+	}{
+		{"live", passing, failing, false, "/livez", http.StatusOK, map[string]string{"worker": "OK"}},
+		{"not live", failing, passing, false, "/livez", http.StatusServiceUnavailable, map[string]string{"worker": "connection refused"}},
+		{"ready", passing, passing, false, "/readyz", http.StatusOK, map[string]string{"database": "OK"}},
+		{"not ready", passing, failing, false, "/readyz", http.StatusServiceUnavailable, map[string]string{"database": "connection refused"}},
+		{"health alias", passing, failing, false, "/health", http.StatusServiceUnavailable, map[string]string{"database": "connection refused"}},
+		{"shutting down", passing, passing, true, "/readyz", http.StatusServiceUnavailable, map[string]string{"database": "OK", "shutdown": "shutting down"}},
+		{"live while shutting down", passing, passing, true, "/livez", http.StatusOK, map[string]string{"worker": "OK"}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New("test-service", 0)
+			h.AddLivenessCheck("worker", tt.liveness)
+			h.AddReadinessCheck("database", tt.readiness)
+			router := gin.New()
+			h.RegisterHTTP(router)
+			if tt.shutdown {
+				h.Shutdown()
+			}
+
+			code, body := get(t, router, tt.path)
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+			if body.Service != "test-service" || len(body.Checks) != len(tt.wantChecks) {
+				t.Fatalf("body = %+v, want checks %v", body, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				if body.Checks[name] != want {
+					t.Errorf("check %s = %q, want %q", name, body.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCServingStatus(t *testing.T) {
+	h := New("test-service", time.Hour)
+	ready := make(chan struct{})
+	h.AddReadinessCheck("database", CheckerFunc(func(context.Context) error {
+		select {
+		case <-ready:
+			return nil
+		default:
+			return errors.New("not yet")
+		}
+	}))
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{ServiceName: "test.TestService", HandlerType: (*any)(nil)}, struct{}{})
+	h.RegisterGRPC(server)
+
+	status := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := h.grpc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", service, err)
+		}
+		return resp.GetStatus()
+	}
+	for _, service := range []string{"", "test.TestService"} {
+		if got := status(service); got != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("%q before Run = %s, want NOT_SERVING", service, got)
+		}
+	}
+
+	close(ready)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// With ctx already cancelled Run checks once and returns
+	h.Run(ctx)
+	for _, service := range []string{"", "test.TestService"} {
+		if got := status(service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("%q when ready = %s, want SERVING", service, got)
+		}
+	}
+
+	h.Shutdown()
+	if got := status(""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status after Shutdown = %s, want NOT_SERVING", got)
+	}
+}
+
+func TestHTTPChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"ready", http.StatusOK, false},
+		{"no content", http.StatusNoContent, false},
+		{"unavailable", http.StatusServiceUnavailable, true},
+		{"redirect", http.StatusFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			err := HTTPChecker(client, server.URL+"/readyz").Check(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check: err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	hb := NewHeartbeat(20 * time.Millisecond)
+	if err := hb.Check(context.Background()); err != nil {
+		t.Fatalf("fresh heartbeat: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := hb.Check(context.Background()); err == nil {
+		t.Fatal("stale heartbeat passed")
+	}
+
+	hb.Beat()
+	if err := hb.Check(context.Background()); err != nil {
+		t.Errorf("heartbeat after Beat: %v", err)
+	}
+}
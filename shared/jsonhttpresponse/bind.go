@@ -0,0 +1,148 @@
+package jsonhttpresponse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report validation errors with the JSON names of the fields
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// bindErrors turns an error of gin's ShouldBind* into one Error per problem.
+// Validation errors carry the failed rule, e.g. "required" or "email", as
+// their code.
+func bindErrors(err error) []Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		errs := make([]Error, 0, len(validationErrs))
+		for _, e := range validationErrs {
+			errs = append(errs, Error{
+				Code:    e.Tag(),
+				Field:   fieldPath(e.Namespace()),
+				Message: validationMessage(e),
+			})
+		}
+		return errs
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []Error{{
+			Code:    "invalid_type",
+			Field:   typeErrorPath(typeErr.Field),
+			Message: "must be " + jsonType(typeErr.Type),
+		}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return []Error{{Code: "malformed_body", Message: "request body is not valid JSON"}}
+	}
+	if errors.Is(err, io.EOF) {
+		return []Error{{Code: "missing_body", Message: "request body is required"}}
+	}
+
+	return []Error{{Code: "invalid_request", Message: err.Error()}}
+}
+
+// fieldPath drops the name of the bound struct from a validator namespace,
+// "request.items[0].quantity" becomes "items[0].quantity"
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// typeErrorPath writes the array indexes of an encoding/json field path the
+// way validator does, "items.0.quantity" becomes "items[0].quantity"
+func typeErrorPath(field string) string {
+	var path strings.Builder
+	for i, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil && i > 0 {
+			path.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			path.WriteByte('.')
+		}
+		path.WriteString(part)
+	}
+	return path.String()
+}
+
+func validationMessage(e validator.FieldError) string {
+	param := e.Param()
+	sized := e.Kind() == reflect.String || e.Kind() == reflect.Slice || e.Kind() == reflect.Map
+	unit := "characters"
+	if e.Kind() != reflect.String {
+		unit = "items"
+	}
+
+	switch e.Tag() {
+	case "required", "required_with", "required_without", "required_if":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "min", "gte":
+		if sized {
+			return fmt.Sprintf("must have at least %s %s", param, unit)
+		}
+		return "must be at least " + param
+	case "max", "lte":
+		if sized {
+			return fmt.Sprintf("must have at most %s %s", param, unit)
+		}
+		return "must be at most " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	case "len":
+		return fmt.Sprintf("must have exactly %s %s", param, unit)
+	default:
+		return fmt.Sprintf("failed the %s rule", e.Tag())
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "an integer"
+	}
+}
@@ -1,137 +1,154 @@
 package jsonhttpresponse
 
 import (
+	"log/slog"
 	"net/http"
-	"strings"
 
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
+// Response is the envelope of every JSON response of the HTTP APIs. Data
+// holds the payload of successful responses and Errors the problems of failed
+// ones; Meta is set on paginated lists.
 type Response struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
-	Errors  interface{} `json:"errors"`
+	Errors  []Error     `json:"errors"`
+	Meta    *Meta       `json:"meta,omitempty"`
+	TraceID string      `json:"trace_id,omitempty"`
 }
 
+// Error is one problem of a failed request. Code is machine-readable, see
+// shared/apperror; Field names the offending request field of validation
+// errors.
 type Error struct {
-	Key   string `json:"key"`
-	Error string `json:"error"`
+	Code     string            `json:"code"`
+	Field    string            `json:"field,omitempty"`
+	Message  string            `json:"message"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Meta is the pagination metadata of list responses
+type Meta struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// NewMeta returns the pagination metadata for a page of limit items out of
+// total
+func NewMeta(page, limit int, total int64) *Meta {
+	meta := &Meta{Page: page, Limit: limit, Total: total}
+	if limit > 0 {
+		meta.TotalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+	return meta
 }
 
 // OK - Function to return Status OK Response (200)
 func OK(c *gin.Context, payloads interface{}) {
-	status := http.StatusOK
-	Resp := Response{
-		Code:    status,
-		Message: http.StatusText(status),
-		Data:    payloads,
-		Errors:  nil,
-	}
-	c.IndentedJSON(status, Resp)
-	return
+	write(c, http.StatusOK, payloads, nil, nil)
+}
+
+// Paginated - Function to return Status OK Response (200) for one page of a
+// list, with the pagination metadata in meta
+func Paginated(c *gin.Context, payloads interface{}, page, limit int, total int64) {
+	write(c, http.StatusOK, payloads, nil, NewMeta(page, limit, total))
+}
+
+// StatusCreated - Function to return Status Created Response (201)
+func StatusCreated(c *gin.Context, payload interface{}) {
+	write(c, http.StatusCreated, payload, nil, nil)
 }
 
 // BadRequest - Function to return Status Bad Request Response (400)
 // use it if user request is wrong
-func BadRequest(c *gin.Context, payloads interface{}) {
-	status := http.StatusBadRequest
-	Resp := Response{
-		Code:    status,
-		Message: http.StatusText(status),
-		Data:    nil,
-		Errors:  payloads,
-	}
-	c.IndentedJSON(status, Resp)
-	return
+func BadRequest(c *gin.Context, code, message string) {
+	write(c, http.StatusBadRequest, nil, []Error{{Code: code, Message: message}}, nil)
 }
 
+// ErrBind - Function to return Status Bad Request Response (400) for a
+// request that could not be bound, with one error per invalid field
 func ErrBind(c *gin.Context, err error) {
-	var errs []Error
-	status := http.StatusBadRequest
-
-	if verrs, ok := err.(validator.ValidationErrors); ok {
-		for _, e := range verrs {
-			raw := e.Error()
-			parts := strings.SplitN(raw, " Error:", 2)
-			errs = append(errs, Error{
-				Key:   e.Field(), // or e.Namespace() for full path
-				Error: parts[1],
-			})
-		}
-	}
-	Resp := Response{
-		Code:    status,
-		Message: http.StatusText(status),
-		Data:    nil,
-		Errors:  errs,
-	}
-	c.IndentedJSON(status, Resp)
-	return
-}
-
-// InternalServerError - Function to return Internal Server Error Response (400)
-// use it for any unhandled error that is not user's fault
-func InternalServerError(c *gin.Context, payloads interface{}) {
-	status := http.StatusInternalServerError
-	Resp := Response{
-		Code:    status,
-		Message: http.StatusText(status),
-		Data:    nil,
-		Errors:  payloads,
-	}
-	c.IndentedJSON(status, Resp)
-	return
+	write(c, http.StatusBadRequest, nil, bindErrors(err), nil)
 }
 
 // Unauthorized - Function to return Unauthorized Response (401)
 // Use it only in authentication process
-func Unauthorized(c *gin.Context, payloads interface{}) {
-	status := http.StatusUnauthorized
-	Resp := Response{
-		Code:    status,
-		Message: http.StatusText(status),
-		Data:    nil,
-		Errors:  payloads,
-	}
-	c.IndentedJSON(status, Resp)
-	return
+func Unauthorized(c *gin.Context, code, message string) {
+	write(c, http.StatusUnauthorized, nil, []Error{{Code: code, Message: message}}, nil)
+}
+
+// Forbidden - Function to return Forbidden Response (403)
+// Use it for any user attempting to access resource
+// with lack of authorization
+func Forbidden(c *gin.Context, code, message string) {
+	write(c, http.StatusForbidden, nil, []Error{{Code: code, Message: message}}, nil)
 }
 
 // NotFound - Function to return Not Found Response (404)
 // Use it in case of any get operation that retrieve
 // for resource and not exist
-func NotFound(c *gin.Context, payloads interface{}) {
-	c.JSON(http.StatusNotFound, payloads)
-	return
+func NotFound(c *gin.Context, code, message string) {
+	write(c, http.StatusNotFound, nil, []Error{{Code: code, Message: message}}, nil)
 }
 
 // Conflict - Function to return Conflict Response (409)
 // Use it in case if a process create a new resource,
 // but somehow, another resource already exist
 // (collision in unique identifier)
-func Conflict(c *gin.Context, payloads interface{}) {
-	c.JSON(http.StatusConflict, payloads)
-	return
+func Conflict(c *gin.Context, code, message string) {
+	write(c, http.StatusConflict, nil, []Error{{Code: code, Message: message}}, nil)
 }
 
-// Forbidden - Function to return Forbidden Response (403)
-// Use it for any user attempting to access resource
-// with lack of authorization
-func Forbidden(c *gin.Context, payloads interface{}) {
-	c.JSON(http.StatusForbidden, payloads)
-	return
+// InternalServerError - Function to return Internal Server Error Response (500)
+// use it for any unhandled error that is not user's fault. err is logged,
+// not returned.
+func InternalServerError(c *gin.Context, err error) {
+	slog.ErrorContext(c.Request.Context(), "request failed", "method", c.Request.Method, "path", c.FullPath(), "error", err)
+	write(c, http.StatusInternalServerError, nil, []Error{{Code: "internal", Message: "internal server error"}}, nil)
 }
 
-func StatusCreated(c *gin.Context, payload interface{}) {
-	status := http.StatusCreated
-	Resp := Response{
+// FromError - Function to return the response for an error returned by a
+// usecase. *apperror.Error values get the status of their kind and their
+// code in the errors payload; anything else is an Internal Server Error.
+func FromError(c *gin.Context, err error) {
+	appErr, ok := apperror.As(err)
+	if !ok || appErr.Kind == apperror.Internal {
+		InternalServerError(c, err)
+		return
+	}
+
+	write(c, appErr.Kind.HTTPStatus(), nil, []Error{{
+		Code:     appErr.Code,
+		Message:  appErr.Message,
+		Metadata: appErr.Metadata,
+	}}, nil)
+}
+
+// RouteNotFound answers requests for unknown routes, for use with
+// gin.Engine.NoRoute
+func RouteNotFound(c *gin.Context) {
+	NotFound(c, "route_not_found", "route not found")
+}
+
+// write sends the envelope. Error responses abort the handler chain so they
+// can be written from middleware.
+func write(c *gin.Context, status int, data interface{}, errs []Error, meta *Meta) {
+	resp := Response{
 		Code:    status,
 		Message: http.StatusText(status),
-		Data:    payload,
-		Errors:  nil,
+		Data:    data,
+		Errors:  errs,
+		Meta:    meta,
+	}
+	if len(errs) > 0 {
+		resp.TraceID = tracing.TraceID(c.Request.Context())
+		c.Abort()
 	}
-	c.IndentedJSON(status, Resp)
-	return
+	c.IndentedJSON(status, resp)
 }
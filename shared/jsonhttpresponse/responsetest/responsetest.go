@@ -0,0 +1,188 @@
+// Package responsetest checks HTTP responses against the jsonhttpresponse
+// envelope in the contract tests of the services' routers.
+package responsetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+)
+
+// Case is one request sent to a router and the response it must get
+type Case struct {
+	Name   string
+	Method string
+	Path   string
+	// Body is sent as JSON when it is not empty, unless ContentType is set
+	Body        string
+	ContentType string
+	// Token is sent as bearer token when it is not empty
+	Token  string
+	Status int
+	// Code is the expected code of the first error of failed requests
+	Code string
+	// Fields are the expected fields of the errors, for bind errors
+	Fields []string
+	// Meta requires pagination metadata
+	Meta bool
+}
+
+// envelopeKeys are the keys every response has; meta and trace_id are
+// optional
+var envelopeKeys = []string{"code", "message", "data", "errors"}
+
+// Run sends the request of each case to handler and checks the envelope,
+// status and errors of its response
+func Run(t *testing.T, handler http.Handler, cases []Case) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Helper()
+			Check(t, Serve(handler, tc), tc)
+		})
+	}
+}
+
+// Serve sends the request of tc to handler and returns the recorded response
+func Serve(handler http.Handler, tc Case) *httptest.ResponseRecorder {
+	var req *http.Request
+	if tc.Body != "" {
+		req = httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+		contentType := tc.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	} else {
+		req = httptest.NewRequest(tc.Method, tc.Path, nil)
+	}
+	if tc.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+tc.Token)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// Check checks that rec holds a well-formed envelope matching tc and returns
+// the decoded response
+func Check(t *testing.T, rec *httptest.ResponseRecorder, tc Case) jsonhttpresponse.Response {
+	t.Helper()
+
+	if rec.Code != tc.Status {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, tc.Status, rec.Body)
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Fatalf("Content-Type = %q, want application/json", contentType)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("body is not a JSON object: %v, body %s", err, rec.Body)
+	}
+	for _, key := range envelopeKeys {
+		if _, ok := raw[key]; !ok {
+			t.Errorf("envelope has no %q, body %s", key, rec.Body)
+		}
+	}
+	for key := range raw {
+		if !slices.Contains(envelopeKeys, key) && key != "meta" && key != "trace_id" {
+			t.Errorf("envelope has unexpected key %q", key)
+		}
+	}
+
+	var resp jsonhttpresponse.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("body does not decode into the envelope: %v", err)
+	}
+	if resp.Code != rec.Code {
+		t.Errorf("code = %d, want the status %d", resp.Code, rec.Code)
+	}
+	if resp.Message != http.StatusText(rec.Code) {
+		t.Errorf("message = %q, want %q", resp.Message, http.StatusText(rec.Code))
+	}
+
+	if rec.Code >= http.StatusBadRequest {
+		checkFailure(t, resp, tc)
+	} else if resp.Errors != nil {
+		t.Errorf("errors = %+v, want null on success", resp.Errors)
+	}
+
+	if tc.Meta {
+		if resp.Meta == nil {
+			t.Errorf("meta is missing")
+		} else if resp.Meta.Page < 1 || resp.Meta.Limit < 1 {
+			t.Errorf("meta = %+v, want page and limit", *resp.Meta)
+		}
+	}
+	return resp
+}
+
+func checkFailure(t *testing.T, resp jsonhttpresponse.Response, tc Case) {
+	t.Helper()
+
+	if resp.Data != nil {
+		t.Errorf("data = %v, want null on failure", resp.Data)
+	}
+	if len(resp.Errors) == 0 {
+		t.Fatalf("errors is empty")
+	}
+	for _, e := range resp.Errors {
+		if e.Code == "" || e.Message == "" {
+			t.Errorf("error %+v has no code or message", e)
+		}
+	}
+	if tc.Code != "" && resp.Errors[0].Code != tc.Code {
+		t.Errorf("error code = %q, want %q", resp.Errors[0].Code, tc.Code)
+	}
+	if tc.Fields != nil {
+		fields := make([]string, 0, len(resp.Errors))
+		for _, e := range resp.Errors {
+			fields = append(fields, e.Field)
+		}
+		slices.Sort(fields)
+		want := slices.Clone(tc.Fields)
+		slices.Sort(want)
+		if !slices.Equal(fields, want) {
+			t.Errorf("error fields = %v, want %v", fields, want)
+		}
+	}
+}
+
+// MultipartFile returns the body and content type of a multipart form
+// holding content as the file field
+func MultipartFile(t *testing.T, field, filename string, content []byte) (string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, filename)
+	if err == nil {
+		_, err = part.Write(content)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		t.Fatalf("building multipart form: %v", err)
+	}
+	return body.String(), writer.FormDataContentType()
+}
+
+// Token returns a signed access token for subject
+func Token(t *testing.T, subject shared.TokenSubject, secret string) string {
+	t.Helper()
+	token, err := shared.GenerateToken(subject, secret)
+	if err != nil {
+		t.Fatalf("generating token: %v", err)
+	}
+	return token
+}
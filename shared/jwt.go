@@ -9,21 +9,33 @@ import (
 )
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
+	UserID      int      `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID int, email, secret string) (string, error) {
+// TokenSubject holds the identity and authorization data embedded in a token
+type TokenSubject struct {
+	UserID      int
+	Email       string
+	Role        string
+	Permissions []string
+}
+
+func GenerateToken(subject TokenSubject, secret string) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:      subject.UserID,
+		Email:       subject.Email,
+		Role:        subject.Role,
+		Permissions: subject.Permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   strconv.Itoa(userID),
+			Subject:   strconv.Itoa(subject.UserID),
 		},
 	}
 
@@ -0,0 +1,246 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultShutdownTimeout bounds each shutdown step when no timeout is given
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Worker is a background task. It runs until ctx is cancelled and should
+// return once the work in progress is finished.
+type Worker func(ctx context.Context)
+
+type httpServer struct {
+	name   string
+	server *http.Server
+}
+
+type grpcServer struct {
+	name   string
+	addr   string
+	server *grpc.Server
+}
+
+type worker struct {
+	name string
+	run  Worker
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// App starts the HTTP servers, gRPC servers and background workers of a
+// service and stops them in order on SIGINT or SIGTERM: after the shutdown
+// hooks ran, HTTP servers are drained first, then gRPC servers are stopped
+// gracefully, workers are cancelled and waited for, and finally the closers
+// run in reverse order of registration.
+type App struct {
+	name            string
+	shutdownTimeout time.Duration
+	httpServers     []httpServer
+	grpcServers     []grpcServer
+	workers         []worker
+	closers         []closer
+	shutdownHooks   []func()
+}
+
+// New returns an App for the named service. Each shutdown step waits at
+// most shutdownTimeout, DefaultShutdownTimeout if it is zero.
+func New(name string, shutdownTimeout time.Duration) *App {
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+	return &App{name: name, shutdownTimeout: shutdownTimeout}
+}
+
+// AddHTTPServer registers an HTTP server listening on addr
+func (a *App) AddHTTPServer(name, addr string, handler http.Handler) {
+	a.httpServers = append(a.httpServers, httpServer{
+		name: name,
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	})
+}
+
+// AddGRPCServer registers a gRPC server listening on addr
+func (a *App) AddGRPCServer(name, addr string, server *grpc.Server) {
+	a.grpcServers = append(a.grpcServers, grpcServer{name: name, addr: addr, server: server})
+}
+
+// AddWorker registers a background worker
+func (a *App) AddWorker(name string, run Worker) {
+	a.workers = append(a.workers, worker{name: name, run: run})
+}
+
+// OnShutdown registers a function that runs as soon as shutdown starts,
+// before the servers are drained, e.g. to fail readiness checks
+func (a *App) OnShutdown(hook func()) {
+	a.shutdownHooks = append(a.shutdownHooks, hook)
+}
+
+// AddCloser registers a resource to release after all servers and workers
+// have stopped, such as the database or the tracer provider
+func (a *App) AddCloser(name string, close func(ctx context.Context) error) {
+	a.closers = append(a.closers, closer{name: name, close: close})
+}
+
+// Run starts everything and blocks until a termination signal arrives or a
+// server fails, then shuts down. It returns the first server error, if any.
+func (a *App) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Listen before starting anything so a taken port fails fast
+	listeners := make([]net.Listener, len(a.grpcServers))
+	for i, s := range a.grpcServers {
+		lis, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			for _, l := range listeners[:i] {
+				l.Close()
+			}
+			a.runClosers()
+			return err
+		}
+		listeners[i] = lis
+	}
+
+	errCh := make(chan error, len(a.httpServers)+len(a.grpcServers))
+
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+	var workers sync.WaitGroup
+	for _, w := range a.workers {
+		workers.Add(1)
+		go func(w worker) {
+			defer workers.Done()
+			slog.InfoContext(workerCtx, "worker started", "worker", w.name)
+			w.run(workerCtx)
+			slog.InfoContext(workerCtx, "worker stopped", "worker", w.name)
+		}(w)
+	}
+
+	for i, s := range a.grpcServers {
+		go func(s grpcServer, lis net.Listener) {
+			slog.InfoContext(ctx, "gRPC server started", "server", s.name, "addr", s.addr)
+			if err := s.server.Serve(lis); err != nil {
+				errCh <- err
+			}
+		}(s, listeners[i])
+	}
+
+	for _, s := range a.httpServers {
+		go func(s httpServer) {
+			slog.InfoContext(ctx, "HTTP server started", "server", s.name, "addr", s.server.Addr)
+			if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}(s)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.InfoContext(ctx, "received shutdown signal", "app", a.name)
+	case runErr = <-errCh:
+		slog.ErrorContext(ctx, "server failed", "app", a.name, "error", runErr)
+	}
+	stop()
+
+	for _, hook := range a.shutdownHooks {
+		hook()
+	}
+	a.shutdownHTTP()
+	a.shutdownGRPC()
+
+	cancelWorkers()
+	if !waitTimeout(&workers, a.shutdownTimeout) {
+		slog.WarnContext(ctx, "workers did not stop in time", "app", a.name, "timeout", a.shutdownTimeout)
+	}
+
+	a.runClosers()
+	slog.InfoContext(ctx, "stopped", "app", a.name)
+	return runErr
+}
+
+func (a *App) shutdownHTTP() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, s := range a.httpServers {
+		wg.Add(1)
+		go func(s httpServer) {
+			defer wg.Done()
+			if err := s.server.Shutdown(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to shut down HTTP server", "server", s.name, "error", err)
+				s.server.Close()
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+func (a *App) shutdownGRPC() {
+	var wg sync.WaitGroup
+	for _, s := range a.grpcServers {
+		wg.Add(1)
+		go func(s grpcServer) {
+			defer wg.Done()
+			done := make(chan struct{})
+			go func() {
+				s.server.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(a.shutdownTimeout):
+				slog.Warn("gRPC server did not stop in time, closing open calls", "server", s.name, "timeout", a.shutdownTimeout)
+				s.server.Stop()
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+func (a *App) runClosers() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		c := a.closers[i]
+		if err := c.close(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to close", "closer", c.name, "error", err)
+		}
+	}
+}
+
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,139 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// recorder collects the shutdown steps in the order they happen
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func (r *recorder) closer(name string) func(context.Context) error {
+	return func(context.Context) error {
+		r.add("close " + name)
+		return nil
+	}
+}
+
+// takenAddr returns an address that is already listened on
+func takenAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	return lis.Addr().String()
+}
+
+func TestRunShutsDownInOrderOnSignal(t *testing.T) {
+	steps := &recorder{}
+	app := New("test", time.Second)
+	app.AddGRPCServer("grpc", "127.0.0.1:0", grpc.NewServer())
+	app.AddWorker("worker", func(ctx context.Context) {
+		// Workers start after the signal handler is installed
+		syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+		<-ctx.Done()
+		steps.add("worker stopped")
+	})
+	app.OnShutdown(func() { steps.add("hook") })
+	app.AddCloser("database", steps.closer("database"))
+	app.AddCloser("tracer", steps.closer("tracer"))
+
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{"hook", "worker stopped", "close tracer", "close database"}
+	if !reflect.DeepEqual(steps.steps, want) {
+		t.Errorf("steps = %v, want %v", steps.steps, want)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(app *App, addr string)
+		// started tells whether workers and hooks ran before the failure
+		started bool
+	}{
+		{"gRPC port taken", func(app *App, addr string) {
+			app.AddGRPCServer("grpc", addr, grpc.NewServer())
+		}, false},
+		{"HTTP port taken", func(app *App, addr string) {
+			app.AddHTTPServer("http", addr, http.NotFoundHandler())
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := &recorder{}
+			app := New("test", time.Second)
+			tt.setup(app, takenAddr(t))
+			app.AddWorker("worker", func(ctx context.Context) {
+				<-ctx.Done()
+				steps.add("worker stopped")
+			})
+			app.OnShutdown(func() { steps.add("hook") })
+			app.AddCloser("database", steps.closer("database"))
+
+			err := app.Run()
+			var opErr *net.OpError
+			if !errors.As(err, &opErr) {
+				t.Fatalf("Run: err = %v, want the listen error", err)
+			}
+
+			want := []string{"close database"}
+			if tt.started {
+				want = []string{"hook", "worker stopped", "close database"}
+			}
+			if !reflect.DeepEqual(steps.steps, want) {
+				t.Errorf("steps = %v, want %v", steps.steps, want)
+			}
+		})
+	}
+}
+
+func TestRunGivesUpOnStuckWorkers(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	closed := false
+	app := New("test", 50*time.Millisecond)
+	app.AddHTTPServer("http", takenAddr(t), http.NotFoundHandler())
+	app.AddWorker("stuck", func(ctx context.Context) { <-release })
+	app.AddCloser("database", func(context.Context) error {
+		closed = true
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- app.Run() }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run waited for a worker that ignores its context")
+	}
+	if !closed {
+		t.Error("closers did not run after the shutdown timeout")
+	}
+}
@@ -1,27 +1,124 @@
 package shared
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
 )
 
-// InitLogger initializes the application logger
-func InitLogger() {
-	log.SetOutput(os.Stdout)
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+// RequestIDHeader is the HTTP header, and lower-cased the gRPC metadata key,
+// carrying the request correlation ID
+const RequestIDHeader = "X-Request-ID"
+
+// redactedKeys are attribute keys whose values never reach the logs
+var redactedKeys = map[string]bool{
+	"password":        true,
+	"new_password":    true,
+	"token":           true,
+	"access_token":    true,
+	"refresh_token":   true,
+	"challenge_token": true,
+	"secret":          true,
+	"authorization":   true,
+	"payment_details": true,
+}
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID attached to ctx, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// InitLogger installs a JSON slog logger as the default logger. Output of the
+// standard log package goes through it as well. level is debug, info, warn or
+// error; anything else means info. Every line carries the service name, and
+// the request and trace IDs when logged with a context.
+func InitLogger(serviceName, level string) {
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level:       logLevel(level),
+		ReplaceAttr: redact,
+	})
+
+	logger := slog.New(&contextHandler{Handler: handler}).With("service", serviceName)
+	slog.SetDefault(logger)
+}
+
+func logLevel(value string) slog.Level {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if redactedKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, "[REDACTED]")
+	}
+	return attr
+}
+
+// contextHandler adds the request and trace IDs from the context to records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		record.AddAttrs(slog.String("trace_id", traceID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
 }
 
 // Info logs informational messages
 func Info(format string, v ...interface{}) {
-	log.Printf("INFO: "+format, v...)
+	slog.Info(fmt.Sprintf(format, v...))
 }
 
 // Error logs error messages
 func Error(format string, v ...interface{}) {
-	log.Printf("ERROR: "+format, v...)
+	slog.Error(fmt.Sprintf(format, v...))
 }
 
 // Debug logs debug messages
 func Debug(format string, v ...interface{}) {
-	log.Printf("DEBUG: "+format, v...)
+	slog.Debug(fmt.Sprintf(format, v...))
 }
@@ -1,45 +1,116 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 
 	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens and sets user context
+// AuthMiddleware validates JWT tokens and sets user context. Requests whose
+// identity was already established by GatewayIdentity are let through
+// without validating the token again.
 func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if _, ok := GetClaims(c); ok {
+			c.Next()
+			return
+		}
+
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+			jsonhttpresponse.Unauthorized(c, "authorization_required", "Authorization header is required")
 			return
 		}
 
-		// Check if the header has the Bearer prefix
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header must start with Bearer"})
-			c.Abort()
+		if authenticate(c, authHeader, jwtSecret) {
+			c.Next()
+		}
+	}
+}
+
+// OptionalAuthMiddleware is AuthMiddleware for routes that also serve
+// anonymous users: requests without an Authorization header pass unchanged,
+// but a token that is sent must be valid.
+func OptionalAuthMiddleware(jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetClaims(c); ok {
+			c.Next()
 			return
 		}
 
-		// Extract the token
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" || authenticate(c, authHeader, jwtSecret) {
+			c.Next()
+		}
+	}
+}
+
+// GatewayIdentity accepts the end user forwarded by the API gateway in the
+// X-Identity-Token header, signed with the service token secret, and sets the
+// user context from it so that AuthMiddleware does not validate the JWT
+// again. Requests without the header are left to AuthMiddleware; a header
+// that does not verify is rejected.
+func GatewayIdentity(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.GetHeader(serviceauth.IdentityHeader)
+		if tokenString == "" {
+			c.Next()
+			return
+		}
 
-		// Validate the token
-		claims, err := shared.ValidateToken(tokenString, jwtSecret)
+		claims, err := serviceauth.ValidateIdentityToken(tokenString, secret)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token", "details": err.Error()})
-			c.Abort()
+			jsonhttpresponse.FromError(c, ErrInvalidToken.Wrap(err))
 			return
 		}
 
-		// Set user information in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
+		setClaims(c, claims)
 		c.Next()
 	}
 }
+
+// authenticate validates the bearer token in authHeader and sets the user
+// context. It writes the error response and returns false when the token is
+// missing or invalid.
+func authenticate(c *gin.Context, authHeader, jwtSecret string) bool {
+	// Check if the header has the Bearer prefix
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		jsonhttpresponse.Unauthorized(c, "authorization_required", "Authorization header must start with Bearer")
+		return false
+	}
+
+	// Extract the token
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+	// Validate the token
+	claims, err := shared.ValidateToken(tokenString, jwtSecret)
+	if err != nil {
+		jsonhttpresponse.FromError(c, ErrInvalidToken.Wrap(err))
+		return false
+	}
+
+	setClaims(c, claims)
+	return true
+}
+
+// setClaims sets user information in context
+func setClaims(c *gin.Context, claims *shared.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_role", claims.Role)
+	c.Set("claims", claims)
+}
+
+// GetClaims returns the claims stored in the context by AuthMiddleware
+func GetClaims(c *gin.Context) (*shared.Claims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*shared.Claims)
+	return claims, ok
+}
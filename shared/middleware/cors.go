@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig lists the browser origins allowed to call the API. CORS is off
+// when AllowedOrigins is empty; "*" allows any origin.
+type CORSConfig struct {
+	AllowedOrigins   []string      `env:"CORS_ALLOWED_ORIGINS" yaml:"allowed_origins"`
+	AllowedHeaders   []string      `env:"CORS_ALLOWED_HEADERS" yaml:"allowed_headers" default:"Authorization,Content-Type,X-Request-ID"`
+	AllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS" yaml:"allow_credentials"`
+	MaxAge           time.Duration `env:"CORS_MAX_AGE" yaml:"max_age" default:"10m"`
+}
+
+const (
+	corsAllowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsExposedHeaders = "X-Request-ID, X-Trace-Id, Retry-After"
+)
+
+// CORS answers preflight requests and adds the CORS headers to responses
+// for allowed origins. Preflights from other origins are rejected with 403;
+// their simple requests get no CORS headers, so browsers withhold the
+// response.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	allowAny := false
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || len(allowed) == 0 {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !allowAny && !allowed[origin] {
+			if preflight {
+				jsonhttpresponse.Forbidden(c, "origin_not_allowed", "origin is not allowed")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAny && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", corsAllowedMethods)
+			c.Header("Access-Control-Allow-Headers", allowedHeaders)
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+		c.Next()
+	}
+}
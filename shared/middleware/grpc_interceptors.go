@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcServerHandledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of gRPC calls completed on the server",
+	}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"})
+
+	grpcServerHandlingSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "grpc_server_handling_seconds",
+		Help: "Duration of gRPC calls handled by the server",
+	}, []string{"grpc_type", "grpc_service", "grpc_method"})
+)
+
+// UnaryRequestIDInterceptor takes the request ID from the x-request-id
+// metadata, or generates one, and attaches it to the context for logging and
+// for forwarding to further calls
+func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(contextWithRequestID(ctx), req)
+	}
+}
+
+// StreamRequestIDInterceptor is the streaming counterpart of UnaryRequestIDInterceptor
+func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: contextWithRequestID(ss.Context())})
+	}
+}
+
+func contextWithRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(shared.RequestIDHeader); len(values) > 0 && len(values[0]) <= 128 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = shared.NewRequestID()
+	}
+
+	grpc.SetHeader(ctx, metadata.Pairs(shared.RequestIDHeader, requestID))
+	return shared.ContextWithRequestID(ctx, requestID)
+}
+
+// UnaryRecoveryInterceptor turns a panic in a handler into an Internal error
+// instead of crashing the process
+func UnaryRecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(ctx, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor is the streaming counterpart of UnaryRecoveryInterceptor
+func StreamRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(ss.Context(), info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recoverPanic(ctx context.Context, method string, r interface{}) error {
+	slog.ErrorContext(ctx, "grpc panic", "method", method, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// UnaryErrorInterceptor converts the errors returned by handlers into gRPC
+// statuses with apperror.ToGRPC. Errors that are not an *apperror.Error are
+// logged and reported as Internal without their message.
+func UnaryErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, convertError(ctx, info.FullMethod, err)
+	}
+}
+
+// StreamErrorInterceptor is the streaming counterpart of UnaryErrorInterceptor
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return convertError(ss.Context(), info.FullMethod, handler(srv, ss))
+	}
+}
+
+func convertError(ctx context.Context, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := apperror.As(err); !ok {
+		if _, ok := status.FromError(err); !ok {
+			slog.ErrorContext(ctx, "grpc handler failed", "method", method, "error", err)
+		}
+	}
+	return apperror.ToGRPC(err)
+}
+
+// UnaryLoggingInterceptor writes an access log line for every call. It must
+// run after the auth interceptors to include the caller.
+func UnaryLoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, "unary", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLoggingInterceptor is the streaming counterpart of UnaryLoggingInterceptor
+func StreamLoggingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), "stream", info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, callType, method string, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("type", callType),
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if caller, ok := serviceauth.CallerFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("caller", caller))
+	}
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		attrs = append(attrs, slog.Int("user_id", claims.UserID))
+	}
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	}
+	slog.LogAttrs(ctx, level, "grpc call", attrs...)
+}
+
+// UnaryMetricsInterceptor records grpc_server_handled_total and
+// grpc_server_handling_seconds
+func UnaryMetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeCall("unary", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamMetricsInterceptor is the streaming counterpart of UnaryMetricsInterceptor
+func StreamMetricsInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeCall("stream", info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeCall(callType, fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	grpcServerHandledTotal.WithLabelValues(callType, service, method, status.Code(err).String()).Inc()
+	grpcServerHandlingSeconds.WithLabelValues(callType, service, method).Observe(time.Since(start).Seconds())
+}
+
+// splitMethod splits "/package.Service/Method" into service and method
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
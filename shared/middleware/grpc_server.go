@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+	"google.golang.org/grpc"
+)
+
+// GRPCServerConfig configures the interceptor chain shared by all gRPC servers
+type GRPCServerConfig struct {
+	// ServiceAuth identifies calling services by client certificate or
+	// service token
+	ServiceAuth serviceauth.Config
+	// Allowlist restricts internal methods to the listed calling services
+	Allowlist serviceauth.Allowlist
+	// JWTSecret validates end-user tokens from the "authorization" metadata.
+	// Identity tokens of the API gateway are verified with the service token
+	// secret of ServiceAuth instead.
+	JWTSecret string
+	// MethodPermissions lists the methods that require an end-user token, see
+	// UnaryPermissionInterceptor
+	MethodPermissions map[string]string
+
+	// ShopPermissions lists the methods that require a shop-scoped action,
+	// see UnaryShopPermissionInterceptor. They require an end-user token
+	// whether or not they are listed in MethodPermissions.
+	ShopPermissions map[string]ShopPermission
+	// ShopAccessChecker decides the shop-scoped actions; required when
+	// ShopPermissions is set
+	ShopAccessChecker ShopAccessChecker
+}
+
+// GRPCServerOptions returns the options every gRPC server is created with:
+// mutual TLS credentials when configured, OpenTelemetry tracing, and unary
+// and stream interceptors for, in order, request IDs, panic recovery,
+// metrics, service authentication, end-user authentication, shop-scoped
+// authorization (unary only, as it needs the request message) and access
+// logging, followed by the conversion of returned errors to gRPC statuses.
+// Logging runs after auth so it can include the authenticated caller; calls
+// rejected by auth show up in the metrics.
+func GRPCServerOptions(cfg GRPCServerConfig) ([]grpc.ServerOption, error) {
+	var options []grpc.ServerOption
+
+	if len(cfg.ShopPermissions) > 0 && cfg.ShopAccessChecker == nil {
+		return nil, errors.New("shop permissions require a shop access checker")
+	}
+	methodPermissions := make(map[string]string, len(cfg.MethodPermissions)+len(cfg.ShopPermissions))
+	for method := range cfg.ShopPermissions {
+		methodPermissions[method] = ""
+	}
+	for method, permission := range cfg.MethodPermissions {
+		methodPermissions[method] = permission
+	}
+
+	creds, err := serviceauth.ServerCredentials(cfg.ServiceAuth)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		options = append(options, creds)
+	}
+
+	options = append(options,
+		grpc.StatsHandler(tracing.ServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			UnaryRequestIDInterceptor(),
+			UnaryRecoveryInterceptor(),
+			UnaryMetricsInterceptor(),
+			serviceauth.UnaryServerInterceptor(cfg.ServiceAuth, cfg.Allowlist),
+			UnaryPermissionInterceptor(cfg.JWTSecret, cfg.ServiceAuth.TokenSecret, methodPermissions),
+			UnaryShopPermissionInterceptor(cfg.ShopAccessChecker, cfg.ShopPermissions),
+			UnaryLoggingInterceptor(),
+			UnaryErrorInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			StreamRequestIDInterceptor(),
+			StreamRecoveryInterceptor(),
+			StreamMetricsInterceptor(),
+			serviceauth.StreamServerInterceptor(cfg.ServiceAuth, cfg.Allowlist),
+			StreamPermissionInterceptor(cfg.JWTSecret, cfg.ServiceAuth.TokenSecret, methodPermissions),
+			StreamLoggingInterceptor(),
+			StreamErrorInterceptor(),
+		),
+	)
+	return options, nil
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned to clients that exceed their request rate
+var ErrRateLimited = apperror.NewResourceExhausted("rate_limited", "too many requests")
+
+// RateLimitConfig is a token bucket per client: RequestsPerSecond tokens are
+// added every second, up to Burst. Rate limiting is off when
+// RequestsPerSecond is 0.
+type RateLimitConfig struct {
+	RequestsPerSecond int `env:"RATE_LIMIT_RPS" yaml:"requests_per_second" default:"20"`
+	Burst             int `env:"RATE_LIMIT_BURST" yaml:"burst" default:"40"`
+}
+
+// rateLimiterIdleTimeout is how long the bucket of an inactive client is
+// kept; a new bucket starts full, so dropping it earlier would only be more
+// lenient
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit throttles requests per authenticated user, or per client IP for
+// anonymous requests, and must run after the authentication middleware.
+// Throttled requests get 429 with a Retry-After header.
+func RateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	if cfg.RequestsPerSecond <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	var mu sync.Mutex
+	clients := make(map[string]*clientLimiter)
+	lastSweep := time.Now()
+
+	limiterFor := func(key string, now time.Time) *rate.Limiter {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if now.Sub(lastSweep) > rateLimiterIdleTimeout {
+			for k, client := range clients {
+				if now.Sub(client.lastSeen) > rateLimiterIdleTimeout {
+					delete(clients, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		client, ok := clients[key]
+		if !ok {
+			client = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), burst)}
+			clients[key] = client
+		}
+		client.lastSeen = now
+		return client.limiter
+	}
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if claims, ok := GetClaims(c); ok {
+			key = "user:" + strconv.Itoa(claims.UserID)
+		}
+
+		now := time.Now()
+		reservation := limiterFor(key, now).ReserveN(now, 1)
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			seconds := strconv.Itoa(int(math.Ceil(delay.Seconds())))
+			c.Header("Retry-After", seconds)
+			jsonhttpresponse.FromError(c, ErrRateLimited.With("retry_after_seconds", seconds))
+			return
+		}
+		c.Next()
+	}
+}
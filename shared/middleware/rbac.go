@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	shopProto "github.com/evrintobing17/ecommerce-system/shared/proto/shop"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrResourceNotFound is returned (wrapped) by a ShopIDResolver when the
+// resource the shop is looked up from does not exist
+var ErrResourceNotFound = apperror.NewNotFound("resource_not_found", "resource not found")
+
+// ErrAuthorizationRequired is returned to gRPC calls that need an end-user
+// token but carry none
+var ErrAuthorizationRequired = apperror.NewUnauthenticated("authorization_required", "authorization metadata is required")
+
+// ErrPermissionDenied is returned when the caller lacks a permission; the
+// permission is in its "permission" metadata
+var ErrPermissionDenied = apperror.NewPermissionDenied("permission_denied", "access denied")
+
+// ShopIDResolver extracts the ID of the shop a request operates on
+type ShopIDResolver func(c *gin.Context) (int, error)
+
+// ShopAccessChecker decides whether the caller may perform a shop-scoped action
+type ShopAccessChecker interface {
+	CanAccessShop(ctx context.Context, claims *shared.Claims, shopID int, action string) (bool, error)
+}
+
+type shopAccessChecker struct {
+	shopClient shopProto.ShopServiceClient
+}
+
+// NewShopAccessChecker returns a checker that grants every action to admins
+// and otherwise asks shop-service whether the caller's shop membership allows it
+func NewShopAccessChecker(shopClient shopProto.ShopServiceClient) ShopAccessChecker {
+	return &shopAccessChecker{shopClient: shopClient}
+}
+
+func (s *shopAccessChecker) CanAccessShop(ctx context.Context, claims *shared.Claims, shopID int, action string) (bool, error) {
+	if claims.Role == shared.RoleAdmin {
+		return true, nil
+	}
+
+	resp, err := s.shopClient.CheckShopPermission(ctx, &shopProto.CheckShopPermissionRequest{
+		UserId: int32(claims.UserID),
+		ShopId: int32(shopID),
+		Action: action,
+	})
+	if err != nil {
+		if apperror.IsKind(err, apperror.NotFound) {
+			return false, fmt.Errorf("%w: shop %d", ErrResourceNotFound, shopID)
+		}
+		return false, err
+	}
+
+	return resp.Allowed, nil
+}
+
+// RequirePermission aborts with 403 unless the authenticated user's role holds
+// every one of the given permissions. It must run after AuthMiddleware.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+			return
+		}
+
+		for _, permission := range permissions {
+			if !claims.HasPermission(permission) {
+				jsonhttpresponse.FromError(c, ErrPermissionDenied.With("permission", permission))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireShopPermission aborts with 403 unless the caller may perform action
+// on the shop returned by resolve, i.e. is an admin or a member of the shop
+// whose role grants the action. It must run after AuthMiddleware.
+func RequireShopPermission(action string, checker ShopAccessChecker, resolve ShopIDResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+			return
+		}
+
+		shopID, err := resolve(c)
+		if err != nil {
+			jsonhttpresponse.FromError(c, err)
+			return
+		}
+
+		allowed, err := checker.CanAccessShop(c.Request.Context(), claims, shopID, action)
+		if err != nil {
+			jsonhttpresponse.FromError(c, err)
+			return
+		}
+		if !allowed {
+			jsonhttpresponse.FromError(c, ErrPermissionDenied.With("permission", action))
+			return
+		}
+
+		c.Set("shop_id", shopID)
+		c.Next()
+	}
+}
+
+// AuthorizeShop is the gRPC counterpart of RequireShopPermission. It reads the
+// claims attached by UnaryPermissionInterceptor and returns an
+// *apperror.Error when the caller may not perform action on the shop.
+func AuthorizeShop(ctx context.Context, checker ShopAccessChecker, shopID int, action string) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return ErrAuthorizationRequired
+	}
+
+	allowed, err := checker.CanAccessShop(ctx, claims, shopID, action)
+	if err != nil {
+		if errors.Is(err, ErrResourceNotFound) {
+			return ErrResourceNotFound.With("shop_id", strconv.Itoa(shopID))
+		}
+		return fmt.Errorf("failed to check shop permission: %w", err)
+	}
+	if !allowed {
+		return ErrPermissionDenied.With("permission", action)
+	}
+	return nil
+}
+
+// ShopIDFromParam resolves the shop ID from a path parameter
+func ShopIDFromParam(name string) ShopIDResolver {
+	return func(c *gin.Context) (int, error) {
+		return IntFromParam(c, name)
+	}
+}
+
+// ShopIDFromJSON resolves the shop ID from an integer field of the JSON body
+func ShopIDFromJSON(field string) ShopIDResolver {
+	return func(c *gin.Context) (int, error) {
+		return IntFromJSON(c, field)
+	}
+}
+
+// ShopIDFromQuery resolves the shop ID from a query string parameter
+func ShopIDFromQuery(key string) ShopIDResolver {
+	return func(c *gin.Context) (int, error) {
+		shopID, err := strconv.Atoi(c.Query(key))
+		if err != nil || shopID == 0 {
+			return 0, invalidParameter(key, key+" is required")
+		}
+		return shopID, nil
+	}
+}
+
+// IntFromParam reads a positive integer path parameter
+func IntFromParam(c *gin.Context, name string) (int, error) {
+	value, err := strconv.Atoi(c.Param(name))
+	if err != nil || value <= 0 {
+		return 0, invalidParameter(name, "invalid "+name)
+	}
+	return value, nil
+}
+
+// IntFromJSON reads an integer field from the JSON body without consuming it,
+// so the handler can still bind the body afterwards
+func IntFromJSON(c *gin.Context, field string) (int, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return 0, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return 0, apperror.NewInvalidArgument("malformed_body", "request body is not valid JSON")
+	}
+
+	value, ok := payload[field].(float64)
+	if !ok || value <= 0 {
+		return 0, invalidParameter(field, field+" is required")
+	}
+	return int(value), nil
+}
+
+func invalidParameter(name, message string) error {
+	return apperror.NewInvalidArgument("invalid_parameter", message).With("parameter", name)
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic in a handler into a 500 response in the JSON
+// envelope. It replaces gin.Recovery and must run after RequestID.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		slog.ErrorContext(c.Request.Context(), "http panic", "path", c.FullPath(), "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		jsonhttpresponse.InternalServerError(c, fmt.Errorf("panic: %v", recovered))
+	})
+}
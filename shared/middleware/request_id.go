@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestID takes the request ID from the X-Request-ID header, or generates
+// one, attaches it to the request context for logging and echoes it in the
+// response header
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(shared.RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = shared.NewRequestID()
+		}
+
+		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(shared.ContextWithRequestID(c.Request.Context(), requestID))
+		c.Header(shared.RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// AccessLog writes one structured log line per HTTP request. It replaces the
+// unstructured logger added by gin.Default and must run after RequestID.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		slog.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
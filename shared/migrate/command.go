@@ -0,0 +1,120 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+)
+
+// Usage describes the migrate subcommand
+const Usage = `usage: <service> migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [n]        revert the last n applied migrations (default 1)
+  status          list the migrations and when they were applied
+  create <name>   add empty up and down files for a new migration`
+
+// RunCommand runs the migrate subcommand given in args for service. files
+// holds the embedded migrations; create writes new files to dir, the
+// migrations directory in the source tree.
+func RunCommand(ctx context.Context, service string, dbConfig shared.DBConfig, files fs.FS, dir string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) != 2 {
+			return errors.New(Usage)
+		}
+		return create(dir, args[1])
+	case "up", "down", "status":
+	default:
+		return errors.New(Usage)
+	}
+
+	db, err := shared.ConnectDB(dbConfig)
+	if err != nil {
+		return err
+	}
+	defer shared.CloseDB(db)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	m, err := New(sqlDB, service, files)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		fmt.Printf("Applied %d migration(s)\n", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		fmt.Printf("Reverted %d migration(s)\n", reverted)
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+// create writes <next version>_<name>.up.sql and .down.sql to dir
+func create(dir, name string) error {
+	name = strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(name))
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		fileName := fmt.Sprintf("%04d_%s.%s.sql", version, name, direction)
+		if !fileNamePattern.MatchString(fileName) {
+			return fmt.Errorf("invalid migration name %q, use letters, digits and underscores", name)
+		}
+		path := filepath.Join(dir, fileName)
+		content := fmt.Sprintf("-- %s (%s)\n", strings.ReplaceAll(name, "_", " "), direction)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+		fmt.Println("Created", path)
+	}
+	return nil
+}
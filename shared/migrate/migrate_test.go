@@ -0,0 +1,272 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeDB is a database/sql driver logging every statement it runs. It keeps
+// the schema_migrations rows of one service and fails statements containing
+// failOn.
+type fakeDB struct {
+	mu      sync.Mutex
+	log     []string
+	applied map[int64]time.Time
+	failOn  string
+}
+
+func newFakeDB(applied ...int64) *fakeDB {
+	db := &fakeDB{applied: map[int64]time.Time{}}
+	for _, version := range applied {
+		db.applied[version] = time.Now()
+	}
+	return db
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+func (db *fakeDB) record(entry string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.log = append(db.log, entry)
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN")
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.record("COMMIT")
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.record("ROLLBACK")
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	entry := query
+	for _, arg := range args {
+		entry += fmt.Sprintf(" %v", arg.Value)
+	}
+	c.db.record(entry)
+	if c.db.failOn != "" && strings.Contains(query, c.db.failOn) {
+		return nil, errors.New("syntax error")
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		c.db.applied[args[1].Value.(int64)] = time.Now()
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		delete(c.db.applied, args[1].Value.(int64))
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.record("SELECT schema_migrations")
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	rows := &fakeRows{}
+	for version, appliedAt := range c.db.applied {
+		rows.values = append(rows.values, []driver.Value{version, appliedAt})
+	}
+	return rows, nil
+}
+
+type fakeRows struct{ values [][]driver.Value }
+
+func (r *fakeRows) Columns() []string { return []string{"version", "applied_at"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var files = fstest.MapFS{
+	"1_create_users.up.sql":     {Data: []byte("CREATE TABLE users")},
+	"1_create_users.down.sql":   {Data: []byte("DROP TABLE users")},
+	"2_add_phone.up.sql":        {Data: []byte("ALTER TABLE users ADD phone")},
+	"10_create_orders.up.sql":   {Data: []byte("CREATE TABLE orders")},
+	"10_create_orders.down.sql": {Data: []byte("DROP TABLE orders")},
+	"README.md":                 {Data: []byte("not a migration")},
+}
+
+const (
+	lock   = "SELECT pg_advisory_lock(hashtext($1)) schema_migrations:user-service"
+	unlock = "SELECT pg_advisory_unlock(hashtext($1)) schema_migrations:user-service"
+)
+
+func newMigrator(t *testing.T, db *fakeDB) *Migrator {
+	t.Helper()
+	m, err := New(sql.OpenDB(db), "user-service", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(files)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var got []string
+	for _, migration := range migrations {
+		got = append(got, fmt.Sprintf("%d_%s", migration.Version, migration.Name))
+	}
+	if want := []string{"1_create_users", "2_add_phone", "10_create_orders"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("migrations = %v, want %v", got, want)
+	}
+	if migrations[0].Down != "DROP TABLE users" || migrations[1].Down != "" {
+		t.Errorf("down files = %q, %q", migrations[0].Down, migrations[1].Down)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{"bad name", fstest.MapFS{"create_users.up.sql": {}}, "is not named"},
+		{"names differ", fstest.MapFS{
+			"1_create_users.up.sql":    {Data: []byte("CREATE TABLE users")},
+			"1_create_people.down.sql": {Data: []byte("DROP TABLE people")},
+		}, "has files named"},
+		{"no up file", fstest.MapFS{"1_create_users.down.sql": {Data: []byte("DROP TABLE users")}}, "has no up file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.files); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load: err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpAppliesPendingInOrderUnderLock(t *testing.T) {
+	db := newFakeDB(1)
+	applied, err := newMigrator(t, db).Up(context.Background())
+	if err != nil || applied != 2 {
+		t.Fatalf("Up = %d, %v, want 2 applied", applied, err)
+	}
+
+	want := []string{
+		lock,
+		createTableSQL,
+		"SELECT schema_migrations",
+		"BEGIN",
+		"ALTER TABLE users ADD phone",
+		"INSERT INTO schema_migrations (service, version, name) VALUES ($1, $2, $3) user-service 2 add_phone",
+		"COMMIT",
+		"BEGIN",
+		"CREATE TABLE orders",
+		"INSERT INTO schema_migrations (service, version, name) VALUES ($1, $2, $3) user-service 10 create_orders",
+		"COMMIT",
+		unlock,
+	}
+	if !reflect.DeepEqual(db.log, want) {
+		t.Errorf("statements =\n%s\nwant\n%s", strings.Join(db.log, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestFailedMigrationReleasesLock(t *testing.T) {
+	db := newFakeDB()
+	db.failOn = "ADD phone"
+	applied, err := newMigrator(t, db).Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "migration 2_add_phone up") {
+		t.Fatalf("Up: err = %v, want the failing migration", err)
+	}
+	if applied != 1 {
+		t.Errorf("applied = %d, want 1", applied)
+	}
+
+	tail := db.log[len(db.log)-3:]
+	if want := []string{"ALTER TABLE users ADD phone", "ROLLBACK", unlock}; !reflect.DeepEqual(tail, want) {
+		t.Errorf("last statements = %v, want %v", tail, want)
+	}
+	if _, ok := db.applied[2]; ok {
+		t.Error("failed migration was recorded")
+	}
+}
+
+func TestDown(t *testing.T) {
+	tests := []struct {
+		name        string
+		applied     []int64
+		steps       int
+		wantErr     error
+		wantApplied []int64
+	}{
+		{"stops at steps", []int64{1, 2, 10}, 1, nil, []int64{1, 2}},
+		{"skips pending", []int64{1}, 1, nil, nil},
+		{"no down file", []int64{1, 2, 10}, 2, ErrNoDownMigration, []int64{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(tt.applied...)
+			_, err := newMigrator(t, db).Down(context.Background(), tt.steps)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Down: err = %v, want %v", err, tt.wantErr)
+			}
+
+			var got []int64
+			for version := range db.applied {
+				got = append(got, version)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !reflect.DeepEqual(got, tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", got, tt.wantApplied)
+			}
+			if last := db.log[len(db.log)-1]; last != unlock {
+				t.Errorf("last statement = %q, want the unlock", last)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	statuses, err := newMigrator(t, newFakeDB(1, 2)).Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+
+	var pending []int64
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Version)
+		}
+	}
+	if len(statuses) != 3 || !reflect.DeepEqual(pending, []int64{10}) {
+		t.Errorf("statuses = %+v, want 3 with 10 pending", statuses)
+	}
+}
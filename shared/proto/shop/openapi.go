@@ -0,0 +1,13 @@
+package shop
+
+import _ "embed"
+
+// OpenAPIV2 is the Swagger 2.0 document generated from shop.proto
+//
+//go:embed shop.swagger.json
+var OpenAPIV2 []byte
+
+// OpenAPIV3 is the OpenAPI 3 document generated from shop.proto
+//
+//go:embed openapi.yaml
+var OpenAPIV3 []byte
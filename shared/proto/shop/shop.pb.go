@@ -7,6 +7,7 @@
 package shop
 
 import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -109,7 +110,7 @@ type CreateShopRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
-	OwnerId       int32                  `protobuf:"varint,3,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	OwnerId       int32                  `protobuf:"varint,3,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"` // ignored for end users, who own the shops they create
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -425,11 +426,123 @@ func (x *GetShopsResponse) GetLimit() int32 {
 	return 0
 }
 
+type CheckShopPermissionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ShopId        int32                  `protobuf:"varint,2,opt,name=shop_id,json=shopId,proto3" json:"shop_id,omitempty"`
+	Action        string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"` // e.g. "product:write", "stock:write", "shop:manage"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckShopPermissionRequest) Reset() {
+	*x = CheckShopPermissionRequest{}
+	mi := &file_proto_shop_shop_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckShopPermissionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckShopPermissionRequest) ProtoMessage() {}
+
+func (x *CheckShopPermissionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shop_shop_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckShopPermissionRequest.ProtoReflect.Descriptor instead.
+func (*CheckShopPermissionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shop_shop_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CheckShopPermissionRequest) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CheckShopPermissionRequest) GetShopId() int32 {
+	if x != nil {
+		return x.ShopId
+	}
+	return 0
+}
+
+func (x *CheckShopPermissionRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+type CheckShopPermissionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"` // member role of the user in the shop, empty if not a member
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckShopPermissionResponse) Reset() {
+	*x = CheckShopPermissionResponse{}
+	mi := &file_proto_shop_shop_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckShopPermissionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckShopPermissionResponse) ProtoMessage() {}
+
+func (x *CheckShopPermissionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shop_shop_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckShopPermissionResponse.ProtoReflect.Descriptor instead.
+func (*CheckShopPermissionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shop_shop_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CheckShopPermissionResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *CheckShopPermissionResponse) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
 var File_proto_shop_shop_proto protoreflect.FileDescriptor
 
 const file_proto_shop_shop_proto_rawDesc = "" +
 	"\n" +
-	"\x15proto/shop/shop.proto\x12\x04shop\"\xa5\x01\n" +
+	"\x15proto/shop/shop.proto\x12\x04shop\x1a\x1cgoogle/api/annotations.proto\"\xa5\x01\n" +
 	"\x04Shop\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
@@ -460,12 +573,20 @@ const file_proto_shop_shop_proto_rawDesc = "" +
 	".shop.ShopR\x05shops\x12\x14\n" +
 	"\x05total\x18\x02 \x01(\x05R\x05total\x12\x12\n" +
 	"\x04page\x18\x03 \x01(\x05R\x04page\x12\x14\n" +
-	"\x05limit\x18\x04 \x01(\x05R\x05limit2\xc1\x01\n" +
-	"\vShopService\x12?\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"f\n" +
+	"\x1aCheckShopPermissionRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x05R\x06userId\x12\x17\n" +
+	"\ashop_id\x18\x02 \x01(\x05R\x06shopId\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\"K\n" +
+	"\x1bCheckShopPermissionResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role2\xef\x02\n" +
+	"\vShopService\x12Y\n" +
 	"\n" +
-	"CreateShop\x12\x17.shop.CreateShopRequest\x1a\x18.shop.CreateShopResponse\x126\n" +
-	"\aGetShop\x12\x14.shop.GetShopRequest\x1a\x15.shop.GetShopResponse\x129\n" +
-	"\bGetShops\x12\x15.shop.GetShopsRequest\x1a\x16.shop.GetShopsResponseB\bZ\x06.;shopb\x06proto3"
+	"CreateShop\x12\x17.shop.CreateShopRequest\x1a\x18.shop.CreateShopResponse\"\x18\x82\xd3\xe4\x93\x02\x12:\x01*\"\r/api/v2/shops\x12W\n" +
+	"\aGetShop\x12\x14.shop.GetShopRequest\x1a\x15.shop.GetShopResponse\"\x1f\x82\xd3\xe4\x93\x02\x19\x12\x17/api/v2/shops/{shop_id}\x12P\n" +
+	"\bGetShops\x12\x15.shop.GetShopsRequest\x1a\x16.shop.GetShopsResponse\"\x15\x82\xd3\xe4\x93\x02\x0f\x12\r/api/v2/shops\x12Z\n" +
+	"\x13CheckShopPermission\x12 .shop.CheckShopPermissionRequest\x1a!.shop.CheckShopPermissionResponseB\bZ\x06.;shopb\x06proto3"
 
 var (
 	file_proto_shop_shop_proto_rawDescOnce sync.Once
@@ -479,15 +600,17 @@ func file_proto_shop_shop_proto_rawDescGZIP() []byte {
 	return file_proto_shop_shop_proto_rawDescData
 }
 
-var file_proto_shop_shop_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_proto_shop_shop_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_proto_shop_shop_proto_goTypes = []any{
-	(*Shop)(nil),               // 0: shop.Shop
-	(*CreateShopRequest)(nil),  // 1: shop.CreateShopRequest
-	(*CreateShopResponse)(nil), // 2: shop.CreateShopResponse
-	(*GetShopRequest)(nil),     // 3: shop.GetShopRequest
-	(*GetShopResponse)(nil),    // 4: shop.GetShopResponse
-	(*GetShopsRequest)(nil),    // 5: shop.GetShopsRequest
-	(*GetShopsResponse)(nil),   // 6: shop.GetShopsResponse
+	(*Shop)(nil),                        // 0: shop.Shop
+	(*CreateShopRequest)(nil),           // 1: shop.CreateShopRequest
+	(*CreateShopResponse)(nil),          // 2: shop.CreateShopResponse
+	(*GetShopRequest)(nil),              // 3: shop.GetShopRequest
+	(*GetShopResponse)(nil),             // 4: shop.GetShopResponse
+	(*GetShopsRequest)(nil),             // 5: shop.GetShopsRequest
+	(*GetShopsResponse)(nil),            // 6: shop.GetShopsResponse
+	(*CheckShopPermissionRequest)(nil),  // 7: shop.CheckShopPermissionRequest
+	(*CheckShopPermissionResponse)(nil), // 8: shop.CheckShopPermissionResponse
 }
 var file_proto_shop_shop_proto_depIdxs = []int32{
 	0, // 0: shop.CreateShopResponse.shop:type_name -> shop.Shop
@@ -496,11 +619,13 @@ var file_proto_shop_shop_proto_depIdxs = []int32{
 	1, // 3: shop.ShopService.CreateShop:input_type -> shop.CreateShopRequest
 	3, // 4: shop.ShopService.GetShop:input_type -> shop.GetShopRequest
 	5, // 5: shop.ShopService.GetShops:input_type -> shop.GetShopsRequest
-	2, // 6: shop.ShopService.CreateShop:output_type -> shop.CreateShopResponse
-	4, // 7: shop.ShopService.GetShop:output_type -> shop.GetShopResponse
-	6, // 8: shop.ShopService.GetShops:output_type -> shop.GetShopsResponse
-	6, // [6:9] is the sub-list for method output_type
-	3, // [3:6] is the sub-list for method input_type
+	7, // 6: shop.ShopService.CheckShopPermission:input_type -> shop.CheckShopPermissionRequest
+	2, // 7: shop.ShopService.CreateShop:output_type -> shop.CreateShopResponse
+	4, // 8: shop.ShopService.GetShop:output_type -> shop.GetShopResponse
+	6, // 9: shop.ShopService.GetShops:output_type -> shop.GetShopsResponse
+	8, // 10: shop.ShopService.CheckShopPermission:output_type -> shop.CheckShopPermissionResponse
+	7, // [7:11] is the sub-list for method output_type
+	3, // [3:7] is the sub-list for method input_type
 	3, // [3:3] is the sub-list for extension type_name
 	3, // [3:3] is the sub-list for extension extendee
 	0, // [0:3] is the sub-list for field type_name
@@ -517,7 +642,7 @@ func file_proto_shop_shop_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_shop_shop_proto_rawDesc), len(file_proto_shop_shop_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
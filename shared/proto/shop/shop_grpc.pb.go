@@ -19,9 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ShopService_CreateShop_FullMethodName = "/shop.ShopService/CreateShop"
-	ShopService_GetShop_FullMethodName    = "/shop.ShopService/GetShop"
-	ShopService_GetShops_FullMethodName   = "/shop.ShopService/GetShops"
+	ShopService_CreateShop_FullMethodName          = "/shop.ShopService/CreateShop"
+	ShopService_GetShop_FullMethodName             = "/shop.ShopService/GetShop"
+	ShopService_GetShops_FullMethodName            = "/shop.ShopService/GetShops"
+	ShopService_CheckShopPermission_FullMethodName = "/shop.ShopService/CheckShopPermission"
 )
 
 // ShopServiceClient is the client API for ShopService service.
@@ -31,6 +32,8 @@ type ShopServiceClient interface {
 	CreateShop(ctx context.Context, in *CreateShopRequest, opts ...grpc.CallOption) (*CreateShopResponse, error)
 	GetShop(ctx context.Context, in *GetShopRequest, opts ...grpc.CallOption) (*GetShopResponse, error)
 	GetShops(ctx context.Context, in *GetShopsRequest, opts ...grpc.CallOption) (*GetShopsResponse, error)
+	// Internal methods have no HTTP mapping
+	CheckShopPermission(ctx context.Context, in *CheckShopPermissionRequest, opts ...grpc.CallOption) (*CheckShopPermissionResponse, error)
 }
 
 type shopServiceClient struct {
@@ -71,6 +74,16 @@ func (c *shopServiceClient) GetShops(ctx context.Context, in *GetShopsRequest, o
 	return out, nil
 }
 
+func (c *shopServiceClient) CheckShopPermission(ctx context.Context, in *CheckShopPermissionRequest, opts ...grpc.CallOption) (*CheckShopPermissionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckShopPermissionResponse)
+	err := c.cc.Invoke(ctx, ShopService_CheckShopPermission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShopServiceServer is the server API for ShopService service.
 // All implementations must embed UnimplementedShopServiceServer
 // for forward compatibility.
@@ -78,6 +91,8 @@ type ShopServiceServer interface {
 	CreateShop(context.Context, *CreateShopRequest) (*CreateShopResponse, error)
 	GetShop(context.Context, *GetShopRequest) (*GetShopResponse, error)
 	GetShops(context.Context, *GetShopsRequest) (*GetShopsResponse, error)
+	// Internal methods have no HTTP mapping
+	CheckShopPermission(context.Context, *CheckShopPermissionRequest) (*CheckShopPermissionResponse, error)
 	mustEmbedUnimplementedShopServiceServer()
 }
 
@@ -97,6 +112,9 @@ func (UnimplementedShopServiceServer) GetShop(context.Context, *GetShopRequest)
 func (UnimplementedShopServiceServer) GetShops(context.Context, *GetShopsRequest) (*GetShopsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetShops not implemented")
 }
+func (UnimplementedShopServiceServer) CheckShopPermission(context.Context, *CheckShopPermissionRequest) (*CheckShopPermissionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckShopPermission not implemented")
+}
 func (UnimplementedShopServiceServer) mustEmbedUnimplementedShopServiceServer() {}
 func (UnimplementedShopServiceServer) testEmbeddedByValue()                     {}
 
@@ -172,6 +190,24 @@ func _ShopService_GetShops_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShopService_CheckShopPermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckShopPermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShopServiceServer).CheckShopPermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShopService_CheckShopPermission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShopServiceServer).CheckShopPermission(ctx, req.(*CheckShopPermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ShopService_ServiceDesc is the grpc.ServiceDesc for ShopService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,6 +227,10 @@ var ShopService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetShops",
 			Handler:    _ShopService_GetShops_Handler,
 		},
+		{
+			MethodName: "CheckShopPermission",
+			Handler:    _ShopService_CheckShopPermission_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/shop/shop.proto",
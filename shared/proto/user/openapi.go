@@ -0,0 +1,13 @@
+package user
+
+import _ "embed"
+
+// OpenAPIV2 is the Swagger 2.0 document generated from user.proto
+//
+//go:embed user.swagger.json
+var OpenAPIV2 []byte
+
+// OpenAPIV3 is the OpenAPI 3 document generated from user.proto
+//
+//go:embed openapi.yaml
+var OpenAPIV3 []byte
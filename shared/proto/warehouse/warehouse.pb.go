@@ -7,6 +7,7 @@
 package warehouse
 
 import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
@@ -115,7 +116,7 @@ func (x *Warehouse) GetUpdatedAt() string {
 
 type Stock struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ProductId     int32                  `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	VariantId     int32                  `protobuf:"varint,1,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
 	WarehouseId   int32                  `protobuf:"varint,2,opt,name=warehouse_id,json=warehouseId,proto3" json:"warehouse_id,omitempty"`
 	Quantity      int32                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
 	Reserved      int32                  `protobuf:"varint,4,opt,name=reserved,proto3" json:"reserved,omitempty"`
@@ -155,9 +156,9 @@ func (*Stock) Descriptor() ([]byte, []int) {
 	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *Stock) GetProductId() int32 {
+func (x *Stock) GetVariantId() int32 {
 	if x != nil {
-		return x.ProductId
+		return x.VariantId
 	}
 	return 0
 }
@@ -599,7 +600,7 @@ func (x *UpdateWarehouseResponse) GetWarehouse() *Warehouse {
 
 type TransferStockRequest struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
-	ProductId       int32                  `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	VariantId       int32                  `protobuf:"varint,1,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
 	FromWarehouseId int32                  `protobuf:"varint,2,opt,name=from_warehouse_id,json=fromWarehouseId,proto3" json:"from_warehouse_id,omitempty"`
 	ToWarehouseId   int32                  `protobuf:"varint,3,opt,name=to_warehouse_id,json=toWarehouseId,proto3" json:"to_warehouse_id,omitempty"`
 	Quantity        int32                  `protobuf:"varint,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
@@ -637,9 +638,9 @@ func (*TransferStockRequest) Descriptor() ([]byte, []int) {
 	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *TransferStockRequest) GetProductId() int32 {
+func (x *TransferStockRequest) GetVariantId() int32 {
 	if x != nil {
-		return x.ProductId
+		return x.VariantId
 	}
 	return 0
 }
@@ -719,7 +720,7 @@ func (x *TransferStockResponse) GetMessage() string {
 
 type GetStockRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ProductId     int32                  `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	VariantId     int32                  `protobuf:"varint,1,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
 	WarehouseId   int32                  `protobuf:"varint,2,opt,name=warehouse_id,json=warehouseId,proto3" json:"warehouse_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -755,9 +756,9 @@ func (*GetStockRequest) Descriptor() ([]byte, []int) {
 	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *GetStockRequest) GetProductId() int32 {
+func (x *GetStockRequest) GetVariantId() int32 {
 	if x != nil {
-		return x.ProductId
+		return x.VariantId
 	}
 	return 0
 }
@@ -815,7 +816,7 @@ func (x *GetStockResponse) GetStock() *Stock {
 
 type UpdateStockRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ProductId     int32                  `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	VariantId     int32                  `protobuf:"varint,1,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
 	WarehouseId   int32                  `protobuf:"varint,2,opt,name=warehouse_id,json=warehouseId,proto3" json:"warehouse_id,omitempty"`
 	Quantity      int32                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
 	Reserved      int32                  `protobuf:"varint,4,opt,name=reserved,proto3" json:"reserved,omitempty"`
@@ -854,9 +855,9 @@ func (*UpdateStockRequest) Descriptor() ([]byte, []int) {
 	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *UpdateStockRequest) GetProductId() int32 {
+func (x *UpdateStockRequest) GetVariantId() int32 {
 	if x != nil {
-		return x.ProductId
+		return x.VariantId
 	}
 	return 0
 }
@@ -941,11 +942,215 @@ func (x *UpdateStockResponse) GetStock() *Stock {
 	return nil
 }
 
+type GetAvailabilityRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// At most 100 variant IDs
+	VariantIds    []int32 `protobuf:"varint,1,rep,packed,name=variant_ids,json=variantIds,proto3" json:"variant_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAvailabilityRequest) Reset() {
+	*x = GetAvailabilityRequest{}
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAvailabilityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAvailabilityRequest) ProtoMessage() {}
+
+func (x *GetAvailabilityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAvailabilityRequest.ProtoReflect.Descriptor instead.
+func (*GetAvailabilityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetAvailabilityRequest) GetVariantIds() []int32 {
+	if x != nil {
+		return x.VariantIds
+	}
+	return nil
+}
+
+type WarehouseAvailability struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WarehouseId   int32                  `protobuf:"varint,1,opt,name=warehouse_id,json=warehouseId,proto3" json:"warehouse_id,omitempty"`
+	Available     int32                  `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WarehouseAvailability) Reset() {
+	*x = WarehouseAvailability{}
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WarehouseAvailability) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WarehouseAvailability) ProtoMessage() {}
+
+func (x *WarehouseAvailability) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WarehouseAvailability.ProtoReflect.Descriptor instead.
+func (*WarehouseAvailability) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WarehouseAvailability) GetWarehouseId() int32 {
+	if x != nil {
+		return x.WarehouseId
+	}
+	return 0
+}
+
+func (x *WarehouseAvailability) GetAvailable() int32 {
+	if x != nil {
+		return x.Available
+	}
+	return 0
+}
+
+type VariantAvailability struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	VariantId int32                  `protobuf:"varint,1,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
+	// Quantity minus reserved, summed over the warehouses
+	Available int32 `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"`
+	// Active warehouses with a positive available quantity
+	Warehouses    []*WarehouseAvailability `protobuf:"bytes,3,rep,name=warehouses,proto3" json:"warehouses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VariantAvailability) Reset() {
+	*x = VariantAvailability{}
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VariantAvailability) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VariantAvailability) ProtoMessage() {}
+
+func (x *VariantAvailability) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VariantAvailability.ProtoReflect.Descriptor instead.
+func (*VariantAvailability) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *VariantAvailability) GetVariantId() int32 {
+	if x != nil {
+		return x.VariantId
+	}
+	return 0
+}
+
+func (x *VariantAvailability) GetAvailable() int32 {
+	if x != nil {
+		return x.Available
+	}
+	return 0
+}
+
+func (x *VariantAvailability) GetWarehouses() []*WarehouseAvailability {
+	if x != nil {
+		return x.Warehouses
+	}
+	return nil
+}
+
+type GetAvailabilityResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One entry per requested variant, in request order
+	Variants      []*VariantAvailability `protobuf:"bytes,1,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAvailabilityResponse) Reset() {
+	*x = GetAvailabilityResponse{}
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAvailabilityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAvailabilityResponse) ProtoMessage() {}
+
+func (x *GetAvailabilityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_warehouse_warehouse_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAvailabilityResponse.ProtoReflect.Descriptor instead.
+func (*GetAvailabilityResponse) Descriptor() ([]byte, []int) {
+	return file_proto_warehouse_warehouse_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GetAvailabilityResponse) GetVariants() []*VariantAvailability {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
 var File_proto_warehouse_warehouse_proto protoreflect.FileDescriptor
 
 const file_proto_warehouse_warehouse_proto_rawDesc = "" +
 	"\n" +
-	"\x1fproto/warehouse/warehouse.proto\x12\twarehouse\"\xba\x01\n" +
+	"\x1fproto/warehouse/warehouse.proto\x12\twarehouse\x1a\x1cgoogle/api/annotations.proto\"\xba\x01\n" +
 	"\tWarehouse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
@@ -958,7 +1163,7 @@ const file_proto_warehouse_warehouse_proto_rawDesc = "" +
 	"updated_at\x18\a \x01(\tR\tupdatedAt\"\xbf\x01\n" +
 	"\x05Stock\x12\x1d\n" +
 	"\n" +
-	"product_id\x18\x01 \x01(\x05R\tproductId\x12!\n" +
+	"variant_id\x18\x01 \x01(\x05R\tvariantId\x12!\n" +
 	"\fwarehouse_id\x18\x02 \x01(\x05R\vwarehouseId\x12\x1a\n" +
 	"\bquantity\x18\x03 \x01(\x05R\bquantity\x12\x1a\n" +
 	"\breserved\x18\x04 \x01(\x05R\breserved\x12\x1d\n" +
@@ -993,7 +1198,7 @@ const file_proto_warehouse_warehouse_proto_rawDesc = "" +
 	"\twarehouse\x18\x01 \x01(\v2\x14.warehouse.WarehouseR\twarehouse\"\xa5\x01\n" +
 	"\x14TransferStockRequest\x12\x1d\n" +
 	"\n" +
-	"product_id\x18\x01 \x01(\x05R\tproductId\x12*\n" +
+	"variant_id\x18\x01 \x01(\x05R\tvariantId\x12*\n" +
 	"\x11from_warehouse_id\x18\x02 \x01(\x05R\x0ffromWarehouseId\x12&\n" +
 	"\x0fto_warehouse_id\x18\x03 \x01(\x05R\rtoWarehouseId\x12\x1a\n" +
 	"\bquantity\x18\x04 \x01(\x05R\bquantity\"K\n" +
@@ -1002,27 +1207,43 @@ const file_proto_warehouse_warehouse_proto_rawDesc = "" +
 	"\amessage\x18\x02 \x01(\tR\amessage\"S\n" +
 	"\x0fGetStockRequest\x12\x1d\n" +
 	"\n" +
-	"product_id\x18\x01 \x01(\x05R\tproductId\x12!\n" +
+	"variant_id\x18\x01 \x01(\x05R\tvariantId\x12!\n" +
 	"\fwarehouse_id\x18\x02 \x01(\x05R\vwarehouseId\":\n" +
 	"\x10GetStockResponse\x12&\n" +
 	"\x05stock\x18\x01 \x01(\v2\x10.warehouse.StockR\x05stock\"\xac\x01\n" +
 	"\x12UpdateStockRequest\x12\x1d\n" +
 	"\n" +
-	"product_id\x18\x01 \x01(\x05R\tproductId\x12!\n" +
+	"variant_id\x18\x01 \x01(\x05R\tvariantId\x12!\n" +
 	"\fwarehouse_id\x18\x02 \x01(\x05R\vwarehouseId\x12\x1a\n" +
 	"\bquantity\x18\x03 \x01(\x05R\bquantity\x12\x1a\n" +
 	"\breserved\x18\x04 \x01(\x05R\breserved\x12\x1c\n" +
 	"\toperation\x18\x05 \x01(\tR\toperation\"W\n" +
 	"\x13UpdateStockResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12&\n" +
-	"\x05stock\x18\x02 \x01(\v2\x10.warehouse.StockR\x05stock2\xd2\x04\n" +
-	"\x10WarehouseService\x12O\n" +
-	"\fGetWarehouse\x12\x1e.warehouse.GetWarehouseRequest\x1a\x1f.warehouse.GetWarehouseResponse\x12R\n" +
-	"\rGetWarehouses\x12\x1f.warehouse.GetWarehousesRequest\x1a .warehouse.GetWarehousesResponse\x12X\n" +
-	"\x0fCreateWarehouse\x12!.warehouse.CreateWarehouseRequest\x1a\".warehouse.CreateWarehouseResponse\x12X\n" +
-	"\x0fUpdateWarehouse\x12!.warehouse.UpdateWarehouseRequest\x1a\".warehouse.UpdateWarehouseResponse\x12R\n" +
-	"\rTransferStock\x12\x1f.warehouse.TransferStockRequest\x1a .warehouse.TransferStockResponse\x12C\n" +
-	"\bGetStock\x12\x1a.warehouse.GetStockRequest\x1a\x1b.warehouse.GetStockResponse\x12L\n" +
+	"\x05stock\x18\x02 \x01(\v2\x10.warehouse.StockR\x05stock\"9\n" +
+	"\x16GetAvailabilityRequest\x12\x1f\n" +
+	"\vvariant_ids\x18\x01 \x03(\x05R\n" +
+	"variantIds\"X\n" +
+	"\x15WarehouseAvailability\x12!\n" +
+	"\fwarehouse_id\x18\x01 \x01(\x05R\vwarehouseId\x12\x1c\n" +
+	"\tavailable\x18\x02 \x01(\x05R\tavailable\"\x94\x01\n" +
+	"\x13VariantAvailability\x12\x1d\n" +
+	"\n" +
+	"variant_id\x18\x01 \x01(\x05R\tvariantId\x12\x1c\n" +
+	"\tavailable\x18\x02 \x01(\x05R\tavailable\x12@\n" +
+	"\n" +
+	"warehouses\x18\x03 \x03(\v2 .warehouse.WarehouseAvailabilityR\n" +
+	"warehouses\"U\n" +
+	"\x17GetAvailabilityResponse\x12:\n" +
+	"\bvariants\x18\x01 \x03(\v2\x1e.warehouse.VariantAvailabilityR\bvariants2\xb5\a\n" +
+	"\x10WarehouseService\x12z\n" +
+	"\fGetWarehouse\x12\x1e.warehouse.GetWarehouseRequest\x1a\x1f.warehouse.GetWarehouseResponse\")\x82\xd3\xe4\x93\x02#\x12!/api/v2/warehouses/{warehouse_id}\x12n\n" +
+	"\rGetWarehouses\x12\x1f.warehouse.GetWarehousesRequest\x1a .warehouse.GetWarehousesResponse\"\x1a\x82\xd3\xe4\x93\x02\x14\x12\x12/api/v2/warehouses\x12w\n" +
+	"\x0fCreateWarehouse\x12!.warehouse.CreateWarehouseRequest\x1a\".warehouse.CreateWarehouseResponse\"\x1d\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/api/v2/warehouses\x12\x86\x01\n" +
+	"\x0fUpdateWarehouse\x12!.warehouse.UpdateWarehouseRequest\x1a\".warehouse.UpdateWarehouseResponse\",\x82\xd3\xe4\x93\x02&:\x01*\x1a!/api/v2/warehouses/{warehouse_id}\x12z\n" +
+	"\rTransferStock\x12\x1f.warehouse.TransferStockRequest\x1a .warehouse.TransferStockResponse\"&\x82\xd3\xe4\x93\x02 :\x01*\"\x1b/api/v2/warehouses/transfer\x12e\n" +
+	"\bGetStock\x12\x1a.warehouse.GetStockRequest\x1a\x1b.warehouse.GetStockResponse\" \x82\xd3\xe4\x93\x02\x1a\x12\x18/api/v2/warehouses/stock\x12\x81\x01\n" +
+	"\x0fGetAvailability\x12!.warehouse.GetAvailabilityRequest\x1a\".warehouse.GetAvailabilityResponse\"'\x82\xd3\xe4\x93\x02!\x12\x1f/api/v2/warehouses/availability\x12L\n" +
 	"\vUpdateStock\x12\x1d.warehouse.UpdateStockRequest\x1a\x1e.warehouse.UpdateStockResponseB\rZ\v.;warehouseb\x06proto3"
 
 var (
@@ -1037,7 +1258,7 @@ func file_proto_warehouse_warehouse_proto_rawDescGZIP() []byte {
 	return file_proto_warehouse_warehouse_proto_rawDescData
 }
 
-var file_proto_warehouse_warehouse_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_proto_warehouse_warehouse_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_proto_warehouse_warehouse_proto_goTypes = []any{
 	(*Warehouse)(nil),               // 0: warehouse.Warehouse
 	(*Stock)(nil),                   // 1: warehouse.Stock
@@ -1055,6 +1276,10 @@ var file_proto_warehouse_warehouse_proto_goTypes = []any{
 	(*GetStockResponse)(nil),        // 13: warehouse.GetStockResponse
 	(*UpdateStockRequest)(nil),      // 14: warehouse.UpdateStockRequest
 	(*UpdateStockResponse)(nil),     // 15: warehouse.UpdateStockResponse
+	(*GetAvailabilityRequest)(nil),  // 16: warehouse.GetAvailabilityRequest
+	(*WarehouseAvailability)(nil),   // 17: warehouse.WarehouseAvailability
+	(*VariantAvailability)(nil),     // 18: warehouse.VariantAvailability
+	(*GetAvailabilityResponse)(nil), // 19: warehouse.GetAvailabilityResponse
 }
 var file_proto_warehouse_warehouse_proto_depIdxs = []int32{
 	0,  // 0: warehouse.GetWarehouseResponse.warehouse:type_name -> warehouse.Warehouse
@@ -1063,25 +1288,29 @@ var file_proto_warehouse_warehouse_proto_depIdxs = []int32{
 	0,  // 3: warehouse.UpdateWarehouseResponse.warehouse:type_name -> warehouse.Warehouse
 	1,  // 4: warehouse.GetStockResponse.stock:type_name -> warehouse.Stock
 	1,  // 5: warehouse.UpdateStockResponse.stock:type_name -> warehouse.Stock
-	2,  // 6: warehouse.WarehouseService.GetWarehouse:input_type -> warehouse.GetWarehouseRequest
-	4,  // 7: warehouse.WarehouseService.GetWarehouses:input_type -> warehouse.GetWarehousesRequest
-	6,  // 8: warehouse.WarehouseService.CreateWarehouse:input_type -> warehouse.CreateWarehouseRequest
-	8,  // 9: warehouse.WarehouseService.UpdateWarehouse:input_type -> warehouse.UpdateWarehouseRequest
-	10, // 10: warehouse.WarehouseService.TransferStock:input_type -> warehouse.TransferStockRequest
-	12, // 11: warehouse.WarehouseService.GetStock:input_type -> warehouse.GetStockRequest
-	14, // 12: warehouse.WarehouseService.UpdateStock:input_type -> warehouse.UpdateStockRequest
-	3,  // 13: warehouse.WarehouseService.GetWarehouse:output_type -> warehouse.GetWarehouseResponse
-	5,  // 14: warehouse.WarehouseService.GetWarehouses:output_type -> warehouse.GetWarehousesResponse
-	7,  // 15: warehouse.WarehouseService.CreateWarehouse:output_type -> warehouse.CreateWarehouseResponse
-	9,  // 16: warehouse.WarehouseService.UpdateWarehouse:output_type -> warehouse.UpdateWarehouseResponse
-	11, // 17: warehouse.WarehouseService.TransferStock:output_type -> warehouse.TransferStockResponse
-	13, // 18: warehouse.WarehouseService.GetStock:output_type -> warehouse.GetStockResponse
-	15, // 19: warehouse.WarehouseService.UpdateStock:output_type -> warehouse.UpdateStockResponse
-	13, // [13:20] is the sub-list for method output_type
-	6,  // [6:13] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	17, // 6: warehouse.VariantAvailability.warehouses:type_name -> warehouse.WarehouseAvailability
+	18, // 7: warehouse.GetAvailabilityResponse.variants:type_name -> warehouse.VariantAvailability
+	2,  // 8: warehouse.WarehouseService.GetWarehouse:input_type -> warehouse.GetWarehouseRequest
+	4,  // 9: warehouse.WarehouseService.GetWarehouses:input_type -> warehouse.GetWarehousesRequest
+	6,  // 10: warehouse.WarehouseService.CreateWarehouse:input_type -> warehouse.CreateWarehouseRequest
+	8,  // 11: warehouse.WarehouseService.UpdateWarehouse:input_type -> warehouse.UpdateWarehouseRequest
+	10, // 12: warehouse.WarehouseService.TransferStock:input_type -> warehouse.TransferStockRequest
+	12, // 13: warehouse.WarehouseService.GetStock:input_type -> warehouse.GetStockRequest
+	16, // 14: warehouse.WarehouseService.GetAvailability:input_type -> warehouse.GetAvailabilityRequest
+	14, // 15: warehouse.WarehouseService.UpdateStock:input_type -> warehouse.UpdateStockRequest
+	3,  // 16: warehouse.WarehouseService.GetWarehouse:output_type -> warehouse.GetWarehouseResponse
+	5,  // 17: warehouse.WarehouseService.GetWarehouses:output_type -> warehouse.GetWarehousesResponse
+	7,  // 18: warehouse.WarehouseService.CreateWarehouse:output_type -> warehouse.CreateWarehouseResponse
+	9,  // 19: warehouse.WarehouseService.UpdateWarehouse:output_type -> warehouse.UpdateWarehouseResponse
+	11, // 20: warehouse.WarehouseService.TransferStock:output_type -> warehouse.TransferStockResponse
+	13, // 21: warehouse.WarehouseService.GetStock:output_type -> warehouse.GetStockResponse
+	19, // 22: warehouse.WarehouseService.GetAvailability:output_type -> warehouse.GetAvailabilityResponse
+	15, // 23: warehouse.WarehouseService.UpdateStock:output_type -> warehouse.UpdateStockResponse
+	16, // [16:24] is the sub-list for method output_type
+	8,  // [8:16] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_proto_warehouse_warehouse_proto_init() }
@@ -1095,7 +1324,7 @@ func file_proto_warehouse_warehouse_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_warehouse_warehouse_proto_rawDesc), len(file_proto_warehouse_warehouse_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   16,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -25,6 +25,7 @@ const (
 	WarehouseService_UpdateWarehouse_FullMethodName = "/warehouse.WarehouseService/UpdateWarehouse"
 	WarehouseService_TransferStock_FullMethodName   = "/warehouse.WarehouseService/TransferStock"
 	WarehouseService_GetStock_FullMethodName        = "/warehouse.WarehouseService/GetStock"
+	WarehouseService_GetAvailability_FullMethodName = "/warehouse.WarehouseService/GetAvailability"
 	WarehouseService_UpdateStock_FullMethodName     = "/warehouse.WarehouseService/UpdateStock"
 )
 
@@ -38,6 +39,10 @@ type WarehouseServiceClient interface {
 	UpdateWarehouse(ctx context.Context, in *UpdateWarehouseRequest, opts ...grpc.CallOption) (*UpdateWarehouseResponse, error)
 	TransferStock(ctx context.Context, in *TransferStockRequest, opts ...grpc.CallOption) (*TransferStockResponse, error)
 	GetStock(ctx context.Context, in *GetStockRequest, opts ...grpc.CallOption) (*GetStockResponse, error)
+	// GetAvailability returns the available quantity of each variant across
+	// the active warehouses, with the per-warehouse breakdown
+	GetAvailability(ctx context.Context, in *GetAvailabilityRequest, opts ...grpc.CallOption) (*GetAvailabilityResponse, error)
+	// Internal methods have no HTTP mapping
 	UpdateStock(ctx context.Context, in *UpdateStockRequest, opts ...grpc.CallOption) (*UpdateStockResponse, error)
 }
 
@@ -109,6 +114,16 @@ func (c *warehouseServiceClient) GetStock(ctx context.Context, in *GetStockReque
 	return out, nil
 }
 
+func (c *warehouseServiceClient) GetAvailability(ctx context.Context, in *GetAvailabilityRequest, opts ...grpc.CallOption) (*GetAvailabilityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAvailabilityResponse)
+	err := c.cc.Invoke(ctx, WarehouseService_GetAvailability_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *warehouseServiceClient) UpdateStock(ctx context.Context, in *UpdateStockRequest, opts ...grpc.CallOption) (*UpdateStockResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(UpdateStockResponse)
@@ -129,6 +144,10 @@ type WarehouseServiceServer interface {
 	UpdateWarehouse(context.Context, *UpdateWarehouseRequest) (*UpdateWarehouseResponse, error)
 	TransferStock(context.Context, *TransferStockRequest) (*TransferStockResponse, error)
 	GetStock(context.Context, *GetStockRequest) (*GetStockResponse, error)
+	// GetAvailability returns the available quantity of each variant across
+	// the active warehouses, with the per-warehouse breakdown
+	GetAvailability(context.Context, *GetAvailabilityRequest) (*GetAvailabilityResponse, error)
+	// Internal methods have no HTTP mapping
 	UpdateStock(context.Context, *UpdateStockRequest) (*UpdateStockResponse, error)
 	mustEmbedUnimplementedWarehouseServiceServer()
 }
@@ -158,6 +177,9 @@ func (UnimplementedWarehouseServiceServer) TransferStock(context.Context, *Trans
 func (UnimplementedWarehouseServiceServer) GetStock(context.Context, *GetStockRequest) (*GetStockResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStock not implemented")
 }
+func (UnimplementedWarehouseServiceServer) GetAvailability(context.Context, *GetAvailabilityRequest) (*GetAvailabilityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAvailability not implemented")
+}
 func (UnimplementedWarehouseServiceServer) UpdateStock(context.Context, *UpdateStockRequest) (*UpdateStockResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateStock not implemented")
 }
@@ -290,6 +312,24 @@ func _WarehouseService_GetStock_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WarehouseService_GetAvailability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAvailabilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WarehouseServiceServer).GetAvailability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WarehouseService_GetAvailability_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WarehouseServiceServer).GetAvailability(ctx, req.(*GetAvailabilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _WarehouseService_UpdateStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpdateStockRequest)
 	if err := dec(in); err != nil {
@@ -339,6 +379,10 @@ var WarehouseService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStock",
 			Handler:    _WarehouseService_GetStock_Handler,
 		},
+		{
+			MethodName: "GetAvailability",
+			Handler:    _WarehouseService_GetAvailability_Handler,
+		},
 		{
 			MethodName: "UpdateStock",
 			Handler:    _WarehouseService_UpdateStock_Handler,
@@ -0,0 +1,73 @@
+package shared
+
+// Roles a user can hold. The role is stored on the user record in user-service
+// and carried in the JWT claims.
+const (
+	RoleCustomer  = "customer"
+	RoleShopOwner = "shop_owner"
+	RoleShopStaff = "shop_staff"
+	RoleAdmin     = "admin"
+)
+
+// Global permissions granted through the user's role and checked by the HTTP
+// middleware and gRPC interceptors.
+const (
+	PermOrderCreate    = "order:create"
+	PermShopCreate     = "shop:create"
+	PermUserManage     = "user:manage"
+	PermCategoryManage = "category:manage"
+)
+
+// Shop-scoped actions. They are granted per shop through the caller's
+// membership role in shop-service and checked with CheckShopPermission.
+const (
+	PermShopView       = "shop:view"
+	PermShopManage     = "shop:manage"
+	PermShopMembers    = "shop:members"
+	PermProductWrite   = "product:write"
+	PermWarehouseWrite = "warehouse:write"
+	PermStockWrite     = "stock:write"
+	PermOrderView      = "order:view"
+)
+
+// AllPermissions lists every global permission known to the system
+var AllPermissions = []string{
+	PermOrderCreate,
+	PermShopCreate,
+	PermUserManage,
+	PermCategoryManage,
+}
+
+// DefaultRolePermissions is the permission set each role is seeded with
+var DefaultRolePermissions = map[string][]string{
+	RoleCustomer: {
+		PermOrderCreate,
+	},
+	RoleShopOwner: {
+		PermOrderCreate,
+		PermShopCreate,
+	},
+	RoleShopStaff: {
+		PermOrderCreate,
+	},
+	RoleAdmin: AllPermissions,
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := DefaultRolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether the claims grant the given permission
+func (c *Claims) HasPermission(permission string) bool {
+	if c.Role == RoleAdmin {
+		return true
+	}
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package serviceauth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// IdentityHeader is the HTTP header, and IdentityMetadataKey the gRPC
+// metadata key, in which the API gateway forwards the end user it
+// authenticated. Services trust it instead of validating the user's JWT
+// again.
+const (
+	IdentityHeader      = "X-Identity-Token"
+	IdentityMetadataKey = "x-identity-token"
+)
+
+const (
+	identityIssuer   = "gateway"
+	identityTokenTTL = time.Minute
+)
+
+// GenerateIdentityToken issues a short-lived token carrying the identity and
+// permissions of claims, signed with the service token secret. It never
+// outlives the user's own token.
+func GenerateIdentityToken(claims *shared.Claims, secret string) (string, error) {
+	now := time.Now()
+	expiresAt := now.Add(identityTokenTTL)
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	identity := &shared.Claims{
+		UserID:      claims.UserID,
+		Email:       claims.Email,
+		Role:        claims.Role,
+		Permissions: claims.Permissions,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   claims.Subject,
+			Issuer:    identityIssuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, identity).SignedString([]byte(secret))
+}
+
+// ValidateIdentityToken returns the end-user claims of a token issued by
+// GenerateIdentityToken
+func ValidateIdentityToken(tokenString, secret string) (*shared.Claims, error) {
+	if secret == "" {
+		return nil, errors.New("identity tokens are not accepted")
+	}
+	claims, err := shared.ValidateToken(tokenString, secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer != identityIssuer {
+		return nil, errors.New("invalid identity token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,154 @@
+package serviceauth
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Allowlist maps full gRPC method names (e.g. "/warehouse.WarehouseService/UpdateStock")
+// to the services allowed to call them. Listed methods are internal and
+// reject callers that cannot prove they are one of those services; methods
+// that are not listed are open to any caller.
+type Allowlist map[string][]string
+
+type serviceContextKey struct{}
+
+// CallerFromContext returns the authenticated name of the calling service
+func CallerFromContext(ctx context.Context) (string, bool) {
+	service, ok := ctx.Value(serviceContextKey{}).(string)
+	return service, ok
+}
+
+// UnaryServerInterceptor identifies the calling service from its client
+// certificate or service token and enforces the allowlist. A service token
+// that is sent but invalid is always rejected.
+func UnaryServerInterceptor(cfg Config, allowlist Allowlist) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorizeCaller(ctx, cfg, allowlist, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(cfg Config, allowlist Allowlist) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizeCaller(ss.Context(), cfg, allowlist, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// ServerCredentials returns the mutual TLS server option when TLS is
+// configured, and nil otherwise
+func ServerCredentials(cfg Config) (grpc.ServerOption, error) {
+	if !cfg.TLSEnabled() {
+		if cfg.TokenSecret == "" {
+			slog.Warn("neither mTLS nor SERVICE_TOKEN_SECRET is configured, internal methods will reject every call")
+		}
+		return nil, nil
+	}
+
+	tlsConfig, err := ServerTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Creds(credentials.NewTLS(tlsConfig)), nil
+}
+
+// DialOptions builds the options used to connect to other services
+func DialOptions(cfg Config) ([]grpc.DialOption, error) {
+	var options []grpc.DialOption
+
+	if cfg.TLSEnabled() {
+		tlsConfig, err := ClientTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	if cfg.TokenSecret != "" {
+		options = append(options, grpc.WithPerRPCCredentials(NewTokenCredentials(cfg.ServiceName, cfg.TokenSecret, cfg.TLSEnabled())))
+	}
+	return options, nil
+}
+
+// authorizeCaller enforces the allowlist for method and returns ctx carrying
+// the caller's service name
+func authorizeCaller(ctx context.Context, cfg Config, allowlist Allowlist, method string) (context.Context, error) {
+	caller, err := callerService(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, internal := allowlist[method]
+	if internal {
+		if caller == "" {
+			return nil, status.Error(codes.Unauthenticated, "service authentication is required")
+		}
+		if !contains(allowed, caller) {
+			return nil, status.Errorf(codes.PermissionDenied, "service %s may not call %s", caller, method)
+		}
+	}
+
+	if caller != "" {
+		ctx = context.WithValue(ctx, serviceContextKey{}, caller)
+	}
+	return ctx, nil
+}
+
+// callerService returns the verified common name of the client certificate,
+// or else the service named by a valid service token, or "" for anonymous
+// callers
+func callerService(ctx context.Context, cfg Config) (string, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName, nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(TokenMetadataKey)
+	if len(values) == 0 {
+		return "", nil
+	}
+	if cfg.TokenSecret == "" {
+		return "", status.Error(codes.Unauthenticated, "service tokens are not accepted")
+	}
+
+	service, err := ValidateToken(values[0], cfg.TokenSecret)
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "invalid service token: %v", err)
+	}
+	return service, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// contextStream overrides the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
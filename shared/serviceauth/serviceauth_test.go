@@ -0,0 +1,179 @@
+package serviceauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testSecret = "service-secret"
+
+// signed returns a token of claims signed with secret
+func signed(t *testing.T, claims jwt.Claims, secret string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// must returns a function failing t unless a token was generated
+func must(t *testing.T) func(token string, err error) string {
+	return func(token string, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	serviceToken := must(t)(GenerateToken("order-service", testSecret))
+	identityToken := must(t)(GenerateIdentityToken(&shared.Claims{UserID: 1}, testSecret))
+	expired := signed(t, &serviceClaims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "order-service",
+		Issuer:    "service",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}}, testSecret)
+	anonymous := signed(t, &serviceClaims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "service",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}, testSecret)
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  string
+		want    string
+		wantErr bool
+	}{
+		{"valid", serviceToken, testSecret, "order-service", false},
+		{"other secret", serviceToken, "other-secret", "", true},
+		{"identity token", identityToken, testSecret, "", true},
+		{"expired", expired, testSecret, "", true},
+		{"no subject", anonymous, testSecret, "", true},
+		{"malformed", "not-a-token", testSecret, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateToken(tt.token, tt.secret)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ValidateToken = %q, %v, want %q and error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateIdentityToken(t *testing.T) {
+	identityToken := must(t)(GenerateIdentityToken(&shared.Claims{UserID: 7, Role: shared.RoleCustomer}, testSecret))
+	serviceToken := must(t)(GenerateToken("order-service", testSecret))
+	// A user token signed with the same secret is not an identity token
+	userToken := must(t)(shared.GenerateToken(shared.TokenSubject{UserID: 7, Role: shared.RoleAdmin}, testSecret))
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  string
+		wantErr bool
+	}{
+		{"valid", identityToken, testSecret, false},
+		{"other secret", identityToken, "other-secret", true},
+		{"no secret configured", identityToken, "", true},
+		{"service token", serviceToken, testSecret, true},
+		{"user token", userToken, testSecret, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateIdentityToken(tt.token, tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateIdentityToken: err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.UserID != 7 || claims.Role != shared.RoleCustomer) {
+				t.Errorf("claims = %+v, want user 7 with role %s", claims, shared.RoleCustomer)
+			}
+		})
+	}
+}
+
+func TestIdentityTokenNeverOutlivesUserToken(t *testing.T) {
+	userExpiry := time.Now().Add(10 * time.Second).Truncate(time.Second)
+	token := must(t)(GenerateIdentityToken(&shared.Claims{
+		UserID:           7,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(userExpiry)},
+	}, testSecret))
+
+	claims, err := ValidateIdentityToken(token, testSecret)
+	if err != nil {
+		t.Fatalf("ValidateIdentityToken: %v", err)
+	}
+	if !claims.ExpiresAt.Time.Equal(userExpiry) {
+		t.Errorf("identity token expires at %s, want the user token's %s", claims.ExpiresAt.Time, userExpiry)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	const internal = "/warehouse.WarehouseService/UpdateStock"
+	const open = "/warehouse.WarehouseService/GetWarehouse"
+	allowlist := Allowlist{internal: {"order-service"}}
+
+	orderToken := must(t)(GenerateToken("order-service", testSecret))
+	shopToken := must(t)(GenerateToken("shop-service", testSecret))
+	forged := must(t)(GenerateToken("order-service", "other-secret"))
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		method     string
+		token      string
+		wantCode   codes.Code
+		wantCaller string
+	}{
+		{"allowed service", Config{TokenSecret: testSecret}, internal, orderToken, codes.OK, "order-service"},
+		{"other service", Config{TokenSecret: testSecret}, internal, shopToken, codes.PermissionDenied, ""},
+		{"anonymous on internal method", Config{TokenSecret: testSecret}, internal, "", codes.Unauthenticated, ""},
+		{"token of another secret", Config{TokenSecret: testSecret}, internal, forged, codes.Unauthenticated, ""},
+		{"invalid token on open method", Config{TokenSecret: testSecret}, open, forged, codes.Unauthenticated, ""},
+		{"token without secret configured", Config{}, open, orderToken, codes.Unauthenticated, ""},
+		{"anonymous on open method", Config{TokenSecret: testSecret}, open, "", codes.OK, ""},
+		{"service on open method", Config{TokenSecret: testSecret}, open, shopToken, codes.OK, "shop-service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(TokenMetadataKey, tt.token))
+			}
+
+			var caller string
+			var called bool
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				caller, _ = CallerFromContext(ctx)
+				return nil, nil
+			}
+			_, err := UnaryServerInterceptor(tt.cfg, allowlist)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("code = %s, want %s (err %v)", got, tt.wantCode, err)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCode == codes.OK)
+			}
+			if caller != tt.wantCaller {
+				t.Errorf("caller = %q, want %q", caller, tt.wantCaller)
+			}
+		})
+	}
+}
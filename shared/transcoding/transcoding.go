@@ -0,0 +1,104 @@
+// Package transcoding serves the gRPC APIs as REST/JSON, following the
+// google.api.http annotations of the protos, and publishes the OpenAPI
+// documents generated from them.
+package transcoding
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/textproto"
+
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// PathPrefix is where the transcoded API is served. The hand written HTTP
+// API stays below /api/v1.
+const PathPrefix = "/api/v2"
+
+// Specs holds the OpenAPI documents generated from the protos of a service
+type Specs struct {
+	Title string
+	// OpenAPIV2 is the Swagger 2.0 JSON document of protoc-gen-openapiv2
+	OpenAPIV2 []byte
+	// OpenAPIV3 is the OpenAPI 3 YAML document of protoc-gen-openapi
+	OpenAPIV3 []byte
+}
+
+// NewServeMux returns a grpc-gateway mux whose JSON uses the proto field
+// names and always includes every field, as the generated specs describe.
+// Besides the "authorization" header it forwards the gateway identity token
+// to the gRPC server. Errors are written as google.rpc.Status with the
+// ErrorInfo detail set by apperror.
+func NewServeMux() *runtime.ServeMux {
+	return runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+	)
+}
+
+// Mount serves mux below PathPrefix
+func Mount(router gin.IRoutes, mux *runtime.ServeMux) {
+	router.Any(PathPrefix+"/*path", gin.WrapH(mux))
+}
+
+func headerMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == serviceauth.IdentityHeader {
+		return serviceauth.IdentityMetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// RegisterSwagger serves the documents in specs at /swagger/swagger.json and
+// /swagger/openapi.yaml and a Swagger UI browsing them at /swagger
+func RegisterSwagger(router gin.IRoutes, specs Specs) {
+	router.GET("/swagger", func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		if err := swaggerUI.Execute(c.Writer, specs.Title); err != nil {
+			c.Error(fmt.Errorf("failed to render swagger ui: %w", err))
+		}
+	})
+	router.GET("/swagger/swagger.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", specs.OpenAPIV2)
+	})
+	router.GET("/swagger/openapi.yaml", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/yaml", specs.OpenAPIV3)
+	})
+}
+
+var swaggerUI = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-standalone-preset.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      urls: [
+        {url: "swagger/openapi.yaml", name: "OpenAPI 3"},
+        {url: "swagger/swagger.json", name: "Swagger 2.0"}
+      ],
+      dom_id: "#swagger-ui",
+      presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+      layout: "StandaloneLayout"
+    });
+  </script>
+</body>
+</html>
+`))
@@ -2,13 +2,11 @@ package grpc
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/shop"
 	usecase "github.com/evrintobing17/ecommerce-system/shop-service/app"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type shopServer struct {
@@ -21,10 +19,17 @@ func NewShopServer(shopUsecase usecase.ShopUsecase) *shopServer {
 }
 
 func (s *shopServer) CreateShop(ctx context.Context, req *proto.CreateShopRequest) (*proto.CreateShopResponse, error) {
-	shop, err := s.shopUsecase.CreateShop(req.Name, req.Description, int(req.OwnerId))
+	// End users always own the shops they create; owner_id is only honoured
+	// for service callers that carry no user claims
+	ownerID := int(req.OwnerId)
+	if claims, ok := middleware.ClaimsFromContext(ctx); ok {
+		ownerID = claims.UserID
+	}
+
+	shop, err := s.shopUsecase.CreateShop(ctx, req.Name, req.Description, ownerID)
 	if err != nil {
-		log.Printf("CreateShop error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to create shop: %v", err)
+		slog.WarnContext(ctx, "create shop failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.CreateShopResponse{
@@ -40,10 +45,10 @@ func (s *shopServer) CreateShop(ctx context.Context, req *proto.CreateShopReques
 }
 
 func (s *shopServer) GetShop(ctx context.Context, req *proto.GetShopRequest) (*proto.GetShopResponse, error) {
-	shop, err := s.shopUsecase.GetShop(int(req.ShopId))
+	shop, err := s.shopUsecase.GetShop(ctx, int(req.ShopId))
 	if err != nil {
-		log.Printf("GetShop error: %v", err)
-		return nil, status.Errorf(codes.NotFound, "shop not found: %v", err)
+		slog.WarnContext(ctx, "get shop failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.GetShopResponse{
@@ -59,10 +64,10 @@ func (s *shopServer) GetShop(ctx context.Context, req *proto.GetShopRequest) (*p
 }
 
 func (s *shopServer) GetShops(ctx context.Context, req *proto.GetShopsRequest) (*proto.GetShopsResponse, error) {
-	shops, total, err := s.shopUsecase.GetShops(int(req.OwnerId), int(req.Page), int(req.Limit))
+	shops, total, err := s.shopUsecase.GetShops(ctx, int(req.OwnerId), int(req.Page), int(req.Limit))
 	if err != nil {
-		log.Printf("GetShops error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to get shops: %v", err)
+		slog.WarnContext(ctx, "get shops failed", "error", err)
+		return nil, err
 	}
 
 	var protoShops []*proto.Shop
@@ -84,3 +89,20 @@ func (s *shopServer) GetShops(ctx context.Context, req *proto.GetShopsRequest) (
 		Limit: req.Limit,
 	}, nil
 }
+
+func (s *shopServer) CheckShopPermission(ctx context.Context, req *proto.CheckShopPermissionRequest) (*proto.CheckShopPermissionResponse, error) {
+	if _, err := s.shopUsecase.GetShop(ctx, int(req.ShopId)); err != nil {
+		return nil, err
+	}
+
+	allowed, role, err := s.shopUsecase.CheckShopPermission(ctx, int(req.UserId), int(req.ShopId), req.Action)
+	if err != nil {
+		slog.WarnContext(ctx, "check shop permission failed", "error", err)
+		return nil, err
+	}
+
+	return &proto.CheckShopPermissionResponse{
+		Allowed: allowed,
+		Role:    string(role),
+	}, nil
+}
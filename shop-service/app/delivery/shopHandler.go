@@ -1,10 +1,16 @@
 package http
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
 	"strconv"
 
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
 	usecase "github.com/evrintobing17/ecommerce-system/shop-service/app"
+	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,7 +25,7 @@ func NewShopHandler(shopUsecase usecase.ShopUsecase) *ShopHandler {
 func (h *ShopHandler) CreateShop(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -29,17 +35,17 @@ func (h *ShopHandler) CreateShop(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	shop, err := h.shopUsecase.CreateShop(request.Name, request.Description, userID.(int))
+	shop, err := h.shopUsecase.CreateShop(c.Request.Context(), request.Name, request.Description, userID.(int))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	jsonhttpresponse.StatusCreated(c, gin.H{
 		"shop": shop,
 	})
 }
@@ -47,13 +53,13 @@ func (h *ShopHandler) CreateShop(c *gin.Context) {
 func (h *ShopHandler) GetShop(c *gin.Context) {
 	shopID, _ := strconv.Atoi(c.Param("id"))
 
-	shop, err := h.shopUsecase.GetShop(shopID)
+	shop, err := h.shopUsecase.GetShop(c.Request.Context(), shopID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "shop not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"shop": shop,
 	})
 }
@@ -61,44 +67,31 @@ func (h *ShopHandler) GetShop(c *gin.Context) {
 func (h *ShopHandler) GetMyShops(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	shops, total, err := h.shopUsecase.GetShops(userID.(int), page, limit)
+	shops, total, err := h.shopUsecase.GetShops(c.Request.Context(), userID.(int), page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.Paginated(c, gin.H{
 		"shops": shops,
-		"total": total,
-		"page":  page,
-		"limit": limit,
-	})
+	}, page, limit, total)
 }
 
+// UpdateShop requires the shop:manage action, enforced by RequireShopPermission
 func (h *ShopHandler) UpdateShop(c *gin.Context) {
 	shopID, _ := strconv.Atoi(c.Param("id"))
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
 
-	shop, err := h.shopUsecase.GetShop(shopID)
+	shop, err := h.shopUsecase.GetShop(c.Request.Context(), shopID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "shop not found"})
-		return
-	}
-
-	// Check if the user owns this shop
-	if shop.OwnerID != userID.(int) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
@@ -108,7 +101,7 @@ func (h *ShopHandler) UpdateShop(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
@@ -119,44 +112,166 @@ func (h *ShopHandler) UpdateShop(c *gin.Context) {
 		shop.Description = request.Description
 	}
 
-	err = h.shopUsecase.UpdateShop(shop)
+	err = h.shopUsecase.UpdateShop(c.Request.Context(), shop)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"shop": shop,
 	})
 }
 
+// DeleteShop requires the shop:manage action, enforced by RequireShopPermission
 func (h *ShopHandler) DeleteShop(c *gin.Context) {
 	shopID, _ := strconv.Atoi(c.Param("id"))
+
+	err := h.shopUsecase.DeleteShop(c.Request.Context(), shopID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"message": "Shop deleted successfully",
+	})
+}
+
+// CanAccessShop lets the handler act as the middleware.ShopAccessChecker of
+// shop-service itself, answering from the local usecase instead of over gRPC
+func (h *ShopHandler) CanAccessShop(ctx context.Context, claims *shared.Claims, shopID int, action string) (bool, error) {
+	if claims.Role == shared.RoleAdmin {
+		return true, nil
+	}
+
+	if _, err := h.shopUsecase.GetShop(ctx, shopID); err != nil {
+		if errors.Is(err, models.ErrShopNotFound) {
+			return false, fmt.Errorf("%w: shop %d", middleware.ErrResourceNotFound, shopID)
+		}
+		return false, err
+	}
+
+	allowed, _, err := h.shopUsecase.CheckShopPermission(ctx, claims.UserID, shopID, action)
+	return allowed, err
+}
+
+func (h *ShopHandler) InviteMember(c *gin.Context) {
+	shopID, _ := strconv.Atoi(c.Param("id"))
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
-	shop, err := h.shopUsecase.GetShop(shopID)
+	var request struct {
+		Email string `json:"email" binding:"required_without=Phone,omitempty,email"`
+		Phone string `json:"phone" binding:"required_without=Email"`
+		Role  string `json:"role" binding:"required,oneof=manager warehouse_operator viewer"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	// Admins act as the owner of every shop
+	inviterRole := models.MemberRoleOwner
+	if c.GetString("user_role") != shared.RoleAdmin {
+		var err error
+		_, inviterRole, err = h.shopUsecase.CheckShopPermission(c.Request.Context(), userID.(int), shopID, "")
+		if err != nil {
+			jsonhttpresponse.FromError(c, err)
+			return
+		}
+	}
+
+	member, err := h.shopUsecase.InviteMember(c.Request.Context(), shopID, userID.(int), inviterRole, request.Email, request.Phone, models.MemberRole(request.Role))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "shop not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	// Check if the user owns this shop
-	if shop.OwnerID != userID.(int) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	jsonhttpresponse.StatusCreated(c, gin.H{
+		"invitation": member,
+	})
+}
+
+func (h *ShopHandler) GetMembers(c *gin.Context) {
+	shopID, _ := strconv.Atoi(c.Param("id"))
+
+	members, err := h.shopUsecase.GetMembers(c.Request.Context(), shopID)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	err = h.shopUsecase.DeleteShop(shopID)
+	jsonhttpresponse.OK(c, gin.H{
+		"members": members,
+	})
+}
+
+func (h *ShopHandler) RemoveMember(c *gin.Context) {
+	shopID, _ := strconv.Atoi(c.Param("id"))
+	memberID, _ := strconv.Atoi(c.Param("member_id"))
+	userID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	err := h.shopUsecase.RemoveMember(c.Request.Context(), shopID, memberID, userID.(int))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Shop deleted successfully",
+	jsonhttpresponse.OK(c, gin.H{
+		"message": "Member removed successfully",
+	})
+}
+
+func (h *ShopHandler) GetInvitations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	invitations, err := h.shopUsecase.GetInvitations(c.Request.Context(), userID.(int))
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"invitations": invitations,
+	})
+}
+
+func (h *ShopHandler) AcceptInvitation(c *gin.Context) {
+	h.respondToInvitation(c, true)
+}
+
+func (h *ShopHandler) DeclineInvitation(c *gin.Context) {
+	h.respondToInvitation(c, false)
+}
+
+func (h *ShopHandler) respondToInvitation(c *gin.Context, accept bool) {
+	invitationID, _ := strconv.Atoi(c.Param("id"))
+	userID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	member, err := h.shopUsecase.RespondToInvitation(c.Request.Context(), invitationID, userID.(int), accept)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"member": member,
 	})
 }
@@ -0,0 +1,15 @@
+package models
+
+import "github.com/evrintobing17/ecommerce-system/shared/apperror"
+
+var (
+	ErrShopNotFound         = apperror.NewNotFound("shop_not_found", "shop not found")
+	ErrMemberNotFound       = apperror.NewNotFound("member_not_found", "member not found")
+	ErrInvitationNotFound   = apperror.NewNotFound("invitation_not_found", "invitation not found")
+	ErrInvalidMemberRole    = apperror.NewInvalidArgument("invalid_member_role", "invalid member role")
+	ErrAlreadyMember        = apperror.NewConflict("already_member", "user is already a member or has a pending invitation")
+	ErrCannotRemoveOwner    = apperror.NewPermissionDenied("cannot_remove_owner", "the shop owner cannot be removed")
+	ErrAccessDenied         = apperror.NewPermissionDenied("access_denied", "access denied")
+	ErrInvitationNotForUser = apperror.NewPermissionDenied("invitation_not_for_user", "invitation was not sent to this user")
+	ErrRoleNotGrantable     = apperror.NewPermissionDenied("role_not_grantable", "members can only invite roles below their own")
+)
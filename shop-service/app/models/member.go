@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+)
+
+type MemberRole string
+
+const (
+	MemberRoleOwner             MemberRole = "owner"
+	MemberRoleManager           MemberRole = "manager"
+	MemberRoleWarehouseOperator MemberRole = "warehouse_operator"
+	MemberRoleViewer            MemberRole = "viewer"
+)
+
+type MemberStatus string
+
+const (
+	MemberStatusPending  MemberStatus = "pending"
+	MemberStatusActive   MemberStatus = "active"
+	MemberStatusDeclined MemberStatus = "declined"
+)
+
+// ShopMember links a user to a shop with a per-shop role. Invitations are
+// stored as pending members addressed by email or phone; UserID is set once
+// the invitation is accepted.
+type ShopMember struct {
+	ID        int          `gorm:"primaryKey" json:"id"`
+	ShopID    int          `gorm:"index" json:"shop_id"`
+	UserID    int          `gorm:"index" json:"user_id,omitempty"`
+	Email     string       `json:"email,omitempty"`
+	Phone     string       `json:"phone,omitempty"`
+	Role      MemberRole   `json:"role"`
+	Status    MemberStatus `json:"status"`
+	InvitedBy int          `json:"invited_by,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// MemberRoleActions lists the shop-scoped actions each member role may perform
+var MemberRoleActions = map[MemberRole][]string{
+	MemberRoleOwner: {
+		shared.PermShopView,
+		shared.PermShopManage,
+		shared.PermShopMembers,
+		shared.PermProductWrite,
+		shared.PermWarehouseWrite,
+		shared.PermStockWrite,
+		shared.PermOrderView,
+	},
+	MemberRoleManager: {
+		shared.PermShopView,
+		shared.PermShopMembers,
+		shared.PermProductWrite,
+		shared.PermWarehouseWrite,
+		shared.PermStockWrite,
+		shared.PermOrderView,
+	},
+	MemberRoleWarehouseOperator: {
+		shared.PermShopView,
+		shared.PermStockWrite,
+	},
+	MemberRoleViewer: {
+		shared.PermShopView,
+		shared.PermOrderView,
+	},
+}
+
+// Can reports whether the role grants the given action
+func (r MemberRole) Can(action string) bool {
+	for _, a := range MemberRoleActions[r] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid reports whether r is a known member role
+func (r MemberRole) IsValid() bool {
+	_, ok := MemberRoleActions[r]
+	return ok
+}
+
+// memberRoleRanks orders the member roles from least to most privileged
+var memberRoleRanks = map[MemberRole]int{
+	MemberRoleViewer:            1,
+	MemberRoleWarehouseOperator: 2,
+	MemberRoleManager:           3,
+	MemberRoleOwner:             4,
+}
+
+// Outranks reports whether r is more privileged than other
+func (r MemberRole) Outranks(other MemberRole) bool {
+	return memberRoleRanks[r] > memberRoleRanks[other]
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	shop "github.com/evrintobing17/ecommerce-system/shop-service/app"
+	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
+
+	"gorm.io/gorm"
+)
+
+type shopMemberRepository struct {
+	db *gorm.DB
+}
+
+func NewShopMemberRepository(db *gorm.DB) shop.ShopMemberRepository {
+	return &shopMemberRepository{db: db}
+}
+
+func (r *shopMemberRepository) Create(ctx context.Context, member *models.ShopMember) error {
+	return r.db.WithContext(ctx).Create(member).Error
+}
+
+func (r *shopMemberRepository) FindByID(ctx context.Context, id int) (*models.ShopMember, error) {
+	var member models.ShopMember
+	err := r.db.WithContext(ctx).First(&member, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *shopMemberRepository) FindActive(ctx context.Context, shopID, userID int) (*models.ShopMember, error) {
+	var member models.ShopMember
+	err := r.db.WithContext(ctx).First(&member, "shop_id = ? AND user_id = ? AND status = ?", shopID, userID, models.MemberStatusActive).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// FindByShopID returns the active members and pending invitations of a shop
+func (r *shopMemberRepository) FindByShopID(ctx context.Context, shopID int) ([]*models.ShopMember, error) {
+	var members []*models.ShopMember
+	err := r.db.WithContext(ctx).Where("shop_id = ? AND status IN ?", shopID, []models.MemberStatus{models.MemberStatusActive, models.MemberStatusPending}).
+		Order("id").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// FindOpen returns an active membership or pending invitation of the shop
+// addressed to the given email or phone
+func (r *shopMemberRepository) FindOpen(ctx context.Context, shopID int, email, phone string) (*models.ShopMember, error) {
+	var member models.ShopMember
+	query := r.db.WithContext(ctx).Where("shop_id = ? AND status IN ?", shopID, []models.MemberStatus{models.MemberStatusActive, models.MemberStatusPending})
+	query = whereContact(query, email, phone)
+
+	err := query.First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *shopMemberRepository) FindPendingInvitations(ctx context.Context, email, phone string) ([]*models.ShopMember, error) {
+	var members []*models.ShopMember
+	query := r.db.WithContext(ctx).Where("status = ?", models.MemberStatusPending)
+	query = whereContact(query, email, phone)
+
+	err := query.Order("id").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *shopMemberRepository) Update(ctx context.Context, member *models.ShopMember) error {
+	return r.db.WithContext(ctx).Save(member).Error
+}
+
+func (r *shopMemberRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.ShopMember{}, "id = ?", id).Error
+}
+
+func (r *shopMemberRepository) DeleteByShopID(ctx context.Context, shopID int) error {
+	return r.db.WithContext(ctx).Delete(&models.ShopMember{}, "shop_id = ?", shopID).Error
+}
+
+// whereContact matches rows addressed to the email or the phone, ignoring
+// whichever of the two is empty
+func whereContact(query *gorm.DB, email, phone string) *gorm.DB {
+	switch {
+	case email != "" && phone != "":
+		return query.Where("(email = ? OR phone = ?)", email, phone)
+	case email != "":
+		return query.Where("email = ?", email)
+	case phone != "":
+		return query.Where("phone = ?", phone)
+	default:
+		return query.Where("1 = 0")
+	}
+}
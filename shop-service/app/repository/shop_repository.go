@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strconv"
+
 	shop "github.com/evrintobing17/ecommerce-system/shop-service/app"
 	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
 
@@ -16,35 +19,35 @@ func NewShopRepository(db *gorm.DB) shop.ShopRepository {
 	return &shopRepository{db: db}
 }
 
-func (r *shopRepository) Create(shop *models.Shop) error {
-	return r.db.Create(shop).Error
+func (r *shopRepository) Create(ctx context.Context, shop *models.Shop) error {
+	return r.db.WithContext(ctx).Create(shop).Error
 }
 
-func (r *shopRepository) FindByID(id int) (*models.Shop, error) {
+func (r *shopRepository) FindByID(ctx context.Context, id int) (*models.Shop, error) {
 	var shop models.Shop
-	err := r.db.First(&shop, "id = ?", id).Error
+	err := r.db.WithContext(ctx).First(&shop, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("shop not found")
+			return nil, models.ErrShopNotFound.With("shop_id", strconv.Itoa(id))
 		}
 		return nil, err
 	}
 	return &shop, nil
 }
 
-func (r *shopRepository) FindByOwnerID(ownerID int, page, limit int) ([]*models.Shop, int64, error) {
+func (r *shopRepository) FindByOwnerID(ctx context.Context, ownerID int, page, limit int) ([]*models.Shop, int64, error) {
 	var shops []*models.Shop
 	var total int64
 
 	// Get total count
-	err := r.db.Model(&models.Shop{}).Where("owner_id = ?", ownerID).Count(&total).Error
+	err := r.db.WithContext(ctx).Model(&models.Shop{}).Where("owner_id = ?", ownerID).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Apply pagination
 	offset := (page - 1) * limit
-	err = r.db.Where("owner_id = ?", ownerID).Offset(offset).Limit(limit).Find(&shops).Error
+	err = r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Offset(offset).Limit(limit).Find(&shops).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -52,10 +55,10 @@ func (r *shopRepository) FindByOwnerID(ownerID int, page, limit int) ([]*models.
 	return shops, total, nil
 }
 
-func (r *shopRepository) Update(shop *models.Shop) error {
-	return r.db.Save(shop).Error
+func (r *shopRepository) Update(ctx context.Context, shop *models.Shop) error {
+	return r.db.WithContext(ctx).Save(shop).Error
 }
 
-func (r *shopRepository) Delete(id int) error {
-	return r.db.Delete(&models.Shop{}, "id = ?", id).Error
+func (r *shopRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.Shop{}, "id = ?", id).Error
 }
@@ -1,13 +1,27 @@
 package app
 
 import (
+	"context"
+
 	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
 )
 
 type ShopRepository interface {
-	Create(shop *models.Shop) error
-	FindByID(id int) (*models.Shop, error)
-	FindByOwnerID(ownerID int, page, limit int) ([]*models.Shop, int64, error)
-	Update(shop *models.Shop) error
-	Delete(id int) error
+	Create(ctx context.Context, shop *models.Shop) error
+	FindByID(ctx context.Context, id int) (*models.Shop, error)
+	FindByOwnerID(ctx context.Context, ownerID int, page, limit int) ([]*models.Shop, int64, error)
+	Update(ctx context.Context, shop *models.Shop) error
+	Delete(ctx context.Context, id int) error
+}
+
+type ShopMemberRepository interface {
+	Create(ctx context.Context, member *models.ShopMember) error
+	FindByID(ctx context.Context, id int) (*models.ShopMember, error)
+	FindActive(ctx context.Context, shopID, userID int) (*models.ShopMember, error)
+	FindByShopID(ctx context.Context, shopID int) ([]*models.ShopMember, error)
+	FindOpen(ctx context.Context, shopID int, email, phone string) (*models.ShopMember, error)
+	FindPendingInvitations(ctx context.Context, email, phone string) ([]*models.ShopMember, error)
+	Update(ctx context.Context, member *models.ShopMember) error
+	Delete(ctx context.Context, id int) error
+	DeleteByShopID(ctx context.Context, shopID int) error
 }
@@ -1,11 +1,21 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/shop-service/app/models"
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
+)
 
 type ShopUsecase interface {
-	CreateShop(name, description string, ownerID int) (*models.Shop, error)
-	GetShop(id int) (*models.Shop, error)
-	GetShops(ownerID, page, limit int) ([]*models.Shop, int64, error)
-	UpdateShop(shop *models.Shop) error
-	DeleteShop(id int) error
+	CreateShop(ctx context.Context, name, description string, ownerID int) (*models.Shop, error)
+	GetShop(ctx context.Context, id int) (*models.Shop, error)
+	GetShops(ctx context.Context, ownerID, page, limit int) ([]*models.Shop, int64, error)
+	UpdateShop(ctx context.Context, shop *models.Shop) error
+	DeleteShop(ctx context.Context, id int) error
+	CheckShopPermission(ctx context.Context, userID, shopID int, action string) (bool, models.MemberRole, error)
+	InviteMember(ctx context.Context, shopID, inviterID int, inviterRole models.MemberRole, email, phone string, role models.MemberRole) (*models.ShopMember, error)
+	GetMembers(ctx context.Context, shopID int) ([]*models.ShopMember, error)
+	RemoveMember(ctx context.Context, shopID, memberID, actorID int) error
+	GetInvitations(ctx context.Context, userID int) ([]*models.ShopMember, error)
+	RespondToInvitation(ctx context.Context, invitationID, userID int, accept bool) (*models.ShopMember, error)
 }
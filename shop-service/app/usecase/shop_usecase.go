@@ -1,21 +1,30 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"time"
 
+	userProto "github.com/evrintobing17/ecommerce-system/shared/proto/user"
 	"github.com/evrintobing17/ecommerce-system/shop-service/app"
 	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
 )
 
 type shopUsecase struct {
-	shopRepo app.ShopRepository
+	shopRepo   app.ShopRepository
+	memberRepo app.ShopMemberRepository
+	userClient userProto.UserServiceClient
 }
 
-func NewShopUsecase(shopRepo app.ShopRepository) app.ShopUsecase {
-	return &shopUsecase{shopRepo: shopRepo}
+func NewShopUsecase(shopRepo app.ShopRepository, memberRepo app.ShopMemberRepository, userClient userProto.UserServiceClient) app.ShopUsecase {
+	return &shopUsecase{
+		shopRepo:   shopRepo,
+		memberRepo: memberRepo,
+		userClient: userClient,
+	}
 }
 
-func (u *shopUsecase) CreateShop(name, description string, ownerID int) (*models.Shop, error) {
+func (u *shopUsecase) CreateShop(ctx context.Context, name, description string, ownerID int) (*models.Shop, error) {
 	shop := &models.Shop{
 		Name:        name,
 		Description: description,
@@ -24,7 +33,20 @@ func (u *shopUsecase) CreateShop(name, description string, ownerID int) (*models
 		UpdatedAt:   time.Now(),
 	}
 
-	err := u.shopRepo.Create(shop)
+	err := u.shopRepo.Create(ctx, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	// The creator becomes the owner member of the shop
+	err = u.memberRepo.Create(ctx, &models.ShopMember{
+		ShopID:    shop.ID,
+		UserID:    ownerID,
+		Role:      models.MemberRoleOwner,
+		Status:    models.MemberStatusActive,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -39,8 +61,8 @@ func (u *shopUsecase) CreateShop(name, description string, ownerID int) (*models
 	}, nil
 }
 
-func (u *shopUsecase) GetShop(id int) (*models.Shop, error) {
-	shop, err := u.shopRepo.FindByID(id)
+func (u *shopUsecase) GetShop(ctx context.Context, id int) (*models.Shop, error) {
+	shop, err := u.shopRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -55,8 +77,8 @@ func (u *shopUsecase) GetShop(id int) (*models.Shop, error) {
 	}, nil
 }
 
-func (u *shopUsecase) GetShops(ownerID, page, limit int) ([]*models.Shop, int64, error) {
-	shops, total, err := u.shopRepo.FindByOwnerID(ownerID, page, limit)
+func (u *shopUsecase) GetShops(ctx context.Context, ownerID, page, limit int) ([]*models.Shop, int64, error) {
+	shops, total, err := u.shopRepo.FindByOwnerID(ctx, ownerID, page, limit)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -76,8 +98,8 @@ func (u *shopUsecase) GetShops(ownerID, page, limit int) ([]*models.Shop, int64,
 	return result, total, nil
 }
 
-func (u *shopUsecase) UpdateShop(shop *models.Shop) error {
-	existingShop, err := u.shopRepo.FindByID(shop.ID)
+func (u *shopUsecase) UpdateShop(ctx context.Context, shop *models.Shop) error {
+	existingShop, err := u.shopRepo.FindByID(ctx, shop.ID)
 	if err != nil {
 		return err
 	}
@@ -86,9 +108,165 @@ func (u *shopUsecase) UpdateShop(shop *models.Shop) error {
 	existingShop.Description = shop.Description
 	existingShop.UpdatedAt = time.Now()
 
-	return u.shopRepo.Update(existingShop)
+	return u.shopRepo.Update(ctx, existingShop)
+}
+
+func (u *shopUsecase) DeleteShop(ctx context.Context, id int) error {
+	if err := u.memberRepo.DeleteByShopID(ctx, id); err != nil {
+		return err
+	}
+	return u.shopRepo.Delete(ctx, id)
 }
 
-func (u *shopUsecase) DeleteShop(id int) error {
-	return u.shopRepo.Delete(id)
+// CheckShopPermission reports whether the user may perform action on the shop
+// and returns the user's member role. Shops created before memberships existed
+// have no owner member, so the shop's OwnerID is treated as the owner.
+func (u *shopUsecase) CheckShopPermission(ctx context.Context, userID, shopID int, action string) (bool, models.MemberRole, error) {
+	shop, err := u.shopRepo.FindByID(ctx, shopID)
+	if err != nil {
+		return false, "", err
+	}
+
+	if shop.OwnerID == userID {
+		return models.MemberRoleOwner.Can(action), models.MemberRoleOwner, nil
+	}
+
+	member, err := u.memberRepo.FindActive(ctx, shopID, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrMemberNotFound) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+
+	return member.Role.Can(action), member.Role, nil
+}
+
+// InviteMember invites a user to the shop. inviterRole is the inviter's role
+// in the shop; everyone but the owner may only invite roles below their own.
+func (u *shopUsecase) InviteMember(ctx context.Context, shopID, inviterID int, inviterRole models.MemberRole, email, phone string, role models.MemberRole) (*models.ShopMember, error) {
+	if !role.IsValid() || role == models.MemberRoleOwner {
+		return nil, models.ErrInvalidMemberRole
+	}
+	if inviterRole != models.MemberRoleOwner && !inviterRole.Outranks(role) {
+		return nil, models.ErrRoleNotGrantable.With("role", string(role))
+	}
+
+	_, err := u.memberRepo.FindOpen(ctx, shopID, email, phone)
+	if err == nil {
+		return nil, models.ErrAlreadyMember
+	}
+	if !errors.Is(err, models.ErrMemberNotFound) {
+		return nil, err
+	}
+
+	member := &models.ShopMember{
+		ShopID:    shopID,
+		Email:     email,
+		Phone:     phone,
+		Role:      role,
+		Status:    models.MemberStatusPending,
+		InvitedBy: inviterID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := u.memberRepo.Create(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (u *shopUsecase) GetMembers(ctx context.Context, shopID int) ([]*models.ShopMember, error) {
+	return u.memberRepo.FindByShopID(ctx, shopID)
+}
+
+// RemoveMember deletes a member or cancels a pending invitation. The owner
+// cannot be removed and only the owner may remove a manager.
+func (u *shopUsecase) RemoveMember(ctx context.Context, shopID, memberID, actorID int) error {
+	member, err := u.memberRepo.FindByID(ctx, memberID)
+	if err != nil {
+		return err
+	}
+	if member.ShopID != shopID {
+		return models.ErrMemberNotFound
+	}
+	if member.Role == models.MemberRoleOwner {
+		return models.ErrCannotRemoveOwner
+	}
+
+	if member.Role == models.MemberRoleManager {
+		_, actorRole, err := u.CheckShopPermission(ctx, actorID, shopID, "")
+		if err != nil {
+			return err
+		}
+		if actorRole != models.MemberRoleOwner {
+			return models.ErrAccessDenied
+		}
+	}
+
+	return u.memberRepo.Delete(ctx, member.ID)
+}
+
+// GetInvitations returns the pending invitations addressed to the user's
+// verified email or phone
+func (u *shopUsecase) GetInvitations(ctx context.Context, userID int) ([]*models.ShopMember, error) {
+	email, phone, err := u.verifiedContact(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.memberRepo.FindPendingInvitations(ctx, email, phone)
+}
+
+func (u *shopUsecase) RespondToInvitation(ctx context.Context, invitationID, userID int, accept bool) (*models.ShopMember, error) {
+	invitation, err := u.memberRepo.FindByID(ctx, invitationID)
+	if err != nil || invitation.Status != models.MemberStatusPending {
+		return nil, models.ErrInvitationNotFound
+	}
+
+	email, phone, err := u.verifiedContact(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	matchesEmail := invitation.Email != "" && invitation.Email == email
+	matchesPhone := invitation.Phone != "" && invitation.Phone == phone
+	if !matchesEmail && !matchesPhone {
+		return nil, models.ErrInvitationNotForUser
+	}
+
+	if accept {
+		if _, err := u.memberRepo.FindActive(ctx, invitation.ShopID, userID); err == nil {
+			return nil, models.ErrAlreadyMember
+		}
+		invitation.Status = models.MemberStatusActive
+		invitation.UserID = userID
+	} else {
+		invitation.Status = models.MemberStatusDeclined
+	}
+	invitation.UpdatedAt = time.Now()
+
+	if err := u.memberRepo.Update(ctx, invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// verifiedContact returns the email and phone of the user, leaving out the
+// ones the user has not verified, so that nobody can claim an invitation by
+// registering an address they do not own
+func (u *shopUsecase) verifiedContact(ctx context.Context, userID int) (email, phone string, err error) {
+	user, err := u.userClient.GetUser(ctx, &userProto.GetUserRequest{UserId: int32(userID)})
+	if err != nil {
+		return "", "", err
+	}
+
+	if user.User.EmailVerified {
+		email = user.User.Email
+	}
+	if user.User.PhoneVerified {
+		phone = user.User.Phone
+	}
+	return email, phone, nil
 }
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	userProto "github.com/evrintobing17/ecommerce-system/shared/proto/user"
+	"github.com/evrintobing17/ecommerce-system/shop-service/app"
+	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
+	"google.golang.org/grpc"
+)
+
+// memberRepoStub holds invitation 1 to invited@example.com and invitation 2
+// to 0811, both pending, and records the contacts it is searched by
+type memberRepoStub struct {
+	app.ShopMemberRepository
+	searched [][2]string
+	updated  []*models.ShopMember
+}
+
+func (r *memberRepoStub) FindByID(ctx context.Context, id int) (*models.ShopMember, error) {
+	switch id {
+	case 1:
+		return &models.ShopMember{ID: 1, ShopID: 1, Email: "invited@example.com", Role: models.MemberRoleManager, Status: models.MemberStatusPending}, nil
+	case 2:
+		return &models.ShopMember{ID: 2, ShopID: 1, Phone: "0811", Role: models.MemberRoleManager, Status: models.MemberStatusPending}, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memberRepoStub) FindActive(ctx context.Context, shopID, userID int) (*models.ShopMember, error) {
+	return nil, errors.New("record not found")
+}
+
+func (r *memberRepoStub) FindPendingInvitations(ctx context.Context, email, phone string) ([]*models.ShopMember, error) {
+	r.searched = append(r.searched, [2]string{email, phone})
+	return nil, nil
+}
+
+func (r *memberRepoStub) Update(ctx context.Context, member *models.ShopMember) error {
+	r.updated = append(r.updated, member)
+	return nil
+}
+
+// userClientStub returns user 1 with the invited email and phone
+type userClientStub struct {
+	userProto.UserServiceClient
+	emailVerified bool
+	phoneVerified bool
+}
+
+func (c *userClientStub) GetUser(ctx context.Context, in *userProto.GetUserRequest, opts ...grpc.CallOption) (*userProto.GetUserResponse, error) {
+	return &userProto.GetUserResponse{User: &userProto.User{
+		Id:            in.UserId,
+		Email:         "invited@example.com",
+		Phone:         "0811",
+		EmailVerified: c.emailVerified,
+		PhoneVerified: c.phoneVerified,
+	}}, nil
+}
+
+func TestInvitationsRequireVerifiedContact(t *testing.T) {
+	tests := []struct {
+		name          string
+		emailVerified bool
+		phoneVerified bool
+		invitationID  int
+		want          error
+		wantSearch    [2]string
+	}{
+		{"unverified email", false, false, 1, models.ErrInvitationNotForUser, [2]string{"", ""}},
+		{"verified email", true, false, 1, nil, [2]string{"invited@example.com", ""}},
+		{"unverified phone", true, false, 2, models.ErrInvitationNotForUser, [2]string{"invited@example.com", ""}},
+		{"verified phone", false, true, 2, nil, [2]string{"", "0811"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := &memberRepoStub{}
+			users := &userClientStub{emailVerified: tt.emailVerified, phoneVerified: tt.phoneVerified}
+			usecase := NewShopUsecase(nil, members, users)
+
+			if _, err := usecase.GetInvitations(context.Background(), 1); err != nil {
+				t.Fatalf("GetInvitations: %v", err)
+			}
+			if len(members.searched) != 1 || members.searched[0] != tt.wantSearch {
+				t.Errorf("searched invitations for %v, want %v", members.searched, tt.wantSearch)
+			}
+
+			_, err := usecase.RespondToInvitation(context.Background(), tt.invitationID, 1, true)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("RespondToInvitation: err = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil && len(members.updated) > 0 {
+				t.Errorf("updated %v, want the invitation left pending", members.updated)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import "github.com/evrintobing17/ecommerce-system/shared/config"
+
+// Config is the configuration of shop-service
+type Config struct {
+	config.Common `yaml:",inline"`
+
+	HTTPAddr config.ListenAddr `env:"SHOP_SERVICE_PORT" yaml:"http_addr" default:"8083"`
+	GRPCAddr config.ListenAddr `env:"SHOP_GRPC_PORT" yaml:"grpc_addr" default:"50054"`
+
+	UserServiceAddr string `env:"USER_SERVICE_GRPC_ADDR" yaml:"user_service_addr" default:"user-service:50058"`
+}
@@ -1,113 +1,156 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net"
-	"net/http"
 	"os"
 
 	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+	"github.com/evrintobing17/ecommerce-system/shared/grpc_client"
+	"github.com/evrintobing17/ecommerce-system/shared/health"
+	"github.com/evrintobing17/ecommerce-system/shared/lifecycle"
 	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/migrate"
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/shop"
+	grpcUser "github.com/evrintobing17/ecommerce-system/shared/proto/user"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+	"github.com/evrintobing17/ecommerce-system/shared/transcoding"
 
 	delivery "github.com/evrintobing17/ecommerce-system/shop-service/app/delivery"
 	grpcServer "github.com/evrintobing17/ecommerce-system/shop-service/app/delivery/grpc"
 
-	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
 	"github.com/evrintobing17/ecommerce-system/shop-service/app/repository"
 	"github.com/evrintobing17/ecommerce-system/shop-service/app/usecase"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	// Load configuration
+	var cfg Config
+	if err := config.Load("shop-service", &cfg); err != nil {
+		log.Fatal(err)
 	}
 
 	// Initialize logger
-	shared.InitLogger()
+	shared.InitLogger("shop-service", cfg.LogLevel)
+	config.Log(&cfg)
+	grpc_client.DefaultTimeout = cfg.GRPCClientTimeout
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.RunCommand(context.Background(), "shop-service", cfg.Database, migrationFiles(), "shop-service/migrations", os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	app := lifecycle.New("shop-service", cfg.ShutdownTimeout)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "shop-service")
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	app.AddCloser("tracing", shutdownTracing)
 
 	// Initialize database
-	db, err := shared.ConnectDB()
+	db, err := shared.ConnectDB(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
-	defer func() {
-		if err := shared.CloseDB(db); err != nil {
-			log.Println("Error closing database:", err)
-		}
-	}()
+	app.AddCloser("database", func(context.Context) error {
+		return shared.CloseDB(db)
+	})
 
-	// Auto migrate models
-	err = shared.MigrateDB(db, &models.Shop{})
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	// Apply pending migrations
+	if cfg.MigrateOnStart {
+		if err := migrate.Up(context.Background(), db, "shop-service", migrationFiles()); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
 	}
 
 	// Initialize repositories
 	shopRepo := repository.NewShopRepository(db)
+	memberRepo := repository.NewShopMemberRepository(db)
+
+	serviceAuth := cfg.ServiceAuth
+
+	userConn, err := grpc_client.NewConnection(cfg.UserServiceAddr, serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to user-service:", err)
+	}
+	defer userConn.Close()
+
+	userClient := grpcUser.NewUserServiceClient(userConn)
 
 	// Initialize use cases
-	shopUsecase := usecase.NewShopUsecase(shopRepo)
+	shopUsecase := usecase.NewShopUsecase(shopRepo, memberRepo, userClient)
 
 	// Initialize HTTP server
-	router := gin.Default()
+	router := gin.New()
+	router.Use(tracing.GinMiddleware("shop-service")...)
+	router.Use(middleware.RequestID(), middleware.AccessLog())
 	shopHandler := delivery.NewShopHandler(shopUsecase)
-	router.Use(gin.Recovery())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.GatewayIdentity(cfg.ServiceAuth.TokenSecret))
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
 	router.Use(shared.GinMetricsMiddleware())
 	shared.RegisterMetricsHandler(router)
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "OK",
-		})
-	})
+	healthChecks := health.New("shop-service", cfg.HealthCheckInterval)
+	healthChecks.AddReadinessCheck("database", health.DatabaseChecker(db))
+	healthChecks.RegisterHTTP(router)
 	// HTTP routes
-	api := router.Group("/api/v1")
-	jwtSecret := os.Getenv("JWT_SECRET")
-	api.Use(middleware.AuthMiddleware(jwtSecret))
-	{
-		api.POST("/shops", shopHandler.CreateShop)
-		api.GET("/shops/:id", shopHandler.GetShop)
-		api.GET("/shops", shopHandler.GetMyShops)
-		api.PUT("/shops/:id", shopHandler.UpdateShop)
-		api.DELETE("/shops/:id", shopHandler.DeleteShop)
-	}
+	jwtSecret := cfg.JWTSecret
+	registerRoutes(router, shopHandler, jwtSecret)
 
 	// Initialize gRPC server
 	shopServer := grpcServer.NewShopServer(shopUsecase)
 
-	// Start gRPC server
-	go func() {
-		grpcPort := os.Getenv("SHOP_GRPC_PORT")
-		if grpcPort == "" {
-			grpcPort = ":50054"
-		}
-
-		lis, err := net.Listen("tcp", grpcPort)
-		if err != nil {
-			log.Fatal("Failed to listen:", err)
-		}
-
-		grpcServer := grpc.NewServer()
-		proto.RegisterShopServiceServer(grpcServer, shopServer)
+	serverOptions, err := middleware.GRPCServerOptions(middleware.GRPCServerConfig{
+		ServiceAuth: serviceAuth,
+		Allowlist: serviceauth.Allowlist{
+			proto.ShopService_CheckShopPermission_FullMethodName: {"product-service", "warehouse-service", "order-service"},
+		},
+		JWTSecret: jwtSecret,
+		MethodPermissions: map[string]string{
+			proto.ShopService_CreateShop_FullMethodName: shared.PermShopCreate,
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to configure gRPC server:", err)
+	}
+	grpcServer := grpc.NewServer(serverOptions...)
+	proto.RegisterShopServiceServer(grpcServer, shopServer)
+	healthChecks.RegisterGRPC(grpcServer)
+	app.AddGRPCServer("Shop", string(cfg.GRPCAddr), grpcServer)
+
+	// REST/JSON transcoding of the gRPC API below /api/v2. It calls the
+	// service's own gRPC server so the same interceptors apply.
+	selfConn, err := grpc_client.NewConnection(cfg.GRPCAddr.DialAddr(), serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to own gRPC server:", err)
+	}
+	defer selfConn.Close()
+	restMux := transcoding.NewServeMux()
+	if err := proto.RegisterShopServiceHandler(context.Background(), restMux, selfConn); err != nil {
+		log.Fatal("Failed to register REST transcoding:", err)
+	}
+	transcoding.Mount(router, restMux)
+	transcoding.RegisterSwagger(router, transcoding.Specs{
+		Title:     "Shop Service API",
+		OpenAPIV2: proto.OpenAPIV2,
+		OpenAPIV3: proto.OpenAPIV3,
+	})
 
-		log.Printf("Shop gRPC server started on port %s", grpcPort)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatal("Failed to serve gRPC:", err)
-		}
-	}()
+	app.AddHTTPServer("Shop", string(cfg.HTTPAddr), router)
 
-	// Start HTTP server
-	httpPort := os.Getenv("SHOP_SERVICE_PORT")
-	if httpPort == "" {
-		httpPort = "8083"
-	}
+	app.AddWorker("health", healthChecks.Run)
+	app.OnShutdown(healthChecks.Shutdown)
 
-	log.Printf("Shop HTTP server started on port %s", httpPort)
-	if err := router.Run(":" + httpPort); err != nil {
-		log.Fatal("Failed to start HTTP server:", err)
+	if err := app.Run(); err != nil {
+		log.Fatal("Shop service stopped with error:", err)
 	}
 }
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS shops (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    description TEXT,
+    owner_id BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS shop_members (
+    id BIGSERIAL PRIMARY KEY,
+    shop_id BIGINT,
+    user_id BIGINT,
+    email TEXT,
+    phone TEXT,
+    role TEXT,
+    status TEXT,
+    invited_by BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_shop_members_shop_id ON shop_members (shop_id);
+CREATE INDEX IF NOT EXISTS idx_shop_members_user_id ON shop_members (user_id);
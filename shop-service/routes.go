@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	delivery "github.com/evrintobing17/ecommerce-system/shop-service/app/delivery"
+	"github.com/gin-gonic/gin"
+)
+
+// registerRoutes adds the HTTP API below /api/v1 to router. shopHandler also
+// checks the shop permissions.
+func registerRoutes(router gin.IRouter, shopHandler *delivery.ShopHandler, jwtSecret string) {
+	api := router.Group("/api/v1")
+	api.Use(middleware.AuthMiddleware(jwtSecret))
+	{
+		api.POST("/shops", middleware.RequirePermission(shared.PermShopCreate), shopHandler.CreateShop)
+		api.GET("/shops/:id", shopHandler.GetShop)
+		api.GET("/shops", shopHandler.GetMyShops)
+		api.PUT("/shops/:id",
+			middleware.RequireShopPermission(shared.PermShopManage, shopHandler, middleware.ShopIDFromParam("id")),
+			shopHandler.UpdateShop)
+		api.DELETE("/shops/:id",
+			middleware.RequireShopPermission(shared.PermShopManage, shopHandler, middleware.ShopIDFromParam("id")),
+			shopHandler.DeleteShop)
+
+		// Shop members and invitations
+		api.GET("/shops/:id/members",
+			middleware.RequireShopPermission(shared.PermShopView, shopHandler, middleware.ShopIDFromParam("id")),
+			shopHandler.GetMembers)
+		api.POST("/shops/:id/members",
+			middleware.RequireShopPermission(shared.PermShopMembers, shopHandler, middleware.ShopIDFromParam("id")),
+			shopHandler.InviteMember)
+		api.DELETE("/shops/:id/members/:member_id",
+			middleware.RequireShopPermission(shared.PermShopMembers, shopHandler, middleware.ShopIDFromParam("id")),
+			shopHandler.RemoveMember)
+		api.GET("/invitations", shopHandler.GetInvitations)
+		api.POST("/invitations/:id/accept", shopHandler.AcceptInvitation)
+		api.POST("/invitations/:id/decline", shopHandler.DeclineInvitation)
+	}
+}
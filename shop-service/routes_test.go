@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse/responsetest"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shop-service/app"
+	delivery "github.com/evrintobing17/ecommerce-system/shop-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/shop-service/app/models"
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "test-secret"
+
+// shopUsecaseStub serves shop 1, owned by user 1, in which user 2 is a
+// viewer and user 3 a manager. Member 5 is the viewer and invitation 6 is
+// addressed to user 2. Deleting shop 1 fails unexpectedly.
+type shopUsecaseStub struct {
+	app.ShopUsecase
+}
+
+func (u *shopUsecaseStub) CreateShop(ctx context.Context, name, description string, ownerID int) (*models.Shop, error) {
+	return &models.Shop{ID: 2, Name: name, Description: description, OwnerID: ownerID}, nil
+}
+
+func (u *shopUsecaseStub) GetShop(ctx context.Context, id int) (*models.Shop, error) {
+	if id != 1 {
+		return nil, models.ErrShopNotFound
+	}
+	return &models.Shop{ID: 1, Name: "Shop", OwnerID: 1}, nil
+}
+
+func (u *shopUsecaseStub) GetShops(ctx context.Context, ownerID, page, limit int) ([]*models.Shop, int64, error) {
+	shop, _ := u.GetShop(ctx, 1)
+	return []*models.Shop{shop}, 1, nil
+}
+
+func (u *shopUsecaseStub) UpdateShop(ctx context.Context, shop *models.Shop) error {
+	return nil
+}
+
+func (u *shopUsecaseStub) DeleteShop(ctx context.Context, id int) error {
+	return errors.New("connection reset")
+}
+
+func (u *shopUsecaseStub) CheckShopPermission(ctx context.Context, userID, shopID int, action string) (bool, models.MemberRole, error) {
+	if _, err := u.GetShop(ctx, shopID); err != nil {
+		return false, "", err
+	}
+	roles := map[int]models.MemberRole{1: models.MemberRoleOwner, 2: models.MemberRoleViewer, 3: models.MemberRoleManager}
+	role, ok := roles[userID]
+	if !ok {
+		return false, "", nil
+	}
+	return role.Can(action), role, nil
+}
+
+func (u *shopUsecaseStub) InviteMember(ctx context.Context, shopID, inviterID int, inviterRole models.MemberRole, email, phone string, role models.MemberRole) (*models.ShopMember, error) {
+	if inviterRole != models.MemberRoleOwner && !inviterRole.Outranks(role) {
+		return nil, models.ErrRoleNotGrantable.With("role", string(role))
+	}
+	return &models.ShopMember{ID: 7, ShopID: shopID, Email: email, Phone: phone, Role: role, Status: models.MemberStatusPending}, nil
+}
+
+func (u *shopUsecaseStub) GetMembers(ctx context.Context, shopID int) ([]*models.ShopMember, error) {
+	return []*models.ShopMember{{ID: 5, ShopID: shopID, UserID: 2, Role: models.MemberRoleViewer, Status: models.MemberStatusActive}}, nil
+}
+
+func (u *shopUsecaseStub) RemoveMember(ctx context.Context, shopID, memberID, actorID int) error {
+	if memberID != 5 {
+		return models.ErrMemberNotFound
+	}
+	return nil
+}
+
+func (u *shopUsecaseStub) GetInvitations(ctx context.Context, userID int) ([]*models.ShopMember, error) {
+	return []*models.ShopMember{}, nil
+}
+
+func (u *shopUsecaseStub) RespondToInvitation(ctx context.Context, invitationID, userID int, accept bool) (*models.ShopMember, error) {
+	if invitationID != 6 {
+		return nil, models.ErrInvitationNotFound
+	}
+	if userID != 2 {
+		return nil, models.ErrInvitationNotForUser
+	}
+	status := models.MemberStatusDeclined
+	if accept {
+		status = models.MemberStatusActive
+	}
+	return &models.ShopMember{ID: 6, ShopID: 1, UserID: userID, Role: models.MemberRoleViewer, Status: status}, nil
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
+	registerRoutes(router, delivery.NewShopHandler(&shopUsecaseStub{}), testJWTSecret)
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	owner := responsetest.Token(t, shared.TokenSubject{UserID: 1, Role: shared.RoleShopOwner, Permissions: []string{shared.PermShopCreate}}, testJWTSecret)
+	viewer := responsetest.Token(t, shared.TokenSubject{UserID: 2, Role: shared.RoleShopStaff}, testJWTSecret)
+	manager := responsetest.Token(t, shared.TokenSubject{UserID: 3, Role: shared.RoleShopStaff}, testJWTSecret)
+	stranger := responsetest.Token(t, shared.TokenSubject{UserID: 4, Role: shared.RoleCustomer}, testJWTSecret)
+
+	responsetest.Run(t, newTestRouter(), []responsetest.Case{
+		{Name: "unknown route", Method: http.MethodGet, Path: "/api/v1/nothing", Status: http.StatusNotFound, Code: "route_not_found"},
+		{Name: "missing token", Method: http.MethodGet, Path: "/api/v1/shops", Status: http.StatusUnauthorized, Code: "authorization_required"},
+		{Name: "invalid token", Method: http.MethodGet, Path: "/api/v1/shops", Token: "invalid", Status: http.StatusUnauthorized, Code: "invalid_token"},
+
+		{Name: "create shop", Method: http.MethodPost, Path: "/api/v1/shops", Token: owner, Body: `{"name":"Shop","description":"Things"}`, Status: http.StatusCreated},
+		{Name: "create shop without fields", Method: http.MethodPost, Path: "/api/v1/shops", Token: owner, Body: `{}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"name", "description"}},
+		{Name: "create shop without body", Method: http.MethodPost, Path: "/api/v1/shops", Token: owner, Status: http.StatusBadRequest, Code: "missing_body", Fields: []string{""}},
+		{Name: "create shop without permission", Method: http.MethodPost, Path: "/api/v1/shops", Token: stranger, Body: `{"name":"Shop","description":"Things"}`, Status: http.StatusForbidden, Code: "permission_denied"},
+
+		{Name: "get shop", Method: http.MethodGet, Path: "/api/v1/shops/1", Token: stranger, Status: http.StatusOK},
+		{Name: "get missing shop", Method: http.MethodGet, Path: "/api/v1/shops/9", Token: stranger, Status: http.StatusNotFound, Code: "shop_not_found"},
+		{Name: "list shops", Method: http.MethodGet, Path: "/api/v1/shops?page=1&limit=5", Token: owner, Status: http.StatusOK, Meta: true},
+
+		{Name: "update shop", Method: http.MethodPut, Path: "/api/v1/shops/1", Token: owner, Body: `{"name":"New name"}`, Status: http.StatusOK},
+		{Name: "update shop with wrong type", Method: http.MethodPut, Path: "/api/v1/shops/1", Token: owner, Body: `{"name":1}`, Status: http.StatusBadRequest, Code: "invalid_type", Fields: []string{"name"}},
+		{Name: "update shop as viewer", Method: http.MethodPut, Path: "/api/v1/shops/1", Token: viewer, Body: `{"name":"New name"}`, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "update missing shop", Method: http.MethodPut, Path: "/api/v1/shops/9", Token: owner, Body: `{"name":"New name"}`, Status: http.StatusNotFound, Code: "resource_not_found"},
+		{Name: "delete shop failing", Method: http.MethodDelete, Path: "/api/v1/shops/1", Token: owner, Status: http.StatusInternalServerError, Code: "internal"},
+
+		{Name: "list members", Method: http.MethodGet, Path: "/api/v1/shops/1/members", Token: viewer, Status: http.StatusOK},
+		{Name: "list members as stranger", Method: http.MethodGet, Path: "/api/v1/shops/1/members", Token: stranger, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "invite member", Method: http.MethodPost, Path: "/api/v1/shops/1/members", Token: owner, Body: `{"email":"staff@example.com","role":"manager"}`, Status: http.StatusCreated},
+		{Name: "invite member with invalid fields", Method: http.MethodPost, Path: "/api/v1/shops/1/members", Token: owner, Body: `{"email":"staff","role":"owner"}`, Status: http.StatusBadRequest, Fields: []string{"email", "role"}},
+		{Name: "invite member without contact", Method: http.MethodPost, Path: "/api/v1/shops/1/members", Token: owner, Body: `{"role":"viewer"}`, Status: http.StatusBadRequest, Code: "required_without", Fields: []string{"email", "phone"}},
+		{Name: "invite peer as manager", Method: http.MethodPost, Path: "/api/v1/shops/1/members", Token: manager, Body: `{"email":"staff@example.com","role":"manager"}`, Status: http.StatusForbidden, Code: "role_not_grantable"},
+		{Name: "invite member as viewer", Method: http.MethodPost, Path: "/api/v1/shops/1/members", Token: viewer, Body: `{"email":"staff@example.com","role":"viewer"}`, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "remove member", Method: http.MethodDelete, Path: "/api/v1/shops/1/members/5", Token: owner, Status: http.StatusOK},
+		{Name: "remove missing member", Method: http.MethodDelete, Path: "/api/v1/shops/1/members/9", Token: owner, Status: http.StatusNotFound, Code: "member_not_found"},
+
+		{Name: "list invitations", Method: http.MethodGet, Path: "/api/v1/invitations", Token: viewer, Status: http.StatusOK},
+		{Name: "accept invitation", Method: http.MethodPost, Path: "/api/v1/invitations/6/accept", Token: viewer, Status: http.StatusOK},
+		{Name: "accept invitation of another user", Method: http.MethodPost, Path: "/api/v1/invitations/6/accept", Token: stranger, Status: http.StatusForbidden, Code: "invitation_not_for_user"},
+		{Name: "decline missing invitation", Method: http.MethodPost, Path: "/api/v1/invitations/9/decline", Token: viewer, Status: http.StatusNotFound, Code: "invitation_not_found"},
+	})
+}
@@ -2,69 +2,87 @@ package grpc
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
 
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/user"
 	usecase "github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 type userServer struct {
 	proto.UnimplementedUserServiceServer
-	userUsecase usecase.UserUsecase
+	userUsecase    usecase.UserUsecase
+	trustedProxies []netip.Prefix
 }
 
-func NewUserServer(userUsecase usecase.UserUsecase) *userServer {
-	return &userServer{userUsecase: userUsecase}
+// NewUserServer returns the gRPC server of userUsecase. The x-forwarded-for
+// metadata is only believed from the addresses or CIDRs in trustedProxies.
+func NewUserServer(userUsecase usecase.UserUsecase, trustedProxies []string) *userServer {
+	s := &userServer{userUsecase: userUsecase}
+	for _, proxy := range trustedProxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			s.trustedProxies = append(s.trustedProxies, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(proxy); err == nil {
+			s.trustedProxies = append(s.trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return s
 }
 
 func (s *userServer) Register(ctx context.Context, req *proto.RegisterRequest) (*proto.RegisterResponse, error) {
-	user, token, err := s.userUsecase.Register(req.Email, req.Phone, req.Password, req.Name)
+	user, token, err := s.userUsecase.Register(ctx, req.Email, req.Phone, req.Password, req.Name, req.Role)
 	if err != nil {
-		log.Printf("Register error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to register user: %v", err)
+		slog.WarnContext(ctx, "register failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.RegisterResponse{
 		User: &proto.User{
-			Id:        int32(user.ID),
-			Email:     user.Email,
-			Phone:     user.Phone,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
+			Id:            int32(user.ID),
+			Email:         user.Email,
+			Phone:         user.Phone,
+			Name:          user.Name,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
+			PhoneVerified: user.PhoneVerified,
+			CreatedAt:     user.CreatedAt.Format("2006-01-02 15:04:05"),
+			UpdatedAt:     user.UpdatedAt.Format("2006-01-02 15:04:05"),
 		},
 		Token: token,
 	}, nil
 }
 
 func (s *userServer) Login(ctx context.Context, req *proto.LoginRequest) (*proto.LoginResponse, error) {
-	user, token, err := s.userUsecase.Login(req.EmailOrPhone, req.Password)
+	result, err := s.userUsecase.Login(ctx, req.EmailOrPhone, req.Password, s.clientIP(ctx))
 	if err != nil {
-		log.Printf("Login error: %v", err)
-		return nil, status.Errorf(codes.Unauthenticated, "invalid credentials: %v", err)
+		slog.WarnContext(ctx, "login failed", "error", err)
+		return nil, err
 	}
 
-	return &proto.LoginResponse{
-		User: &proto.User{
-			Id:        int32(user.ID),
-			Email:     user.Email,
-			Phone:     user.Phone,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
-		Token: token,
-	}, nil
+	return toLoginResponse(result), nil
+}
+
+func (s *userServer) VerifyMFA(ctx context.Context, req *proto.VerifyMFARequest) (*proto.LoginResponse, error) {
+	result, err := s.userUsecase.CompleteMFALogin(ctx, req.ChallengeToken, req.Code, s.clientIP(ctx))
+	if err != nil {
+		slog.WarnContext(ctx, "verify m f a failed", "error", err)
+		return nil, err
+	}
+
+	return toLoginResponse(result), nil
 }
 
 func (s *userServer) ValidateToken(ctx context.Context, req *proto.ValidateTokenRequest) (*proto.ValidateTokenResponse, error) {
-	valid, user, err := s.userUsecase.ValidateToken(req.Token)
+	valid, user, err := s.userUsecase.ValidateToken(ctx, req.Token)
 	if err != nil {
-		log.Printf("ValidateToken error: %v", err)
-		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+		slog.WarnContext(ctx, "validate token failed", "error", err)
+		return nil, err
 	}
 
 	if !valid {
@@ -74,31 +92,112 @@ func (s *userServer) ValidateToken(ctx context.Context, req *proto.ValidateToken
 	return &proto.ValidateTokenResponse{
 		Valid: true,
 		User: &proto.User{
-			Id:        int32(user.ID),
-			Email:     user.Email,
-			Phone:     user.Phone,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
+			Id:            int32(user.ID),
+			Email:         user.Email,
+			Phone:         user.Phone,
+			Name:          user.Name,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
+			PhoneVerified: user.PhoneVerified,
+			CreatedAt:     user.CreatedAt.Format("2006-01-02 15:04:05"),
+			UpdatedAt:     user.UpdatedAt.Format("2006-01-02 15:04:05"),
 		},
 	}, nil
 }
 
 func (s *userServer) GetUser(ctx context.Context, req *proto.GetUserRequest) (*proto.GetUserResponse, error) {
-	user, err := s.userUsecase.GetUser(int(req.UserId))
+	user, err := s.userUsecase.GetUser(ctx, int(req.UserId))
 	if err != nil {
-		log.Printf("GetUser error: %v", err)
-		return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
+		slog.WarnContext(ctx, "get user failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.GetUserResponse{
 		User: &proto.User{
-			Id:        int32(user.ID),
-			Email:     user.Email,
-			Phone:     user.Phone,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
+			Id:            int32(user.ID),
+			Email:         user.Email,
+			Phone:         user.Phone,
+			Name:          user.Name,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
+			PhoneVerified: user.PhoneVerified,
+			CreatedAt:     user.CreatedAt.Format("2006-01-02 15:04:05"),
+			UpdatedAt:     user.UpdatedAt.Format("2006-01-02 15:04:05"),
 		},
 	}, nil
 }
+
+func toLoginResponse(result *models.LoginResult) *proto.LoginResponse {
+	if result.MFARequired {
+		return &proto.LoginResponse{
+			MfaRequired:           true,
+			MfaEnrollmentRequired: result.MFAEnrollmentRequired,
+			ChallengeToken:        result.ChallengeToken,
+		}
+	}
+
+	user := result.User
+	return &proto.LoginResponse{
+		User: &proto.User{
+			Id:            int32(user.ID),
+			Email:         user.Email,
+			Phone:         user.Phone,
+			Name:          user.Name,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
+			PhoneVerified: user.PhoneVerified,
+			CreatedAt:     user.CreatedAt.Format("2006-01-02 15:04:05"),
+			UpdatedAt:     user.UpdatedAt.Format("2006-01-02 15:04:05"),
+		},
+		Token:         result.Token,
+		RecoveryCodes: result.RecoveryCodes,
+	}
+}
+
+// clientIP returns the address of the client that sent the request. Calls
+// relayed by a trusted proxy, the gateway in grpc mode or the REST
+// transcoding of the service itself, name the client in the x-forwarded-for
+// metadata, which is read from the right past the trusted proxies as gin
+// does for HTTP. It is empty when no client outside the trusted proxies is
+// known, so that the login protection is not keyed on a proxy shared by all
+// users.
+func (s *userServer) clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return ""
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+	if !s.trusted(addr) {
+		return addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	forwarded := strings.Split(strings.Join(md.Get("x-forwarded-for"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			return ""
+		}
+		if !s.trusted(addr) {
+			return addr.String()
+		}
+	}
+	return ""
+}
+
+func (s *userServer) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	proto "github.com/evrintobing17/ecommerce-system/shared/proto/user"
+	"github.com/evrintobing17/ecommerce-system/shared/transcoding"
+	usecase "github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// gatewayIP is the address of the gateway, trusted next to loopback
+const gatewayIP = "10.0.0.2"
+
+var testTrustedProxies = []string{"127.0.0.1", "::1", gatewayIP}
+
+// userUsecaseStub answers every login with an MFA challenge and records the
+// client IPs the logins are counted for
+type userUsecaseStub struct {
+	usecase.UserUsecase
+	mu        sync.Mutex
+	clientIPs []string
+}
+
+func (u *userUsecaseStub) Login(ctx context.Context, emailOrPhone, password, clientIP string) (*models.LoginResult, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.clientIPs = append(u.clientIPs, clientIP)
+	return &models.LoginResult{MFARequired: true, ChallengeToken: "challenge"}, nil
+}
+
+func (u *userUsecaseStub) lastClientIP(t *testing.T) string {
+	t.Helper()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.clientIPs) == 0 {
+		t.Fatal("no login reached the usecase")
+	}
+	return u.clientIPs[len(u.clientIPs)-1]
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded []string
+		want      string
+	}{
+		{"direct client", "203.0.113.7", nil, "203.0.113.7"},
+		{"direct client forging metadata", "203.0.113.7", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"gateway in grpc mode", gatewayIP, []string{"198.51.100.9"}, "198.51.100.9"},
+		{"transcoded through the gateway", "127.0.0.1", []string{"198.51.100.9, " + gatewayIP}, "198.51.100.9"},
+		{"transcoded with a forged header", "127.0.0.1", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"trusted peer naming no client", gatewayIP, nil, ""},
+		{"loopback naming only proxies", "::1", []string{gatewayIP + ", 127.0.0.1"}, ""},
+		{"malformed metadata", "127.0.0.1", []string{"not-an-ip"}, ""},
+	}
+
+	server := NewUserServer(&userUsecaseStub{}, testTrustedProxies)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 4000},
+			})
+			if tt.forwarded != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", strings.Join(tt.forwarded, ",")))
+			}
+
+			if got := server.clientIP(ctx); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTranscodedLoginClientIP sends logins through the /api/v2 transcoding,
+// which reaches the gRPC server over loopback like the service's own
+// connection does
+func TestTranscodedLoginClientIP(t *testing.T) {
+	stub := &userUsecaseStub{}
+	grpcServer := grpc.NewServer()
+	proto.RegisterUserServiceServer(grpcServer, NewUserServer(stub, testTrustedProxies))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	mux := transcoding.NewServeMux()
+	if err := proto.RegisterUserServiceHandler(context.Background(), mux, conn); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	transcoding.Mount(router, mux)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"proxied by the gateway", gatewayIP + ":4000", "198.51.100.9", "198.51.100.9"},
+		{"direct client forging the header", "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"direct client", "203.0.113.8:4000", "", "203.0.113.8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/login", strings.NewReader(`{"email_or_phone":"customer@example.com","password":"secret"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body)
+			}
+			if got := stub.lastClientIP(t); got != tt.want {
+				t.Errorf("login counted for client IP %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package http
 
 import (
-	"net/http"
+	"errors"
+	"math"
+	"strconv"
 
 	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
 	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,6 +25,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Phone    string `json:"phone" binding:"required"`
 		Password string `json:"password" binding:"required,min=6"`
 		Name     string `json:"name" binding:"required"`
+		Role     string `json:"role" binding:"omitempty,oneof=customer shop_owner"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -29,9 +33,9 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	user, token, err := h.userUsecase.Register(request.Email, request.Phone, request.Password, request.Name)
+	user, token, err := h.userUsecase.Register(c.Request.Context(), request.Email, request.Phone, request.Password, request.Name, request.Role)
 	if err != nil {
-		jsonhttpresponse.InternalServerError(c, err)
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 	jsonhttpresponse.StatusCreated(c, gin.H{"user": gin.H{
@@ -39,6 +43,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		"email":      user.Email,
 		"phone":      user.Phone,
 		"name":       user.Name,
+		"role":       user.Role,
 		"created_at": user.CreatedAt,
 		"updated_at": user.UpdatedAt,
 	},
@@ -53,39 +58,111 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&request); err != nil {
 		jsonhttpresponse.ErrBind(c, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user, token, err := h.userUsecase.Login(request.EmailOrPhone, request.Password)
+	result, err := h.userUsecase.Login(c.Request.Context(), request.EmailOrPhone, request.Password, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		setRetryAfter(c, err)
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	writeLoginResult(c, result)
+}
+
+// setRetryAfter sets the Retry-After header when err is a ThrottledError
+func setRetryAfter(c *gin.Context, err error) {
+	var throttled *models.ThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	}
+}
+
+// VerifyMFA is the second login step for users that need MFA. It takes the
+// challenge token returned by Login and a TOTP or recovery code.
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	var request struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	result, err := h.userUsecase.CompleteMFALogin(c.Request.Context(), request.ChallengeToken, request.Code, c.ClientIP())
+	if err != nil {
+		setRetryAfter(c, err)
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	writeLoginResult(c, result)
+}
+
+// EnrollMFAWithChallenge lets a user whose role enforces MFA enrol during
+// login, before an access token has been issued
+func (h *UserHandler) EnrollMFAWithChallenge(c *gin.Context) {
+	var request struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	enrollment, err := h.userUsecase.BeginChallengeEnrollment(c.Request.Context(), request.ChallengeToken)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, enrollment)
+}
+
+func writeLoginResult(c *gin.Context, result *models.LoginResult) {
+	if result.MFARequired {
+		jsonhttpresponse.OK(c, gin.H{
+			"mfa_required":            true,
+			"mfa_enrollment_required": result.MFAEnrollmentRequired,
+			"challenge_token":         result.ChallengeToken,
+		})
+		return
+	}
+
+	user := result.User
+	response := gin.H{
 		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"phone":      user.Phone,
-			"name":       user.Name,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
+			"id":          user.ID,
+			"email":       user.Email,
+			"phone":       user.Phone,
+			"name":        user.Name,
+			"role":        user.Role,
+			"mfa_enabled": user.MFAEnabled,
+			"created_at":  user.CreatedAt,
+			"updated_at":  user.UpdatedAt,
 		},
-		"token": token,
-	})
+		"token": result.Token,
+	}
+	if len(result.RecoveryCodes) > 0 {
+		response["recovery_codes"] = result.RecoveryCodes
+	}
+	jsonhttpresponse.OK(c, response)
 }
 
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		jsonhttpresponse.Unauthorized(c, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
-	user, err := h.userUsecase.GetUser(userID.(int))
+	user, err := h.userUsecase.GetUser(c.Request.Context(), userID.(int))
 	if err != nil {
-		jsonhttpresponse.BadRequest(c, err)
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
@@ -95,7 +172,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
 		return
 	}
 
@@ -106,13 +183,13 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	user, err := h.userUsecase.GetUser(userID.(int))
+	user, err := h.userUsecase.GetUser(c.Request.Context(), userID.(int))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
@@ -126,20 +203,247 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 		user.Phone = request.Phone
 	}
 
-	err = h.userUsecase.UpdateUser(user)
+	err = h.userUsecase.UpdateUser(c.Request.Context(), user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"user": gin.H{
 			"id":         user.ID,
 			"email":      user.Email,
 			"phone":      user.Phone,
 			"name":       user.Name,
+			"role":       user.Role,
 			"created_at": user.CreatedAt,
 			"updated_at": user.UpdatedAt,
 		},
 	})
 }
+
+func (h *UserHandler) AssignRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		jsonhttpresponse.BadRequest(c, "invalid_parameter", "invalid user id")
+		return
+	}
+
+	var request struct {
+		Role string `json:"role" binding:"required,oneof=customer shop_owner shop_staff admin"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	user, err := h.userUsecase.AssignRole(c.Request.Context(), userID, request.Role)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"user": user})
+}
+
+// ForgotPassword always answers with the same message so it cannot be used to
+// find out which emails or phone numbers are registered
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var request struct {
+		EmailOrPhone string `json:"email_or_phone" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	if err := h.userUsecase.ForgotPassword(c.Request.Context(), request.EmailOrPhone); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"message": "if the account exists, a reset code has been sent"})
+}
+
+// ResetPassword accepts either the emailed reset token alone, or the
+// email_or_phone the OTP code was sent to together with that code
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var request struct {
+		EmailOrPhone string `json:"email_or_phone"`
+		Token        string `json:"token" binding:"required"`
+		NewPassword  string `json:"new_password" binding:"required,min=6"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	err := h.userUsecase.ResetPassword(c.Request.Context(), request.EmailOrPhone, request.Token, request.NewPassword, c.ClientIP())
+	if err != nil {
+		setRetryAfter(c, err)
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"message": "password has been reset"})
+}
+
+func (h *UserHandler) SendVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	var request struct {
+		Channel models.Channel `json:"channel" binding:"required,oneof=email phone"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	err := h.userUsecase.SendVerification(c.Request.Context(), userID.(int), request.Channel)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"message": "verification code sent"})
+}
+
+// Verify accepts either the emailed verification token alone, or the phone
+// number the OTP code was sent to together with that code
+func (h *UserHandler) Verify(c *gin.Context) {
+	var request struct {
+		Phone string `json:"phone"`
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	user, err := h.userUsecase.Verify(c.Request.Context(), request.Phone, request.Token, c.ClientIP())
+	if err != nil {
+		setRetryAfter(c, err)
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"user": user})
+}
+
+func (h *UserHandler) EnrollMFA(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	enrollment, err := h.userUsecase.BeginMFAEnrollment(c.Request.Context(), userID.(int))
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, enrollment)
+}
+
+// ConfirmMFA enables MFA and returns the recovery codes, which are not shown
+// again
+func (h *UserHandler) ConfirmMFA(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	recoveryCodes, err := h.userUsecase.ConfirmMFAEnrollment(c.Request.Context(), userID.(int), request.Code)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"recovery_codes": recoveryCodes})
+}
+
+func (h *UserHandler) DisableMFA(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	err := h.userUsecase.DisableMFA(c.Request.Context(), userID.(int), request.Code, c.ClientIP())
+	if err != nil {
+		setRetryAfter(c, err)
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"message": "MFA disabled"})
+}
+
+func (h *UserHandler) SetRoleMFARequired(c *gin.Context) {
+	var request struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jsonhttpresponse.ErrBind(c, err)
+		return
+	}
+
+	role := c.Param("role")
+	if err := h.userUsecase.SetRoleMFARequired(c.Request.Context(), role, *request.Required); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"role": role, "require_mfa": *request.Required})
+}
+
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	actorID, exists := c.Get("user_id")
+	if !exists {
+		jsonhttpresponse.Unauthorized(c, "unauthorized", "unauthorized")
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		jsonhttpresponse.BadRequest(c, "invalid_parameter", "invalid user id")
+		return
+	}
+
+	if err := h.userUsecase.UnlockUser(c.Request.Context(), userID, actorID.(int)); err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{"message": "account unlocked"})
+}
@@ -0,0 +1,13 @@
+package app
+
+import "context"
+
+// LoginGuard protects Login and the OTP codes of password reset and phone
+// verification against guessing by tracking failed attempts per user and per
+// client IP. Identifiers that match no user are tracked by themselves.
+type LoginGuard interface {
+	Check(ctx context.Context, userID int, identifier, clientIP string) error
+	RecordFailure(ctx context.Context, userID int, identifier, clientIP string) error
+	RecordSuccess(ctx context.Context, userID int) error
+	Unlock(ctx context.Context, userID, actorID int) error
+}
@@ -1,6 +1,6 @@
 package models
 
-import "errors"
+import "github.com/evrintobing17/ecommerce-system/shared/apperror"
 
 type Error struct {
 	Key   string `json:"key"`
@@ -8,6 +8,20 @@ type Error struct {
 }
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials = apperror.NewUnauthenticated("invalid_credentials", "invalid credentials")
+	ErrUserNotFound       = apperror.NewNotFound("user_not_found", "user not found")
+	ErrEmailTaken         = apperror.NewConflict("email_taken", "user with this email already exists")
+	ErrPhoneTaken         = apperror.NewConflict("phone_taken", "user with this phone already exists")
+	ErrInvalidRole        = apperror.NewInvalidArgument("invalid_role", "invalid role")
+	ErrInvalidToken       = apperror.NewUnauthenticated("invalid_token", "invalid or expired token")
+	ErrIdentifierRequired = apperror.NewInvalidArgument("identifier_required", "the email or phone the code was sent to is required")
+	ErrInvalidChannel     = apperror.NewInvalidArgument("invalid_channel", "unsupported verification channel")
+	ErrAccountNotVerified = apperror.NewPermissionDenied("account_not_verified", "account is not verified")
+	ErrAlreadyVerified    = apperror.NewConflict("already_verified", "already verified")
+	ErrInvalidMFACode     = apperror.NewUnauthenticated("invalid_mfa_code", "invalid MFA code")
+	ErrMFANotEnrolled     = apperror.NewFailedPrecondition("mfa_not_enrolled", "MFA enrolment has not been started")
+	ErrMFAAlreadyEnabled  = apperror.NewConflict("mfa_already_enabled", "MFA is already enabled")
+	ErrMFARequiredByRole  = apperror.NewPermissionDenied("mfa_required_by_role", "MFA is required for this role")
+	ErrAccountLocked      = apperror.NewResourceExhausted("account_locked", "account is temporarily locked")
+	ErrTooManyAttempts    = apperror.NewResourceExhausted("too_many_attempts", "too many failed login attempts")
 )
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RecoveryCode is a one-time code that can replace a TOTP code when the
+// authenticator is lost. Only the keyed hash of the code is stored.
+type RecoveryCode struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	UserID    int        `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"index" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAEnrollment holds the TOTP secret to add to an authenticator app, either
+// typed in or scanned as a QR code of the provisioning URI
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// LoginResult is the outcome of a login step. When MFARequired is set the
+// password was correct but Token is empty, and ChallengeToken has to be sent
+// back together with a TOTP or recovery code to finish the login.
+// MFAEnrollmentRequired means the user's role enforces MFA and the user has to
+// enrol before the login can be completed.
+type LoginResult struct {
+	User                  *User
+	Token                 string
+	MFARequired           bool
+	MFAEnrollmentRequired bool
+	ChallengeToken        string
+	RecoveryCodes         []string
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Role groups a set of permissions. Users reference a role by name.
+type Role struct {
+	Name        string       `gorm:"primaryKey" json:"name"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+	RequireMFA  bool         `json:"require_mfa"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type Permission struct {
+	Name      string    `gorm:"primaryKey" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
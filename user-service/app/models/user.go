@@ -3,23 +3,30 @@ package models
 import "time"
 
 type User struct {
-	ID        int    `gorm:"primaryKey" json:"id"`
-	Email     string    `gorm:"uniqueIndex" json:"email"`
-	Phone     string    `gorm:"uniqueIndex" json:"phone"`
-	Name      string    `json:"name"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int       `gorm:"primaryKey" json:"id"`
+	Email         string    `gorm:"uniqueIndex" json:"email"`
+	Phone         string    `gorm:"uniqueIndex" json:"phone"`
+	Name          string    `json:"name"`
+	Role          string    `gorm:"default:customer" json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	PhoneVerified bool      `json:"phone_verified"`
+	MFAEnabled    bool      `json:"mfa_enabled"`
+	MFASecret     string    `json:"-"`
+	MFALastStep   int64     `json:"-"`
+	Password      string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type UserResponse struct {
 	ID    int    `json:"id"`
 	Email string `json:"email"`
 	Phone string `json:"phone"`
+	Role  string `json:"role"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"omitempty,required_without=Phone,email"`
-	Phone    string `json:"phone" binding:"omitempty,required_without=Email,number"`
+	Email    string `json:"email" binding:"required_without=Phone,omitempty,email"`
+	Phone    string `json:"phone" binding:"required_without=Email,omitempty,number"`
 	Password string `json:"password" binding:"required"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposePhoneVerification TokenPurpose = "phone_verification"
+	TokenPurposeMFAChallenge      TokenPurpose = "mfa_challenge"
+)
+
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelPhone Channel = "phone"
+)
+
+// VerificationToken is a single-use secret sent to the user. Links sent by
+// email carry a long random token, SMS messages carry a short OTP code. Only
+// the keyed hash of the secret is stored, along with the address it was
+// sent to.
+type VerificationToken struct {
+	ID        int          `gorm:"primaryKey" json:"id"`
+	UserID    int          `gorm:"index" json:"user_id"`
+	Purpose   TokenPurpose `gorm:"index" json:"purpose"`
+	Channel   Channel      `json:"channel"`
+	Address   string       `json:"-"`
+	TokenHash string       `gorm:"index" json:"-"`
+	Attempts  int          `json:"attempts"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Message is a notification handed to a Sender. Secret is the token or code
+// the message delivers, which senders append to Body. It is kept out of Body
+// so that it can be logged as an attribute that gets redacted.
+type Message struct {
+	Channel Channel
+	To      string
+	Subject string
+	Body    string
+	Secret  string
+}
@@ -0,0 +1,12 @@
+package app
+
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
+
+// NotificationSender delivers email and SMS messages to users
+type NotificationSender interface {
+	Send(ctx context.Context, message models.Message) error
+}
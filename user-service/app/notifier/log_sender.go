@@ -0,0 +1,29 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
+
+type logSender struct{}
+
+// NewLogSender returns a NotificationSender stub for local development that
+// writes messages to the log instead of delivering them. The secret is logged
+// under the "secret" key, which the shared logger redacts.
+func NewLogSender() app.NotificationSender {
+	return &logSender{}
+}
+
+func (s *logSender) Send(ctx context.Context, message models.Message) error {
+	slog.InfoContext(ctx, "notification",
+		"channel", message.Channel,
+		"to", message.To,
+		"subject", message.Subject,
+		"body", message.Body,
+		"secret", message.Secret,
+	)
+	return nil
+}
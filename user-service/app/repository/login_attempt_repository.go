@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"gorm.io/gorm"
+)
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) app.LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// Find returns the attempts tracked for key, or an empty record if there are
+// none
+func (r *loginAttemptRepository) Find(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.WithContext(ctx).First(&attempt, "key = ?", key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.LoginAttempt{Key: key}, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts a failure for key in a single statement, so that
+// concurrent failures are all counted, and returns the updated record.
+// Failures are restarted when a lockout has run out, or when the last one is
+// older than windowStart unless that is zero.
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (*models.LoginAttempt, error) {
+	var start interface{}
+	if !windowStart.IsZero() {
+		start = windowStart
+	}
+
+	var attempt models.LoginAttempt
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO login_attempts (key, failures, last_failure_at, updated_at)
+		VALUES (@key, 1, @now, @now)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.failures = 0
+					OR login_attempts.locked_until <= @now
+					OR (login_attempts.locked_until IS NULL AND login_attempts.last_failure_at < @window_start)
+				THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			locked_until = CASE WHEN login_attempts.locked_until <= @now THEN NULL ELSE login_attempts.locked_until END,
+			last_failure_at = @now,
+			updated_at = @now
+		RETURNING key, failures, last_failure_at, locked_until, updated_at`,
+		map[string]interface{}{"key": key, "now": now, "window_start": start},
+	).Scan(&attempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Lock locks key until the given time unless it is already locked, and
+// reports whether it did, so that concurrent failures lock it only once
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.LoginAttempt{}).
+		Where("key = ? AND locked_until IS NULL", key).
+		Updates(map[string]interface{}{"locked_until": until, "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *loginAttemptRepository) Delete(ctx context.Context, keys ...string) error {
+	return r.db.WithContext(ctx).Where("key IN ?", keys).Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"gorm.io/gorm"
+)
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) app.RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// ReplaceAll deletes the user's existing recovery codes and stores new ones
+func (r *recoveryCodeRepository) ReplaceAll(ctx context.Context, userID int, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, models.RecoveryCode{
+				UserID:    userID,
+				CodeHash:  hash,
+				CreatedAt: time.Now(),
+			})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *recoveryCodeRepository) FindUnused(ctx context.Context, userID int, codeHash string) (*models.RecoveryCode, error) {
+	var code models.RecoveryCode
+	err := r.db.WithContext(ctx).First(&code, "user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInvalidMFACode
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkUsed uses up the code. It returns ErrInvalidMFACode when the code was
+// used in the meantime, so a code is only accepted once.
+func (r *recoveryCodeRepository) MarkUsed(ctx context.Context, code *models.RecoveryCode) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", code.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrInvalidMFACode
+	}
+	code.UsedAt = &now
+	return nil
+}
+
+func (r *recoveryCodeRepository) DeleteAll(ctx context.Context, userID int) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"gorm.io/gorm"
+)
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) app.RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// EnsureRole creates the role with the given permissions if it does not exist.
+// Existing roles are left untouched so that permission changes made in the
+// database survive restarts.
+func (r *roleRepository) EnsureRole(ctx context.Context, name string, permissions []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		err := tx.First(&role, "name = ?", name).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		role = models.Role{Name: name, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		for _, permission := range permissions {
+			p := models.Permission{Name: permission}
+			if err := tx.Where(models.Permission{Name: permission}).Attrs(models.Permission{CreatedAt: time.Now()}).FirstOrCreate(&p).Error; err != nil {
+				return err
+			}
+			role.Permissions = append(role.Permissions, p)
+		}
+
+		return tx.Create(&role).Error
+	})
+}
+
+func (r *roleRepository) FindPermissions(ctx context.Context, role string) ([]string, error) {
+	var permissions []string
+	err := r.db.WithContext(ctx).Table("role_permissions").
+		Where("role_name = ?", role).
+		Order("permission_name").
+		Pluck("permission_name", &permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *roleRepository) IsMFARequired(ctx context.Context, role string) (bool, error) {
+	var result models.Role
+	err := r.db.WithContext(ctx).Select("require_mfa").First(&result, "name = ?", role).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return result.RequireMFA, nil
+}
+
+func (r *roleRepository) SetMFARequired(ctx context.Context, role string, required bool) error {
+	result := r.db.WithContext(ctx).Model(&models.Role{}).
+		Where("name = ?", role).
+		Updates(map[string]interface{}{"require_mfa": required, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrInvalidRole
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 
 	"github.com/evrintobing17/ecommerce-system/user-service/app"
@@ -16,50 +17,64 @@ func NewUserRepository(db *gorm.DB) app.UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *userRepository) FindByID(id int) (*models.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, id int) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, "id = ?", id).Error
+	err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, models.ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, "email = ?", email).Error
+	err := r.db.WithContext(ctx).First(&user, "email = ?", email).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, models.ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByPhone(phone string) (*models.User, error) {
+func (r *userRepository) FindByPhone(ctx context.Context, phone string) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, "phone = ?", phone).Error
+	err := r.db.WithContext(ctx).First(&user, "phone = ?", phone).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, models.ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
-func (r *userRepository) Delete(id int) error {
-	return r.db.Delete(&models.User{}, "id = ?", id).Error
+// AdvanceMFAStep records step as the time step of the user's last accepted
+// TOTP code unless that or a later step was already recorded, and reports
+// whether it was recorded. The condition makes concurrent uses of one code
+// succeed only once.
+func (r *userRepository) AdvanceMFAStep(ctx context.Context, userID int, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND mfa_last_step < ?", userID, step).
+		Update("mfa_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.User{}, "id = ?", id).Error
 }
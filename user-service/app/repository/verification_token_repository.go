@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"gorm.io/gorm"
+)
+
+type verificationTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewVerificationTokenRepository(db *gorm.DB) app.VerificationTokenRepository {
+	return &verificationTokenRepository{db: db}
+}
+
+func (r *verificationTokenRepository) Create(ctx context.Context, token *models.VerificationToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *verificationTokenRepository) FindActiveByHash(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.VerificationToken, error) {
+	var token models.VerificationToken
+	err := r.db.WithContext(ctx).First(&token, "purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, tokenHash, time.Now()).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindActiveEmailToken finds an unused token sent by email. OTP codes sent by
+// SMS are short enough to guess and are only matched per user.
+func (r *verificationTokenRepository) FindActiveEmailToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.VerificationToken, error) {
+	var token models.VerificationToken
+	err := r.db.WithContext(ctx).First(&token, "purpose = ? AND channel = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		purpose, models.ChannelEmail, tokenHash, time.Now()).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *verificationTokenRepository) FindLatestActive(ctx context.Context, userID int, purpose models.TokenPurpose) (*models.VerificationToken, error) {
+	var token models.VerificationToken
+	err := r.db.WithContext(ctx).Order("created_at DESC").
+		First(&token, "user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", userID, purpose, time.Now()).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RecordAttempt counts a wrong secret against the token and uses it up once
+// maxAttempts is reached. Used tokens and tokens at the limit are left alone,
+// so concurrent guesses cannot get past the limit.
+func (r *verificationTokenRepository) RecordAttempt(ctx context.Context, id, maxAttempts int) error {
+	return r.db.WithContext(ctx).Model(&models.VerificationToken{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", id, maxAttempts).
+		Updates(map[string]any{
+			"attempts": gorm.Expr("attempts + 1"),
+			"used_at":  gorm.Expr("CASE WHEN attempts + 1 >= ? THEN CAST(? AS TIMESTAMPTZ) END", maxAttempts, time.Now()),
+		}).Error
+}
+
+// Consume marks the token as used. It returns ErrInvalidToken when the token
+// was used or expired in the meantime, so a token is only consumed once.
+func (r *verificationTokenRepository) Consume(ctx context.Context, token *models.VerificationToken) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.VerificationToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrInvalidToken
+	}
+	token.UsedAt = &now
+	return nil
+}
+
+// InvalidateAll marks every unused token of the user for the purpose as used
+func (r *verificationTokenRepository) InvalidateAll(ctx context.Context, userID int, purpose models.TokenPurpose) error {
+	return r.db.WithContext(ctx).Model(&models.VerificationToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,342 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	customer = models.User{ID: 1, Email: "customer@example.com", Phone: "0811", EmailVerified: true}
+	other    = models.User{ID: 2, Email: "other@example.com", Phone: "0822", EmailVerified: true}
+)
+
+// assertThrottled fails unless err is a ThrottledError wrapping want
+func assertThrottled(t *testing.T, err error, want error) {
+	t.Helper()
+	var throttled *models.ThrottledError
+	if !errors.As(err, &throttled) || !errors.Is(err, want) {
+		t.Fatalf("err = %v, want a ThrottledError wrapping %v", err, want)
+	}
+	if throttled.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %s, want a positive wait", throttled.RetryAfter)
+	}
+}
+
+// The fakes below keep their records in memory and hand out copies, like the
+// database does, so that the usecase cannot rely on sharing pointers with
+// the repository.
+
+type fakeUserRepo struct {
+	users map[int]models.User
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, user *models.User) error {
+	user.ID = len(r.users) + 1
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *fakeUserRepo) find(match func(models.User) bool) (*models.User, error) {
+	for _, user := range r.users {
+		if match(user) {
+			return &user, nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) FindByID(ctx context.Context, id int) (*models.User, error) {
+	return r.find(func(user models.User) bool { return user.ID == id })
+}
+
+func (r *fakeUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(func(user models.User) bool { return user.Email == email })
+}
+
+func (r *fakeUserRepo) FindByPhone(ctx context.Context, phone string) (*models.User, error) {
+	return r.find(func(user models.User) bool { return user.Phone == phone })
+}
+
+func (r *fakeUserRepo) Update(ctx context.Context, user *models.User) error {
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *fakeUserRepo) AdvanceMFAStep(ctx context.Context, userID int, step int64) (bool, error) {
+	user := r.users[userID]
+	if step <= user.MFALastStep {
+		return false, nil
+	}
+	user.MFALastStep = step
+	r.users[userID] = user
+	return true, nil
+}
+
+func (r *fakeUserRepo) Delete(ctx context.Context, id int) error {
+	delete(r.users, id)
+	return nil
+}
+
+type fakeRoleRepo struct{}
+
+func (fakeRoleRepo) EnsureRole(ctx context.Context, name string, permissions []string) error {
+	return nil
+}
+
+func (fakeRoleRepo) FindPermissions(ctx context.Context, role string) ([]string, error) {
+	return nil, nil
+}
+
+func (fakeRoleRepo) IsMFARequired(ctx context.Context, role string) (bool, error) {
+	return false, nil
+}
+
+func (fakeRoleRepo) SetMFARequired(ctx context.Context, role string, required bool) error {
+	return nil
+}
+
+type fakeTokenRepo struct {
+	tokens []models.VerificationToken
+}
+
+func (r *fakeTokenRepo) Create(ctx context.Context, token *models.VerificationToken) error {
+	token.ID = len(r.tokens) + 1
+	r.tokens = append(r.tokens, *token)
+	return nil
+}
+
+// findActive returns the latest unused and unexpired token matching match
+func (r *fakeTokenRepo) findActive(match func(models.VerificationToken) bool) (*models.VerificationToken, error) {
+	for i := len(r.tokens) - 1; i >= 0; i-- {
+		token := r.tokens[i]
+		if token.UsedAt == nil && token.ExpiresAt.After(time.Now()) && match(token) {
+			return &token, nil
+		}
+	}
+	return nil, models.ErrInvalidToken
+}
+
+func (r *fakeTokenRepo) FindActiveByHash(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.VerificationToken, error) {
+	return r.findActive(func(token models.VerificationToken) bool {
+		return token.Purpose == purpose && token.TokenHash == tokenHash
+	})
+}
+
+func (r *fakeTokenRepo) FindActiveEmailToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.VerificationToken, error) {
+	return r.findActive(func(token models.VerificationToken) bool {
+		return token.Purpose == purpose && token.Channel == models.ChannelEmail && token.TokenHash == tokenHash
+	})
+}
+
+func (r *fakeTokenRepo) FindLatestActive(ctx context.Context, userID int, purpose models.TokenPurpose) (*models.VerificationToken, error) {
+	return r.findActive(func(token models.VerificationToken) bool {
+		return token.UserID == userID && token.Purpose == purpose
+	})
+}
+
+func (r *fakeTokenRepo) RecordAttempt(ctx context.Context, id, maxAttempts int) error {
+	token := &r.tokens[id-1]
+	if token.UsedAt == nil && token.Attempts < maxAttempts {
+		token.Attempts++
+		if token.Attempts >= maxAttempts {
+			now := time.Now()
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *fakeTokenRepo) Consume(ctx context.Context, token *models.VerificationToken) error {
+	stored := &r.tokens[token.ID-1]
+	if stored.UsedAt != nil || !stored.ExpiresAt.After(time.Now()) {
+		return models.ErrInvalidToken
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	token.UsedAt = &now
+	return nil
+}
+
+func (r *fakeTokenRepo) InvalidateAll(ctx context.Context, userID int, purpose models.TokenPurpose) error {
+	now := time.Now()
+	for i, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			r.tokens[i].UsedAt = &now
+		}
+	}
+	return nil
+}
+
+type fakeRecoveryCodeRepo struct {
+	codes []models.RecoveryCode
+}
+
+func (r *fakeRecoveryCodeRepo) ReplaceAll(ctx context.Context, userID int, codeHashes []string) error {
+	r.codes = r.codes[:0]
+	for _, hash := range codeHashes {
+		r.codes = append(r.codes, models.RecoveryCode{ID: len(r.codes) + 1, UserID: userID, CodeHash: hash})
+	}
+	return nil
+}
+
+func (r *fakeRecoveryCodeRepo) FindUnused(ctx context.Context, userID int, codeHash string) (*models.RecoveryCode, error) {
+	for _, code := range r.codes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			return &code, nil
+		}
+	}
+	return nil, models.ErrInvalidMFACode
+}
+
+func (r *fakeRecoveryCodeRepo) MarkUsed(ctx context.Context, code *models.RecoveryCode) error {
+	stored := &r.codes[code.ID-1]
+	if stored.UsedAt != nil {
+		return models.ErrInvalidMFACode
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	code.UsedAt = &now
+	return nil
+}
+
+func (r *fakeRecoveryCodeRepo) DeleteAll(ctx context.Context, userID int) error {
+	r.codes = nil
+	return nil
+}
+
+type fakeAttemptRepo struct {
+	attempts map[string]models.LoginAttempt
+}
+
+func (r *fakeAttemptRepo) Find(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	return &attempt, nil
+}
+
+func (r *fakeAttemptRepo) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (*models.LoginAttempt, error) {
+	attempt := r.attempts[key]
+	attempt.Key = key
+	lockExpired := attempt.LockedUntil != nil && !attempt.LockedUntil.After(now)
+	if attempt.Failures == 0 || lockExpired || (attempt.LockedUntil == nil && attempt.LastFailureAt.Before(windowStart)) {
+		attempt.Failures = 1
+	} else {
+		attempt.Failures++
+	}
+	if lockExpired {
+		attempt.LockedUntil = nil
+	}
+	attempt.LastFailureAt = now
+	attempt.UpdatedAt = now
+	r.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (r *fakeAttemptRepo) Lock(ctx context.Context, key string, until time.Time) (bool, error) {
+	attempt, ok := r.attempts[key]
+	if !ok || attempt.LockedUntil != nil {
+		return false, nil
+	}
+	attempt.LockedUntil = &until
+	r.attempts[key] = attempt
+	return true, nil
+}
+
+func (r *fakeAttemptRepo) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(r.attempts, key)
+	}
+	return nil
+}
+
+// failures returns the failures counted for key
+func (r *fakeAttemptRepo) failures(key string) int {
+	return r.attempts[key].Failures
+}
+
+type fakeEventRepo struct {
+	events []models.SecurityEvent
+}
+
+func (r *fakeEventRepo) Create(ctx context.Context, event *models.SecurityEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+// fakeSender keeps the messages it is asked to send
+type fakeSender struct {
+	messages []models.Message
+}
+
+func (s *fakeSender) Send(ctx context.Context, message models.Message) error {
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// lastSecret returns the secret of the last message sent to
+func (s *fakeSender) lastSecret(t *testing.T, to string) string {
+	t.Helper()
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].To == to {
+			return s.messages[i].Secret
+		}
+	}
+	t.Fatalf("no message sent to %s", to)
+	return ""
+}
+
+const (
+	testPassword = "secret"
+	testClientIP = "192.0.2.1"
+)
+
+// testPolicy locks a user after three failures and a client IP after ten,
+// without delays between attempts
+var testPolicy = models.LockoutPolicy{
+	MaxAttempts:     3,
+	IPMaxAttempts:   10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// fixture is a userUsecase wired to in-memory repositories
+type fixture struct {
+	usecase  *userUsecase
+	users    *fakeUserRepo
+	tokens   *fakeTokenRepo
+	attempts *fakeAttemptRepo
+	sender   *fakeSender
+}
+
+func newFixture(t *testing.T, users ...models.User) *fixture {
+	t.Helper()
+	password, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fixture{
+		users:    &fakeUserRepo{users: map[int]models.User{}},
+		tokens:   &fakeTokenRepo{},
+		attempts: &fakeAttemptRepo{attempts: map[string]models.LoginAttempt{}},
+		sender:   &fakeSender{},
+	}
+	for _, user := range users {
+		user.Password = string(password)
+		if user.Role == "" {
+			user.Role = "customer"
+		}
+		f.users.users[user.ID] = user
+	}
+
+	guard := NewLoginGuard(f.attempts, &fakeEventRepo{}, testPolicy)
+	f.usecase = NewUserUsecase(f.users, fakeRoleRepo{}, f.tokens, &fakeRecoveryCodeRepo{}, f.sender, guard, "test-secret", false).(*userUsecase)
+	return f
+}
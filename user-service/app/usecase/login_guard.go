@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared/apperror"
+	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
+
+type loginGuard struct {
+	attemptRepo app.LoginAttemptRepository
+	eventRepo   app.SecurityEventRepository
+	policy      models.LockoutPolicy
+}
+
+func NewLoginGuard(attemptRepo app.LoginAttemptRepository, eventRepo app.SecurityEventRepository, policy models.LockoutPolicy) app.LoginGuard {
+	return &loginGuard{
+		attemptRepo: attemptRepo,
+		eventRepo:   eventRepo,
+		policy:      policy,
+	}
+}
+
+// Check returns a ThrottledError if the user, or the identifier when it
+// matches no user (userID zero), or the client IP is locked or still has to
+// wait after its last failure
+func (g *loginGuard) Check(ctx context.Context, userID int, identifier, clientIP string) error {
+	now := time.Now()
+	for _, key := range g.keys(userID, identifier, clientIP) {
+		attempt, err := g.attemptRepo.Find(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+			return throttled(models.ErrAccountLocked, attempt.LockedUntil.Sub(now))
+		}
+		if g.expired(attempt, now) {
+			continue
+		}
+
+		if next := attempt.LastFailureAt.Add(g.delay(attempt.Failures)); now.Before(next) {
+			return throttled(models.ErrTooManyAttempts, next.Sub(now))
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login for the user and the client IP, locking
+// either one that reaches its limit. userID is zero for unknown identifiers,
+// whose failures are counted for the identifier instead.
+func (g *loginGuard) RecordFailure(ctx context.Context, userID int, identifier, clientIP string) error {
+	now := time.Now()
+	err := g.eventRepo.Create(ctx, &models.SecurityEvent{
+		UserID:     userID,
+		Type:       models.SecurityEventLoginFailed,
+		Identifier: identifier,
+		ClientIP:   clientIP,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return err
+	}
+
+	var windowStart time.Time
+	if g.policy.Window > 0 {
+		windowStart = now.Add(-g.policy.Window)
+	}
+
+	for _, key := range g.keys(userID, identifier, clientIP) {
+		attempt, err := g.attemptRepo.RecordFailure(ctx, key, now, windowStart)
+		if err != nil {
+			return err
+		}
+
+		limit := g.policy.MaxAttempts
+		if strings.HasPrefix(key, "ip:") {
+			limit = g.policy.IPMaxAttempts
+		}
+		if limit <= 0 || attempt.Failures < limit || attempt.LockedUntil != nil {
+			continue
+		}
+
+		lockedUntil := now.Add(g.policy.LockoutDuration)
+		locked, err := g.attemptRepo.Lock(ctx, key, lockedUntil)
+		if err != nil {
+			return err
+		}
+		if locked {
+			err := g.eventRepo.Create(ctx, &models.SecurityEvent{
+				UserID:     userID,
+				Type:       models.SecurityEventLockout,
+				Identifier: identifier,
+				ClientIP:   clientIP,
+				Details:    fmt.Sprintf("%s locked until %s after %d failed attempts", key, lockedUntil.Format(time.RFC3339), attempt.Failures),
+				CreatedAt:  now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the failures of the user. Failures of the client IP
+// are kept so that one valid account cannot be used to reset them.
+func (g *loginGuard) RecordSuccess(ctx context.Context, userID int) error {
+	return g.attemptRepo.Delete(ctx, userKey(userID))
+}
+
+// Unlock clears the failures and lockout of the user
+func (g *loginGuard) Unlock(ctx context.Context, userID, actorID int) error {
+	if err := g.attemptRepo.Delete(ctx, userKey(userID)); err != nil {
+		return err
+	}
+
+	return g.eventRepo.Create(ctx, &models.SecurityEvent{
+		UserID:    userID,
+		Type:      models.SecurityEventAccountUnlocked,
+		Details:   fmt.Sprintf("unlocked by user %d", actorID),
+		CreatedAt: time.Now(),
+	})
+}
+
+// keys returns the throttling keys of an attempt. A known user is tracked by
+// ID, so that the email and the phone share one counter.
+func (g *loginGuard) keys(userID int, identifier, clientIP string) []string {
+	keys := []string{identifierKey(identifier)}
+	if userID != 0 {
+		keys[0] = userKey(userID)
+	}
+	if clientIP != "" {
+		keys = append(keys, "ip:"+clientIP)
+	}
+	return keys
+}
+
+// expired reports whether the tracked failures no longer count, because the
+// last one is older than the window or a lockout has run out
+func (g *loginGuard) expired(attempt *models.LoginAttempt, now time.Time) bool {
+	if attempt.Failures == 0 {
+		return true
+	}
+	if attempt.LockedUntil != nil {
+		return !now.Before(*attempt.LockedUntil)
+	}
+	return g.policy.Window > 0 && now.Sub(attempt.LastFailureAt) > g.policy.Window
+}
+
+// delay is the wait imposed after the given number of consecutive failures
+func (g *loginGuard) delay(failures int) time.Duration {
+	if g.policy.BaseDelay <= 0 || failures < g.policy.DelayAfter {
+		return 0
+	}
+
+	delay := g.policy.BaseDelay
+	for i := g.policy.DelayAfter; i < failures; i++ {
+		delay *= 2
+		if g.policy.MaxDelay > 0 && delay >= g.policy.MaxDelay {
+			return g.policy.MaxDelay
+		}
+	}
+	return delay
+}
+
+func userKey(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+func identifierKey(identifier string) string {
+	return "identifier:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// throttled returns a ThrottledError wrapping err, which carries the wait in
+// its retry_after_seconds metadata for clients of the gRPC API
+func throttled(err *apperror.Error, retryAfter time.Duration) error {
+	seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+	return &models.ThrottledError{Err: err.With("retry_after_seconds", seconds), RetryAfter: retryAfter}
+}
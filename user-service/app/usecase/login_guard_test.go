@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
+
+func TestLoginLocksUserAcrossIdentifiers(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	// The email and the phone share the counter of the user
+	for _, identifier := range []string{customer.Email, customer.Phone, customer.Email} {
+		if _, err := f.usecase.Login(ctx, identifier, "wrong", testClientIP); !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Fatalf("Login with wrong password: err = %v, want %v", err, models.ErrInvalidCredentials)
+		}
+	}
+	if got := f.attempts.failures("user:1"); got != testPolicy.MaxAttempts {
+		t.Errorf("failures of user:1 = %d, want %d", got, testPolicy.MaxAttempts)
+	}
+
+	// The lockout holds for the right password, from any client IP
+	_, err := f.usecase.Login(ctx, customer.Phone, testPassword, "192.0.2.2")
+	assertThrottled(t, err, models.ErrAccountLocked)
+}
+
+func TestLoginLockoutIsPerUser(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer, other)
+
+	for i := 0; i < testPolicy.MaxAttempts; i++ {
+		f.usecase.Login(ctx, customer.Email, "wrong", testClientIP)
+	}
+	if _, err := f.usecase.Login(ctx, customer.Email, testPassword, testClientIP); !errors.Is(err, models.ErrAccountLocked) {
+		t.Fatalf("Login of locked user: err = %v, want %v", err, models.ErrAccountLocked)
+	}
+
+	// Another user behind the same client IP is not affected
+	result, err := f.usecase.Login(ctx, other.Email, testPassword, testClientIP)
+	if err != nil || result.Token == "" {
+		t.Fatalf("Login of other user: result = %+v, err = %v", result, err)
+	}
+}
+
+func TestLoginTracksUnknownIdentifiers(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	for i := 0; i < testPolicy.MaxAttempts; i++ {
+		if _, err := f.usecase.Login(ctx, "Unknown@example.com", "wrong", testClientIP); !errors.Is(err, models.ErrInvalidCredentials) {
+			t.Fatalf("Login of unknown user: err = %v, want %v", err, models.ErrInvalidCredentials)
+		}
+	}
+	if got := f.attempts.failures("identifier:unknown@example.com"); got != testPolicy.MaxAttempts {
+		t.Errorf("failures of the identifier = %d, want %d", got, testPolicy.MaxAttempts)
+	}
+
+	_, err := f.usecase.Login(ctx, "unknown@example.com", "wrong", testClientIP)
+	assertThrottled(t, err, models.ErrAccountLocked)
+}
+
+func TestLoginLocksClientIP(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	// Spreading guesses over many identifiers still counts for the client IP
+	for i := 0; i < testPolicy.IPMaxAttempts; i++ {
+		f.usecase.Login(ctx, "unknown"+strconv.Itoa(i)+"@example.com", "wrong", testClientIP)
+	}
+
+	_, err := f.usecase.Login(ctx, customer.Email, testPassword, testClientIP)
+	assertThrottled(t, err, models.ErrAccountLocked)
+
+	if _, err := f.usecase.Login(ctx, customer.Email, testPassword, "192.0.2.2"); err != nil {
+		t.Fatalf("Login from another client IP: %v", err)
+	}
+}
+
+func TestLoginSuccessKeepsClientIPFailures(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	f.usecase.Login(ctx, customer.Email, "wrong", testClientIP)
+	if _, err := f.usecase.Login(ctx, customer.Email, testPassword, testClientIP); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	if got := f.attempts.failures("user:1"); got != 0 {
+		t.Errorf("failures of user:1 = %d, want them cleared", got)
+	}
+	if got := f.attempts.failures("ip:" + testClientIP); got != 1 {
+		t.Errorf("failures of the client IP = %d, want 1", got)
+	}
+}
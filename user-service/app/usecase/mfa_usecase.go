@@ -0,0 +1,362 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	mfaIssuer          = "Ecommerce System"
+	mfaChallengeTTL    = 5 * time.Minute
+	maxMFAAttempts     = 5
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// totpOpts are the parameters of the codes of authenticator apps; one step of
+// clock skew is tolerated in either direction
+var totpOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// CompleteMFALogin finishes a login started by Login when MFA is required.
+// code is a TOTP code or, for enrolled users, one of their recovery codes.
+// Users completing an enforced enrolment get their recovery codes in the
+// result. Wrong codes count as failed logins of the user and clientIP, so
+// starting new challenges does not allow more guesses.
+func (u *userUsecase) CompleteMFALogin(ctx context.Context, challengeToken, code, clientIP string) (*models.LoginResult, error) {
+	challenge, err := u.tokenRepo.FindActiveByHash(ctx, models.TokenPurposeMFAChallenge, u.hashSecret(challengeToken))
+	if err != nil {
+		return nil, models.ErrInvalidToken
+	}
+
+	user, err := u.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.loginGuard.Check(ctx, user.ID, "", clientIP); err != nil {
+		return nil, err
+	}
+
+	var valid bool
+	if user.MFAEnabled {
+		valid, err = u.checkMFACode(ctx, user, code)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if user.MFASecret == "" {
+			return nil, models.ErrMFANotEnrolled
+		}
+		valid, err = u.validateTOTP(ctx, user, code)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !valid {
+		// Too many wrong codes burn the challenge and force a new password step
+		if err := u.tokenRepo.RecordAttempt(ctx, challenge.ID, maxMFAAttempts); err != nil {
+			return nil, err
+		}
+		return nil, u.recordMFAFailure(ctx, user.ID, clientIP)
+	}
+
+	// A challenge completed concurrently is not completed again
+	if err := u.tokenRepo.Consume(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	// Failed logins are only cleared once both factors are proven
+	if err := u.loginGuard.RecordSuccess(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if !user.MFAEnabled {
+		recoveryCodes, err = u.enableMFA(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return u.completeLogin(ctx, user, recoveryCodes)
+}
+
+// BeginMFAEnrollment generates a new TOTP secret for the user. MFA is only
+// enabled once a code generated from it is confirmed.
+func (u *userUsecase) BeginMFAEnrollment(ctx context.Context, userID int) (*models.MFAEnrollment, error) {
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      mfaIssuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFASecret = key.Secret()
+	user.UpdatedAt = time.Now()
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollment{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+	}, nil
+}
+
+// BeginChallengeEnrollment starts enrolment for a user whose role enforces MFA
+// and who therefore cannot obtain an access token yet. The challenge stays
+// valid and is completed with CompleteMFALogin.
+func (u *userUsecase) BeginChallengeEnrollment(ctx context.Context, challengeToken string) (*models.MFAEnrollment, error) {
+	challenge, err := u.tokenRepo.FindActiveByHash(ctx, models.TokenPurposeMFAChallenge, u.hashSecret(challengeToken))
+	if err != nil {
+		return nil, models.ErrInvalidToken
+	}
+
+	return u.BeginMFAEnrollment(ctx, challenge.UserID)
+}
+
+// ConfirmMFAEnrollment enables MFA once the user proves the authenticator
+// works and returns the recovery codes, which are only shown this once
+func (u *userUsecase) ConfirmMFAEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return nil, models.ErrMFANotEnrolled
+	}
+	valid, err := u.validateTOTP(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, models.ErrInvalidMFACode
+	}
+
+	return u.enableMFA(ctx, user)
+}
+
+// DisableMFA turns MFA off after checking a TOTP or recovery code. Wrong
+// codes count as failed logins like those of the login challenge, so an
+// access token alone does not allow unlimited guesses.
+func (u *userUsecase) DisableMFA(ctx context.Context, userID int, code, clientIP string) error {
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return models.ErrMFANotEnrolled
+	}
+	if err := u.loginGuard.Check(ctx, user.ID, "", clientIP); err != nil {
+		return err
+	}
+
+	required, err := u.roleRepo.IsMFARequired(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return models.ErrMFARequiredByRole
+	}
+
+	valid, err := u.checkMFACode(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return u.recordMFAFailure(ctx, user.ID, clientIP)
+	}
+
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	user.UpdatedAt = time.Now()
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	return u.recoveryCodeRepo.DeleteAll(ctx, user.ID)
+}
+
+// SetRoleMFARequired enforces or relaxes MFA for every user with the role
+func (u *userUsecase) SetRoleMFARequired(ctx context.Context, role string, required bool) error {
+	if !shared.IsValidRole(role) {
+		return models.ErrInvalidRole
+	}
+	return u.roleRepo.SetMFARequired(ctx, role, required)
+}
+
+// newMFAChallenge stores a short-lived challenge that stands in for the
+// password during the second login step
+func (u *userUsecase) newMFAChallenge(ctx context.Context, user *models.User, enrollmentRequired bool) (*models.LoginResult, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.VerificationToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeMFAChallenge,
+		TokenHash: u.hashSecret(secret),
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+		CreatedAt: time.Now(),
+	}
+	if err := u.tokenRepo.Create(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResult{
+		MFARequired:           true,
+		MFAEnrollmentRequired: enrollmentRequired,
+		ChallengeToken:        secret,
+	}, nil
+}
+
+// completeLogin issues the access token for a fully authenticated user
+func (u *userUsecase) completeLogin(ctx context.Context, user *models.User, recoveryCodes []string) (*models.LoginResult, error) {
+	token, err := u.generateToken(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResult{
+		User: &models.User{
+			ID:            user.ID,
+			Email:         user.Email,
+			Phone:         user.Phone,
+			Name:          user.Name,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
+			PhoneVerified: user.PhoneVerified,
+			MFAEnabled:    user.MFAEnabled,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+		Token:         token,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// enableMFA turns MFA on and replaces the user's recovery codes
+func (u *userUsecase) enableMFA(ctx context.Context, user *models.User) ([]string, error) {
+	user.MFAEnabled = true
+	user.UpdatedAt = time.Now()
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, u.hashSecret(code))
+	}
+
+	if err := u.recoveryCodeRepo.ReplaceAll(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkMFACode accepts a current TOTP code or an unused recovery code, which
+// is consumed
+func (u *userUsecase) checkMFACode(ctx context.Context, user *models.User, code string) (bool, error) {
+	valid, err := u.validateTOTP(ctx, user, code)
+	if err != nil || valid {
+		return valid, err
+	}
+
+	recoveryCode, err := u.recoveryCodeRepo.FindUnused(ctx, user.ID, u.hashSecret(strings.ToLower(strings.TrimSpace(code))))
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidMFACode) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// A code used up concurrently does not count
+	if err := u.recoveryCodeRepo.MarkUsed(ctx, recoveryCode); err != nil {
+		if errors.Is(err, models.ErrInvalidMFACode) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// recordMFAFailure counts a wrong MFA code against the user and client IP
+// and returns ErrInvalidMFACode
+func (u *userUsecase) recordMFAFailure(ctx context.Context, userID int, clientIP string) error {
+	if err := u.loginGuard.RecordFailure(ctx, userID, "", clientIP); err != nil {
+		return err
+	}
+	return models.ErrInvalidMFACode
+}
+
+// validateTOTP accepts a code of the user's TOTP secret for the current time
+// step or an adjacent one, but only once: the step of an accepted code is
+// recorded and codes of that or an earlier step are rejected as replays
+func (u *userUsecase) validateTOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	current := time.Now().Unix() / int64(totpOpts.Period)
+	for step := current - int64(totpOpts.Skew); step <= current+int64(totpOpts.Skew); step++ {
+		expected, err := totp.GenerateCodeCustom(user.MFASecret, time.Unix(step*int64(totpOpts.Period), 0), totpOpts)
+		if err != nil {
+			return false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+
+		if step <= user.MFALastStep {
+			return false, nil
+		}
+		advanced, err := u.userRepo.AdvanceMFAStep(ctx, user.ID, step)
+		if err != nil || !advanced {
+			return false, err
+		}
+		user.MFALastStep = step
+		return true, nil
+	}
+	return false, nil
+}
+
+// randomRecoveryCode returns a code formatted as xxxxx-xxxxx
+func randomRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:recoveryCodeLength]
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:], nil
+}
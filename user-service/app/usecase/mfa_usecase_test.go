@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"github.com/pquerna/otp/totp"
+)
+
+const testMFASecret = "JBSWY3DPEHPK3PXP"
+
+var mfaUser = models.User{ID: 1, Email: "mfa@example.com", Phone: "0811", EmailVerified: true, MFAEnabled: true, MFASecret: testMFASecret}
+
+func currentCode(t *testing.T) string {
+	t.Helper()
+	code, err := totp.GenerateCodeCustom(testMFASecret, time.Now(), totpOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// challenge logs mfaUser in with the password and returns the MFA challenge
+func challenge(t *testing.T, f *fixture) string {
+	t.Helper()
+	result, err := f.usecase.Login(context.Background(), mfaUser.Email, testPassword, testClientIP)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !result.MFARequired || result.ChallengeToken == "" {
+		t.Fatalf("Login result = %+v, want an MFA challenge", result)
+	}
+	return result.ChallengeToken
+}
+
+func TestCompleteMFALoginRejectsReplayedCode(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, mfaUser)
+	code := currentCode(t)
+
+	result, err := f.usecase.CompleteMFALogin(ctx, challenge(t, f), code, testClientIP)
+	if err != nil || result.Token == "" {
+		t.Fatalf("CompleteMFALogin: result = %+v, err = %v", result, err)
+	}
+
+	if _, err := f.usecase.CompleteMFALogin(ctx, challenge(t, f), code, testClientIP); !errors.Is(err, models.ErrInvalidMFACode) {
+		t.Fatalf("CompleteMFALogin with replayed code: err = %v, want %v", err, models.ErrInvalidMFACode)
+	}
+	if err := f.usecase.DisableMFA(ctx, mfaUser.ID, code, testClientIP); !errors.Is(err, models.ErrInvalidMFACode) {
+		t.Fatalf("DisableMFA with replayed code: err = %v, want %v", err, models.ErrInvalidMFACode)
+	}
+}
+
+func TestMFALoginClearsFailuresAfterSecondStep(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, mfaUser)
+
+	f.usecase.Login(ctx, mfaUser.Email, "wrong", testClientIP)
+	challengeToken := challenge(t, f)
+
+	// The password alone does not clear the failures
+	if got := f.attempts.failures("user:1"); got != 1 {
+		t.Fatalf("failures of user:1 after password step = %d, want 1", got)
+	}
+	if _, err := f.usecase.CompleteMFALogin(ctx, challengeToken, "not-a-code", testClientIP); !errors.Is(err, models.ErrInvalidMFACode) {
+		t.Fatalf("CompleteMFALogin with wrong code: err = %v, want %v", err, models.ErrInvalidMFACode)
+	}
+	if got := f.attempts.failures("user:1"); got != 2 {
+		t.Fatalf("failures of user:1 after wrong code = %d, want 2", got)
+	}
+
+	if _, err := f.usecase.CompleteMFALogin(ctx, challengeToken, currentCode(t), testClientIP); err != nil {
+		t.Fatalf("CompleteMFALogin: %v", err)
+	}
+	if got := f.attempts.failures("user:1"); got != 0 {
+		t.Errorf("failures of user:1 after MFA = %d, want them cleared", got)
+	}
+}
+
+func TestWrongMFACodesCountAsLoginFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		submit func(f *fixture, challengeToken, code string) error
+	}{
+		{"login challenge", func(f *fixture, challengeToken, code string) error {
+			_, err := f.usecase.CompleteMFALogin(context.Background(), challengeToken, code, testClientIP)
+			return err
+		}},
+		{"disable", func(f *fixture, challengeToken, code string) error {
+			return f.usecase.DisableMFA(context.Background(), mfaUser.ID, code, testClientIP)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, mfaUser)
+			challengeToken := challenge(t, f)
+
+			for i := 0; i < testPolicy.MaxAttempts; i++ {
+				if err := tt.submit(f, challengeToken, "not-a-code"); !errors.Is(err, models.ErrInvalidMFACode) {
+					t.Fatalf("wrong code: err = %v, want %v", err, models.ErrInvalidMFACode)
+				}
+			}
+
+			// The user is locked, so even the right code is refused
+			assertThrottled(t, tt.submit(f, challengeToken, currentCode(t)), models.ErrAccountLocked)
+		})
+	}
+}
+
+func TestRecoveryCodeIsAcceptedOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, mfaUser)
+	user, err := f.users.FindByID(ctx, mfaUser.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	codes, err := f.usecase.enableMFA(ctx, user)
+	if err != nil {
+		t.Fatalf("enableMFA: %v", err)
+	}
+
+	if _, err := f.usecase.CompleteMFALogin(ctx, challenge(t, f), codes[0], testClientIP); err != nil {
+		t.Fatalf("CompleteMFALogin with recovery code: %v", err)
+	}
+	if _, err := f.usecase.CompleteMFALogin(ctx, challenge(t, f), codes[0], testClientIP); !errors.Is(err, models.ErrInvalidMFACode) {
+		t.Fatalf("CompleteMFALogin with used recovery code: err = %v, want %v", err, models.ErrInvalidMFACode)
+	}
+}
@@ -1,7 +1,7 @@
 package usecase
 
 import (
-	"errors"
+	"context"
 	"time"
 
 	"github.com/evrintobing17/ecommerce-system/shared"
@@ -13,27 +13,57 @@ import (
 )
 
 type userUsecase struct {
-	userRepo  app.UserRepository
-	jwtSecret string
+	userRepo             app.UserRepository
+	roleRepo             app.RoleRepository
+	tokenRepo            app.VerificationTokenRepository
+	recoveryCodeRepo     app.RecoveryCodeRepository
+	sender               app.NotificationSender
+	loginGuard           app.LoginGuard
+	jwtSecret            string
+	requireVerifiedLogin bool
 }
 
-func NewUserUsecase(userRepo app.UserRepository, jwtSecret string) app.UserUsecase {
+func NewUserUsecase(userRepo app.UserRepository, roleRepo app.RoleRepository, tokenRepo app.VerificationTokenRepository, recoveryCodeRepo app.RecoveryCodeRepository, sender app.NotificationSender, loginGuard app.LoginGuard, jwtSecret string, requireVerifiedLogin bool) app.UserUsecase {
 	return &userUsecase{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:             userRepo,
+		roleRepo:             roleRepo,
+		tokenRepo:            tokenRepo,
+		recoveryCodeRepo:     recoveryCodeRepo,
+		sender:               sender,
+		loginGuard:           loginGuard,
+		jwtSecret:            jwtSecret,
+		requireVerifiedLogin: requireVerifiedLogin,
 	}
 }
 
-func (u *userUsecase) Register(email, phone, password, name string) (*models.User, string, error) {
+// SeedRoles creates the default roles and their permissions if they are missing
+func (u *userUsecase) SeedRoles(ctx context.Context) error {
+	for role, permissions := range shared.DefaultRolePermissions {
+		if err := u.roleRepo.EnsureRole(ctx, role, permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *userUsecase) Register(ctx context.Context, email, phone, password, name, role string) (*models.User, string, error) {
+	// Only customer and shop owner accounts can be self-registered
+	if role == "" {
+		role = shared.RoleCustomer
+	}
+	if role != shared.RoleCustomer && role != shared.RoleShopOwner {
+		return nil, "", models.ErrInvalidRole
+	}
+
 	// Check if user already exists
-	_, err := u.userRepo.FindByEmail(email)
+	_, err := u.userRepo.FindByEmail(ctx, email)
 	if err == nil {
-		return nil, "", errors.New("user with this email already exists")
+		return nil, "", models.ErrEmailTaken
 	}
 
-	_, err = u.userRepo.FindByPhone(phone)
+	_, err = u.userRepo.FindByPhone(ctx, phone)
 	if err == nil {
-		return nil, "", errors.New("user with this phone already exists")
+		return nil, "", models.ErrPhoneTaken
 	}
 
 	// Hash password
@@ -47,117 +77,239 @@ func (u *userUsecase) Register(email, phone, password, name string) (*models.Use
 		Email:     email,
 		Phone:     phone,
 		Name:      name,
+		Role:      role,
 		Password:  string(hashedPassword),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	err = u.userRepo.Create(user)
+	err = u.userRepo.Create(ctx, user)
 	if err != nil {
 		return nil, "", err
 	}
 
 	// Generate JWT token
-	token, err := shared.GenerateToken(user.ID, user.Email, u.jwtSecret)
+	token, err := u.generateToken(ctx, user)
 	if err != nil {
 		return nil, "", err
 	}
 
 	return &models.User{
-		ID:        user.ID,
-		Email:     user.Email,
-		Phone:     user.Phone,
-		Name:      user.Name,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:            user.ID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		Name:          user.Name,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		MFAEnabled:    user.MFAEnabled,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
 	}, token, nil
 }
 
-func (u *userUsecase) Login(emailOrPhone, password string) (*models.User, string, error) {
+// Login checks the password of the user identified by email or phone.
+// Failed attempts are tracked per user, or per identifier when it matches no
+// user, and per clientIP, and a ThrottledError is returned while either of
+// them is locked out.
+func (u *userUsecase) Login(ctx context.Context, emailOrPhone, password, clientIP string) (*models.LoginResult, error) {
 	var user *models.User
 	var err error
 
 	// Try to find by email first
-	user, err = u.userRepo.FindByEmail(emailOrPhone)
-	if err != nil {
+	verified := true
+	user, err = u.userRepo.FindByEmail(ctx, emailOrPhone)
+	if err == nil {
+		verified = user.EmailVerified
+	} else {
 		// If not found by email, try by phone
-		user, err = u.userRepo.FindByPhone(emailOrPhone)
+		user, err = u.userRepo.FindByPhone(ctx, emailOrPhone)
 		if err != nil {
-			return nil, "", errors.New("invalid credentials")
+			if err := u.loginGuard.Check(ctx, 0, emailOrPhone, clientIP); err != nil {
+				return nil, err
+			}
+			if err := u.loginGuard.RecordFailure(ctx, 0, emailOrPhone, clientIP); err != nil {
+				return nil, err
+			}
+			return nil, models.ErrInvalidCredentials
 		}
+		verified = user.PhoneVerified
+	}
+
+	if err := u.loginGuard.Check(ctx, user.ID, emailOrPhone, clientIP); err != nil {
+		return nil, err
 	}
 
 	// Check password
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, "", errors.New("invalid credentials")
+		if err := u.loginGuard.RecordFailure(ctx, user.ID, emailOrPhone, clientIP); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrInvalidCredentials
 	}
 
-	// Generate JWT token
-	token, err := shared.GenerateToken(user.ID, user.Email, u.jwtSecret)
+	// The identifier used to log in must be verified when required
+	if u.requireVerifiedLogin && !verified {
+		return nil, models.ErrAccountNotVerified
+	}
+
+	// Enrolled users, and users whose role enforces MFA, finish the login with
+	// a second step
+	if user.MFAEnabled {
+		return u.newMFAChallenge(ctx, user, false)
+	}
+	required, err := u.roleRepo.IsMFARequired(ctx, user.Role)
 	if err != nil {
-		return nil, "", err
+		return nil, err
+	}
+	if required {
+		return u.newMFAChallenge(ctx, user, true)
 	}
 
-	return &models.User{
-		ID:        user.ID,
-		Email:     user.Email,
-		Phone:     user.Phone,
-		Name:      user.Name,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, token, nil
+	// Failed logins are only cleared once the login is complete, which for
+	// MFA users is after the second step
+	if err := u.loginGuard.RecordSuccess(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return u.completeLogin(ctx, user, nil)
 }
 
-func (u *userUsecase) ValidateToken(token string) (bool, *models.User, error) {
+func (u *userUsecase) ValidateToken(ctx context.Context, token string) (bool, *models.User, error) {
 	claims, err := u.parseToken(token)
 	if err != nil {
 		return false, nil, err
 	}
 
-	user, err := u.userRepo.FindByID(claims.UserID)
+	user, err := u.userRepo.FindByID(ctx, claims.UserID)
 	if err != nil {
-		return false, nil, errors.New("user not found")
+		return false, nil, err
 	}
 
 	return true, &models.User{
-		ID:        user.ID,
-		Email:     user.Email,
-		Phone:     user.Phone,
-		Name:      user.Name,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:            user.ID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		Name:          user.Name,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		MFAEnabled:    user.MFAEnabled,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
 	}, nil
 }
 
-func (u *userUsecase) GetUser(id int) (*models.User, error) {
-	user, err := u.userRepo.FindByID(id)
+func (u *userUsecase) GetUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := u.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.User{
-		ID:        user.ID,
-		Email:     user.Email,
-		Phone:     user.Phone,
-		Name:      user.Name,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:            user.ID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		Name:          user.Name,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		MFAEnabled:    user.MFAEnabled,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
 	}, nil
 }
 
-func (u *userUsecase) UpdateUser(user *models.User) error {
-	existingUser, err := u.userRepo.FindByID(user.ID)
+func (u *userUsecase) UpdateUser(ctx context.Context, user *models.User) error {
+	existingUser, err := u.userRepo.FindByID(ctx, user.ID)
 	if err != nil {
 		return err
 	}
 
+	// A changed email or phone has to be verified again, and tokens sent to
+	// the old address must not verify the new one
+	if existingUser.Email != user.Email {
+		existingUser.EmailVerified = false
+		if err := u.tokenRepo.InvalidateAll(ctx, user.ID, models.TokenPurposeEmailVerification); err != nil {
+			return err
+		}
+	}
+	if existingUser.Phone != user.Phone {
+		existingUser.PhoneVerified = false
+		if err := u.tokenRepo.InvalidateAll(ctx, user.ID, models.TokenPurposePhoneVerification); err != nil {
+			return err
+		}
+	}
+
 	existingUser.Name = user.Name
 	existingUser.Email = user.Email
 	existingUser.Phone = user.Phone
 	existingUser.UpdatedAt = time.Now()
 
-	return u.userRepo.Update(existingUser)
+	return u.userRepo.Update(ctx, existingUser)
+}
+
+// AssignRole changes the role of a user. Access to individual shops is
+// granted separately through shop membership in shop-service.
+func (u *userUsecase) AssignRole(ctx context.Context, userID int, role string) (*models.User, error) {
+	if !shared.IsValidRole(role) {
+		return nil, models.ErrInvalidRole
+	}
+
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &models.User{
+		ID:            user.ID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		Name:          user.Name,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		MFAEnabled:    user.MFAEnabled,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}, nil
+}
+
+// UnlockUser lifts a login lockout of the user
+func (u *userUsecase) UnlockUser(ctx context.Context, userID, actorID int) error {
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return u.loginGuard.Unlock(ctx, user.ID, actorID)
+}
+
+// generateToken issues a JWT carrying the user's role and the permissions
+// granted to that role
+func (u *userUsecase) generateToken(ctx context.Context, user *models.User) (string, error) {
+	role := user.Role
+	if role == "" {
+		role = shared.RoleCustomer
+	}
+
+	permissions, err := u.roleRepo.FindPermissions(ctx, role)
+	if err != nil {
+		return "", err
+	}
+
+	return shared.GenerateToken(shared.TokenSubject{
+		UserID:      user.ID,
+		Email:       user.Email,
+		Role:        role,
+		Permissions: permissions,
+	}, u.jwtSecret)
 }
 
 func (u *userUsecase) parseToken(tokenString string) (*shared.Claims, error) {
@@ -166,12 +318,12 @@ func (u *userUsecase) parseToken(tokenString string) (*shared.Claims, error) {
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, models.ErrInvalidToken.Wrap(err)
 	}
 
 	if claims, ok := token.Claims.(*shared.Claims); ok && token.Valid {
 		return claims, nil
 	}
 
-	return nil, errors.New("invalid token")
+	return nil, models.ErrInvalidToken
 }
@@ -0,0 +1,293 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTTL = 30 * time.Minute
+	verificationTTL  = 24 * time.Hour
+	otpTTL           = 10 * time.Minute
+	maxOTPAttempts   = 5
+	otpDigits        = 6
+)
+
+// ForgotPassword sends a reset token by email or an OTP code by SMS, depending
+// on whether an email or a phone number is given. Unknown identifiers are
+// silently ignored so the endpoint does not reveal which accounts exist.
+func (u *userUsecase) ForgotPassword(ctx context.Context, emailOrPhone string) error {
+	user, channel, err := u.findByEmailOrPhone(ctx, emailOrPhone)
+	if err != nil {
+		return nil
+	}
+
+	return u.issueToken(ctx, user, models.TokenPurposePasswordReset, channel)
+}
+
+// ResetPassword sets a new password. secret is either the emailed reset token,
+// or, when emailOrPhone is given, the OTP code sent to that address.
+func (u *userUsecase) ResetPassword(ctx context.Context, emailOrPhone, secret, newPassword, clientIP string) error {
+	token, err := u.consumeToken(ctx, models.TokenPurposePasswordReset, emailOrPhone, secret, clientIP)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.userRepo.FindByID(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.Password = string(hashedPassword)
+	user.UpdatedAt = time.Now()
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	// Any other outstanding reset token is now stale
+	return u.tokenRepo.InvalidateAll(ctx, user.ID, models.TokenPurposePasswordReset)
+}
+
+// SendVerification sends a verification token to the user's email or an OTP
+// code to the user's phone
+func (u *userUsecase) SendVerification(ctx context.Context, userID int, channel models.Channel) error {
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	switch channel {
+	case models.ChannelEmail:
+		if user.EmailVerified {
+			return models.ErrAlreadyVerified
+		}
+		return u.issueToken(ctx, user, models.TokenPurposeEmailVerification, channel)
+	case models.ChannelPhone:
+		if user.PhoneVerified {
+			return models.ErrAlreadyVerified
+		}
+		return u.issueToken(ctx, user, models.TokenPurposePhoneVerification, channel)
+	default:
+		return models.ErrInvalidChannel.With("channel", string(channel))
+	}
+}
+
+// Verify marks the email or phone of a user as verified. secret is either the
+// emailed verification token, or, when emailOrPhone is given, the OTP code
+// sent by SMS to that phone number.
+func (u *userUsecase) Verify(ctx context.Context, emailOrPhone, secret, clientIP string) (*models.User, error) {
+	purpose := models.TokenPurposeEmailVerification
+	if emailOrPhone != "" {
+		purpose = models.TokenPurposePhoneVerification
+	}
+
+	token, err := u.consumeToken(ctx, purpose, emailOrPhone, secret, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.userRepo.FindByID(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token only proves the address it was sent to, which is no longer
+	// the user's if it was changed since
+	if purpose == models.TokenPurposeEmailVerification {
+		if token.Address != user.Email {
+			return nil, models.ErrInvalidToken
+		}
+		user.EmailVerified = true
+	} else {
+		if token.Address != user.Phone {
+			return nil, models.ErrInvalidToken
+		}
+		user.PhoneVerified = true
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// issueToken creates a new token for the purpose, replacing older ones, and
+// delivers it over the channel
+func (u *userUsecase) issueToken(ctx context.Context, user *models.User, purpose models.TokenPurpose, channel models.Channel) error {
+	if err := u.tokenRepo.InvalidateAll(ctx, user.ID, purpose); err != nil {
+		return err
+	}
+
+	var secret string
+	var err error
+	ttl := otpTTL
+	if channel == models.ChannelEmail {
+		secret, err = randomToken()
+		ttl = passwordResetTTL
+		if purpose == models.TokenPurposeEmailVerification {
+			ttl = verificationTTL
+		}
+	} else {
+		secret, err = randomOTP()
+	}
+	if err != nil {
+		return err
+	}
+
+	message := models.Message{Channel: channel, Secret: secret}
+	if channel == models.ChannelEmail {
+		message.To = user.Email
+	} else {
+		message.To = user.Phone
+	}
+
+	token := &models.VerificationToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Channel:   channel,
+		Address:   message.To,
+		TokenHash: u.hashSecret(secret),
+		ExpiresAt: time.Now().Add(ttl),
+		CreatedAt: time.Now(),
+	}
+	if err := u.tokenRepo.Create(ctx, token); err != nil {
+		return err
+	}
+
+	switch purpose {
+	case models.TokenPurposePasswordReset:
+		message.Subject = "Reset your password"
+		message.Body = fmt.Sprintf("Use this code to reset your password, it expires in %s:", ttl)
+	default:
+		message.Subject = "Verify your account"
+		message.Body = fmt.Sprintf("Use this code to verify your account, it expires in %s:", ttl)
+	}
+
+	return u.sender.Send(ctx, message)
+}
+
+// consumeToken validates secret and marks the matching token as used. Without
+// emailOrPhone the secret is looked up directly by its hash, which only
+// matches the long tokens sent by email; OTP codes require emailOrPhone.
+// With emailOrPhone it is compared to the latest token of that user, which
+// is invalidated after too many wrong attempts. Wrong codes also count as
+// failed logins of the user and clientIP, so requesting new codes does not
+// allow more guesses. Attempts and use are recorded with conditional
+// updates, so concurrent requests can neither exceed the attempt limit nor
+// consume a token twice.
+func (u *userUsecase) consumeToken(ctx context.Context, purpose models.TokenPurpose, emailOrPhone, secret, clientIP string) (*models.VerificationToken, error) {
+	var token *models.VerificationToken
+	var err error
+
+	if emailOrPhone == "" {
+		if isOTP(secret) {
+			return nil, models.ErrIdentifierRequired
+		}
+		token, err = u.tokenRepo.FindActiveEmailToken(ctx, purpose, u.hashSecret(secret))
+		if err != nil {
+			return nil, models.ErrInvalidToken
+		}
+	} else {
+		user, _, err := u.findByEmailOrPhone(ctx, emailOrPhone)
+		if err != nil {
+			if err := u.loginGuard.Check(ctx, 0, emailOrPhone, clientIP); err != nil {
+				return nil, err
+			}
+			return nil, u.recordTokenFailure(ctx, 0, emailOrPhone, clientIP)
+		}
+		if err := u.loginGuard.Check(ctx, user.ID, emailOrPhone, clientIP); err != nil {
+			return nil, err
+		}
+
+		token, err = u.tokenRepo.FindLatestActive(ctx, user.ID, purpose)
+		if err != nil {
+			return nil, u.recordTokenFailure(ctx, user.ID, emailOrPhone, clientIP)
+		}
+
+		if !hmac.Equal([]byte(token.TokenHash), []byte(u.hashSecret(secret))) {
+			if err := u.tokenRepo.RecordAttempt(ctx, token.ID, maxOTPAttempts); err != nil {
+				return nil, err
+			}
+			return nil, u.recordTokenFailure(ctx, user.ID, emailOrPhone, clientIP)
+		}
+	}
+
+	if err := u.tokenRepo.Consume(ctx, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// recordTokenFailure counts a wrong code against the user and client IP
+// and returns ErrInvalidToken
+func (u *userUsecase) recordTokenFailure(ctx context.Context, userID int, emailOrPhone, clientIP string) error {
+	if err := u.loginGuard.RecordFailure(ctx, userID, emailOrPhone, clientIP); err != nil {
+		return err
+	}
+	return models.ErrInvalidToken
+}
+
+// findByEmailOrPhone looks the user up by email when the identifier contains
+// an @, and by phone otherwise, returning the matching channel
+func (u *userUsecase) findByEmailOrPhone(ctx context.Context, emailOrPhone string) (*models.User, models.Channel, error) {
+	if strings.Contains(emailOrPhone, "@") {
+		user, err := u.userRepo.FindByEmail(ctx, emailOrPhone)
+		return user, models.ChannelEmail, err
+	}
+	user, err := u.userRepo.FindByPhone(ctx, emailOrPhone)
+	return user, models.ChannelPhone, err
+}
+
+// hashSecret returns the keyed hash under which tokens and codes are stored
+func (u *userUsecase) hashSecret(secret string) string {
+	mac := hmac.New(sha256.New, []byte(u.jwtSecret))
+	mac.Write([]byte(secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func randomOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n.Int64()), nil
+}
+
+// isOTP reports whether secret has the form of an OTP code rather than of an
+// emailed token
+func isOTP(secret string) bool {
+	if len(secret) != otpDigits {
+		return false
+	}
+	for _, r := range secret {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
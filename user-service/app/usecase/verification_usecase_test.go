@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
+
+func TestResetPasswordCodeRequiresIdentifier(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	if err := f.usecase.ForgotPassword(ctx, customer.Phone); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	code := f.sender.lastSecret(t, customer.Phone)
+	if !isOTP(code) {
+		t.Fatalf("SMS secret %q is not an OTP code", code)
+	}
+
+	// A bare code could match the code of any user, so it is refused
+	if err := f.usecase.ResetPassword(ctx, "", code, "new-secret", testClientIP); !errors.Is(err, models.ErrIdentifierRequired) {
+		t.Fatalf("ResetPassword without identifier: err = %v, want %v", err, models.ErrIdentifierRequired)
+	}
+
+	if err := f.usecase.ResetPassword(ctx, customer.Phone, code, "new-secret", testClientIP); err != nil {
+		t.Fatalf("ResetPassword with identifier: %v", err)
+	}
+	if _, err := f.usecase.Login(ctx, customer.Email, "new-secret", testClientIP); err != nil {
+		t.Fatalf("Login with new password: %v", err)
+	}
+}
+
+func TestResetPasswordWithEmailedToken(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	if err := f.usecase.ForgotPassword(ctx, customer.Email); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	token := f.sender.lastSecret(t, customer.Email)
+
+	if err := f.usecase.ResetPassword(ctx, "", token, "new-secret", testClientIP); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if err := f.usecase.ResetPassword(ctx, "", token, "other-secret", testClientIP); !errors.Is(err, models.ErrInvalidToken) {
+		t.Fatalf("ResetPassword with used token: err = %v, want %v", err, models.ErrInvalidToken)
+	}
+}
+
+func TestWrongCodesCountAsLoginFailures(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	if err := f.usecase.ForgotPassword(ctx, customer.Phone); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	code := f.sender.lastSecret(t, customer.Phone)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < testPolicy.MaxAttempts; i++ {
+		if err := f.usecase.ResetPassword(ctx, customer.Phone, wrong, "new-secret", testClientIP); !errors.Is(err, models.ErrInvalidToken) {
+			t.Fatalf("ResetPassword with wrong code: err = %v, want %v", err, models.ErrInvalidToken)
+		}
+	}
+	if got := f.attempts.failures("user:1"); got != testPolicy.MaxAttempts {
+		t.Errorf("failures of user:1 = %d, want %d", got, testPolicy.MaxAttempts)
+	}
+
+	// Requesting a new code does not allow more guesses
+	if err := f.usecase.ForgotPassword(ctx, customer.Phone); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	code = f.sender.lastSecret(t, customer.Phone)
+	err := f.usecase.ResetPassword(ctx, customer.Phone, code, "new-secret", testClientIP)
+	assertThrottled(t, err, models.ErrAccountLocked)
+}
+
+func TestVerifyPhoneCodeRequiresIdentifier(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, customer)
+
+	if err := f.usecase.SendVerification(ctx, customer.ID, models.ChannelPhone); err != nil {
+		t.Fatalf("SendVerification: %v", err)
+	}
+	code := f.sender.lastSecret(t, customer.Phone)
+
+	if _, err := f.usecase.Verify(ctx, "", code, testClientIP); !errors.Is(err, models.ErrIdentifierRequired) {
+		t.Fatalf("Verify without identifier: err = %v, want %v", err, models.ErrIdentifierRequired)
+	}
+
+	user, err := f.usecase.Verify(ctx, customer.Phone, code, testClientIP)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !user.PhoneVerified {
+		t.Errorf("phone is not verified")
+	}
+}
+
+func TestChangedAddressIsNotVerifiedByOldToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		channel    models.Channel
+		oldAddress string
+		change     func(user *models.User)
+		verify     func(f *fixture, secret string) (*models.User, error)
+	}{
+		{
+			name:       "email",
+			channel:    models.ChannelEmail,
+			oldAddress: "unverified@example.com",
+			change:     func(user *models.User) { user.Email = "changed@example.com" },
+			verify: func(f *fixture, secret string) (*models.User, error) {
+				return f.usecase.Verify(context.Background(), "", secret, testClientIP)
+			},
+		},
+		{
+			name:       "phone",
+			channel:    models.ChannelPhone,
+			oldAddress: "0833",
+			change:     func(user *models.User) { user.Phone = "0899" },
+			verify: func(f *fixture, secret string) (*models.User, error) {
+				return f.usecase.Verify(context.Background(), "0899", secret, testClientIP)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFixture(t, models.User{ID: 3, Email: "unverified@example.com", Phone: "0833"})
+
+			if err := f.usecase.SendVerification(ctx, 3, tt.channel); err != nil {
+				t.Fatalf("SendVerification: %v", err)
+			}
+			secret := f.sender.lastSecret(t, tt.oldAddress)
+
+			user, err := f.usecase.GetUser(ctx, 3)
+			if err != nil {
+				t.Fatalf("GetUser: %v", err)
+			}
+			tt.change(user)
+			if err := f.usecase.UpdateUser(ctx, user); err != nil {
+				t.Fatalf("UpdateUser: %v", err)
+			}
+
+			if _, err := tt.verify(f, secret); !errors.Is(err, models.ErrInvalidToken) {
+				t.Fatalf("Verify with token sent to the old address: err = %v, want %v", err, models.ErrInvalidToken)
+			}
+			for _, token := range f.tokens.tokens {
+				if token.Address != tt.oldAddress {
+					t.Errorf("token sent to %q, want %q", token.Address, tt.oldAddress)
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,53 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/user-service/app/models"
-
+import (
+	"context"
+	"time"
 
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
 
 type UserRepository interface {
-	Create(user *models.User) error
-	FindByID(id int) (*models.User, error)
-	FindByEmail(email string) (*models.User, error)
-	FindByPhone(phone string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id int) error
+	Create(ctx context.Context, user *models.User) error
+	FindByID(ctx context.Context, id int) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByPhone(ctx context.Context, phone string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	AdvanceMFAStep(ctx context.Context, userID int, step int64) (bool, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type RoleRepository interface {
+	EnsureRole(ctx context.Context, name string, permissions []string) error
+	FindPermissions(ctx context.Context, role string) ([]string, error)
+	IsMFARequired(ctx context.Context, role string) (bool, error)
+	SetMFARequired(ctx context.Context, role string, required bool) error
+}
+
+type VerificationTokenRepository interface {
+	Create(ctx context.Context, token *models.VerificationToken) error
+	FindActiveByHash(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.VerificationToken, error)
+	FindActiveEmailToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.VerificationToken, error)
+	FindLatestActive(ctx context.Context, userID int, purpose models.TokenPurpose) (*models.VerificationToken, error)
+	RecordAttempt(ctx context.Context, id, maxAttempts int) error
+	Consume(ctx context.Context, token *models.VerificationToken) error
+	InvalidateAll(ctx context.Context, userID int, purpose models.TokenPurpose) error
+}
+
+type RecoveryCodeRepository interface {
+	ReplaceAll(ctx context.Context, userID int, codeHashes []string) error
+	FindUnused(ctx context.Context, userID int, codeHash string) (*models.RecoveryCode, error)
+	MarkUsed(ctx context.Context, code *models.RecoveryCode) error
+	DeleteAll(ctx context.Context, userID int) error
+}
+
+type LoginAttemptRepository interface {
+	Find(ctx context.Context, key string) (*models.LoginAttempt, error)
+	RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (*models.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *models.SecurityEvent) error
 }
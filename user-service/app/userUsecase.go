@@ -1,13 +1,28 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/user-service/app/models"
-
+import (
+	"context"
 
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
 
 type UserUsecase interface {
-	Register(email, phone, password, name string) (*models.User, string, error)
-	Login(emailOrPhone, password string) (*models.User, string, error)
-	ValidateToken(token string) (bool, *models.User, error)
-	GetUser(id int) (*models.User, error)
-	UpdateUser(user *models.User) error
+	Register(ctx context.Context, email, phone, password, name, role string) (*models.User, string, error)
+	Login(ctx context.Context, emailOrPhone, password, clientIP string) (*models.LoginResult, error)
+	CompleteMFALogin(ctx context.Context, challengeToken, code, clientIP string) (*models.LoginResult, error)
+	ValidateToken(ctx context.Context, token string) (bool, *models.User, error)
+	GetUser(ctx context.Context, id int) (*models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	AssignRole(ctx context.Context, userID int, role string) (*models.User, error)
+	UnlockUser(ctx context.Context, userID, actorID int) error
+	SeedRoles(ctx context.Context) error
+	ForgotPassword(ctx context.Context, emailOrPhone string) error
+	ResetPassword(ctx context.Context, emailOrPhone, secret, newPassword, clientIP string) error
+	SendVerification(ctx context.Context, userID int, channel models.Channel) error
+	Verify(ctx context.Context, emailOrPhone, secret, clientIP string) (*models.User, error)
+	BeginMFAEnrollment(ctx context.Context, userID int) (*models.MFAEnrollment, error)
+	BeginChallengeEnrollment(ctx context.Context, challengeToken string) (*models.MFAEnrollment, error)
+	ConfirmMFAEnrollment(ctx context.Context, userID int, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID int, code, clientIP string) error
+	SetRoleMFARequired(ctx context.Context, role string, required bool) error
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+)
+
+// Config is the configuration of user-service
+type Config struct {
+	config.Common `yaml:",inline"`
+
+	HTTPAddr config.ListenAddr `env:"USER_SERVICE_PORT" yaml:"http_addr" default:"8080"`
+	GRPCAddr config.ListenAddr `env:"USER_GRPC_PORT" yaml:"grpc_addr" default:"50058"`
+
+	// RequireVerifiedLogin rejects logins with an unverified email or phone
+	RequireVerifiedLogin bool                 `env:"REQUIRE_VERIFIED_LOGIN" yaml:"require_verified_login"`
+	Lockout              models.LockoutPolicy `yaml:"lockout"`
+
+	// TrustedProxies are the addresses or CIDRs whose X-Forwarded-For header
+	// or metadata is believed, such as the gateway. Loopback covers the REST
+	// transcoding, which calls the gRPC server of the service itself.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" yaml:"trusted_proxies" default:"127.0.0.1,::1"`
+}
+
+// Validate checks the login protection settings next to the common ones
+func (c *Config) Validate() error {
+	var errs []error
+	if err := c.Common.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Lockout.MaxAttempts <= 0 || c.Lockout.IPMaxAttempts <= 0 {
+		errs = append(errs, errors.New("LOGIN_MAX_ATTEMPTS and LOGIN_IP_MAX_ATTEMPTS must be positive"))
+	}
+	if c.Lockout.BaseDelay > c.Lockout.MaxDelay {
+		errs = append(errs, errors.New("LOGIN_BASE_DELAY must not exceed LOGIN_MAX_DELAY"))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR", proxy))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net"
 	"os"
 
 	"github.com/gin-gonic/gin"
@@ -10,81 +10,147 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/config"
+	"github.com/evrintobing17/ecommerce-system/shared/grpc_client"
 	userDelivery "github.com/evrintobing17/ecommerce-system/user-service/app/delivery"
-	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/notifier"
 	userRepo "github.com/evrintobing17/ecommerce-system/user-service/app/repository"
 	userUsecase "github.com/evrintobing17/ecommerce-system/user-service/app/usecase"
 
+	"github.com/evrintobing17/ecommerce-system/shared/health"
 	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/lifecycle"
 	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/migrate"
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/user"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+	"github.com/evrintobing17/ecommerce-system/shared/transcoding"
 	userGrpc "github.com/evrintobing17/ecommerce-system/user-service/app/delivery/grpc"
 )
 
 func main() {
-	shared.InitLogger()
-	var err error
-	db, err := shared.ConnectDB()
+	// Load configuration
+	var cfg Config
+	if err := config.Load("user-service", &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize logger
+	shared.InitLogger("user-service", cfg.LogLevel)
+	config.Log(&cfg)
+	grpc_client.DefaultTimeout = cfg.GRPCClientTimeout
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.RunCommand(context.Background(), "user-service", cfg.Database, migrationFiles(), "user-service/migrations", os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	app := lifecycle.New("user-service", cfg.ShutdownTimeout)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "user-service")
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		log.Fatal("Failed to initialize tracing:", err)
 	}
+	app.AddCloser("tracing", shutdownTracing)
 
-	// Auto migrate models
-	err = shared.MigrateDB(db, &models.User{})
+	db, err := shared.ConnectDB(cfg.Database)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		log.Fatal("Failed to connect to database:", err)
 	}
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(shared.GinMetricsMiddleware())
-	shared.RegisterMetricsHandler(r)
-
-	r.GET("/health", func(c *gin.Context) {
-		jsonhttpresponse.OK(c, gin.H{
-			"status": "OK",
-		})
+	app.AddCloser("database", func(context.Context) error {
+		return shared.CloseDB(db)
 	})
-	jwtSecret := os.Getenv("JWT_SECRET")
+
+	// Apply pending migrations
+	if cfg.MigrateOnStart {
+		if err := migrate.Up(context.Background(), db, "user-service", migrationFiles()); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+	jwtSecret := cfg.JWTSecret
 
 	userRepository := userRepo.NewUserRepository(db)
-	userUseCase := userUsecase.NewUserUsecase(userRepository, string(jwtSecret))
+	roleRepository := userRepo.NewRoleRepository(db)
+	tokenRepository := userRepo.NewVerificationTokenRepository(db)
+	recoveryCodeRepository := userRepo.NewRecoveryCodeRepository(db)
+	loginGuard := userUsecase.NewLoginGuard(
+		userRepo.NewLoginAttemptRepository(db),
+		userRepo.NewSecurityEventRepository(db),
+		cfg.Lockout,
+	)
+	userUseCase := userUsecase.NewUserUsecase(userRepository, roleRepository, tokenRepository, recoveryCodeRepository, notifier.NewLogSender(), loginGuard, jwtSecret, cfg.RequireVerifiedLogin)
+	if err := userUseCase.SeedRoles(context.Background()); err != nil {
+		log.Fatal("Failed to seed roles:", err)
+	}
 	// Initialize HTTP server
-	router := gin.Default()
+	router := gin.New()
+	// The login protection is keyed on the client IP, which clients must not
+	// be able to pick with X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Failed to set trusted proxies: %v", err)
+	}
+	router.Use(tracing.GinMiddleware("user-service")...)
+	router.Use(middleware.RequestID(), middleware.AccessLog())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.GatewayIdentity(cfg.ServiceAuth.TokenSecret))
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
+	router.Use(shared.GinMetricsMiddleware())
+	shared.RegisterMetricsHandler(router)
+	healthChecks := health.New("user-service", cfg.HealthCheckInterval)
+	healthChecks.AddReadinessCheck("database", health.DatabaseChecker(db))
+	healthChecks.RegisterHTTP(router)
 	userHandler := userDelivery.NewUserHandler(userUseCase)
 
-	api := router.Group("/api/v1")
-	{
-		api.POST("/register", userHandler.Register)
-		api.POST("/login", userHandler.Login)
-	}
-	private := api.Use(middleware.AuthMiddleware(jwtSecret))
-	{
-		private.GET("/profile", userHandler.GetProfile)
-		private.PUT("/profile", userHandler.UpdateProfile)
-	}
+	registerRoutes(router, userHandler, jwtSecret)
 
 	// Initialize gRPC server
-	userServer := userGrpc.NewUserServer(userUseCase)
+	userServer := userGrpc.NewUserServer(userUseCase, cfg.TrustedProxies)
+	serviceAuth := cfg.ServiceAuth
 
-	// Start gRPC server
-	go func() {
-		lis, err := net.Listen("tcp", ":50058")
-		if err != nil {
-			log.Fatal("Failed to listen:", err)
-		}
+	serverOptions, err := middleware.GRPCServerOptions(middleware.GRPCServerConfig{
+		ServiceAuth: serviceAuth,
+		Allowlist: serviceauth.Allowlist{
+			// Exposes email and phone, which shop-service matches invitations against
+			proto.UserService_GetUser_FullMethodName: {"shop-service"},
+		},
+		JWTSecret: jwtSecret,
+	})
+	if err != nil {
+		log.Fatal("Failed to configure gRPC server:", err)
+	}
+	grpcServer := grpc.NewServer(serverOptions...)
+	proto.RegisterUserServiceServer(grpcServer, userServer)
+	healthChecks.RegisterGRPC(grpcServer)
+	app.AddGRPCServer("User", string(cfg.GRPCAddr), grpcServer)
 
-		grpcServer := grpc.NewServer()
-		proto.RegisterUserServiceServer(grpcServer, userServer)
+	// REST/JSON transcoding of the gRPC API below /api/v2. It calls the
+	// service's own gRPC server so the same interceptors apply.
+	selfConn, err := grpc_client.NewConnection(cfg.GRPCAddr.DialAddr(), serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to own gRPC server:", err)
+	}
+	defer selfConn.Close()
+	restMux := transcoding.NewServeMux()
+	if err := proto.RegisterUserServiceHandler(context.Background(), restMux, selfConn); err != nil {
+		log.Fatal("Failed to register REST transcoding:", err)
+	}
+	transcoding.Mount(router, restMux)
+	transcoding.RegisterSwagger(router, transcoding.Specs{
+		Title:     "User Service API",
+		OpenAPIV2: proto.OpenAPIV2,
+		OpenAPIV3: proto.OpenAPIV3,
+	})
 
-		log.Println("User gRPC server started on port 50051")
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatal("Failed to serve gRPC:", err)
-		}
-	}()
+	app.AddHTTPServer("User", string(cfg.HTTPAddr), router)
+
+	app.AddWorker("health", healthChecks.Run)
+	app.OnShutdown(healthChecks.Shutdown)
 
-	// Start HTTP server
-	log.Println("User HTTP server started on port 8080")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Failed to start HTTP server:", err)
+	if err := app.Run(); err != nil {
+		log.Fatal("User service stopped with error:", err)
 	}
 }
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT,
+    phone TEXT,
+    name TEXT,
+    role TEXT DEFAULT 'customer',
+    email_verified BOOLEAN,
+    phone_verified BOOLEAN,
+    mfa_enabled BOOLEAN,
+    mfa_secret TEXT,
+    password TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone ON users (phone);
+
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    require_mfa BOOLEAN,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_name TEXT NOT NULL,
+    permission_name TEXT NOT NULL,
+    PRIMARY KEY (role_name, permission_name),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_name) REFERENCES roles (name),
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_name) REFERENCES permissions (name)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS verification_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    purpose TEXT,
+    channel TEXT,
+    token_hash TEXT,
+    attempts BIGINT,
+    expires_at TIMESTAMPTZ,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_verification_tokens_user_id ON verification_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_verification_tokens_purpose ON verification_tokens (purpose);
+CREATE INDEX IF NOT EXISTS idx_verification_tokens_token_hash ON verification_tokens (token_hash);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT PRIMARY KEY,
+    failures BIGINT,
+    last_failure_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS user_security_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    type TEXT,
+    identifier TEXT,
+    client_ip TEXT,
+    details TEXT,
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_user_security_events_user_id ON user_security_events (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_security_events_type ON user_security_events (type);
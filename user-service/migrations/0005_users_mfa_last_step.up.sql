@@ -0,0 +1,3 @@
+-- Time step of the last accepted TOTP code, so that a code cannot be used
+-- twice within its validity window
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_last_step BIGINT NOT NULL DEFAULT 0;
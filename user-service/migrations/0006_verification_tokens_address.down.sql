@@ -0,0 +1 @@
+ALTER TABLE verification_tokens DROP COLUMN IF EXISTS address;
@@ -0,0 +1,3 @@
+-- Email address or phone number a token was sent to, so that it only verifies
+-- that address
+ALTER TABLE verification_tokens ADD COLUMN IF NOT EXISTS address TEXT NOT NULL DEFAULT '';
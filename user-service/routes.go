@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	userDelivery "github.com/evrintobing17/ecommerce-system/user-service/app/delivery"
+	"github.com/gin-gonic/gin"
+)
+
+// registerRoutes adds the HTTP API below /api/v1 to router
+func registerRoutes(router gin.IRouter, userHandler *userDelivery.UserHandler, jwtSecret string) {
+	api := router.Group("/api/v1")
+	{
+		api.POST("/register", userHandler.Register)
+		api.POST("/login", userHandler.Login)
+		api.POST("/login/mfa", userHandler.VerifyMFA)
+		api.POST("/login/mfa/enroll", userHandler.EnrollMFAWithChallenge)
+		api.POST("/password/forgot", userHandler.ForgotPassword)
+		api.POST("/password/reset", userHandler.ResetPassword)
+		api.POST("/verification/verify", userHandler.Verify)
+	}
+	private := api.Use(middleware.AuthMiddleware(jwtSecret))
+	{
+		private.GET("/profile", userHandler.GetProfile)
+		private.PUT("/profile", userHandler.UpdateProfile)
+		private.POST("/verification/send", userHandler.SendVerification)
+		private.POST("/mfa/enroll", userHandler.EnrollMFA)
+		private.POST("/mfa/enroll/confirm", userHandler.ConfirmMFA)
+		private.POST("/mfa/disable", userHandler.DisableMFA)
+		private.PUT("/users/:id/role", middleware.RequirePermission(shared.PermUserManage), userHandler.AssignRole)
+		private.POST("/users/:id/unlock", middleware.RequirePermission(shared.PermUserManage), userHandler.UnlockUser)
+		private.PUT("/roles/:role/mfa", middleware.RequirePermission(shared.PermUserManage), userHandler.SetRoleMFARequired)
+	}
+}
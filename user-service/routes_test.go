@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse/responsetest"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/user-service/app"
+	userDelivery "github.com/evrintobing17/ecommerce-system/user-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/user-service/app/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testJWTSecret = "test-secret"
+	validCode     = "123456"
+)
+
+// userUsecaseStub serves user 1, a verified customer, and user 2, an admin
+// with MFA enabled. taken@example.com is registered, mfa@example.com needs
+// MFA, locked@example.com is locked out and the notifier is down for
+// down@example.com. Wrong passwords and codes are rejected.
+type userUsecaseStub struct {
+	app.UserUsecase
+}
+
+func (u *userUsecaseStub) Register(ctx context.Context, email, phone, password, name, role string) (*models.User, string, error) {
+	if email == "taken@example.com" {
+		return nil, "", models.ErrEmailTaken
+	}
+	return &models.User{ID: 3, Email: email, Phone: phone, Name: name, Role: shared.RoleCustomer}, "token", nil
+}
+
+func (u *userUsecaseStub) Login(ctx context.Context, emailOrPhone, password, clientIP string) (*models.LoginResult, error) {
+	switch {
+	case emailOrPhone == "locked@example.com":
+		return nil, &models.ThrottledError{Err: models.ErrAccountLocked, RetryAfter: 90 * time.Second}
+	case password != "secret":
+		return nil, models.ErrInvalidCredentials
+	case emailOrPhone == "mfa@example.com":
+		return &models.LoginResult{MFARequired: true, ChallengeToken: "challenge"}, nil
+	}
+	user, _ := u.GetUser(ctx, 1)
+	return &models.LoginResult{User: user, Token: "token"}, nil
+}
+
+func (u *userUsecaseStub) CompleteMFALogin(ctx context.Context, challengeToken, code, clientIP string) (*models.LoginResult, error) {
+	if challengeToken != "challenge" {
+		return nil, models.ErrInvalidToken
+	}
+	if code != validCode {
+		return nil, models.ErrInvalidMFACode
+	}
+	user, _ := u.GetUser(ctx, 2)
+	return &models.LoginResult{User: user, Token: "token"}, nil
+}
+
+func (u *userUsecaseStub) BeginChallengeEnrollment(ctx context.Context, challengeToken string) (*models.MFAEnrollment, error) {
+	if challengeToken != "challenge" {
+		return nil, models.ErrInvalidToken
+	}
+	return u.BeginMFAEnrollment(ctx, 1)
+}
+
+func (u *userUsecaseStub) GetUser(ctx context.Context, id int) (*models.User, error) {
+	switch id {
+	case 1:
+		return &models.User{ID: 1, Email: "customer@example.com", Role: shared.RoleCustomer, EmailVerified: true}, nil
+	case 2:
+		return &models.User{ID: 2, Email: "admin@example.com", Role: shared.RoleAdmin, MFAEnabled: true}, nil
+	}
+	return nil, models.ErrUserNotFound
+}
+
+func (u *userUsecaseStub) UpdateUser(ctx context.Context, user *models.User) error {
+	if user.Email == "taken@example.com" {
+		return models.ErrEmailTaken
+	}
+	return nil
+}
+
+func (u *userUsecaseStub) AssignRole(ctx context.Context, userID int, role string) (*models.User, error) {
+	user, err := u.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+func (u *userUsecaseStub) UnlockUser(ctx context.Context, userID, actorID int) error {
+	_, err := u.GetUser(ctx, userID)
+	return err
+}
+
+func (u *userUsecaseStub) ForgotPassword(ctx context.Context, emailOrPhone string) error {
+	if emailOrPhone == "down@example.com" {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+// consumeSecret mirrors the checks of the usecase on reset and verification
+// secrets: OTP codes need the identifier they were sent to
+func (u *userUsecaseStub) consumeSecret(emailOrPhone, secret string) error {
+	switch {
+	case emailOrPhone == "locked@example.com":
+		return &models.ThrottledError{Err: models.ErrTooManyAttempts, RetryAfter: time.Minute}
+	case emailOrPhone == "" && secret == validCode:
+		return models.ErrIdentifierRequired
+	case secret != validCode && secret != "emailed-token":
+		return models.ErrInvalidToken
+	}
+	return nil
+}
+
+func (u *userUsecaseStub) ResetPassword(ctx context.Context, emailOrPhone, secret, newPassword, clientIP string) error {
+	return u.consumeSecret(emailOrPhone, secret)
+}
+
+func (u *userUsecaseStub) SendVerification(ctx context.Context, userID int, channel models.Channel) error {
+	if channel == models.ChannelEmail {
+		return models.ErrAlreadyVerified
+	}
+	return nil
+}
+
+func (u *userUsecaseStub) Verify(ctx context.Context, emailOrPhone, secret, clientIP string) (*models.User, error) {
+	if err := u.consumeSecret(emailOrPhone, secret); err != nil {
+		return nil, err
+	}
+	return u.GetUser(ctx, 1)
+}
+
+func (u *userUsecaseStub) BeginMFAEnrollment(ctx context.Context, userID int) (*models.MFAEnrollment, error) {
+	if userID == 2 {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+	return &models.MFAEnrollment{Secret: "SECRET", ProvisioningURI: "otpauth://totp/shop:customer"}, nil
+}
+
+func (u *userUsecaseStub) ConfirmMFAEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	if code != validCode {
+		return nil, models.ErrInvalidMFACode
+	}
+	return []string{"recovery"}, nil
+}
+
+func (u *userUsecaseStub) DisableMFA(ctx context.Context, userID int, code, clientIP string) error {
+	if userID == 2 {
+		return models.ErrMFARequiredByRole
+	}
+	return models.ErrMFANotEnrolled
+}
+
+func (u *userUsecaseStub) SetRoleMFARequired(ctx context.Context, role string, required bool) error {
+	if !shared.IsValidRole(role) {
+		return models.ErrInvalidRole
+	}
+	return nil
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
+	registerRoutes(router, userDelivery.NewUserHandler(&userUsecaseStub{}), testJWTSecret)
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	customer := responsetest.Token(t, shared.TokenSubject{UserID: 1, Role: shared.RoleCustomer}, testJWTSecret)
+	admin := responsetest.Token(t, shared.TokenSubject{UserID: 2, Role: shared.RoleAdmin}, testJWTSecret)
+
+	responsetest.Run(t, newTestRouter(), []responsetest.Case{
+		{Name: "unknown route", Method: http.MethodGet, Path: "/api/v1/nothing", Status: http.StatusNotFound, Code: "route_not_found"},
+		{Name: "missing token", Method: http.MethodGet, Path: "/api/v1/profile", Status: http.StatusUnauthorized, Code: "authorization_required"},
+		{Name: "invalid token", Method: http.MethodGet, Path: "/api/v1/profile", Token: "invalid", Status: http.StatusUnauthorized, Code: "invalid_token"},
+
+		{Name: "register", Method: http.MethodPost, Path: "/api/v1/register", Body: `{"email":"new@example.com","phone":"0812","password":"secret","name":"New"}`, Status: http.StatusCreated},
+		{Name: "register with invalid fields", Method: http.MethodPost, Path: "/api/v1/register", Body: `{"email":"new","password":"short","role":"admin"}`, Status: http.StatusBadRequest, Fields: []string{"email", "phone", "password", "name", "role"}},
+		{Name: "register with malformed body", Method: http.MethodPost, Path: "/api/v1/register", Body: `{"email":`, Status: http.StatusBadRequest, Code: "malformed_body", Fields: []string{""}},
+		{Name: "register taken email", Method: http.MethodPost, Path: "/api/v1/register", Body: `{"email":"taken@example.com","phone":"0812","password":"secret","name":"New"}`, Status: http.StatusConflict, Code: "email_taken"},
+
+		{Name: "login", Method: http.MethodPost, Path: "/api/v1/login", Body: `{"email_or_phone":"customer@example.com","password":"secret"}`, Status: http.StatusOK},
+		{Name: "login without password", Method: http.MethodPost, Path: "/api/v1/login", Body: `{"email_or_phone":"customer@example.com"}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"password"}},
+		{Name: "login with wrong password", Method: http.MethodPost, Path: "/api/v1/login", Body: `{"email_or_phone":"customer@example.com","password":"wrong"}`, Status: http.StatusUnauthorized, Code: "invalid_credentials"},
+		{Name: "login locked account", Method: http.MethodPost, Path: "/api/v1/login", Body: `{"email_or_phone":"locked@example.com","password":"secret"}`, Status: http.StatusTooManyRequests, Code: "account_locked"},
+		{Name: "login with MFA", Method: http.MethodPost, Path: "/api/v1/login", Body: `{"email_or_phone":"mfa@example.com","password":"secret"}`, Status: http.StatusOK},
+		{Name: "complete MFA login", Method: http.MethodPost, Path: "/api/v1/login/mfa", Body: `{"challenge_token":"challenge","code":"123456"}`, Status: http.StatusOK},
+		{Name: "complete MFA login with wrong code", Method: http.MethodPost, Path: "/api/v1/login/mfa", Body: `{"challenge_token":"challenge","code":"000000"}`, Status: http.StatusUnauthorized, Code: "invalid_mfa_code"},
+		{Name: "complete MFA login without fields", Method: http.MethodPost, Path: "/api/v1/login/mfa", Body: `{}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"challenge_token", "code"}},
+		{Name: "enroll MFA during login", Method: http.MethodPost, Path: "/api/v1/login/mfa/enroll", Body: `{"challenge_token":"challenge"}`, Status: http.StatusOK},
+		{Name: "enroll MFA with invalid challenge", Method: http.MethodPost, Path: "/api/v1/login/mfa/enroll", Body: `{"challenge_token":"other"}`, Status: http.StatusUnauthorized, Code: "invalid_token"},
+
+		{Name: "forgot password", Method: http.MethodPost, Path: "/api/v1/password/forgot", Body: `{"email_or_phone":"customer@example.com"}`, Status: http.StatusOK},
+		{Name: "forgot password failing", Method: http.MethodPost, Path: "/api/v1/password/forgot", Body: `{"email_or_phone":"down@example.com"}`, Status: http.StatusInternalServerError, Code: "internal"},
+		{Name: "reset password", Method: http.MethodPost, Path: "/api/v1/password/reset", Body: `{"email_or_phone":"customer@example.com","token":"123456","new_password":"secret"}`, Status: http.StatusOK},
+		{Name: "reset password with emailed token", Method: http.MethodPost, Path: "/api/v1/password/reset", Body: `{"token":"emailed-token","new_password":"secret"}`, Status: http.StatusOK},
+		{Name: "reset password with code and no identifier", Method: http.MethodPost, Path: "/api/v1/password/reset", Body: `{"token":"123456","new_password":"secret"}`, Status: http.StatusBadRequest, Code: "identifier_required"},
+		{Name: "reset password with wrong code", Method: http.MethodPost, Path: "/api/v1/password/reset", Body: `{"email_or_phone":"customer@example.com","token":"000000","new_password":"secret"}`, Status: http.StatusUnauthorized, Code: "invalid_token"},
+		{Name: "reset password too often", Method: http.MethodPost, Path: "/api/v1/password/reset", Body: `{"email_or_phone":"locked@example.com","token":"123456","new_password":"secret"}`, Status: http.StatusTooManyRequests, Code: "too_many_attempts"},
+		{Name: "reset password with short password", Method: http.MethodPost, Path: "/api/v1/password/reset", Body: `{"token":"123456","new_password":"short"}`, Status: http.StatusBadRequest, Code: "min", Fields: []string{"new_password"}},
+
+		{Name: "verify", Method: http.MethodPost, Path: "/api/v1/verification/verify", Body: `{"phone":"0812","token":"123456"}`, Status: http.StatusOK},
+		{Name: "verify code without phone", Method: http.MethodPost, Path: "/api/v1/verification/verify", Body: `{"token":"123456"}`, Status: http.StatusBadRequest, Code: "identifier_required"},
+		{Name: "verify without token", Method: http.MethodPost, Path: "/api/v1/verification/verify", Body: `{"phone":"0812"}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"token"}},
+		{Name: "send verification", Method: http.MethodPost, Path: "/api/v1/verification/send", Token: customer, Body: `{"channel":"phone"}`, Status: http.StatusOK},
+		{Name: "send verification to verified email", Method: http.MethodPost, Path: "/api/v1/verification/send", Token: customer, Body: `{"channel":"email"}`, Status: http.StatusConflict, Code: "already_verified"},
+		{Name: "send verification to unknown channel", Method: http.MethodPost, Path: "/api/v1/verification/send", Token: customer, Body: `{"channel":"fax"}`, Status: http.StatusBadRequest, Code: "oneof", Fields: []string{"channel"}},
+
+		{Name: "get profile", Method: http.MethodGet, Path: "/api/v1/profile", Token: customer, Status: http.StatusOK},
+		{Name: "update profile", Method: http.MethodPut, Path: "/api/v1/profile", Token: customer, Body: `{"name":"Customer","email":"customer@example.com"}`, Status: http.StatusOK},
+		{Name: "update profile with invalid email", Method: http.MethodPut, Path: "/api/v1/profile", Token: customer, Body: `{"email":"customer"}`, Status: http.StatusBadRequest, Code: "email", Fields: []string{"email"}},
+		{Name: "update profile with taken email", Method: http.MethodPut, Path: "/api/v1/profile", Token: customer, Body: `{"email":"taken@example.com"}`, Status: http.StatusConflict, Code: "email_taken"},
+
+		{Name: "enroll MFA", Method: http.MethodPost, Path: "/api/v1/mfa/enroll", Token: customer, Status: http.StatusOK},
+		{Name: "enroll MFA twice", Method: http.MethodPost, Path: "/api/v1/mfa/enroll", Token: admin, Status: http.StatusConflict, Code: "mfa_already_enabled"},
+		{Name: "confirm MFA", Method: http.MethodPost, Path: "/api/v1/mfa/enroll/confirm", Token: customer, Body: `{"code":"123456"}`, Status: http.StatusOK},
+		{Name: "confirm MFA with wrong code", Method: http.MethodPost, Path: "/api/v1/mfa/enroll/confirm", Token: customer, Body: `{"code":"000000"}`, Status: http.StatusUnauthorized, Code: "invalid_mfa_code"},
+		{Name: "disable MFA without enrolment", Method: http.MethodPost, Path: "/api/v1/mfa/disable", Token: customer, Body: `{"code":"123456"}`, Status: http.StatusUnprocessableEntity, Code: "mfa_not_enrolled"},
+		{Name: "disable MFA required by role", Method: http.MethodPost, Path: "/api/v1/mfa/disable", Token: admin, Body: `{"code":"123456"}`, Status: http.StatusForbidden, Code: "mfa_required_by_role"},
+
+		{Name: "assign role", Method: http.MethodPut, Path: "/api/v1/users/1/role", Token: admin, Body: `{"role":"shop_owner"}`, Status: http.StatusOK},
+		{Name: "assign unknown role", Method: http.MethodPut, Path: "/api/v1/users/1/role", Token: admin, Body: `{"role":"root"}`, Status: http.StatusBadRequest, Code: "oneof", Fields: []string{"role"}},
+		{Name: "assign role to missing user", Method: http.MethodPut, Path: "/api/v1/users/9/role", Token: admin, Body: `{"role":"shop_owner"}`, Status: http.StatusNotFound, Code: "user_not_found"},
+		{Name: "assign role with invalid id", Method: http.MethodPut, Path: "/api/v1/users/a/role", Token: admin, Body: `{"role":"shop_owner"}`, Status: http.StatusBadRequest, Code: "invalid_parameter"},
+		{Name: "assign role without permission", Method: http.MethodPut, Path: "/api/v1/users/1/role", Token: customer, Body: `{"role":"admin"}`, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "unlock user", Method: http.MethodPost, Path: "/api/v1/users/1/unlock", Token: admin, Status: http.StatusOK},
+		{Name: "unlock missing user", Method: http.MethodPost, Path: "/api/v1/users/9/unlock", Token: admin, Status: http.StatusNotFound, Code: "user_not_found"},
+		{Name: "unlock user without permission", Method: http.MethodPost, Path: "/api/v1/users/1/unlock", Token: customer, Status: http.StatusForbidden, Code: "permission_denied"},
+		{Name: "require MFA for role", Method: http.MethodPut, Path: "/api/v1/roles/admin/mfa", Token: admin, Body: `{"required":true}`, Status: http.StatusOK},
+		{Name: "require MFA without flag", Method: http.MethodPut, Path: "/api/v1/roles/admin/mfa", Token: admin, Body: `{}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"required"}},
+		{Name: "require MFA for unknown role", Method: http.MethodPut, Path: "/api/v1/roles/root/mfa", Token: admin, Body: `{"required":true}`, Status: http.StatusBadRequest, Code: "invalid_role"},
+	})
+}
+
+func TestThrottledRequestsSetRetryAfter(t *testing.T) {
+	router := newTestRouter()
+	for _, tc := range []struct {
+		responsetest.Case
+		retryAfter string
+	}{
+		{responsetest.Case{Method: http.MethodPost, Path: "/api/v1/login", Body: `{"email_or_phone":"locked@example.com","password":"secret"}`, Status: http.StatusTooManyRequests, Code: "account_locked"}, "90"},
+		{responsetest.Case{Method: http.MethodPost, Path: "/api/v1/verification/verify", Body: `{"phone":"locked@example.com","token":"123456"}`, Status: http.StatusTooManyRequests, Code: "too_many_attempts"}, "60"},
+	} {
+		rec := responsetest.Serve(router, tc.Case)
+		responsetest.Check(t, rec, tc.Case)
+		if got := rec.Header().Get("Retry-After"); got != tc.retryAfter {
+			t.Errorf("%s: Retry-After = %q, want %q", tc.Path, got, tc.retryAfter)
+		}
+	}
+}
@@ -2,14 +2,12 @@ package grpc
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/warehouse"
 	usecase "github.com/evrintobing17/ecommerce-system/warehouse-service/app"
 	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type warehouseServer struct {
@@ -18,14 +16,44 @@ type warehouseServer struct {
 }
 
 func NewWarehouseServer(warehouseUsecase usecase.WarehouseUsecase) *warehouseServer {
-	return &warehouseServer{warehouseUsecase: warehouseUsecase}
+	return &warehouseServer{
+		warehouseUsecase: warehouseUsecase,
+	}
+}
+
+// ShopIDFromWarehouse resolves the shop that owns the warehouse in the
+// warehouse_id field of the request, for use in middleware.ShopPermission
+func (s *warehouseServer) ShopIDFromWarehouse(ctx context.Context, req interface{}) (int, error) {
+	r, ok := req.(interface{ GetWarehouseId() int32 })
+	if !ok {
+		return 0, fmt.Errorf("%T has no warehouse_id", req)
+	}
+	return s.shopIDOf(ctx, int(r.GetWarehouseId()))
+}
+
+// ShopIDFromSourceWarehouse resolves the shop that owns the warehouse stock
+// is transferred from
+func (s *warehouseServer) ShopIDFromSourceWarehouse(ctx context.Context, req interface{}) (int, error) {
+	r, ok := req.(interface{ GetFromWarehouseId() int32 })
+	if !ok {
+		return 0, fmt.Errorf("%T has no from_warehouse_id", req)
+	}
+	return s.shopIDOf(ctx, int(r.GetFromWarehouseId()))
+}
+
+func (s *warehouseServer) shopIDOf(ctx context.Context, warehouseID int) (int, error) {
+	warehouse, err := s.warehouseUsecase.GetWarehouse(ctx, warehouseID)
+	if err != nil {
+		return 0, err
+	}
+	return warehouse.ShopID, nil
 }
 
 func (s *warehouseServer) GetWarehouse(ctx context.Context, req *proto.GetWarehouseRequest) (*proto.GetWarehouseResponse, error) {
-	warehouse, err := s.warehouseUsecase.GetWarehouse(int(req.WarehouseId))
+	warehouse, err := s.warehouseUsecase.GetWarehouse(ctx, int(req.WarehouseId))
 	if err != nil {
-		log.Printf("GetWarehouse error: %v", err)
-		return nil, status.Errorf(codes.NotFound, "warehouse not found: %v", err)
+		slog.WarnContext(ctx, "get warehouse failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.GetWarehouseResponse{
@@ -42,10 +70,10 @@ func (s *warehouseServer) GetWarehouse(ctx context.Context, req *proto.GetWareho
 }
 
 func (s *warehouseServer) GetWarehouses(ctx context.Context, req *proto.GetWarehousesRequest) (*proto.GetWarehousesResponse, error) {
-	warehouses, err := s.warehouseUsecase.GetWarehouses(int(req.ShopId), req.ActiveOnly)
+	warehouses, err := s.warehouseUsecase.GetWarehouses(ctx, int(req.ShopId), req.ActiveOnly)
 	if err != nil {
-		log.Printf("GetWarehouses error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to get warehouses: %v", err)
+		slog.WarnContext(ctx, "get warehouses failed", "error", err)
+		return nil, err
 	}
 
 	var protoWarehouses []*proto.Warehouse
@@ -67,10 +95,10 @@ func (s *warehouseServer) GetWarehouses(ctx context.Context, req *proto.GetWareh
 }
 
 func (s *warehouseServer) CreateWarehouse(ctx context.Context, req *proto.CreateWarehouseRequest) (*proto.CreateWarehouseResponse, error) {
-	warehouse, err := s.warehouseUsecase.CreateWarehouse(req.Name, req.Location, int(req.ShopId))
+	warehouse, err := s.warehouseUsecase.CreateWarehouse(ctx, req.Name, req.Location, int(req.ShopId))
 	if err != nil {
-		log.Printf("CreateWarehouse error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to create warehouse: %v", err)
+		slog.WarnContext(ctx, "create warehouse failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.CreateWarehouseResponse{
@@ -87,10 +115,10 @@ func (s *warehouseServer) CreateWarehouse(ctx context.Context, req *proto.Create
 }
 
 func (s *warehouseServer) UpdateWarehouse(ctx context.Context, req *proto.UpdateWarehouseRequest) (*proto.UpdateWarehouseResponse, error) {
-	warehouse, err := s.warehouseUsecase.UpdateWarehouse(int(req.WarehouseId), req.Name, req.Location, &req.Active)
+	warehouse, err := s.warehouseUsecase.UpdateWarehouse(ctx, int(req.WarehouseId), req.Name, req.Location, &req.Active)
 	if err != nil {
-		log.Printf("UpdateWarehouse error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to update warehouse: %v", err)
+		slog.WarnContext(ctx, "update warehouse failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.UpdateWarehouseResponse{
@@ -107,10 +135,10 @@ func (s *warehouseServer) UpdateWarehouse(ctx context.Context, req *proto.Update
 }
 
 func (s *warehouseServer) TransferStock(ctx context.Context, req *proto.TransferStockRequest) (*proto.TransferStockResponse, error) {
-	err := s.warehouseUsecase.TransferStock(int(req.ProductId), int(req.FromWarehouseId), int(req.ToWarehouseId), req.Quantity)
+	err := s.warehouseUsecase.TransferStock(ctx, int(req.VariantId), int(req.FromWarehouseId), int(req.ToWarehouseId), req.Quantity)
 	if err != nil {
-		log.Printf("TransferStock error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to transfer stock: %v", err)
+		slog.WarnContext(ctx, "transfer stock failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.TransferStockResponse{
@@ -120,15 +148,15 @@ func (s *warehouseServer) TransferStock(ctx context.Context, req *proto.Transfer
 }
 
 func (s *warehouseServer) GetStock(ctx context.Context, req *proto.GetStockRequest) (*proto.GetStockResponse, error) {
-	stock, err := s.warehouseUsecase.GetStock(int(req.ProductId), int(req.WarehouseId))
+	stock, err := s.warehouseUsecase.GetStock(ctx, int(req.VariantId), int(req.WarehouseId))
 	if err != nil {
-		log.Printf("GetStock error: %v", err)
-		return nil, status.Errorf(codes.NotFound, "stock not found: %v", err)
+		slog.WarnContext(ctx, "get stock failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.GetStockResponse{
 		Stock: &proto.Stock{
-			ProductId:   int32(stock.ProductID),
+			VariantId:   int32(stock.VariantID),
 			WarehouseId: int32(stock.WarehouseID),
 			Quantity:    stock.Quantity,
 			Reserved:    stock.Reserved,
@@ -138,30 +166,63 @@ func (s *warehouseServer) GetStock(ctx context.Context, req *proto.GetStockReque
 	}, nil
 }
 
+func (s *warehouseServer) GetAvailability(ctx context.Context, req *proto.GetAvailabilityRequest) (*proto.GetAvailabilityResponse, error) {
+	variantIDs := make([]int, 0, len(req.VariantIds))
+	for _, variantID := range req.VariantIds {
+		variantIDs = append(variantIDs, int(variantID))
+	}
+
+	availability, err := s.warehouseUsecase.GetAvailability(ctx, variantIDs)
+	if err != nil {
+		slog.WarnContext(ctx, "get availability failed", "error", err)
+		return nil, err
+	}
+
+	variants := make([]*proto.VariantAvailability, 0, len(availability))
+	for _, variant := range availability {
+		warehouses := make([]*proto.WarehouseAvailability, 0, len(variant.Warehouses))
+		for _, warehouse := range variant.Warehouses {
+			warehouses = append(warehouses, &proto.WarehouseAvailability{
+				WarehouseId: int32(warehouse.WarehouseID),
+				Available:   warehouse.Available,
+			})
+		}
+		variants = append(variants, &proto.VariantAvailability{
+			VariantId:  int32(variant.VariantID),
+			Available:  variant.Available,
+			Warehouses: warehouses,
+		})
+	}
+
+	return &proto.GetAvailabilityResponse{
+		Variants: variants,
+	}, nil
+}
+
 func (s *warehouseServer) UpdateStock(ctx context.Context, req *proto.UpdateStockRequest) (*proto.UpdateStockResponse, error) {
 	var err error
 	var stock *models.Stock
 
 	switch req.Operation {
 	case "add":
-		stock, err = s.warehouseUsecase.AddStock(int(req.ProductId), int(req.WarehouseId), req.Quantity, req.Reserved)
+		stock, err = s.warehouseUsecase.AddStock(ctx, int(req.VariantId), int(req.WarehouseId), req.Quantity, req.Reserved)
 	case "subtract":
-		stock, err = s.warehouseUsecase.SubtractStock(int(req.ProductId), int(req.WarehouseId), req.Quantity, req.Reserved)
+		stock, err = s.warehouseUsecase.SubtractStock(ctx, int(req.VariantId), int(req.WarehouseId), req.Quantity, req.Reserved)
 	case "set":
-		stock, err = s.warehouseUsecase.SetStock(int(req.ProductId), int(req.WarehouseId), req.Quantity, req.Reserved)
+		stock, err = s.warehouseUsecase.SetStock(ctx, int(req.VariantId), int(req.WarehouseId), req.Quantity, req.Reserved)
 	default:
-		return nil, status.Errorf(codes.InvalidArgument, "invalid operation: %s", req.Operation)
+		return nil, models.ErrInvalidStockOperation.With("operation", req.Operation)
 	}
 
 	if err != nil {
-		log.Printf("UpdateStock error: %v", err)
-		return nil, status.Errorf(codes.Internal, "failed to update stock: %v", err)
+		slog.WarnContext(ctx, "update stock failed", "error", err)
+		return nil, err
 	}
 
 	return &proto.UpdateStockResponse{
 		Success: true,
 		Stock: &proto.Stock{
-			ProductId:   int32(stock.ProductID),
+			VariantId:   int32(stock.VariantID),
 			WarehouseId: int32(stock.WarehouseID),
 			Quantity:    stock.Quantity,
 			Reserved:    stock.Reserved,
@@ -1,9 +1,14 @@
 package http
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
 	usecase "github.com/evrintobing17/ecommerce-system/warehouse-service/app"
 	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
 	"github.com/gin-gonic/gin"
@@ -20,13 +25,13 @@ func NewWarehouseHandler(warehouseUsecase usecase.WarehouseUsecase) *WarehouseHa
 func (h *WarehouseHandler) GetWarehouse(c *gin.Context) {
 	warehouseID, _ := strconv.Atoi(c.Param("id"))
 
-	warehouse, err := h.warehouseUsecase.GetWarehouse(warehouseID)
+	warehouse, err := h.warehouseUsecase.GetWarehouse(c.Request.Context(), warehouseID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "warehouse not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"warehouse": warehouse,
 	})
 }
@@ -35,13 +40,13 @@ func (h *WarehouseHandler) GetWarehouses(c *gin.Context) {
 	shopID, _ := strconv.Atoi(c.Query("shop_id"))
 	activeOnly := c.Query("active_only") == "true"
 
-	warehouses, err := h.warehouseUsecase.GetWarehouses(shopID, activeOnly)
+	warehouses, err := h.warehouseUsecase.GetWarehouses(c.Request.Context(), shopID, activeOnly)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"warehouses": warehouses,
 	})
 }
@@ -54,17 +59,17 @@ func (h *WarehouseHandler) CreateWarehouse(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	warehouse, err := h.warehouseUsecase.CreateWarehouse(request.Name, request.Location, request.ShopID)
+	warehouse, err := h.warehouseUsecase.CreateWarehouse(c.Request.Context(), request.Name, request.Location, request.ShopID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	jsonhttpresponse.StatusCreated(c, gin.H{
 		"warehouse": warehouse,
 	})
 }
@@ -79,68 +84,98 @@ func (h *WarehouseHandler) UpdateWarehouse(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	warehouse, err := h.warehouseUsecase.UpdateWarehouse(warehouseID, request.Name, request.Location, request.Active)
+	warehouse, err := h.warehouseUsecase.UpdateWarehouse(c.Request.Context(), warehouseID, request.Name, request.Location, request.Active)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"warehouse": warehouse,
 	})
 }
 
 func (h *WarehouseHandler) TransferStock(c *gin.Context) {
 	var request struct {
-		ProductID       int   `json:"product_id" binding:"required"`
+		VariantID       int   `json:"variant_id" binding:"required"`
 		FromWarehouseID int   `json:"from_warehouse_id" binding:"required"`
 		ToWarehouseID   int   `json:"to_warehouse_id" binding:"required"`
 		Quantity        int32 `json:"quantity" binding:"required,min=1"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
-	err := h.warehouseUsecase.TransferStock(request.ProductID, request.FromWarehouseID, request.ToWarehouseID, request.Quantity)
+	err := h.warehouseUsecase.TransferStock(c.Request.Context(), request.VariantID, request.FromWarehouseID, request.ToWarehouseID, request.Quantity)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"message": "Stock transferred successfully",
 	})
 }
 
 func (h *WarehouseHandler) GetStock(c *gin.Context) {
-	productID, _ := strconv.Atoi(c.Query("product_id"))
+	variantID, _ := strconv.Atoi(c.Query("variant_id"))
 	warehouseID, _ := strconv.Atoi(c.Query("warehouse_id"))
 
-	if productID == 0 || warehouseID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "product_id and warehouse_id are required"})
+	if variantID == 0 || warehouseID == 0 {
+		jsonhttpresponse.BadRequest(c, "invalid_parameter", "variant_id and warehouse_id are required")
 		return
 	}
 
-	stock, err := h.warehouseUsecase.GetStock(productID, warehouseID)
+	stock, err := h.warehouseUsecase.GetStock(c.Request.Context(), variantID, warehouseID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "stock not found"})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"stock": stock,
 	})
 }
 
+// GetAvailability answers GET /warehouses/availability. Variant IDs are
+// given as repeated or comma separated variant_ids parameters.
+func (h *WarehouseHandler) GetAvailability(c *gin.Context) {
+	var variantIDs []int
+	for _, value := range c.QueryArray("variant_ids") {
+		for _, field := range strings.Split(value, ",") {
+			variantID, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || variantID <= 0 {
+				jsonhttpresponse.BadRequest(c, "invalid_parameter", "variant_ids must be positive integers")
+				return
+			}
+			variantIDs = append(variantIDs, variantID)
+		}
+	}
+	if len(variantIDs) == 0 {
+		jsonhttpresponse.BadRequest(c, "invalid_parameter", "variant_ids is required")
+		return
+	}
+
+	availability, err := h.warehouseUsecase.GetAvailability(c.Request.Context(), variantIDs)
+	if err != nil {
+		jsonhttpresponse.FromError(c, err)
+		return
+	}
+
+	jsonhttpresponse.OK(c, gin.H{
+		"variants": availability,
+	})
+}
+
 func (h *WarehouseHandler) UpdateStock(c *gin.Context) {
 	var request struct {
-		ProductID   int    `json:"product_id" binding:"required"`
+		VariantID   int    `json:"variant_id" binding:"required"`
 		WarehouseID int    `json:"warehouse_id" binding:"required"`
 		Operation   string `json:"operation" binding:"required,oneof=add subtract set"`
 		Quantity    int32  `json:"quantity" binding:"required,min=1"`
@@ -148,7 +183,7 @@ func (h *WarehouseHandler) UpdateStock(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		jsonhttpresponse.ErrBind(c, err)
 		return
 	}
 
@@ -157,19 +192,52 @@ func (h *WarehouseHandler) UpdateStock(c *gin.Context) {
 
 	switch request.Operation {
 	case "add":
-		stock, err = h.warehouseUsecase.AddStock(request.ProductID, request.WarehouseID, request.Quantity, request.Reserved)
+		stock, err = h.warehouseUsecase.AddStock(c.Request.Context(), request.VariantID, request.WarehouseID, request.Quantity, request.Reserved)
 	case "subtract":
-		stock, err = h.warehouseUsecase.SubtractStock(request.ProductID, request.WarehouseID, request.Quantity, request.Reserved)
+		stock, err = h.warehouseUsecase.SubtractStock(c.Request.Context(), request.VariantID, request.WarehouseID, request.Quantity, request.Reserved)
 	case "set":
-		stock, err = h.warehouseUsecase.SetStock(request.ProductID, request.WarehouseID, request.Quantity, request.Reserved)
+		stock, err = h.warehouseUsecase.SetStock(c.Request.Context(), request.VariantID, request.WarehouseID, request.Quantity, request.Reserved)
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		jsonhttpresponse.FromError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonhttpresponse.OK(c, gin.H{
 		"stock": stock,
 	})
 }
+
+// ShopIDFromWarehouseParam resolves the shop that owns the warehouse in the :id
+// path parameter, for use with middleware.RequireShopPermission
+func (h *WarehouseHandler) ShopIDFromWarehouseParam(c *gin.Context) (int, error) {
+	warehouseID, err := middleware.IntFromParam(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	return h.shopIDFromWarehouse(c.Request.Context(), warehouseID)
+}
+
+// ShopIDFromWarehouseBody returns a resolver for the shop that owns the
+// warehouse referenced by the given JSON body field
+func (h *WarehouseHandler) ShopIDFromWarehouseBody(field string) middleware.ShopIDResolver {
+	return func(c *gin.Context) (int, error) {
+		warehouseID, err := middleware.IntFromJSON(c, field)
+		if err != nil {
+			return 0, err
+		}
+		return h.shopIDFromWarehouse(c.Request.Context(), warehouseID)
+	}
+}
+
+func (h *WarehouseHandler) shopIDFromWarehouse(ctx context.Context, warehouseID int) (int, error) {
+	warehouse, err := h.warehouseUsecase.GetWarehouse(ctx, warehouseID)
+	if err != nil {
+		if errors.Is(err, models.ErrWarehouseNotFound) {
+			return 0, fmt.Errorf("%w: warehouse %d", middleware.ErrResourceNotFound, warehouseID)
+		}
+		return 0, err
+	}
+	return warehouse.ShopID, nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "github.com/evrintobing17/ecommerce-system/shared/apperror"
+
+var (
+	ErrWarehouseNotFound          = apperror.NewNotFound("warehouse_not_found", "warehouse not found")
+	ErrStockNotFound              = apperror.NewNotFound("stock_not_found", "stock not found")
+	ErrSourceWarehouseInactive    = apperror.NewFailedPrecondition("source_warehouse_inactive", "source warehouse is not active")
+	ErrTargetWarehouseInactive    = apperror.NewFailedPrecondition("destination_warehouse_inactive", "destination warehouse is not active")
+	ErrWarehousesInDifferentShops = apperror.NewInvalidArgument("warehouses_in_different_shops", "source and destination warehouses belong to different shops")
+	ErrInsufficientStock          = apperror.NewFailedPrecondition("insufficient_stock", "insufficient stock in source warehouse")
+	ErrInsufficientQuantity       = apperror.NewFailedPrecondition("insufficient_quantity", "insufficient quantity")
+	ErrInsufficientReserved       = apperror.NewFailedPrecondition("insufficient_reserved_stock", "insufficient reserved stock")
+	ErrInvalidStockOperation      = apperror.NewInvalidArgument("invalid_stock_operation", "invalid stock operation")
+	ErrTooManyVariants            = apperror.NewInvalidArgument("too_many_variants", "too many variant IDs")
+)
@@ -13,10 +13,26 @@ type Warehouse struct {
 }
 
 type Stock struct {
-	ProductID   int       `gorm:"primaryKey" json:"product_id"`
+	VariantID   int       `gorm:"primaryKey" json:"variant_id"`
 	WarehouseID int       `gorm:"primaryKey" json:"warehouse_id"`
 	Quantity    int32     `json:"quantity"`
 	Reserved    int32     `json:"reserved"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// WarehouseAvailability is the available quantity, that is quantity minus
+// reserved, of a variant in one warehouse
+type WarehouseAvailability struct {
+	VariantID   int   `json:"-"`
+	WarehouseID int   `json:"warehouse_id"`
+	Available   int32 `json:"available"`
+}
+
+// VariantAvailability is the available quantity of a variant across the
+// active warehouses, with the per-warehouse breakdown
+type VariantAvailability struct {
+	VariantID  int                     `json:"variant_id"`
+	Available  int32                   `json:"available"`
+	Warehouses []WarehouseAvailability `json:"warehouses"`
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"strconv"
 
 	"github.com/evrintobing17/ecommerce-system/warehouse-service/app"
 	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
@@ -16,26 +18,26 @@ func NewWarehouseRepository(db *gorm.DB) app.WarehouseRepository {
 	return &warehouseRepository{db: db}
 }
 
-func (r *warehouseRepository) Create(warehouse *models.Warehouse) error {
-	return r.db.Create(warehouse).Error
+func (r *warehouseRepository) Create(ctx context.Context, warehouse *models.Warehouse) error {
+	return r.db.WithContext(ctx).Create(warehouse).Error
 }
 
-func (r *warehouseRepository) FindByID(id int) (*models.Warehouse, error) {
+func (r *warehouseRepository) FindByID(ctx context.Context, id int) (*models.Warehouse, error) {
 	var warehouse models.Warehouse
-	err := r.db.First(&warehouse, "id = ?", id).Error
+	err := r.db.WithContext(ctx).First(&warehouse, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("warehouse not found")
+			return nil, models.ErrWarehouseNotFound.With("warehouse_id", strconv.Itoa(id))
 		}
 		return nil, err
 	}
 	return &warehouse, nil
 }
 
-func (r *warehouseRepository) FindByShopID(shopID int, activeOnly bool) ([]*models.Warehouse, error) {
+func (r *warehouseRepository) FindByShopID(ctx context.Context, shopID int, activeOnly bool) ([]*models.Warehouse, error) {
 	var warehouses []*models.Warehouse
 
-	query := r.db.Where("shop_id = ?", shopID)
+	query := r.db.WithContext(ctx).Where("shop_id = ?", shopID)
 	if activeOnly {
 		query = query.Where("active = ?", true)
 	}
@@ -48,12 +50,12 @@ func (r *warehouseRepository) FindByShopID(shopID int, activeOnly bool) ([]*mode
 	return warehouses, nil
 }
 
-func (r *warehouseRepository) Update(warehouse *models.Warehouse) error {
-	return r.db.Save(warehouse).Error
+func (r *warehouseRepository) Update(ctx context.Context, warehouse *models.Warehouse) error {
+	return r.db.WithContext(ctx).Save(warehouse).Error
 }
 
-func (r *warehouseRepository) Delete(id int) error {
-	return r.db.Delete(&models.Warehouse{}, "id = ?", id).Error
+func (r *warehouseRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.Warehouse{}, "id = ?", id).Error
 }
 
 type stockRepository struct {
@@ -64,46 +66,65 @@ func NewStockRepository(db *gorm.DB) app.StockRepository {
 	return &stockRepository{db: db}
 }
 
-func (r *stockRepository) Create(stock *models.Stock) error {
-	return r.db.Create(stock).Error
+func (r *stockRepository) Create(ctx context.Context, stock *models.Stock) error {
+	return r.db.WithContext(ctx).Create(stock).Error
 }
 
-func (r *stockRepository) Find(productID, warehouseID int) (*models.Stock, error) {
+func (r *stockRepository) Find(ctx context.Context, variantID, warehouseID int) (*models.Stock, error) {
 	var stock models.Stock
-	err := r.db.First(&stock, "product_id = ? AND warehouse_id = ?", productID, warehouseID).Error
+	err := r.db.WithContext(ctx).First(&stock, "variant_id = ? AND warehouse_id = ?", variantID, warehouseID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("stock not found")
+			return nil, models.ErrStockNotFound
 		}
 		return nil, err
 	}
 	return &stock, nil
 }
 
-func (r *stockRepository) FindByProduct(productID int) ([]*models.Stock, error) {
+func (r *stockRepository) FindByVariant(ctx context.Context, variantID int) ([]*models.Stock, error) {
 	var stocks []*models.Stock
-	err := r.db.Find(&stocks, "product_id = ?", productID).Error
+	err := r.db.WithContext(ctx).Find(&stocks, "variant_id = ?", variantID).Error
 	if err != nil {
 		return nil, err
 	}
 	return stocks, nil
 }
 
-func (r *stockRepository) Update(stock *models.Stock) error {
-	return r.db.Save(stock).Error
+// FindAvailability returns the available quantity of the variants in every
+// active warehouse holding them, ordered by variant and warehouse
+func (r *stockRepository) FindAvailability(ctx context.Context, variantIDs []int) ([]*models.WarehouseAvailability, error) {
+	var availability []*models.WarehouseAvailability
+	err := r.db.WithContext(ctx).Table("stocks").
+		Select("stocks.variant_id, stocks.warehouse_id, stocks.quantity - stocks.reserved AS available").
+		Joins("JOIN warehouses ON warehouses.id = stocks.warehouse_id").
+		Where("stocks.variant_id IN ? AND warehouses.active = ?", variantIDs, true).
+		Order("stocks.variant_id, stocks.warehouse_id").
+		Scan(&availability).Error
+	if err != nil {
+		return nil, err
+	}
+	return availability, nil
 }
 
-func (r *stockRepository) Transfer(productID, fromWarehouseID, toWarehouseID int, quantity int32) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *stockRepository) Update(ctx context.Context, stock *models.Stock) error {
+	return r.db.WithContext(ctx).Save(stock).Error
+}
+
+func (r *stockRepository) Transfer(ctx context.Context, variantID, fromWarehouseID, toWarehouseID int, quantity int32) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Subtract from source warehouse
 		var fromStock models.Stock
-		err := tx.First(&fromStock, "product_id = ? AND warehouse_id = ?", productID, fromWarehouseID).Error
+		err := tx.First(&fromStock, "variant_id = ? AND warehouse_id = ?", variantID, fromWarehouseID).Error
 		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrStockNotFound
+			}
 			return err
 		}
 
 		if fromStock.Quantity-fromStock.Reserved < quantity {
-			return errors.New("insufficient stock in source warehouse")
+			return models.ErrInsufficientStock
 		}
 
 		fromStock.Quantity -= quantity
@@ -114,12 +135,12 @@ func (r *stockRepository) Transfer(productID, fromWarehouseID, toWarehouseID int
 
 		// Add to destination warehouse
 		var toStock models.Stock
-		err = tx.First(&toStock, "product_id = ? AND warehouse_id = ?", productID, toWarehouseID).Error
+		err = tx.First(&toStock, "variant_id = ? AND warehouse_id = ?", variantID, toWarehouseID).Error
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				// Create new stock record
 				toStock = models.Stock{
-					ProductID:   productID,
+					VariantID:   variantID,
 					WarehouseID: toWarehouseID,
 					Quantity:    quantity,
 					Reserved:    0,
@@ -136,6 +157,6 @@ func (r *stockRepository) Transfer(productID, fromWarehouseID, toWarehouseID int
 	})
 }
 
-func (r *stockRepository) Delete(productID, warehouseID int) error {
-	return r.db.Delete(&models.Stock{}, "product_id = ? AND warehouse_id = ?", productID, warehouseID).Error
+func (r *stockRepository) Delete(ctx context.Context, variantID, warehouseID int) error {
+	return r.db.WithContext(ctx).Delete(&models.Stock{}, "variant_id = ? AND warehouse_id = ?", variantID, warehouseID).Error
 }
@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 
 	protoShop "github.com/evrintobing17/ecommerce-system/shared/proto/shop"
@@ -10,6 +11,9 @@ import (
 	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
 )
 
+// MaxAvailabilityVariants bounds the variants of one GetAvailability call
+const MaxAvailabilityVariants = 100
+
 type warehouseUsecase struct {
 	warehouseRepo app.WarehouseRepository
 	stockRepo     app.StockRepository
@@ -24,8 +28,8 @@ func NewWarehouseUsecase(warehouseRepo app.WarehouseRepository, stockRepo app.St
 	}
 }
 
-func (u *warehouseUsecase) GetWarehouse(id int) (*models.Warehouse, error) {
-	warehouse, err := u.warehouseRepo.FindByID(id)
+func (u *warehouseUsecase) GetWarehouse(ctx context.Context, id int) (*models.Warehouse, error) {
+	warehouse, err := u.warehouseRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +45,8 @@ func (u *warehouseUsecase) GetWarehouse(id int) (*models.Warehouse, error) {
 	}, nil
 }
 
-func (u *warehouseUsecase) GetWarehouses(shopID int, activeOnly bool) ([]*models.Warehouse, error) {
-	warehouses, err := u.warehouseRepo.FindByShopID(shopID, activeOnly)
+func (u *warehouseUsecase) GetWarehouses(ctx context.Context, shopID int, activeOnly bool) ([]*models.Warehouse, error) {
+	warehouses, err := u.warehouseRepo.FindByShopID(ctx, shopID, activeOnly)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +67,7 @@ func (u *warehouseUsecase) GetWarehouses(shopID int, activeOnly bool) ([]*models
 	return result, nil
 }
 
-func (u *warehouseUsecase) CreateWarehouse(name, location string, shopID int) (*models.Warehouse, error) {
+func (u *warehouseUsecase) CreateWarehouse(ctx context.Context, name, location string, shopID int) (*models.Warehouse, error) {
 	warehouse := &models.Warehouse{
 		Name:      name,
 		Location:  location,
@@ -73,12 +77,12 @@ func (u *warehouseUsecase) CreateWarehouse(name, location string, shopID int) (*
 		UpdatedAt: time.Now(),
 	}
 
-	err := u.warehouseRepo.Create(warehouse)
+	err := u.warehouseRepo.Create(ctx, warehouse)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = u.shopProto.GetShop(context.Background(), &protoShop.GetShopRequest{ShopId: int32(shopID)})
+	_, err = u.shopProto.GetShop(ctx, &protoShop.GetShopRequest{ShopId: int32(shopID)})
 	if err != nil {
 		return nil, err
 	}
@@ -94,8 +98,8 @@ func (u *warehouseUsecase) CreateWarehouse(name, location string, shopID int) (*
 	}, nil
 }
 
-func (u *warehouseUsecase) UpdateWarehouse(id int, name, location string, active *bool) (*models.Warehouse, error) {
-	warehouse, err := u.warehouseRepo.FindByID(id)
+func (u *warehouseUsecase) UpdateWarehouse(ctx context.Context, id int, name, location string, active *bool) (*models.Warehouse, error) {
+	warehouse, err := u.warehouseRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -111,7 +115,7 @@ func (u *warehouseUsecase) UpdateWarehouse(id int, name, location string, active
 	}
 	warehouse.UpdatedAt = time.Now()
 
-	err = u.warehouseRepo.Update(warehouse)
+	err = u.warehouseRepo.Update(ctx, warehouse)
 	if err != nil {
 		return nil, err
 	}
@@ -127,47 +131,52 @@ func (u *warehouseUsecase) UpdateWarehouse(id int, name, location string, active
 	}, nil
 }
 
-func (u *warehouseUsecase) TransferStock(productID, fromWarehouseID, toWarehouseID int, quantity int32) error {
+func (u *warehouseUsecase) TransferStock(ctx context.Context, variantID, fromWarehouseID, toWarehouseID int, quantity int32) error {
 	// Check if from warehouse exists and is active
-	fromWarehouse, err := u.warehouseRepo.FindByID(fromWarehouseID)
+	fromWarehouse, err := u.warehouseRepo.FindByID(ctx, fromWarehouseID)
 	if err != nil {
 		return err
 	}
 	if !fromWarehouse.Active {
-		return errors.New("source warehouse is not active")
+		return models.ErrSourceWarehouseInactive
 	}
 
 	// Check if to warehouse exists and is active
-	toWarehouse, err := u.warehouseRepo.FindByID(toWarehouseID)
+	toWarehouse, err := u.warehouseRepo.FindByID(ctx, toWarehouseID)
 	if err != nil {
 		return err
 	}
 	if !toWarehouse.Active {
-		return errors.New("destination warehouse is not active")
+		return models.ErrTargetWarehouseInactive
+	}
+
+	// Stock may only move between warehouses of the same shop
+	if fromWarehouse.ShopID != toWarehouse.ShopID {
+		return models.ErrWarehousesInDifferentShops
 	}
 
 	// Check if there's enough stock in the source warehouse
-	stock, err := u.stockRepo.Find(productID, fromWarehouseID)
+	stock, err := u.stockRepo.Find(ctx, variantID, fromWarehouseID)
 	if err != nil {
 		return err
 	}
 
 	if stock.Quantity-stock.Reserved < quantity {
-		return errors.New("insufficient stock in source warehouse")
+		return models.ErrInsufficientStock
 	}
 
 	// Perform the transfer
-	return u.stockRepo.Transfer(productID, fromWarehouseID, toWarehouseID, quantity)
+	return u.stockRepo.Transfer(ctx, variantID, fromWarehouseID, toWarehouseID, quantity)
 }
 
-func (u *warehouseUsecase) GetStock(productID, warehouseID int) (*models.Stock, error) {
-	stock, err := u.stockRepo.Find(productID, warehouseID)
+func (u *warehouseUsecase) GetStock(ctx context.Context, variantID, warehouseID int) (*models.Stock, error) {
+	stock, err := u.stockRepo.Find(ctx, variantID, warehouseID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.Stock{
-		ProductID:   stock.ProductID,
+		VariantID:   stock.VariantID,
 		WarehouseID: stock.WarehouseID,
 		Quantity:    stock.Quantity,
 		Reserved:    stock.Reserved,
@@ -176,19 +185,62 @@ func (u *warehouseUsecase) GetStock(productID, warehouseID int) (*models.Stock,
 	}, nil
 }
 
-func (u *warehouseUsecase) AddStock(productID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
-	stock, err := u.stockRepo.Find(productID, warehouseID)
+// GetAvailability returns one entry per distinct variant in variantIDs, in
+// order. Only active warehouses with a positive available quantity are
+// listed, so variants without any have none.
+func (u *warehouseUsecase) GetAvailability(ctx context.Context, variantIDs []int) ([]*models.VariantAvailability, error) {
+	byVariant := make(map[int]*models.VariantAvailability, len(variantIDs))
+	var result []*models.VariantAvailability
+	for _, variantID := range variantIDs {
+		if _, ok := byVariant[variantID]; ok {
+			continue
+		}
+		availability := &models.VariantAvailability{
+			VariantID:  variantID,
+			Warehouses: []models.WarehouseAvailability{},
+		}
+		byVariant[variantID] = availability
+		result = append(result, availability)
+	}
+	if len(result) > MaxAvailabilityVariants {
+		return nil, models.ErrTooManyVariants.With("max", strconv.Itoa(MaxAvailabilityVariants))
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	rows, err := u.stockRepo.FindAvailability(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		availability, ok := byVariant[row.VariantID]
+		if !ok || row.Available <= 0 {
+			continue
+		}
+		availability.Available += row.Available
+		availability.Warehouses = append(availability.Warehouses, *row)
+	}
+
+	return result, nil
+}
+
+func (u *warehouseUsecase) AddStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
+	stock, err := u.stockRepo.Find(ctx, variantID, warehouseID)
+	if err != nil && !errors.Is(err, models.ErrStockNotFound) {
+		return nil, err
+	}
 	if err != nil {
 		// Stock doesn't exist, create it
 		stock = &models.Stock{
-			ProductID:   productID,
+			VariantID:   variantID,
 			WarehouseID: warehouseID,
 			Quantity:    quantity,
 			Reserved:    reserved,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		}
-		err = u.stockRepo.Create(stock)
+		err = u.stockRepo.Create(ctx, stock)
 		if err != nil {
 			return nil, err
 		}
@@ -197,14 +249,14 @@ func (u *warehouseUsecase) AddStock(productID, warehouseID int, quantity, reserv
 		stock.Quantity += quantity
 		stock.Reserved += reserved
 		stock.UpdatedAt = time.Now()
-		err = u.stockRepo.Update(stock)
+		err = u.stockRepo.Update(ctx, stock)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	return &models.Stock{
-		ProductID:   stock.ProductID,
+		VariantID:   stock.VariantID,
 		WarehouseID: stock.WarehouseID,
 		Quantity:    stock.Quantity,
 		Reserved:    stock.Reserved,
@@ -213,31 +265,31 @@ func (u *warehouseUsecase) AddStock(productID, warehouseID int, quantity, reserv
 	}, nil
 }
 
-func (u *warehouseUsecase) SubtractStock(productID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
-	stock, err := u.stockRepo.Find(productID, warehouseID)
+func (u *warehouseUsecase) SubtractStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
+	stock, err := u.stockRepo.Find(ctx, variantID, warehouseID)
 	if err != nil {
-		return nil, errors.New("stock not found")
+		return nil, err
 	}
 
 	if stock.Quantity < quantity {
-		return nil, errors.New("insufficient quantity")
+		return nil, models.ErrInsufficientQuantity
 	}
 
 	if stock.Reserved < reserved {
-		return nil, errors.New("insufficient reserved stock")
+		return nil, models.ErrInsufficientReserved
 	}
 
 	stock.Quantity -= quantity
 	stock.Reserved -= reserved
 	stock.UpdatedAt = time.Now()
 
-	err = u.stockRepo.Update(stock)
+	err = u.stockRepo.Update(ctx, stock)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.Stock{
-		ProductID:   stock.ProductID,
+		VariantID:   stock.VariantID,
 		WarehouseID: stock.WarehouseID,
 		Quantity:    stock.Quantity,
 		Reserved:    stock.Reserved,
@@ -246,19 +298,22 @@ func (u *warehouseUsecase) SubtractStock(productID, warehouseID int, quantity, r
 	}, nil
 }
 
-func (u *warehouseUsecase) SetStock(productID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
-	stock, err := u.stockRepo.Find(productID, warehouseID)
+func (u *warehouseUsecase) SetStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
+	stock, err := u.stockRepo.Find(ctx, variantID, warehouseID)
+	if err != nil && !errors.Is(err, models.ErrStockNotFound) {
+		return nil, err
+	}
 	if err != nil {
 		// Stock doesn't exist, create it
 		stock = &models.Stock{
-			ProductID:   productID,
+			VariantID:   variantID,
 			WarehouseID: warehouseID,
 			Quantity:    quantity,
 			Reserved:    reserved,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		}
-		err = u.stockRepo.Create(stock)
+		err = u.stockRepo.Create(ctx, stock)
 		if err != nil {
 			return nil, err
 		}
@@ -267,14 +322,14 @@ func (u *warehouseUsecase) SetStock(productID, warehouseID int, quantity, reserv
 		stock.Quantity = quantity
 		stock.Reserved = reserved
 		stock.UpdatedAt = time.Now()
-		err = u.stockRepo.Update(stock)
+		err = u.stockRepo.Update(ctx, stock)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	return &models.Stock{
-		ProductID:   stock.ProductID,
+		VariantID:   stock.VariantID,
 		WarehouseID: stock.WarehouseID,
 		Quantity:    stock.Quantity,
 		Reserved:    stock.Reserved,
@@ -1,20 +1,25 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
+)
 
 type WarehouseRepository interface {
-	Create(warehouse *models.Warehouse) error
-	FindByID(id int) (*models.Warehouse, error)
-	FindByShopID(shopID int, activeOnly bool) ([]*models.Warehouse, error)
-	Update(warehouse *models.Warehouse) error
-	Delete(id int) error
+	Create(ctx context.Context, warehouse *models.Warehouse) error
+	FindByID(ctx context.Context, id int) (*models.Warehouse, error)
+	FindByShopID(ctx context.Context, shopID int, activeOnly bool) ([]*models.Warehouse, error)
+	Update(ctx context.Context, warehouse *models.Warehouse) error
+	Delete(ctx context.Context, id int) error
 }
 
 type StockRepository interface {
-	Create(stock *models.Stock) error
-	Find(productID, warehouseID int) (*models.Stock, error)
-	FindByProduct(productID int) ([]*models.Stock, error)
-	Update(stock *models.Stock) error
-	Transfer(productID, fromWarehouseID, toWarehouseID int, quantity int32) error
-	Delete(productID, warehouseID int) error
+	Create(ctx context.Context, stock *models.Stock) error
+	Find(ctx context.Context, variantID, warehouseID int) (*models.Stock, error)
+	FindByVariant(ctx context.Context, variantID int) ([]*models.Stock, error)
+	FindAvailability(ctx context.Context, variantIDs []int) ([]*models.WarehouseAvailability, error)
+	Update(ctx context.Context, stock *models.Stock) error
+	Transfer(ctx context.Context, variantID, fromWarehouseID, toWarehouseID int, quantity int32) error
+	Delete(ctx context.Context, variantID, warehouseID int) error
 }
@@ -1,15 +1,20 @@
 package app
 
-import "github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
+import (
+	"context"
+
+	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
+)
 
 type WarehouseUsecase interface {
-	GetWarehouse(id int) (*models.Warehouse, error)
-	GetWarehouses(shopID int, activeOnly bool) ([]*models.Warehouse, error)
-	CreateWarehouse(name, location string, shopID int) (*models.Warehouse, error)
-	UpdateWarehouse(id int, name, location string, active *bool) (*models.Warehouse, error)
-	TransferStock(productID, fromWarehouseID, toWarehouseID int, quantity int32) error
-	GetStock(productID, warehouseID int) (*models.Stock, error)
-	AddStock(productID, warehouseID int, quantity, reserved int32) (*models.Stock, error)
-	SubtractStock(productID, warehouseID int, quantity, reserved int32) (*models.Stock, error)
-	SetStock(productID, warehouseID int, quantity, reserved int32) (*models.Stock, error)
+	GetWarehouse(ctx context.Context, id int) (*models.Warehouse, error)
+	GetWarehouses(ctx context.Context, shopID int, activeOnly bool) ([]*models.Warehouse, error)
+	CreateWarehouse(ctx context.Context, name, location string, shopID int) (*models.Warehouse, error)
+	UpdateWarehouse(ctx context.Context, id int, name, location string, active *bool) (*models.Warehouse, error)
+	TransferStock(ctx context.Context, variantID, fromWarehouseID, toWarehouseID int, quantity int32) error
+	GetStock(ctx context.Context, variantID, warehouseID int) (*models.Stock, error)
+	GetAvailability(ctx context.Context, variantIDs []int) ([]*models.VariantAvailability, error)
+	AddStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error)
+	SubtractStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error)
+	SetStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error)
 }
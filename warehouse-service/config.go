@@ -0,0 +1,13 @@
+package main
+
+import "github.com/evrintobing17/ecommerce-system/shared/config"
+
+// Config is the configuration of warehouse-service
+type Config struct {
+	config.Common `yaml:",inline"`
+
+	HTTPAddr config.ListenAddr `env:"WAREHOUSE_SERVICE_PORT" yaml:"http_addr" default:"8084"`
+	GRPCAddr config.ListenAddr `env:"WAREHOUSE_GRPC_PORT" yaml:"grpc_addr" default:"50055"`
+
+	ShopServiceAddr string `env:"SHOP_SERVICE_GRPC_ADDR" yaml:"shop_service_addr" default:"shop-service:50054"`
+}
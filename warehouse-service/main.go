@@ -1,128 +1,165 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net"
 	"os"
 
 	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/config"
 	"github.com/evrintobing17/ecommerce-system/shared/grpc_client"
+	"github.com/evrintobing17/ecommerce-system/shared/health"
 	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/lifecycle"
 	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/shared/migrate"
 	grpcShop "github.com/evrintobing17/ecommerce-system/shared/proto/shop"
 	proto "github.com/evrintobing17/ecommerce-system/shared/proto/warehouse"
+	"github.com/evrintobing17/ecommerce-system/shared/serviceauth"
+	"github.com/evrintobing17/ecommerce-system/shared/tracing"
+	"github.com/evrintobing17/ecommerce-system/shared/transcoding"
 	http "github.com/evrintobing17/ecommerce-system/warehouse-service/app/delivery"
 	grpcServer "github.com/evrintobing17/ecommerce-system/warehouse-service/app/delivery/grpc"
-	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
 	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/repository"
 	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/usecase"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	// Load configuration
+	var cfg Config
+	if err := config.Load("warehouse-service", &cfg); err != nil {
+		log.Fatal(err)
 	}
 
 	// Initialize logger
-	shared.InitLogger()
+	shared.InitLogger("warehouse-service", cfg.LogLevel)
+	config.Log(&cfg)
+	grpc_client.DefaultTimeout = cfg.GRPCClientTimeout
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.RunCommand(context.Background(), "warehouse-service", cfg.Database, migrationFiles(), "warehouse-service/migrations", os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	app := lifecycle.New("warehouse-service", cfg.ShutdownTimeout)
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "warehouse-service")
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	app.AddCloser("tracing", shutdownTracing)
 
 	// Initialize database
-	db, err := shared.ConnectDB()
+	db, err := shared.ConnectDB(cfg.Database)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
-	defer func() {
-		if err := shared.CloseDB(db); err != nil {
-			log.Println("Error closing database:", err)
-		}
-	}()
+	app.AddCloser("database", func(context.Context) error {
+		return shared.CloseDB(db)
+	})
 
-	// Auto migrate models
-	err = shared.MigrateDB(db, &models.Warehouse{}, &models.Stock{})
-	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	// Apply pending migrations
+	if cfg.MigrateOnStart {
+		if err := migrate.Up(context.Background(), db, "warehouse-service", migrationFiles()); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
 	}
 
 	// Initialize repositories
 	warehouseRepo := repository.NewWarehouseRepository(db)
 	stockRepo := repository.NewStockRepository(db)
 
-	shopServiceAddr := os.Getenv("SHOP_SERVICE_GRPC_ADDR")
-	if shopServiceAddr == "" {
-		shopServiceAddr = "shop-service:50054"
-	}
+	serviceAuth := cfg.ServiceAuth
 
-	shopConn, _ := grpc_client.NewConnection(shopServiceAddr)
+	shopConn, err := grpc_client.NewConnection(cfg.ShopServiceAddr, serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to shop-service:", err)
+	}
 	defer shopConn.Close()
 
 	shopClient := grpcShop.NewShopServiceClient(shopConn)
+	shopAccessChecker := middleware.NewShopAccessChecker(shopClient)
 
 	// Initialize use cases
 	warehouseUsecase := usecase.NewWarehouseUsecase(warehouseRepo, stockRepo, shopClient)
 
 	// Initialize HTTP server
-	router := gin.Default()
+	router := gin.New()
+	router.Use(tracing.GinMiddleware("warehouse-service")...)
+	router.Use(middleware.RequestID(), middleware.AccessLog())
 	warehouseHandler := http.NewWarehouseHandler(warehouseUsecase)
 
-	router.Use(gin.Recovery())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.GatewayIdentity(cfg.ServiceAuth.TokenSecret))
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
 	router.Use(shared.GinMetricsMiddleware())
 	shared.RegisterMetricsHandler(router)
 
-	router.GET("/health", func(c *gin.Context) {
-		jsonhttpresponse.OK(c, gin.H{
-			"status": "OK",
-		})
-	})
+	healthChecks := health.New("warehouse-service", cfg.HealthCheckInterval)
+	healthChecks.AddReadinessCheck("database", health.DatabaseChecker(db))
+	healthChecks.AddReadinessCheck("shop-service", health.GRPCChecker(shopConn, ""))
+	healthChecks.RegisterHTTP(router)
 	// HTTP routes
-	api := router.Group("/api/v1")
-	jwtSecret := os.Getenv("JWT_SECRET")
-	api.Use(middleware.AuthMiddleware(jwtSecret))
-	{
-		api.GET("/warehouses/:id", warehouseHandler.GetWarehouse)
-		api.GET("/warehouses", warehouseHandler.GetWarehouses)
-		api.POST("/warehouses", warehouseHandler.CreateWarehouse)
-		api.PUT("/warehouses/:id", warehouseHandler.UpdateWarehouse)
-		api.POST("/warehouses/transfer", warehouseHandler.TransferStock)
-		api.GET("/warehouses/stock", warehouseHandler.GetStock)
-		api.PATCH("/warehouses/stock", warehouseHandler.UpdateStock)
-	}
+	jwtSecret := cfg.JWTSecret
+	registerRoutes(router, warehouseHandler, shopAccessChecker, jwtSecret)
 
 	// Initialize gRPC server
 	warehouseServer := grpcServer.NewWarehouseServer(warehouseUsecase)
 
-	// Start gRPC server
-	go func() {
-		grpcPort := os.Getenv("WAREHOUSE_GRPC_PORT")
-		if grpcPort == "" {
-			grpcPort = ":50055"
-		}
-
-		lis, err := net.Listen("tcp", grpcPort)
-		if err != nil {
-			log.Fatal("Failed to listen:", err)
-		}
-
-		grpcServer := grpc.NewServer()
-		proto.RegisterWarehouseServiceServer(grpcServer, warehouseServer)
+	// UpdateStock is internal: order-service reserves and releases stock
+	// through it on behalf of customers, and product-service forwards its
+	// deprecated UpdateStock. Warehouse and transfer writes require the
+	// permission in the shop that owns the warehouse.
+	serverOptions, err := middleware.GRPCServerOptions(middleware.GRPCServerConfig{
+		ServiceAuth: serviceAuth,
+		Allowlist: serviceauth.Allowlist{
+			proto.WarehouseService_UpdateStock_FullMethodName: {"order-service", "product-service"},
+		},
+		JWTSecret: jwtSecret,
+		ShopPermissions: map[string]middleware.ShopPermission{
+			proto.WarehouseService_CreateWarehouse_FullMethodName: {Action: shared.PermWarehouseWrite, Resolve: middleware.ShopIDFromRequest},
+			proto.WarehouseService_UpdateWarehouse_FullMethodName: {Action: shared.PermWarehouseWrite, Resolve: warehouseServer.ShopIDFromWarehouse},
+			proto.WarehouseService_TransferStock_FullMethodName:   {Action: shared.PermStockWrite, Resolve: warehouseServer.ShopIDFromSourceWarehouse},
+		},
+		ShopAccessChecker: shopAccessChecker,
+	})
+	if err != nil {
+		log.Fatal("Failed to configure gRPC server:", err)
+	}
+	grpcServer := grpc.NewServer(serverOptions...)
+	proto.RegisterWarehouseServiceServer(grpcServer, warehouseServer)
+	healthChecks.RegisterGRPC(grpcServer)
+	app.AddGRPCServer("Warehouse", string(cfg.GRPCAddr), grpcServer)
+
+	// REST/JSON transcoding of the gRPC API below /api/v2. It calls the
+	// service's own gRPC server so the same interceptors apply.
+	selfConn, err := grpc_client.NewConnection(cfg.GRPCAddr.DialAddr(), serviceAuth)
+	if err != nil {
+		log.Fatal("Failed to connect to own gRPC server:", err)
+	}
+	defer selfConn.Close()
+	restMux := transcoding.NewServeMux()
+	if err := proto.RegisterWarehouseServiceHandler(context.Background(), restMux, selfConn); err != nil {
+		log.Fatal("Failed to register REST transcoding:", err)
+	}
+	transcoding.Mount(router, restMux)
+	transcoding.RegisterSwagger(router, transcoding.Specs{
+		Title:     "Warehouse Service API",
+		OpenAPIV2: proto.OpenAPIV2,
+		OpenAPIV3: proto.OpenAPIV3,
+	})
 
-		log.Printf("Warehouse gRPC server started on port %s", grpcPort)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatal("Failed to serve gRPC:", err)
-		}
-	}()
+	app.AddHTTPServer("Warehouse", string(cfg.HTTPAddr), router)
 
-	// Start HTTP server
-	httpPort := os.Getenv("WAREHOUSE_SERVICE_PORT")
-	if httpPort == "" {
-		httpPort = "8084"
-	}
+	app.AddWorker("health", healthChecks.Run)
+	app.OnShutdown(healthChecks.Shutdown)
 
-	log.Printf("Warehouse HTTP server started on port %s", httpPort)
-	if err := router.Run(":" + httpPort); err != nil {
-		log.Fatal("Failed to start HTTP server:", err)
+	if err := app.Run(); err != nil {
+		log.Fatal("Warehouse service stopped with error:", err)
 	}
 }
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    location TEXT,
+    shop_id BIGINT,
+    active BOOLEAN,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_warehouses_shop_id ON warehouses (shop_id);
+
+CREATE TABLE IF NOT EXISTS stocks (
+    product_id BIGINT NOT NULL,
+    warehouse_id BIGINT NOT NULL,
+    quantity INTEGER,
+    reserved INTEGER,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    PRIMARY KEY (product_id, warehouse_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE stocks RENAME COLUMN variant_id TO product_id;
@@ -0,0 +1,3 @@
+-- Stock is kept per product variant. Every existing product got a default
+-- variant with its own ID, so the existing rows keep their meaning.
+ALTER TABLE stocks RENAME COLUMN product_id TO variant_id;
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	http "github.com/evrintobing17/ecommerce-system/warehouse-service/app/delivery"
+	"github.com/gin-gonic/gin"
+)
+
+// registerRoutes adds the HTTP API below /api/v1 to router
+func registerRoutes(router gin.IRouter, warehouseHandler *http.WarehouseHandler, shopAccessChecker middleware.ShopAccessChecker, jwtSecret string) {
+	api := router.Group("/api/v1")
+	api.Use(middleware.AuthMiddleware(jwtSecret))
+	{
+		api.GET("/warehouses/:id", warehouseHandler.GetWarehouse)
+		api.GET("/warehouses", warehouseHandler.GetWarehouses)
+		api.POST("/warehouses",
+			middleware.RequireShopPermission(shared.PermWarehouseWrite, shopAccessChecker, middleware.ShopIDFromJSON("shop_id")),
+			warehouseHandler.CreateWarehouse)
+		api.PUT("/warehouses/:id",
+			middleware.RequireShopPermission(shared.PermWarehouseWrite, shopAccessChecker, warehouseHandler.ShopIDFromWarehouseParam),
+			warehouseHandler.UpdateWarehouse)
+		api.POST("/warehouses/transfer",
+			middleware.RequireShopPermission(shared.PermStockWrite, shopAccessChecker, warehouseHandler.ShopIDFromWarehouseBody("from_warehouse_id")),
+			warehouseHandler.TransferStock)
+		api.GET("/warehouses/stock", warehouseHandler.GetStock)
+		api.GET("/warehouses/availability", warehouseHandler.GetAvailability)
+		api.PATCH("/warehouses/stock",
+			middleware.RequireShopPermission(shared.PermStockWrite, shopAccessChecker, warehouseHandler.ShopIDFromWarehouseBody("warehouse_id")),
+			warehouseHandler.UpdateStock)
+	}
+}
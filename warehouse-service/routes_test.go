@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/evrintobing17/ecommerce-system/shared"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse"
+	"github.com/evrintobing17/ecommerce-system/shared/jsonhttpresponse/responsetest"
+	"github.com/evrintobing17/ecommerce-system/shared/middleware"
+	"github.com/evrintobing17/ecommerce-system/warehouse-service/app"
+	delivery "github.com/evrintobing17/ecommerce-system/warehouse-service/app/delivery"
+	"github.com/evrintobing17/ecommerce-system/warehouse-service/app/models"
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "test-secret"
+
+// warehouseUsecaseStub serves warehouses 1 and 2 of shop 1 and warehouse 3
+// of shop 2. Variant 1 has stock in warehouse 1; reading the stock of any
+// other variant fails unexpectedly.
+type warehouseUsecaseStub struct {
+	app.WarehouseUsecase
+}
+
+func (u *warehouseUsecaseStub) GetWarehouse(ctx context.Context, id int) (*models.Warehouse, error) {
+	switch id {
+	case 1, 2:
+		return &models.Warehouse{ID: id, Name: "Main", ShopID: 1, Active: true}, nil
+	case 3:
+		return &models.Warehouse{ID: id, Name: "Other", ShopID: 2, Active: true}, nil
+	}
+	return nil, models.ErrWarehouseNotFound
+}
+
+func (u *warehouseUsecaseStub) GetWarehouses(ctx context.Context, shopID int, activeOnly bool) ([]*models.Warehouse, error) {
+	warehouse, _ := u.GetWarehouse(ctx, 1)
+	return []*models.Warehouse{warehouse}, nil
+}
+
+func (u *warehouseUsecaseStub) CreateWarehouse(ctx context.Context, name, location string, shopID int) (*models.Warehouse, error) {
+	return &models.Warehouse{ID: 4, Name: name, Location: location, ShopID: shopID, Active: true}, nil
+}
+
+func (u *warehouseUsecaseStub) UpdateWarehouse(ctx context.Context, id int, name, location string, active *bool) (*models.Warehouse, error) {
+	return u.GetWarehouse(ctx, id)
+}
+
+func (u *warehouseUsecaseStub) TransferStock(ctx context.Context, variantID, fromWarehouseID, toWarehouseID int, quantity int32) error {
+	if quantity > 10 {
+		return models.ErrInsufficientStock
+	}
+	return nil
+}
+
+func (u *warehouseUsecaseStub) GetStock(ctx context.Context, variantID, warehouseID int) (*models.Stock, error) {
+	if variantID != 1 {
+		return nil, errors.New("connection reset")
+	}
+	return &models.Stock{VariantID: variantID, WarehouseID: warehouseID, Quantity: 10}, nil
+}
+
+func (u *warehouseUsecaseStub) GetAvailability(ctx context.Context, variantIDs []int) ([]*models.VariantAvailability, error) {
+	return []*models.VariantAvailability{}, nil
+}
+
+func (u *warehouseUsecaseStub) AddStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
+	return &models.Stock{VariantID: variantID, WarehouseID: warehouseID, Quantity: quantity}, nil
+}
+
+func (u *warehouseUsecaseStub) SubtractStock(ctx context.Context, variantID, warehouseID int, quantity, reserved int32) (*models.Stock, error) {
+	return nil, models.ErrInsufficientQuantity
+}
+
+// shopAccessStub grants every action on shop 1
+type shopAccessStub struct{}
+
+func (shopAccessStub) CanAccessShop(ctx context.Context, claims *shared.Claims, shopID int, action string) (bool, error) {
+	return shopID == 1, nil
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.NoRoute(jsonhttpresponse.RouteNotFound)
+	registerRoutes(router, delivery.NewWarehouseHandler(&warehouseUsecaseStub{}), shopAccessStub{}, testJWTSecret)
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	user := responsetest.Token(t, shared.TokenSubject{UserID: 1, Role: shared.RoleShopOwner}, testJWTSecret)
+
+	responsetest.Run(t, newTestRouter(), []responsetest.Case{
+		{Name: "unknown route", Method: http.MethodGet, Path: "/api/v1/nothing", Status: http.StatusNotFound, Code: "route_not_found"},
+		{Name: "missing token", Method: http.MethodGet, Path: "/api/v1/warehouses", Status: http.StatusUnauthorized, Code: "authorization_required"},
+		{Name: "invalid token", Method: http.MethodGet, Path: "/api/v1/warehouses", Token: "invalid", Status: http.StatusUnauthorized, Code: "invalid_token"},
+
+		{Name: "get warehouse", Method: http.MethodGet, Path: "/api/v1/warehouses/1", Token: user, Status: http.StatusOK},
+		{Name: "get missing warehouse", Method: http.MethodGet, Path: "/api/v1/warehouses/9", Token: user, Status: http.StatusNotFound, Code: "warehouse_not_found"},
+		{Name: "list warehouses", Method: http.MethodGet, Path: "/api/v1/warehouses?shop_id=1", Token: user, Status: http.StatusOK},
+
+		{Name: "create warehouse", Method: http.MethodPost, Path: "/api/v1/warehouses", Token: user, Body: `{"name":"Main","location":"Jakarta","shop_id":1}`, Status: http.StatusCreated},
+		{Name: "create warehouse without fields", Method: http.MethodPost, Path: "/api/v1/warehouses", Token: user, Body: `{"shop_id":1}`, Status: http.StatusBadRequest, Code: "required", Fields: []string{"name", "location"}},
+		{Name: "create warehouse in another shop", Method: http.MethodPost, Path: "/api/v1/warehouses", Token: user, Body: `{"name":"Main","location":"Jakarta","shop_id":2}`, Status: http.StatusForbidden, Code: "permission_denied"},
+
+		{Name: "update warehouse", Method: http.MethodPut, Path: "/api/v1/warehouses/1", Token: user, Body: `{"active":false}`, Status: http.StatusOK},
+		{Name: "update warehouse with wrong type", Method: http.MethodPut, Path: "/api/v1/warehouses/1", Token: user, Body: `{"active":"no"}`, Status: http.StatusBadRequest, Code: "invalid_type", Fields: []string{"active"}},
+		{Name: "update missing warehouse", Method: http.MethodPut, Path: "/api/v1/warehouses/9", Token: user, Body: `{"active":false}`, Status: http.StatusNotFound, Code: "resource_not_found"},
+		{Name: "update warehouse of another shop", Method: http.MethodPut, Path: "/api/v1/warehouses/3", Token: user, Body: `{"active":false}`, Status: http.StatusForbidden, Code: "permission_denied"},
+
+		{Name: "transfer stock", Method: http.MethodPost, Path: "/api/v1/warehouses/transfer", Token: user, Body: `{"variant_id":1,"from_warehouse_id":1,"to_warehouse_id":2,"quantity":5}`, Status: http.StatusOK},
+		{Name: "transfer too much stock", Method: http.MethodPost, Path: "/api/v1/warehouses/transfer", Token: user, Body: `{"variant_id":1,"from_warehouse_id":1,"to_warehouse_id":2,"quantity":11}`, Status: http.StatusUnprocessableEntity, Code: "insufficient_stock"},
+		{Name: "transfer invalid quantity", Method: http.MethodPost, Path: "/api/v1/warehouses/transfer", Token: user, Body: `{"from_warehouse_id":1,"to_warehouse_id":2,"quantity":-1}`, Status: http.StatusBadRequest, Fields: []string{"variant_id", "quantity"}},
+
+		{Name: "get stock", Method: http.MethodGet, Path: "/api/v1/warehouses/stock?variant_id=1&warehouse_id=1", Token: user, Status: http.StatusOK},
+		{Name: "get stock without parameters", Method: http.MethodGet, Path: "/api/v1/warehouses/stock", Token: user, Status: http.StatusBadRequest, Code: "invalid_parameter"},
+		{Name: "get stock failing", Method: http.MethodGet, Path: "/api/v1/warehouses/stock?variant_id=2&warehouse_id=1", Token: user, Status: http.StatusInternalServerError, Code: "internal"},
+		{Name: "get availability", Method: http.MethodGet, Path: "/api/v1/warehouses/availability?variant_ids=1,2", Token: user, Status: http.StatusOK},
+		{Name: "get availability of invalid variant", Method: http.MethodGet, Path: "/api/v1/warehouses/availability?variant_ids=a", Token: user, Status: http.StatusBadRequest, Code: "invalid_parameter"},
+
+		{Name: "add stock", Method: http.MethodPatch, Path: "/api/v1/warehouses/stock", Token: user, Body: `{"variant_id":1,"warehouse_id":1,"operation":"add","quantity":5}`, Status: http.StatusOK},
+		{Name: "subtract too much stock", Method: http.MethodPatch, Path: "/api/v1/warehouses/stock", Token: user, Body: `{"variant_id":1,"warehouse_id":1,"operation":"subtract","quantity":5}`, Status: http.StatusUnprocessableEntity, Code: "insufficient_quantity"},
+		{Name: "update stock with unknown operation", Method: http.MethodPatch, Path: "/api/v1/warehouses/stock", Token: user, Body: `{"variant_id":1,"warehouse_id":1,"operation":"move","quantity":5}`, Status: http.StatusBadRequest, Code: "oneof", Fields: []string{"operation"}},
+		{Name: "update stock of another shop", Method: http.MethodPatch, Path: "/api/v1/warehouses/stock", Token: user, Body: `{"variant_id":1,"warehouse_id":3,"operation":"add","quantity":5}`, Status: http.StatusForbidden, Code: "permission_denied"},
+	})
+}
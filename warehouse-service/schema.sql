@@ -1,19 +0,0 @@
-CREATE TABLE warehouses (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    location VARCHAR(500),
-    is_active BOOLEAN DEFAULT TRUE,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
-);
-
-CREATE TABLE stock (
-    id SERIAL PRIMARY KEY,
-    product_id SERIAL NOT NULL,
-    warehouse_id SERIAL REFERENCES warehouses(id),
-    quantity INTEGER NOT NULL DEFAULT 0,
-    reserved INTEGER NOT NULL DEFAULT 0,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW(),
-    UNIQUE(product_id, warehouse_id)
-);
\ No newline at end of file